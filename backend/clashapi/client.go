@@ -0,0 +1,482 @@
+package clashapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// ============================================================================
+// Clash API 客户端（Mihomo external-controller 与 Sing-Box clash_api 通用）
+// ============================================================================
+
+// DefaultAddress 控制器默认地址
+const DefaultAddress = "127.0.0.1:9090"
+
+// defaultTimeout 普通请求超时
+const defaultTimeout = 10 * time.Second
+
+// Client Clash API 客户端
+type Client struct {
+	addr   string
+	socket string // unix socket 路径，非空时通过 socket 访问
+	secret string
+	http   *http.Client
+}
+
+// APIError 控制器返回的错误
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("控制器返回 %d: %s", e.StatusCode, e.Message)
+}
+
+// New 创建客户端，addr 为空时使用默认地址
+func New(addr, secret string) *Client {
+	if addr == "" {
+		addr = DefaultAddress
+	}
+	// 监听所有地址时通过本机访问
+	if host, port, err := net.SplitHostPort(addr); err == nil && (host == "" || host == "0.0.0.0" || host == "::") {
+		addr = net.JoinHostPort("127.0.0.1", port)
+	}
+	return &Client{
+		addr:   addr,
+		secret: secret,
+		http:   &http.Client{Timeout: defaultTimeout},
+	}
+}
+
+// NewUnix 创建通过 unix socket 访问控制器的客户端（仅 Mihomo 支持）
+func NewUnix(socketPath, secret string) *Client {
+	c := &Client{
+		addr:   "unix",
+		socket: socketPath,
+		secret: secret,
+	}
+	c.http = &http.Client{
+		Timeout:   defaultTimeout,
+		Transport: &http.Transport{DialContext: c.dialUnix},
+	}
+	return c
+}
+
+// dialUnix 连接 unix socket，忽略请求地址
+func (c *Client) dialUnix(ctx context.Context, _, _ string) (net.Conn, error) {
+	var d net.Dialer
+	return d.DialContext(ctx, "unix", c.socket)
+}
+
+// CloseIdleConnections 关闭空闲连接（客户端不再使用时调用）
+func (c *Client) CloseIdleConnections() {
+	c.http.CloseIdleConnections()
+}
+
+// Address 控制器地址
+func (c *Client) Address() string {
+	if c.socket != "" {
+		return "unix:" + c.socket
+	}
+	return c.addr
+}
+
+// url 拼接请求地址
+func (c *Client) url(scheme, path string, query url.Values) string {
+	u := scheme + "://" + c.addr + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	return u
+}
+
+// header 认证请求头
+func (c *Client) header() http.Header {
+	h := http.Header{}
+	if c.secret != "" {
+		h.Set("Authorization", "Bearer "+c.secret)
+	}
+	return h
+}
+
+// newRequest 构建请求
+func (c *Client) newRequest(ctx context.Context, method, path string, query url.Values, body interface{}) (*http.Request, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.url("http", path, query), reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header = c.header()
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return req, nil
+}
+
+// do 发送请求，out 非空时解析 JSON 响应
+func (c *Client) do(method, path string, query url.Values, body, out interface{}) error {
+	req, err := c.newRequest(context.Background(), method, path, query, body)
+	if err != nil {
+		return err
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("控制器不可用: %w", err)
+	}
+	defer resp.Body.Close()
+
+	data, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode >= 300 {
+		return &APIError{StatusCode: resp.StatusCode, Message: errorMessage(data)}
+	}
+	if out == nil || len(data) == 0 {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
+
+// errorMessage 提取错误响应中的 message 字段
+func errorMessage(data []byte) string {
+	var body struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal(data, &body) == nil && body.Message != "" {
+		return body.Message
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// escape 转义路径中的名称
+func escape(name string) string {
+	return url.PathEscape(name)
+}
+
+// ========== 代理与代理组 ==========
+
+// Proxies 获取全部代理与代理组
+func (c *Client) Proxies() (map[string]*Proxy, error) {
+	var result struct {
+		Proxies map[string]*Proxy `json:"proxies"`
+	}
+	if err := c.do("GET", "/proxies", nil, nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Proxies, nil
+}
+
+// Proxy 获取单个代理或代理组
+func (c *Client) Proxy(name string) (*Proxy, error) {
+	var proxy Proxy
+	if err := c.do("GET", "/proxies/"+escape(name), nil, nil, &proxy); err != nil {
+		return nil, err
+	}
+	return &proxy, nil
+}
+
+// Groups 获取全部代理组
+func (c *Client) Groups() ([]*Proxy, error) {
+	proxies, err := c.Proxies()
+	if err != nil {
+		return nil, err
+	}
+	var groups []*Proxy
+	for _, p := range proxies {
+		if p.IsGroup() {
+			groups = append(groups, p)
+		}
+	}
+	return groups, nil
+}
+
+// SelectProxy 切换选择器分组的选中项
+func (c *Client) SelectProxy(group, name string) error {
+	return c.do("PUT", "/proxies/"+escape(group), nil, map[string]string{"name": name}, nil)
+}
+
+// ProxyDelay 测试代理延迟（毫秒），timeout 单位为毫秒
+func (c *Client) ProxyDelay(name, testURL string, timeout int) (int, error) {
+	query := url.Values{}
+	query.Set("url", testURL)
+	query.Set("timeout", strconv.Itoa(timeout))
+	var result struct {
+		Delay int `json:"delay"`
+	}
+	if err := c.do("GET", "/proxies/"+escape(name)+"/delay", query, nil, &result); err != nil {
+		return 0, err
+	}
+	if result.Delay <= 0 {
+		return 0, fmt.Errorf("%s 测速失败", name)
+	}
+	return result.Delay, nil
+}
+
+// GroupDelay 测试代理组所有成员延迟，返回 成员 -> 延迟（毫秒）
+func (c *Client) GroupDelay(group, testURL string, timeout int) (map[string]int, error) {
+	query := url.Values{}
+	query.Set("url", testURL)
+	query.Set("timeout", strconv.Itoa(timeout))
+	result := make(map[string]int)
+	if err := c.do("GET", "/group/"+escape(group)+"/delay", query, nil, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ========== 连接 ==========
+
+// Connections 获取活动连接
+func (c *Client) Connections() (*Connections, error) {
+	var result Connections
+	if err := c.do("GET", "/connections", nil, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CloseConnection 关闭单个连接
+func (c *Client) CloseConnection(id string) error {
+	return c.do("DELETE", "/connections/"+escape(id), nil, nil, nil)
+}
+
+// CloseAllConnections 关闭所有连接
+func (c *Client) CloseAllConnections() error {
+	return c.do("DELETE", "/connections", nil, nil, nil)
+}
+
+// ========== 提供者 ==========
+
+// ProxyProviders 获取代理提供者
+func (c *Client) ProxyProviders() (map[string]*ProxyProvider, error) {
+	var result struct {
+		Providers map[string]*ProxyProvider `json:"providers"`
+	}
+	if err := c.do("GET", "/providers/proxies", nil, nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Providers, nil
+}
+
+// UpdateProxyProvider 更新代理提供者
+func (c *Client) UpdateProxyProvider(name string) error {
+	return c.do("PUT", "/providers/proxies/"+escape(name), nil, nil, nil)
+}
+
+// HealthCheckProxyProvider 对代理提供者进行健康检查
+func (c *Client) HealthCheckProxyProvider(name string) error {
+	return c.do("GET", "/providers/proxies/"+escape(name)+"/healthcheck", nil, nil, nil)
+}
+
+// RuleProviders 获取规则提供者
+func (c *Client) RuleProviders() (map[string]*RuleProvider, error) {
+	var result struct {
+		Providers map[string]*RuleProvider `json:"providers"`
+	}
+	if err := c.do("GET", "/providers/rules", nil, nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Providers, nil
+}
+
+// UpdateRuleProvider 更新规则提供者
+func (c *Client) UpdateRuleProvider(name string) error {
+	return c.do("PUT", "/providers/rules/"+escape(name), nil, nil, nil)
+}
+
+// ========== 规则与配置 ==========
+
+// Rules 获取当前生效的规则
+func (c *Client) Rules() ([]Rule, error) {
+	var result struct {
+		Rules []Rule `json:"rules"`
+	}
+	if err := c.do("GET", "/rules", nil, nil, &result); err != nil {
+		return nil, err
+	}
+	return result.Rules, nil
+}
+
+// Configs 获取运行配置
+func (c *Client) Configs() (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	if err := c.do("GET", "/configs", nil, nil, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// PatchConfigs 热更新运行配置（如 mode、log-level）
+func (c *Client) PatchConfigs(patch map[string]interface{}) error {
+	return c.do("PATCH", "/configs", nil, patch, nil)
+}
+
+// SetMode 热切换运行模式
+func (c *Client) SetMode(mode string) error {
+	return c.PatchConfigs(map[string]interface{}{"mode": mode})
+}
+
+// ReloadConfigs 重新加载配置文件，path 为空时重载当前配置（仅 Mihomo 支持）
+func (c *Client) ReloadConfigs(path string, force bool) error {
+	query := url.Values{}
+	if force {
+		query.Set("force", "true")
+	}
+	return c.do("PUT", "/configs", query, map[string]string{"path": path}, nil)
+}
+
+// FlushFakeIP 清空 FakeIP 缓存
+func (c *Client) FlushFakeIP() error {
+	return c.do("POST", "/cache/fakeip/flush", nil, nil, nil)
+}
+
+// Version 获取核心版本
+func (c *Client) Version() (*Version, error) {
+	var result Version
+	if err := c.do("GET", "/version", nil, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DNSQuery 通过核心 DNS 查询（仅 Mihomo 支持），qtype 为空时查询 A 记录
+func (c *Client) DNSQuery(name, qtype string) (*DNSResult, error) {
+	query := url.Values{}
+	query.Set("name", name)
+	if qtype != "" {
+		query.Set("type", qtype)
+	}
+	var result DNSResult
+	if err := c.do("GET", "/dns/query", query, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ========== 流式接口 ==========
+
+// stream 读取流式 JSON 接口，handle 返回 false 或 ctx 取消时结束
+func (c *Client) stream(ctx context.Context, path string, query url.Values, handle func(json.RawMessage) bool) error {
+	req, err := c.newRequest(ctx, "GET", path, query, nil)
+	if err != nil {
+		return err
+	}
+	// 流式接口不设置整体超时
+	resp, err := (&http.Client{Transport: c.http.Transport}).Do(req)
+	if err != nil {
+		return fmt.Errorf("控制器不可用: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(resp.Body)
+		return &APIError{StatusCode: resp.StatusCode, Message: errorMessage(data)}
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var msg json.RawMessage
+		if err := decoder.Decode(&msg); err != nil {
+			if ctx.Err() != nil || err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if !handle(msg) {
+			return nil
+		}
+	}
+}
+
+// StreamTraffic 订阅实时流量（每秒一条）
+func (c *Client) StreamTraffic(ctx context.Context, handle func(Traffic) bool) error {
+	return c.stream(ctx, "/traffic", nil, func(msg json.RawMessage) bool {
+		var t Traffic
+		if json.Unmarshal(msg, &t) != nil {
+			return true
+		}
+		return handle(t)
+	})
+}
+
+// StreamMemory 订阅内存占用
+func (c *Client) StreamMemory(ctx context.Context, handle func(Memory) bool) error {
+	return c.stream(ctx, "/memory", nil, func(msg json.RawMessage) bool {
+		var m Memory
+		if json.Unmarshal(msg, &m) != nil {
+			return true
+		}
+		return handle(m)
+	})
+}
+
+// StreamLogs 订阅核心日志，level 为空时使用核心默认级别
+func (c *Client) StreamLogs(ctx context.Context, level string, handle func(LogEntry) bool) error {
+	query := url.Values{}
+	if level != "" {
+		query.Set("level", level)
+	}
+	return c.stream(ctx, "/logs", query, func(msg json.RawMessage) bool {
+		var entry LogEntry
+		if json.Unmarshal(msg, &entry) != nil {
+			return true
+		}
+		return handle(entry)
+	})
+}
+
+// DialWebSocket 连接控制器 WebSocket 接口（traffic、logs、connections 等），rawQuery 原样转发
+func (c *Client) DialWebSocket(path, rawQuery string) (*websocket.Conn, error) {
+	u := "ws://" + c.addr + path
+	if rawQuery != "" {
+		u += "?" + rawQuery
+	}
+	dialer := *websocket.DefaultDialer
+	if c.socket != "" {
+		dialer.NetDialContext = c.dialUnix
+	}
+	conn, _, err := dialer.Dial(u, c.header())
+	return conn, err
+}
+
+// Forward 将请求转发到控制器并附加密钥，rawPath 为已转义的控制器路径（支持 WebSocket 升级与流式响应）
+func (c *Client) Forward(w http.ResponseWriter, r *http.Request, rawPath string) {
+	path, err := url.PathUnescape(rawPath)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	proxy := &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			req.URL.Scheme = "http"
+			req.URL.Host = c.addr
+			req.URL.Path = path
+			req.URL.RawPath = rawPath
+			req.Host = c.addr
+			req.Header.Del("Authorization")
+			if c.secret != "" {
+				req.Header.Set("Authorization", "Bearer "+c.secret)
+			}
+		},
+		Transport:     c.http.Transport,
+		FlushInterval: -1,
+	}
+	proxy.ServeHTTP(w, r)
+}
@@ -0,0 +1,151 @@
+package clashapi
+
+import "time"
+
+// ============================================================================
+// Clash API 数据结构（Mihomo 与 Sing-Box clash_api 通用字段）
+// ============================================================================
+
+// DelayHistory 延迟测试历史
+type DelayHistory struct {
+	Time  time.Time `json:"time"`
+	Delay int       `json:"delay"`
+}
+
+// Proxy 代理节点或代理组
+type Proxy struct {
+	Name    string         `json:"name"`
+	Type    string         `json:"type"`
+	UDP     bool           `json:"udp"`
+	Alive   *bool          `json:"alive,omitempty"`
+	History []DelayHistory `json:"history"`
+	Now     string         `json:"now,omitempty"`     // 代理组当前选中
+	All     []string       `json:"all,omitempty"`     // 代理组成员
+	Hidden  bool           `json:"hidden,omitempty"`  // 代理组是否隐藏
+	Icon    string         `json:"icon,omitempty"`    // 代理组图标
+	TestURL string         `json:"testUrl,omitempty"` // 代理组测速地址
+}
+
+// IsGroup 是否为代理组
+func (p *Proxy) IsGroup() bool {
+	return p.All != nil
+}
+
+// IsSelector 是否为手动选择器分组
+func (p *Proxy) IsSelector() bool {
+	return p.Type == "Selector"
+}
+
+// Metadata 连接元数据
+type Metadata struct {
+	Network         string `json:"network"`
+	Type            string `json:"type"`
+	SourceIP        string `json:"sourceIP"`
+	DestinationIP   string `json:"destinationIP"`
+	SourcePort      string `json:"sourcePort"`
+	DestinationPort string `json:"destinationPort"`
+	Host            string `json:"host"`
+	DNSMode         string `json:"dnsMode,omitempty"`
+	Process         string `json:"process,omitempty"`
+	ProcessPath     string `json:"processPath,omitempty"`
+	InboundName     string `json:"inboundName,omitempty"`
+	InboundUser     string `json:"inboundUser,omitempty"`
+	SniffHost       string `json:"sniffHost,omitempty"`
+}
+
+// Connection 活动连接
+type Connection struct {
+	ID          string    `json:"id"`
+	Metadata    Metadata  `json:"metadata"`
+	Upload      int64     `json:"upload"`
+	Download    int64     `json:"download"`
+	Start       time.Time `json:"start"`
+	Chains      []string  `json:"chains"`
+	Rule        string    `json:"rule"`
+	RulePayload string    `json:"rulePayload"`
+}
+
+// Connections 连接快照
+type Connections struct {
+	DownloadTotal int64        `json:"downloadTotal"`
+	UploadTotal   int64        `json:"uploadTotal"`
+	Connections   []Connection `json:"connections"`
+	Memory        int64        `json:"memory,omitempty"`
+}
+
+// ProxyProvider 代理提供者
+type ProxyProvider struct {
+	Name             string           `json:"name"`
+	Type             string           `json:"type"`
+	VehicleType      string           `json:"vehicleType"`
+	Proxies          []Proxy          `json:"proxies"`
+	TestURL          string           `json:"testUrl,omitempty"`
+	UpdatedAt        *time.Time       `json:"updatedAt,omitempty"`
+	SubscriptionInfo map[string]int64 `json:"subscriptionInfo,omitempty"`
+}
+
+// RuleProvider 规则提供者
+type RuleProvider struct {
+	Name        string     `json:"name"`
+	Type        string     `json:"type"`
+	VehicleType string     `json:"vehicleType"`
+	Behavior    string     `json:"behavior"`
+	Format      string     `json:"format,omitempty"`
+	RuleCount   int        `json:"ruleCount"`
+	UpdatedAt   *time.Time `json:"updatedAt,omitempty"`
+}
+
+// Rule 规则
+type Rule struct {
+	Type    string `json:"type"`
+	Payload string `json:"payload"`
+	Proxy   string `json:"proxy"`
+	Size    int    `json:"size,omitempty"`
+}
+
+// Traffic 实时流量（字节/秒）
+type Traffic struct {
+	Up   int64 `json:"up"`
+	Down int64 `json:"down"`
+}
+
+// Memory 内存占用
+type Memory struct {
+	InUse   int64 `json:"inuse"`
+	OSLimit int64 `json:"oslimit"`
+}
+
+// LogEntry 核心日志
+type LogEntry struct {
+	Type    string `json:"type"`
+	Payload string `json:"payload"`
+}
+
+// Version 核心版本
+type Version struct {
+	Version string `json:"version"`
+	Meta    bool   `json:"meta,omitempty"`    // Mihomo
+	Premium bool   `json:"premium,omitempty"` // Sing-Box 兼容字段
+}
+
+// DNSAnswer DNS 应答记录
+type DNSAnswer struct {
+	Name string `json:"name"`
+	Type int    `json:"type"`
+	TTL  int    `json:"TTL"`
+	Data string `json:"data"`
+}
+
+// DNSQuestion DNS 查询问题
+type DNSQuestion struct {
+	Name   string `json:"Name"`
+	Qtype  int    `json:"Qtype"`
+	Qclass int    `json:"Qclass"`
+}
+
+// DNSResult DNS 查询结果（仅 Mihomo 支持）
+type DNSResult struct {
+	Status   int           `json:"Status"`
+	Question []DNSQuestion `json:"Question"`
+	Answer   []DNSAnswer   `json:"Answer,omitempty"`
+}
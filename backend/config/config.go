@@ -42,9 +42,12 @@ type ProxyConfig struct {
 
 // LogConfig 日志配置
 type LogConfig struct {
-	Level   string `yaml:"level"` // debug, info, warn, error
-	File    string `yaml:"file"`
-	Console bool   `yaml:"console"`
+	Level      string `yaml:"level"` // debug, info, warn, error
+	File       string `yaml:"file"`
+	Console    bool   `yaml:"console"`
+	MaxSize    int    `yaml:"max_size"`    // 单个日志文件大小上限（MB）
+	MaxAge     int    `yaml:"max_age"`     // 旧日志保留天数
+	MaxBackups int    `yaml:"max_backups"` // 旧日志保留数量
 }
 
 // SecurityConfig 安全配置
@@ -116,9 +119,12 @@ func DefaultConfig() *Config {
 			Mode:      "rule",
 		},
 		Log: LogConfig{
-			Level:   "info",
-			File:    filepath.Join(dataDir, "logs", "p-box.log"),
-			Console: true,
+			Level:      "info",
+			File:       filepath.Join(dataDir, "logs", "p-box.log"),
+			Console:    true,
+			MaxSize:    10,
+			MaxAge:     7,
+			MaxBackups: 5,
 		},
 		Security: SecurityConfig{
 			Enabled:  false,
@@ -158,8 +164,11 @@ func (c *Config) ensureAbsolutePaths() {
 		c.DataDir = filepath.Join(baseDir, c.DataDir)
 	}
 
-	// Log.File
-	if c.Log.File != "" && !filepath.IsAbs(c.Log.File) {
+	// Log.File，未配置时写入数据目录
+	if c.Log.File == "" {
+		c.Log.File = filepath.Join(c.DataDir, "logs", "p-box.log")
+	}
+	if !filepath.IsAbs(c.Log.File) {
 		c.Log.File = filepath.Join(baseDir, c.Log.File)
 	}
 }
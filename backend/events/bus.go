@@ -0,0 +1,124 @@
+package events
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// historySize 保留的最近事件数量（用于断线重连后补发）
+const historySize = 200
+
+// Event 事件
+type Event struct {
+	ID   int64       `json:"id"` // 递增序号
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data,omitempty"`
+}
+
+// Bus 进程内事件总线
+// On 注册的处理函数在发布者的 goroutine 中同步执行，Subscribe 的通道订阅者处理不过来时丢弃事件
+type Bus struct {
+	seq      int64
+	handlers map[string][]func(Event)
+	subs     map[*subscription]struct{}
+	history  []Event
+	mu       sync.RWMutex
+}
+
+// subscription 通道订阅
+type subscription struct {
+	ch       chan Event
+	prefixes []string
+}
+
+// matches 判断事件类型是否匹配订阅前缀，未指定前缀时匹配全部
+func (s *subscription) matches(eventType string) bool {
+	return matchTypes(s.prefixes, eventType)
+}
+
+// matchTypes 事件类型匹配（前缀匹配，例如 core. 匹配全部核心事件）
+func matchTypes(prefixes []string, eventType string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, p := range prefixes {
+		if strings.HasPrefix(eventType, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewBus 创建事件总线
+func NewBus() *Bus {
+	return &Bus{
+		handlers: make(map[string][]func(Event)),
+		subs:     make(map[*subscription]struct{}),
+	}
+}
+
+// Publish 发布事件，总线为 nil 时忽略
+func (b *Bus) Publish(eventType string, data interface{}) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	b.seq++
+	event := Event{ID: b.seq, Type: eventType, Time: time.Now(), Data: data}
+	b.history = append(b.history, event)
+	if len(b.history) > historySize {
+		b.history = b.history[len(b.history)-historySize:]
+	}
+	handlers := append([]func(Event){}, b.handlers[eventType]...)
+	for sub := range b.subs {
+		if !sub.matches(eventType) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+		}
+	}
+	b.mu.Unlock()
+
+	for _, handler := range handlers {
+		handler(event)
+	}
+}
+
+// On 注册同步处理函数
+func (b *Bus) On(eventType string, handler func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[eventType] = append(b.handlers[eventType], handler)
+}
+
+// Subscribe 订阅事件，prefixes 为事件类型前缀，返回的取消函数用于退订
+func (b *Bus) Subscribe(prefixes ...string) (<-chan Event, func()) {
+	sub := &subscription{ch: make(chan Event, 64), prefixes: prefixes}
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	return sub.ch, func() {
+		b.mu.Lock()
+		delete(b.subs, sub)
+		b.mu.Unlock()
+	}
+}
+
+// Since 获取序号大于 id 的历史事件
+func (b *Bus) Since(id int64, prefixes ...string) []Event {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	result := []Event{}
+	for _, event := range b.history {
+		if event.ID > id && matchTypes(prefixes, event.Type) {
+			result = append(result, event)
+		}
+	}
+	return result
+}
@@ -0,0 +1,85 @@
+package events
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// Handler 事件推送处理器
+type Handler struct {
+	bus *Bus
+}
+
+// NewHandler 创建事件推送处理器
+func NewHandler(bus *Bus) *Handler {
+	return &Handler{bus: bus}
+}
+
+// HandleWebSocket 事件推送 WebSocket
+// types=core.,subscription. 按类型前缀过滤；since=ID 先补发该序号之后的历史事件
+func (h *Handler) HandleWebSocket(c *gin.Context) {
+	var prefixes []string
+	for _, p := range strings.Split(c.Query("types"), ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			prefixes = append(prefixes, p)
+		}
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// 先订阅再补发历史，避免两者之间的事件丢失
+	ch, cancel := h.bus.Subscribe(prefixes...)
+	defer cancel()
+
+	var lastID int64
+	if v := c.Query("since"); v != "" {
+		since, _ := strconv.ParseInt(v, 10, 64)
+		for _, event := range h.bus.Since(since, prefixes...) {
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+			lastID = event.ID
+		}
+	}
+
+	// 客户端断开时结束推送
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case event := <-ch:
+			if event.ID <= lastID {
+				continue
+			}
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
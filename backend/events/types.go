@@ -0,0 +1,92 @@
+package events
+
+// ============================================================================
+// 事件类型与负载定义
+// ============================================================================
+
+// 事件类型（订阅时可按前缀过滤，例如 core.）
+const (
+	CoreStarted    = "core.started"          // 核心启动，负载 CorePayload
+	CoreStopped    = "core.stopped"          // 核心停止，负载 CorePayload
+	CoreCrashed    = "core.crashed"          // 核心意外退出，负载 CorePayload
+	CoreSwitched   = "core.switched"         // 切换核心类型，负载 CorePayload
+	CoreDownload   = "core.download"         // 核心下载状态变化，负载 CoreDownloadPayload
+	CoreUpdate     = "core.update_available" // 已安装核心有新版本，负载 CoreUpdatePayload
+	CoreActivated  = "core.activated"        // 切换核心版本，负载 CoreVersionPayload
+	CoreRollback   = "core.rollback"         // 新版本校验失败已自动回滚，负载 CoreVersionPayload
+	SubUpdated     = "subscription.updated"  // 订阅更新完成（含失败），负载 SubscriptionPayload
+	RulesetUpdated = "ruleset.updated"       // 规则集下载完成，负载 RulesetPayload
+	WGApplied      = "wireguard.applied"     // WireGuard 接口应用配置（含失败），负载 WireGuardPayload
+	WGStopped      = "wireguard.stopped"     // WireGuard 接口停止，负载 WireGuardPayload
+	FailoverSwitch = "failover.switched"     // 故障转移切换节点，负载 FailoverPayload
+	FailoverDown   = "failover.all_down"     // 分组内没有可用成员，负载 FailoverPayload
+)
+
+// CorePayload 代理核心状态
+type CorePayload struct {
+	Core  string `json:"core"` // mihomo, singbox
+	PID   int    `json:"pid,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// 核心下载状态
+const (
+	DownloadStarted   = "started"
+	DownloadCompleted = "completed"
+	DownloadFailed    = "failed"
+)
+
+// CoreDownloadPayload 核心下载状态
+type CoreDownloadPayload struct {
+	Core  string `json:"core"`
+	State string `json:"state"` // started, completed, failed
+	Error string `json:"error,omitempty"`
+}
+
+// CoreUpdatePayload 核心新版本
+type CoreUpdatePayload struct {
+	Core    string `json:"core"`
+	Current string `json:"current"`
+	Latest  string `json:"latest"`
+}
+
+// CoreVersionPayload 核心版本切换
+type CoreVersionPayload struct {
+	Core     string `json:"core"`
+	Version  string `json:"version"`            // 当前使用的版本
+	Previous string `json:"previous,omitempty"` // 切换前的版本；回滚时为校验失败的版本
+	Error    string `json:"error,omitempty"`
+}
+
+// SubscriptionPayload 订阅更新结果
+type SubscriptionPayload struct {
+	ID        string `json:"id"`
+	Name      string `json:"name"`
+	Success   bool   `json:"success"`
+	NodeCount int    `json:"nodeCount"`
+	Error     string `json:"error,omitempty"`
+}
+
+// RulesetPayload 规则集下载结果
+type RulesetPayload struct {
+	Success int      `json:"success"`
+	Failed  int      `json:"failed"`
+	Errors  []string `json:"errors,omitempty"`
+}
+
+// WireGuardPayload WireGuard 接口状态
+type WireGuardPayload struct {
+	ServerID string `json:"serverId,omitempty"`
+	Tag      string `json:"tag"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+}
+
+// FailoverPayload 故障转移状态
+type FailoverPayload struct {
+	Group  string `json:"group"`
+	From   string `json:"from"`
+	To     string `json:"to,omitempty"`
+	Reason string `json:"reason,omitempty"` // failover, rotate
+	Detail string `json:"detail,omitempty"`
+}
@@ -0,0 +1,164 @@
+package logging
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+// Handler 日志处理器
+type Handler struct {
+	manager *Manager
+}
+
+// NewHandler 创建日志处理器
+func NewHandler(manager *Manager) *Handler {
+	return &Handler{manager: manager}
+}
+
+// RegisterRoutes 注册路由
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("", h.QueryLogs)
+}
+
+// parseQuery 解析查询参数：level, source, keyword, from, to (RFC3339), cursor, limit
+func parseQuery(c *gin.Context) (Query, error) {
+	q := Query{
+		Level:   c.Query("level"),
+		Source:  c.Query("source"),
+		Keyword: c.Query("keyword"),
+	}
+	if v := c.Query("from"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return q, err
+		}
+		q.From = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return q, err
+		}
+		q.To = t
+	}
+	if v := c.Query("cursor"); v != "" {
+		cursor, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return q, err
+		}
+		q.Cursor = cursor
+	}
+	q.Limit, _ = strconv.Atoi(c.DefaultQuery("limit", "200"))
+	return q, nil
+}
+
+// QueryLogs 查询日志（按时间倒序，使用 nextCursor 翻页）
+func (h *Handler) QueryLogs(c *gin.Context) {
+	q, err := parseQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	page, err := h.manager.Query(q)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    page,
+	})
+}
+
+// HandleTail 实时推送日志 WebSocket，不依赖代理核心
+// 支持与查询相同的 level, source, keyword 过滤；backlog=N 先推送最近 N 条
+func (h *Handler) HandleTail(c *gin.Context) {
+	q, err := parseQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+	if q.Source != "" {
+		if _, ok := h.manager.writers[q.Source]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    1,
+				"message": "不支持的日志来源: " + q.Source,
+			})
+			return
+		}
+	}
+
+	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// 先订阅再读取历史，避免两者之间的日志丢失
+	entries, cancel := h.manager.Subscribe()
+	defer cancel()
+
+	var lastSeq int64
+	if backlog, _ := strconv.Atoi(c.Query("backlog")); backlog > 0 {
+		history := q
+		history.Limit = backlog
+		if page, err := h.manager.Query(history); err == nil {
+			for i := len(page.Entries) - 1; i >= 0; i-- {
+				if err := conn.WriteJSON(page.Entries[i]); err != nil {
+					return
+				}
+				lastSeq = page.Entries[i].Seq
+			}
+		}
+	}
+
+	// 客户端断开时结束推送
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	live := Query{Level: q.Level, Keyword: q.Keyword}
+	for {
+		select {
+		case entry := <-entries:
+			if entry.Seq <= lastSeq || (q.Source != "" && entry.Source != q.Source) || !live.match(entry) {
+				continue
+			}
+			if err := conn.WriteJSON(entry); err != nil {
+				return
+			}
+		case <-done:
+			return
+		}
+	}
+}
@@ -0,0 +1,394 @@
+package logging
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 日志来源
+const (
+	SourceApp  = "app"  // P-BOX 自身输出
+	SourceCore = "core" // 代理核心输出
+)
+
+// 日志级别（数值越大越严重）
+var levelRank = map[string]int{
+	"debug": 0,
+	"info":  1,
+	"warn":  2,
+	"error": 3,
+}
+
+// Entry 日志条目，以 JSON Lines 写入文件
+type Entry struct {
+	Seq     int64     `json:"seq"` // 全局递增序号，用作分页游标
+	Time    time.Time `json:"time"`
+	Level   string    `json:"level"`
+	Source  string    `json:"source"`
+	Message string    `json:"message"`
+}
+
+// Options 日志配置
+type Options struct {
+	Level      string // 最低记录级别
+	File       string // P-BOX 日志文件，核心日志写入同目录的 core.log
+	Console    bool   // 同时输出到控制台
+	MaxSize    int    // 单个文件大小上限（MB）
+	MaxAge     int    // 旧文件保留天数
+	MaxBackups int    // 旧文件保留数量
+}
+
+// Manager 日志管理器
+type Manager struct {
+	level   int
+	console bool
+	writers map[string]*RotatingWriter
+	stdout  *os.File // 接管前的标准输出
+	closed  bool
+	seq     int64
+	subs    map[chan Entry]struct{}
+	mu      sync.Mutex
+}
+
+// New 创建日志管理器
+func New(opts Options) (*Manager, error) {
+	m := &Manager{
+		level:   levelRank[normalizeLevel(opts.Level)],
+		console: opts.Console,
+		writers: make(map[string]*RotatingWriter),
+		stdout:  os.Stdout,
+		subs:    make(map[chan Entry]struct{}),
+	}
+	paths := map[string]string{
+		SourceApp:  opts.File,
+		SourceCore: filepath.Join(filepath.Dir(opts.File), "core.log"),
+	}
+	for source, path := range paths {
+		w, err := NewRotatingWriter(path, opts.MaxSize, opts.MaxAge, opts.MaxBackups)
+		if err != nil {
+			m.Close()
+			return nil, err
+		}
+		m.writers[source] = w
+		if seq := lastSeq(w.Files()); seq > m.seq {
+			m.seq = seq
+		}
+	}
+	return m, nil
+}
+
+// Log 记录一条日志，level 为空时根据内容推断
+func (m *Manager) Log(source, level, message string) {
+	message = strings.TrimRight(ansiPattern.ReplaceAllString(message, ""), "\r\n")
+	if message == "" {
+		return
+	}
+	if level == "" {
+		level = DetectLevel(message)
+	}
+	level = normalizeLevel(level)
+	if levelRank[level] < m.level {
+		return
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.closed {
+		// 关闭后管道中剩余的输出直接写到控制台
+		fmt.Fprintln(m.stdout, message)
+		return
+	}
+
+	m.seq++
+	entry := Entry{Seq: m.seq, Time: time.Now(), Level: level, Source: source, Message: message}
+	if w, ok := m.writers[source]; ok {
+		data, _ := json.Marshal(entry)
+		if _, err := w.Write(append(data, '\n')); err != nil {
+			fmt.Fprintf(m.stdout, "⚠️ 写入日志失败: %v\n", err)
+		}
+	}
+	if m.console {
+		fmt.Fprintln(m.stdout, message)
+	}
+	for ch := range m.subs {
+		select {
+		case ch <- entry:
+		default:
+			// 订阅者处理不过来时丢弃，避免阻塞写日志
+		}
+	}
+}
+
+// Core 记录一行核心输出
+func (m *Manager) Core(line string) {
+	m.Log(SourceCore, "", line)
+}
+
+// Writer 返回按行写入指定来源的 io.Writer
+func (m *Manager) Writer(source string) io.Writer {
+	return &lineWriter{manager: m, source: source}
+}
+
+// CaptureStdout 接管标准输出，fmt.Print 等输出按行记录为 P-BOX 日志
+func (m *Manager) CaptureStdout() error {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	os.Stdout = w
+	go func() {
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			m.Log(SourceApp, "", scanner.Text())
+		}
+	}()
+	return nil
+}
+
+// Subscribe 订阅新日志，返回的取消函数用于退订
+func (m *Manager) Subscribe() (<-chan Entry, func()) {
+	ch := make(chan Entry, 256)
+	m.mu.Lock()
+	m.subs[ch] = struct{}{}
+	m.mu.Unlock()
+	return ch, func() {
+		m.mu.Lock()
+		delete(m.subs, ch)
+		m.mu.Unlock()
+	}
+}
+
+// Close 恢复标准输出并关闭日志文件
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.closed = true
+	if os.Stdout != m.stdout {
+		pipe := os.Stdout
+		os.Stdout = m.stdout
+		pipe.Close()
+	}
+	for _, w := range m.writers {
+		w.Close()
+	}
+}
+
+// lineWriter 按行拆分写入
+type lineWriter struct {
+	manager *Manager
+	source  string
+	buf     bytes.Buffer
+	mu      sync.Mutex
+}
+
+// Write 写入数据，完整的行立即记录
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadString('\n')
+		if err != nil {
+			// 不完整的行留待下次写入
+			w.buf.Reset()
+			w.buf.WriteString(line)
+			break
+		}
+		w.manager.Log(w.source, "", line)
+	}
+	return len(p), nil
+}
+
+// ========== 级别识别 ==========
+
+var (
+	ansiPattern      = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+	keyLevelPattern  = regexp.MustCompile(`level=(\w+)`)
+	wordLevelPattern = regexp.MustCompile(`\b(TRACE|DEBUG|INFO|WARN|WARNING|ERROR|ERR|FATAL|PANIC)\b`)
+)
+
+// DetectLevel 根据日志内容推断级别
+// 支持 Mihomo 的 level=xxx、sing-box 的 INFO/WARN 前缀，以及 P-BOX 输出中的 [ERROR] 与 ❌/⚠️ 标记
+func DetectLevel(line string) string {
+	if m := keyLevelPattern.FindStringSubmatch(line); m != nil {
+		return normalizeLevel(m[1])
+	}
+	if m := wordLevelPattern.FindStringSubmatch(line); m != nil {
+		return normalizeLevel(m[1])
+	}
+	switch {
+	case strings.Contains(line, "❌"):
+		return "error"
+	case strings.Contains(line, "⚠️"):
+		return "warn"
+	}
+	return "info"
+}
+
+// normalizeLevel 统一级别名称
+func normalizeLevel(level string) string {
+	switch strings.ToLower(level) {
+	case "trace", "debug":
+		return "debug"
+	case "warn", "warning":
+		return "warn"
+	case "error", "err", "fatal", "panic":
+		return "error"
+	}
+	return "info"
+}
+
+// ========== 查询 ==========
+
+// Query 日志查询条件
+type Query struct {
+	Level   string    // 最低级别
+	Source  string    // app、core，为空表示全部
+	Keyword string    // 关键字（不区分大小写）
+	From    time.Time // 起始时间（包含）
+	To      time.Time // 结束时间（不包含）
+	Cursor  int64     // 仅返回序号小于该值的日志，0 表示从最新开始
+	Limit   int
+}
+
+// Page 日志查询结果（按时间倒序）
+type Page struct {
+	Entries    []Entry `json:"entries"`
+	NextCursor int64   `json:"nextCursor"` // 下一页游标，0 表示没有更多
+}
+
+// match 判断日志是否满足条件
+func (q Query) match(e Entry) bool {
+	if q.Cursor > 0 && e.Seq >= q.Cursor {
+		return false
+	}
+	if q.Level != "" && levelRank[e.Level] < levelRank[normalizeLevel(q.Level)] {
+		return false
+	}
+	if !q.From.IsZero() && e.Time.Before(q.From) {
+		return false
+	}
+	if !q.To.IsZero() && !e.Time.Before(q.To) {
+		return false
+	}
+	if q.Keyword != "" && !strings.Contains(strings.ToLower(e.Message), strings.ToLower(q.Keyword)) {
+		return false
+	}
+	return true
+}
+
+// Query 查询日志文件
+func (m *Manager) Query(q Query) (*Page, error) {
+	if q.Limit <= 0 || q.Limit > 1000 {
+		q.Limit = 200
+	}
+	if q.Source != "" {
+		if _, ok := m.writers[q.Source]; !ok {
+			return nil, fmt.Errorf("不支持的日志来源: %s", q.Source)
+		}
+	}
+
+	var matched []Entry
+	for source, w := range m.writers {
+		if q.Source != "" && source != q.Source {
+			continue
+		}
+		// 同一来源内文件按时间排列，从最新文件开始读，够数后无需再读更旧的文件
+		files := w.Files()
+		count := 0
+		for i := len(files) - 1; i >= 0 && count < q.Limit; i-- {
+			entries, err := readEntries(files[i], q)
+			if err != nil {
+				continue
+			}
+			matched = append(matched, entries...)
+			count += len(entries)
+		}
+	}
+
+	sort.Slice(matched, func(i, j int) bool { return matched[i].Seq > matched[j].Seq })
+	page := &Page{Entries: []Entry{}}
+	if len(matched) >= q.Limit {
+		// 恰好取满一页时也返回游标，下一页可能为空
+		matched = matched[:q.Limit]
+		page.NextCursor = matched[len(matched)-1].Seq
+	}
+	page.Entries = append(page.Entries, matched...)
+	return page, nil
+}
+
+// readEntries 读取文件中满足条件的日志
+func readEntries(path string, q Query) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if json.Unmarshal(scanner.Bytes(), &e) != nil {
+			continue
+		}
+		if q.match(e) {
+			entries = append(entries, e)
+		}
+	}
+	return entries, scanner.Err()
+}
+
+// lastSeq 获取文件中最后一条日志的序号（从最新文件向前查找）
+func lastSeq(files []string) int64 {
+	for i := len(files) - 1; i >= 0; i-- {
+		data, err := readTail(files[i], 64*1024)
+		if err != nil {
+			continue
+		}
+		lines := bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+		for j := len(lines) - 1; j >= 0; j-- {
+			var e Entry
+			if json.Unmarshal(lines[j], &e) == nil && e.Seq > 0 {
+				return e.Seq
+			}
+		}
+	}
+	return 0
+}
+
+// readTail 读取文件末尾最多 n 字节
+func readTail(path string, n int64) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := info.Size() - n
+	if offset < 0 {
+		offset = 0
+	}
+	buf := make([]byte, info.Size()-offset)
+	_, err = f.ReadAt(buf, offset)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return buf, nil
+}
@@ -0,0 +1,152 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RotatingWriter 按大小切分的日志文件
+// 当前文件写满后重命名为 name-20060102-150405.000.ext，并按数量与保留天数清理旧文件
+type RotatingWriter struct {
+	path       string
+	maxSize    int64         // 单个文件最大字节数
+	maxAge     time.Duration // 旧文件保留时长，0 表示不限制
+	maxBackups int           // 旧文件保留数量，0 表示不限制
+	file       *os.File
+	size       int64
+	mu         sync.Mutex
+}
+
+// NewRotatingWriter 创建按大小切分的日志文件
+func NewRotatingWriter(path string, maxSizeMB, maxAgeDays, maxBackups int) (*RotatingWriter, error) {
+	if maxSizeMB <= 0 {
+		maxSizeMB = 10
+	}
+	w := &RotatingWriter{
+		path:       path,
+		maxSize:    int64(maxSizeMB) * 1024 * 1024,
+		maxAge:     time.Duration(maxAgeDays) * 24 * time.Hour,
+		maxBackups: maxBackups,
+	}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	w.cleanup()
+	return w, nil
+}
+
+// open 打开当前日志文件（追加写入）
+func (w *RotatingWriter) open() error {
+	if err := os.MkdirAll(filepath.Dir(w.path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	w.file = f
+	w.size = info.Size()
+	return nil
+}
+
+// Write 写入日志，超出大小时先切分
+// 切分失败但当前文件仍可写入时继续写入，下次写入时重试切分
+func (w *RotatingWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.file == nil {
+		return 0, fmt.Errorf("日志文件已关闭")
+	}
+	var rotateErr error
+	if w.size > 0 && w.size+int64(len(p)) > w.maxSize {
+		if rotateErr = w.rotate(); rotateErr != nil && w.file == nil {
+			return 0, rotateErr
+		}
+	}
+	n, err := w.file.Write(p)
+	w.size += int64(n)
+	if err == nil && rotateErr != nil {
+		err = fmt.Errorf("日志切分失败: %w", rotateErr)
+	}
+	return n, err
+}
+
+// rotate 切分当前文件（调用者需持有锁）
+// 无论重命名是否成功都重新打开当前文件，避免之后的写入没有可用的文件
+func (w *RotatingWriter) rotate() error {
+	err := w.file.Close()
+	w.file = nil
+	if err == nil {
+		ext := filepath.Ext(w.path)
+		backup := strings.TrimSuffix(w.path, ext) + "-" + time.Now().Format("20060102-150405.000") + ext
+		err = os.Rename(w.path, backup)
+	}
+	if openErr := w.open(); openErr != nil {
+		if err != nil {
+			return fmt.Errorf("%v；重新打开日志文件失败: %v", err, openErr)
+		}
+		return openErr
+	}
+	if err != nil {
+		return err
+	}
+	w.cleanup()
+	return nil
+}
+
+// backups 获取旧日志文件，按时间从旧到新排序
+func (w *RotatingWriter) backups() []string {
+	ext := filepath.Ext(w.path)
+	matches, _ := filepath.Glob(strings.TrimSuffix(w.path, ext) + "-*" + ext)
+	sort.Strings(matches)
+	return matches
+}
+
+// cleanup 按数量与保留时长删除旧文件
+func (w *RotatingWriter) cleanup() {
+	backups := w.backups()
+	if w.maxBackups > 0 && len(backups) > w.maxBackups {
+		for _, name := range backups[:len(backups)-w.maxBackups] {
+			os.Remove(name)
+		}
+		backups = backups[len(backups)-w.maxBackups:]
+	}
+	if w.maxAge > 0 {
+		cutoff := time.Now().Add(-w.maxAge)
+		for _, name := range backups {
+			if info, err := os.Stat(name); err == nil && info.ModTime().Before(cutoff) {
+				os.Remove(name)
+			}
+		}
+	}
+}
+
+// Files 获取全部日志文件（旧文件在前，当前文件在最后）
+func (w *RotatingWriter) Files() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append(w.backups(), w.path)
+}
+
+// Close 关闭日志文件
+func (w *RotatingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRotatingWriterRotates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := NewRotatingWriter(path, 1, 0, 2)
+	if err != nil {
+		t.Fatalf("NewRotatingWriter: %v", err)
+	}
+	defer w.Close()
+	w.maxSize = 10
+
+	for _, line := range []string{"first\n", "second\n", "third\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	files := w.Files()
+	if len(files) < 2 || files[len(files)-1] != path {
+		t.Fatalf("files = %v, want backups followed by %s", files, path)
+	}
+	data, _ := os.ReadFile(path)
+	if string(data) != "third\n" {
+		t.Errorf("current file = %q, want %q", data, "third\n")
+	}
+}
+
+func TestRotatingWriterRenameFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	w, err := NewRotatingWriter(path, 1, 0, 0)
+	if err != nil {
+		t.Fatalf("NewRotatingWriter: %v", err)
+	}
+	defer w.Close()
+	w.maxSize = 10
+
+	if _, err := w.Write([]byte("first line\n")); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	// 当前文件被外部删除，切分时重命名失败
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := w.Write([]byte("second\n"))
+	if err == nil || !strings.Contains(err.Error(), "日志切分失败") {
+		t.Errorf("Write err = %v, want rotate error", err)
+	}
+	if n != len("second\n") {
+		t.Errorf("Write n = %d, want entry written after failed rotation", n)
+	}
+	if _, err := w.Write([]byte("third\n")); err != nil {
+		t.Fatalf("Write after failed rotation: %v", err)
+	}
+	// 重新打开的文件写入了 second，之后正常切分
+	files := w.Files()
+	if len(files) != 2 {
+		t.Fatalf("files = %v, want one backup and the current file", files)
+	}
+	if data, _ := os.ReadFile(files[0]); string(data) != "second\n" {
+		t.Errorf("backup = %q, want %q", data, "second\n")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "third\n" {
+		t.Errorf("current file = %q, want %q", data, "third\n")
+	}
+}
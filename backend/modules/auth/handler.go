@@ -56,6 +56,10 @@ func (h *Handler) AuthMiddleware() gin.HandlerFunc {
 			// 尝试从 cookie 获取
 			token, _ = c.Cookie("p-box-token")
 		}
+		if token == "" {
+			// WebSocket 无法设置请求头，允许通过查询参数传递
+			token = c.Query("token")
+		}
 
 		// 移除 Bearer 前缀
 		token = strings.TrimPrefix(token, "Bearer ")
@@ -1,7 +1,11 @@
 package core
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
+	"os"
+	"path/filepath"
 
 	"github.com/gin-gonic/gin"
 )
@@ -32,6 +36,12 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 	r.POST("/switch", h.SwitchCore)
 	r.POST("/download/:core", h.DownloadCore)
 	r.GET("/download/:core/progress", h.GetDownloadProgress)
+	r.GET("/installed/:core", h.ListInstalled)
+	r.POST("/installed/:core/activate", h.ActivateVersion)
+	r.POST("/installed/:core/rollback", h.Rollback)
+	r.POST("/upload/:core", h.UploadCore)
+	r.GET("/mirrors", h.GetMirrors)
+	r.PUT("/mirrors", h.SetMirrors)
 }
 
 func (h *Handler) GetStatus(c *gin.Context) {
@@ -89,7 +99,9 @@ func (h *Handler) SwitchCore(c *gin.Context) {
 
 func (h *Handler) DownloadCore(c *gin.Context) {
 	coreType := c.Param("core")
-	go h.service.DownloadCore(coreType)
+	// allowUnverified=true 时无法获取发布方校验和也继续安装
+	allowUnverified := c.Query("allowUnverified") == "true"
+	go h.service.DownloadCore(coreType, allowUnverified)
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
@@ -136,3 +148,157 @@ func (h *Handler) GetPlatformInfo(c *gin.Context) {
 		"data":    info,
 	})
 }
+
+// ListInstalled 获取核心的已安装版本
+func (h *Handler) ListInstalled(c *gin.Context) {
+	versions, err := h.service.ListInstalled(c.Param("core"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    versions,
+	})
+}
+
+// ActivateVersion 切换到已安装的指定版本
+func (h *Handler) ActivateVersion(c *gin.Context) {
+	var req struct {
+		Version string `json:"version" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := h.service.ActivateVersion(c.Param("core"), req.Version); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// Rollback 回滚到上一个已安装的版本
+func (h *Handler) Rollback(c *gin.Context) {
+	version, err := h.service.Rollback(c.Param("core"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    gin.H{"version": version},
+	})
+}
+
+// UploadCore 上传核心文件离线安装（表单字段 file，支持 .tar.gz、.zip、.gz 或二进制）
+func (h *Handler) UploadCore(c *gin.Context) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, MaxUploadSize)
+	file, err := c.FormFile("file")
+	if err != nil {
+		message := "请选择要上传的文件"
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			message = fmt.Sprintf("文件超过 %d MB", MaxUploadSize>>20)
+		}
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": message,
+		})
+		return
+	}
+
+	// 每次上传使用独立的临时文件，避免并发上传互相覆盖
+	uploadDir := filepath.Join(h.service.dataDir, "cores")
+	os.MkdirAll(uploadDir, 0755)
+	tmp, err := os.CreateTemp(uploadDir, "upload-*.tmp")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+	tmpPath := tmp.Name()
+	tmp.Close()
+	defer os.Remove(tmpPath)
+	if err := c.SaveUploadedFile(file, tmpPath); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	record, err := h.service.InstallUpload(c.Param("core"), tmpPath)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    record,
+	})
+}
+
+// GetMirrors 获取下载镜像列表
+func (h *Handler) GetMirrors(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetMirrors(),
+	})
+}
+
+// SetMirrors 设置下载镜像列表（按顺序尝试）
+func (h *Handler) SetMirrors(c *gin.Context) {
+	var mirrors []Mirror
+	if err := c.ShouldBindJSON(&mirrors); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if err := h.service.SetMirrors(mirrors); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    mirrors,
+	})
+}
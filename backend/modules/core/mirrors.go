@@ -0,0 +1,88 @@
+package core
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ============================================================================
+// 核心下载镜像
+// ============================================================================
+
+// Mirror 核心下载镜像
+// URL 为前缀时拼接在 GitHub 下载地址之前，例如 https://ghfast.top/；
+// 包含 {filename} 时作为模板，可用变量 {repo} {version} {filename}；留空表示直连 GitHub
+type Mirror struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// defaultMirrors 默认镜像列表（按顺序尝试）
+var defaultMirrors = []Mirror{
+	{Name: "ghfast.top", URL: "https://ghfast.top/"},
+	{Name: "GitHub", URL: ""},
+}
+
+// downloadSource 一个镜像对应的下载地址
+type downloadSource struct {
+	Mirror string
+	URL    string
+}
+
+// resolve 生成镜像下载地址
+func (m Mirror) resolve(repo, version, filename string) string {
+	official := fmt.Sprintf("https://github.com/%s/releases/download/v%s/%s", repo, version, filename)
+	if m.URL == "" {
+		return official
+	}
+	if strings.Contains(m.URL, "{filename}") {
+		return strings.NewReplacer("{repo}", repo, "{version}", version, "{filename}", filename).Replace(m.URL)
+	}
+	return strings.TrimSuffix(m.URL, "/") + "/" + official
+}
+
+// validateMirrors 校验镜像列表
+func validateMirrors(mirrors []Mirror) error {
+	if len(mirrors) == 0 {
+		return fmt.Errorf("至少需要一个下载镜像")
+	}
+	for i, m := range mirrors {
+		if m.URL == "" {
+			continue
+		}
+		u, err := url.Parse(strings.NewReplacer("{repo}", "r", "{version}", "v", "{filename}", "f").Replace(m.URL))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("第 %d 个镜像地址无效: %s", i+1, m.URL)
+		}
+	}
+	return nil
+}
+
+// GetMirrors 获取下载镜像列表
+func (s *Service) GetMirrors() []Mirror {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Mirror(nil), s.mirrors...)
+}
+
+// SetMirrors 设置下载镜像列表
+func (s *Service) SetMirrors(mirrors []Mirror) error {
+	for i := range mirrors {
+		mirrors[i].URL = strings.TrimSpace(mirrors[i].URL)
+		if mirrors[i].Name == "" {
+			mirrors[i].Name = mirrors[i].URL
+			if mirrors[i].Name == "" {
+				mirrors[i].Name = "GitHub"
+			}
+		}
+	}
+	if err := validateMirrors(mirrors); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.mirrors = append([]Mirror(nil), mirrors...)
+	s.mu.Unlock()
+	return s.saveStatus()
+}
@@ -0,0 +1,57 @@
+package core
+
+import "testing"
+
+func TestMirrorResolve(t *testing.T) {
+	const (
+		repo     = "MetaCubeX/mihomo"
+		version  = "1.18.10"
+		filename = "mihomo-linux-amd64-v1.18.10.gz"
+		official = "https://github.com/MetaCubeX/mihomo/releases/download/v1.18.10/mihomo-linux-amd64-v1.18.10.gz"
+	)
+
+	tests := []struct {
+		name string
+		url  string
+		want string
+	}{
+		{name: "留空直连 GitHub", url: "", want: official},
+		{name: "前缀", url: "https://ghfast.top/", want: "https://ghfast.top/" + official},
+		{name: "前缀不带斜杠", url: "https://ghfast.top", want: "https://ghfast.top/" + official},
+		{name: "文件名模板", url: "https://mirror.example.com/cores/{filename}", want: "https://mirror.example.com/cores/" + filename},
+		{
+			name: "完整模板",
+			url:  "https://mirror.example.com/{repo}/v{version}/{filename}",
+			want: "https://mirror.example.com/MetaCubeX/mihomo/v1.18.10/" + filename,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Mirror{URL: tt.url}).resolve(repo, version, filename); got != tt.want {
+				t.Errorf("resolve = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateMirrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		mirrors []Mirror
+		wantErr bool
+	}{
+		{name: "默认镜像", mirrors: defaultMirrors},
+		{name: "模板", mirrors: []Mirror{{URL: "https://mirror.example.com/{repo}/{version}/{filename}"}}},
+		{name: "空列表", mirrors: nil, wantErr: true},
+		{name: "不支持的协议", mirrors: []Mirror{{URL: "ftp://mirror.example.com/"}}, wantErr: true},
+		{name: "缺少主机名", mirrors: []Mirror{{URL: "https:///{filename}"}}, wantErr: true},
+		{name: "不是 URL", mirrors: []Mirror{{URL: "ghfast.top"}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateMirrors(tt.mirrors); (err != nil) != tt.wantErr {
+				t.Errorf("validateMirrors = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
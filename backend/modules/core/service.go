@@ -3,6 +3,8 @@ package core
 import (
 	"archive/tar"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -14,6 +16,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"p-box/backend/events"
 )
 
 type CoreType string
@@ -23,12 +27,6 @@ const (
 	CoreTypeSingbox CoreType = "singbox"
 )
 
-// CDN 镜像地址
-const (
-	MihomoCDNBase  = "https://ghfast.top/https://github.com/MetaCubeX/mihomo/releases/download"
-	SingboxCDNBase = "https://ghfast.top/https://github.com/SagerNet/sing-box/releases/download"
-)
-
 type CoreStatus struct {
 	CurrentCore CoreType         `json:"currentCore"`
 	Cores       map[string]*Core `json:"cores"`
@@ -54,8 +52,12 @@ type Service struct {
 	currentCore      CoreType
 	cores            map[string]*Core
 	downloadProgress map[string]*DownloadProgress
+	mirrors          []Mirror // 下载镜像（按顺序尝试）
 	mu               sync.RWMutex
-	onCoreSwitch     func(coreType string) // 核心切换回调
+	bus              *events.Bus // 事件总线（核心切换与下载状态）
+
+	installed map[string][]*InstalledVersion // 已安装的版本
+	installMu sync.Mutex                     // 保护 installed 与核心二进制替换
 }
 
 // 持久化状态
@@ -64,6 +66,7 @@ type SavedCoreStatus struct {
 	Versions       map[string]string `json:"versions"`
 	LatestVersions map[string]string `json:"latestVersions"`
 	LastChecked    time.Time         `json:"lastChecked"`
+	Mirrors        []Mirror          `json:"mirrors,omitempty"`
 }
 
 func NewService(dataDir string) *Service {
@@ -72,6 +75,7 @@ func NewService(dataDir string) *Service {
 		currentCore:      CoreTypeMihomo,
 		cores:            make(map[string]*Core),
 		downloadProgress: make(map[string]*DownloadProgress),
+		mirrors:          append([]Mirror(nil), defaultMirrors...),
 	}
 
 	s.cores["mihomo"] = &Core{
@@ -86,6 +90,7 @@ func NewService(dataDir string) *Service {
 	}
 
 	s.loadSavedStatus()
+	s.loadVersions()
 	s.checkInstalledCores()
 	return s
 }
@@ -105,6 +110,9 @@ func (s *Service) loadSavedStatus() {
 	if saved.CurrentCore != "" {
 		s.currentCore = CoreType(saved.CurrentCore)
 	}
+	if len(saved.Mirrors) > 0 {
+		s.mirrors = saved.Mirrors
+	}
 
 	for name, version := range saved.Versions {
 		if core, ok := s.cores[name]; ok {
@@ -130,6 +138,7 @@ func (s *Service) saveStatus() error {
 		Versions:       make(map[string]string),
 		LatestVersions: make(map[string]string),
 		LastChecked:    time.Now(),
+		Mirrors:        s.mirrors,
 	}
 	for name, core := range s.cores {
 		if core.Installed {
@@ -274,9 +283,29 @@ func (s *Service) GetLatestVersions() (map[string]string, error) {
 		s.mu.Unlock()
 	}
 
+	s.publishAvailableUpdates()
 	return versions, nil
 }
 
+// publishAvailableUpdates 已安装的核心存在新版本时发布事件
+func (s *Service) publishAvailableUpdates() {
+	s.mu.RLock()
+	var updates []events.CoreUpdatePayload
+	for name, core := range s.cores {
+		current := strings.TrimPrefix(core.Version, "v")
+		latest := strings.TrimPrefix(core.LatestVersion, "v")
+		if !core.Installed || latest == "" || current == "" || current == "unknown" || current == latest {
+			continue
+		}
+		updates = append(updates, events.CoreUpdatePayload{Core: name, Current: core.Version, Latest: core.LatestVersion})
+	}
+	s.mu.RUnlock()
+
+	for _, update := range updates {
+		s.bus.Publish(events.CoreUpdate, update)
+	}
+}
+
 func (s *Service) fetchMihomoLatestVersion() (string, error) {
 	resp, err := http.Get("https://api.github.com/repos/MetaCubeX/mihomo/releases/latest")
 	if err != nil {
@@ -339,9 +368,7 @@ func (s *Service) SwitchCore(coreType string) error {
 	s.currentCore = CoreType(coreType)
 
 	// 通知 proxy 模块切换核心
-	if s.onCoreSwitch != nil {
-		s.onCoreSwitch(coreType)
-	}
+	s.bus.Publish(events.CoreSwitched, events.CorePayload{Core: coreType})
 
 	// 持久化保存
 	go s.saveStatus()
@@ -349,11 +376,11 @@ func (s *Service) SwitchCore(coreType string) error {
 	return nil
 }
 
-// SetOnCoreSwitch 设置核心切换回调
-func (s *Service) SetOnCoreSwitch(callback func(coreType string)) {
+// SetEventBus 设置事件总线
+func (s *Service) SetEventBus(bus *events.Bus) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.onCoreSwitch = callback
+	s.bus = bus
 }
 
 // GetCurrentCore 获取当前核心类型
@@ -363,10 +390,12 @@ func (s *Service) GetCurrentCore() string {
 	return string(s.currentCore)
 }
 
-func (s *Service) DownloadCore(coreType string) error {
+// DownloadCore 下载并安装核心；默认必须通过发布方校验和验证，allowUnverified 为 true 时无法获取校验和也继续安装
+func (s *Service) DownloadCore(coreType string, allowUnverified bool) error {
 	s.mu.Lock()
 	s.downloadProgress[coreType] = &DownloadProgress{Downloading: true}
 	s.mu.Unlock()
+	s.bus.Publish(events.CoreDownload, events.CoreDownloadPayload{Core: coreType, State: events.DownloadStarted})
 
 	defer func() {
 		s.mu.Lock()
@@ -374,37 +403,64 @@ func (s *Service) DownloadCore(coreType string) error {
 		s.mu.Unlock()
 	}()
 
-	// 获取 CDN 和官方下载地址
-	cdnURL, officialURL, err := s.getCoreDownloadURLs(coreType)
+	// 获取各镜像的下载地址
+	sources, filename, err := s.getCoreDownloadURLs(coreType)
 	if err != nil {
 		s.mu.Lock()
 		s.downloadProgress[coreType].Error = err.Error()
 		s.mu.Unlock()
+		s.bus.Publish(events.CoreDownload, events.CoreDownloadPayload{Core: coreType, State: events.DownloadFailed, Error: err.Error()})
 		return err
 	}
 
-	// 尝试 CDN 下载
-	fmt.Printf("📦 尝试从 CDN 下载 %s: %s\n", coreType, cdnURL)
-	err = s.downloadFromURL(coreType, cdnURL)
+	// 获取发布方公布的校验和（镜像下载的文件必须与之一致）
+	s.mu.RLock()
+	version := s.cores[coreType].LatestVersion
+	s.mu.RUnlock()
+	checksum, err := s.fetchChecksum(coreType, version, filename)
 	if err != nil {
-		fmt.Printf("⚠️ CDN 下载失败: %v，尝试官方地址...\n", err)
-		// 回退到官方地址
-		fmt.Printf("📦 尝试从官方下载 %s: %s\n", coreType, officialURL)
-		err = s.downloadFromURL(coreType, officialURL)
-		if err != nil {
+		if !allowUnverified {
+			err = fmt.Errorf("无法获取 %s 的校验和，已取消安装（可选择跳过校验安装，或离线上传核心）: %v", filename, err)
 			s.mu.Lock()
 			s.downloadProgress[coreType].Error = err.Error()
 			s.mu.Unlock()
-			return fmt.Errorf("下载失败: %v", err)
+			s.bus.Publish(events.CoreDownload, events.CoreDownloadPayload{Core: coreType, State: events.DownloadFailed, Error: err.Error()})
+			return err
 		}
+		fmt.Printf("⚠️ 无法获取 %s 的校验和: %v，按请求跳过校验\n", filename, err)
+		checksum = ""
+	}
+
+	// 按顺序尝试各镜像
+	var record *InstalledVersion
+	for _, source := range sources {
+		fmt.Printf("📦 尝试从 %s 下载 %s: %s\n", source.Mirror, coreType, source.URL)
+		record, err = s.downloadFromURL(coreType, source.URL, version, checksum)
+		if err == nil {
+			break
+		}
+		fmt.Printf("⚠️ 从 %s 下载失败: %v\n", source.Mirror, err)
+	}
+	if err == nil {
+		// 切换到新版本，无法运行或配置校验失败时保留/回滚到原版本
+		err = s.installVersion(coreType, record)
+	}
+	if err != nil {
+		s.mu.Lock()
+		s.downloadProgress[coreType].Error = err.Error()
+		s.mu.Unlock()
+		s.bus.Publish(events.CoreDownload, events.CoreDownloadPayload{Core: coreType, State: events.DownloadFailed, Error: err.Error()})
+		return fmt.Errorf("下载失败: %v", err)
 	}
 
 	fmt.Printf("✅ %s 下载完成\n", coreType)
+	s.bus.Publish(events.CoreDownload, events.CoreDownloadPayload{Core: coreType, State: events.DownloadCompleted})
 	return nil
 }
 
-// downloadFromURL 从指定 URL 下载核心
-func (s *Service) downloadFromURL(coreType, downloadURL string) error {
+// downloadFromURL 从指定 URL 下载核心并解压到版本目录
+// checksum 不为空时校验下载包的 SHA-256，不一致则丢弃
+func (s *Service) downloadFromURL(coreType, downloadURL, version, checksum string) (*InstalledVersion, error) {
 	// 创建带超时的 HTTP 客户端
 	client := &http.Client{
 		Timeout: 5 * time.Minute,
@@ -412,23 +468,25 @@ func (s *Service) downloadFromURL(coreType, downloadURL string) error {
 
 	resp, err := client.Get(downloadURL)
 	if err != nil {
-		return fmt.Errorf("请求失败: %v", err)
+		return nil, fmt.Errorf("请求失败: %v", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != 200 {
-		return fmt.Errorf("HTTP %d", resp.StatusCode)
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
 	}
 
 	os.MkdirAll(filepath.Join(s.dataDir, "cores"), 0755)
 
-	// 下载到临时文件
+	// 下载到临时文件，同时计算 SHA-256
 	tmpFile := filepath.Join(s.dataDir, "cores", "download.tmp")
 	out, err := os.Create(tmpFile)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer os.Remove(tmpFile)
 
+	hash := sha256.New()
 	totalSize := resp.ContentLength
 	written := int64(0)
 
@@ -436,7 +494,11 @@ func (s *Service) downloadFromURL(coreType, downloadURL string) error {
 	for {
 		n, err := resp.Body.Read(buf)
 		if n > 0 {
-			out.Write(buf[:n])
+			if _, werr := out.Write(buf[:n]); werr != nil {
+				out.Close()
+				return nil, werr
+			}
+			hash.Write(buf[:n])
 			written += int64(n)
 
 			s.mu.Lock()
@@ -450,32 +512,36 @@ func (s *Service) downloadFromURL(coreType, downloadURL string) error {
 		}
 		if err != nil {
 			out.Close()
-			os.Remove(tmpFile)
-			return err
+			return nil, err
 		}
 	}
-	out.Close()
+	if err := out.Close(); err != nil {
+		return nil, err
+	}
 
-	// 解压文件
-	binPath := s.getCoreBinaryPath(coreType)
+	sum := hex.EncodeToString(hash.Sum(nil))
+	if checksum != "" && !strings.EqualFold(sum, checksum) {
+		return nil, fmt.Errorf("校验和不匹配: 期望 %s，实际 %s", checksum, sum)
+	}
+
+	// 解压到版本目录，切换前不覆盖正在使用的二进制
+	binPath := s.versionBinaryPath(coreType, version)
+	os.MkdirAll(filepath.Dir(binPath), 0755)
 	if err := s.extractCore(tmpFile, binPath, coreType); err != nil {
-		os.Remove(tmpFile)
-		return fmt.Errorf("解压失败: %v", err)
+		os.RemoveAll(filepath.Dir(binPath))
+		return nil, fmt.Errorf("解压失败: %v", err)
 	}
-	os.Remove(tmpFile)
 
 	// 设置执行权限
 	os.Chmod(binPath, 0755)
 
-	s.mu.Lock()
-	s.cores[coreType].Installed = true
-	s.cores[coreType].Version = s.cores[coreType].LatestVersion
-	s.mu.Unlock()
-
-	// 持久化保存
-	s.saveStatus()
-
-	return nil
+	return &InstalledVersion{
+		Version:     version,
+		Path:        binPath,
+		SHA256:      sum,
+		Verified:    checksum != "",
+		InstalledAt: time.Now(),
+	}, nil
 }
 
 // extractCore 解压核心文件
@@ -533,50 +599,38 @@ func (s *Service) extractCore(archivePath, destPath, coreType string) error {
 	return fmt.Errorf("executable not found in archive")
 }
 
-// getCoreDownloadURLs 获取下载 URL（CDN 优先，官方备用）
-func (s *Service) getCoreDownloadURLs(coreType string) (cdnURL, officialURL string, err error) {
+// getCoreDownloadURLs 按镜像顺序获取下载 URL，同时返回发布文件名
+func (s *Service) getCoreDownloadURLs(coreType string) (sources []downloadSource, filename string, err error) {
 	arch := runtime.GOARCH
 	goos := runtime.GOOS
 
 	s.mu.RLock()
 	version := s.cores[coreType].LatestVersion
+	mirrors := append([]Mirror(nil), s.mirrors...)
 	s.mu.RUnlock()
 
 	if version == "" {
-		return "", "", fmt.Errorf("version not found, please check latest version first")
-	}
-
-	// 转换架构名称
-	archName := arch
-	if arch == "amd64" {
-		archName = "amd64"
-	} else if arch == "arm64" {
-		archName = "arm64"
-	}
-
-	// 转换系统名称
-	osName := goos
-	if goos == "darwin" {
-		osName = "darwin"
+		return nil, "", fmt.Errorf("version not found, please check latest version first")
 	}
 
 	switch coreType {
 	case "mihomo":
 		// mihomo releases 格式: mihomo-darwin-arm64-v1.18.10.gz
-		filename := fmt.Sprintf("mihomo-%s-%s-v%s.gz", osName, archName, version)
-		cdnURL = fmt.Sprintf("%s/v%s/%s", MihomoCDNBase, version, filename)
-		officialURL = fmt.Sprintf("https://github.com/MetaCubeX/mihomo/releases/download/v%s/%s", version, filename)
-		return cdnURL, officialURL, nil
-
+		filename = fmt.Sprintf("mihomo-%s-%s-v%s.gz", goos, arch, version)
 	case "singbox":
 		// sing-box releases 格式: sing-box-1.10.5-darwin-arm64.tar.gz
-		filename := fmt.Sprintf("sing-box-%s-%s-%s.tar.gz", version, osName, archName)
-		cdnURL = fmt.Sprintf("%s/v%s/%s", SingboxCDNBase, version, filename)
-		officialURL = fmt.Sprintf("https://github.com/SagerNet/sing-box/releases/download/v%s/%s", version, filename)
-		return cdnURL, officialURL, nil
+		filename = fmt.Sprintf("sing-box-%s-%s-%s.tar.gz", version, goos, arch)
+	default:
+		return nil, "", fmt.Errorf("unknown core type")
 	}
 
-	return "", "", fmt.Errorf("unknown core type")
+	for _, mirror := range mirrors {
+		sources = append(sources, downloadSource{
+			Mirror: mirror.Name,
+			URL:    mirror.resolve(coreRepos[coreType], version, filename),
+		})
+	}
+	return sources, filename, nil
 }
 
 func (s *Service) GetDownloadProgress(coreType string) *DownloadProgress {
@@ -609,7 +663,7 @@ func (s *Service) Initialize(delaySeconds int) {
 		if !mihomoInstalled && mihomoLatestVersion != "" {
 			fmt.Printf("📦 检测到未安装 mihomo 核心，开始自动下载...\n")
 			fmt.Printf("   平台: %s/%s\n", runtime.GOOS, runtime.GOARCH)
-			if err := s.DownloadCore("mihomo"); err != nil {
+			if err := s.DownloadCore("mihomo", false); err != nil {
 				fmt.Printf("❌ 自动下载 mihomo 失败: %v\n", err)
 			} else {
 				fmt.Printf("✅ mihomo 核心自动下载完成\n")
@@ -0,0 +1,190 @@
+package core
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// 离线安装：上传核心压缩包或二进制
+// ============================================================================
+
+// MaxUploadSize 上传文件大小上限
+const MaxUploadSize = 200 << 20
+
+// maxBinarySize 解压后的可执行文件大小上限
+var maxBinarySize int64 = 512 << 20
+
+// coreBinaryPrefix 压缩包内可执行文件的名称前缀
+var coreBinaryPrefix = map[string]string{
+	"mihomo":  "mihomo",
+	"singbox": "sing-box",
+}
+
+// InstallUpload 安装上传的核心文件（.tar.gz、.zip、.gz 或二进制）
+// 通过实际运行确认平台与架构匹配，版本号与 getCoreVersion 的解析方式一致
+func (s *Service) InstallUpload(coreType, uploadPath string) (*InstalledVersion, error) {
+	if _, ok := coreRepos[coreType]; !ok {
+		return nil, fmt.Errorf("unknown core type: %s", coreType)
+	}
+
+	sum, err := fileSHA256(uploadPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// 先解压到独立的临时目录，识别出版本后再移到版本目录
+	versionsDir := filepath.Join(s.dataDir, "cores", "versions", coreType)
+	if err := os.MkdirAll(versionsDir, 0755); err != nil {
+		return nil, err
+	}
+	stageDir, err := os.MkdirTemp(versionsDir, ".upload-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(stageDir)
+	stagePath := filepath.Join(stageDir, filepath.Base(s.getCoreBinaryPath(coreType)))
+	if err := extractUpload(uploadPath, stagePath, coreType); err != nil {
+		return nil, fmt.Errorf("解压失败: %v", err)
+	}
+	os.Chmod(stagePath, 0755)
+
+	version, err := s.probeBinary(coreType, stagePath)
+	if err != nil {
+		return nil, fmt.Errorf("核心无法在当前平台运行（请确认系统与架构匹配）: %v", err)
+	}
+	if version == "" {
+		return nil, fmt.Errorf("无法识别核心版本，请确认上传的是 %s 核心", coreType)
+	}
+
+	record := &InstalledVersion{
+		Version:     version,
+		Path:        s.versionBinaryPath(coreType, version),
+		SHA256:      sum,
+		InstalledAt: time.Now(),
+	}
+
+	// 复制到版本目录与切换在同一把锁内完成，避免并发上传互相覆盖
+	s.installMu.Lock()
+	err = copyBinary(stagePath, record.Path)
+	if err == nil {
+		err = s.installVersionLocked(coreType, record)
+	}
+	s.installMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	fmt.Printf("✅ 已离线安装 %s %s\n", coreType, version)
+	return record, nil
+}
+
+// extractUpload 按文件内容识别格式并取出核心可执行文件
+func extractUpload(srcPath, destPath, coreType string) error {
+	file, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	br := bufio.NewReader(file)
+	magic, _ := br.Peek(4)
+
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gzr, err := gzip.NewReader(br)
+		if err != nil {
+			return err
+		}
+		defer gzr.Close()
+
+		// .tar.gz 的 tar 头在 257 字节处有 ustar 标识，否则为单文件 .gz
+		inner := bufio.NewReaderSize(gzr, 512)
+		header, _ := inner.Peek(262)
+		if len(header) == 262 && string(header[257:262]) == "ustar" {
+			return extractFromTar(tar.NewReader(inner), destPath, coreType)
+		}
+		return writeBinary(inner, destPath)
+
+	case bytes.Equal(magic, []byte{'P', 'K', 0x03, 0x04}):
+		file.Close()
+		return extractFromZip(srcPath, destPath, coreType)
+
+	default:
+		// 未压缩的二进制
+		return writeBinary(br, destPath)
+	}
+}
+
+// isCoreBinaryName 判断压缩包内的文件是否为核心可执行文件
+func isCoreBinaryName(name, coreType string) bool {
+	base := path.Base(strings.ReplaceAll(name, "\\", "/"))
+	return strings.HasPrefix(base, coreBinaryPrefix[coreType])
+}
+
+// extractFromTar 从 tar 中取出核心可执行文件
+func extractFromTar(tr *tar.Reader, destPath, coreType string) error {
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if header.Typeflag == tar.TypeReg && isCoreBinaryName(header.Name, coreType) {
+			return writeBinary(tr, destPath)
+		}
+	}
+	return fmt.Errorf("executable not found in archive")
+}
+
+// extractFromZip 从 zip 中取出核心可执行文件
+func extractFromZip(srcPath, destPath, coreType string) error {
+	zr, err := zip.OpenReader(srcPath)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || !isCoreBinaryName(f.Name, coreType) {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		return writeBinary(rc, destPath)
+	}
+	return fmt.Errorf("executable not found in archive")
+}
+
+// writeBinary 写出可执行文件，超过 maxBinarySize 时中止（防止压缩炸弹占满磁盘）
+func writeBinary(r io.Reader, destPath string) error {
+	out, err := os.OpenFile(destPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	n, err := io.Copy(out, io.LimitReader(r, maxBinarySize+1))
+	if err != nil {
+		out.Close()
+		return err
+	}
+	if n > maxBinarySize {
+		out.Close()
+		os.Remove(destPath)
+		return fmt.Errorf("解压后的文件超过 %d MB", maxBinarySize>>20)
+	}
+	return out.Close()
+}
@@ -0,0 +1,196 @@
+package core
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// archiveFile 压缩包内的文件
+type archiveFile struct {
+	name string
+	data []byte
+	dir  bool
+}
+
+// gzipBytes gzip 压缩
+func gzipBytes(data []byte) []byte {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write(data)
+	zw.Close()
+	return buf.Bytes()
+}
+
+// buildTarGz 构造 .tar.gz
+func buildTarGz(files ...archiveFile) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, f := range files {
+		if f.dir {
+			tw.WriteHeader(&tar.Header{Name: f.name, Typeflag: tar.TypeDir, Mode: 0755})
+			continue
+		}
+		tw.WriteHeader(&tar.Header{Name: f.name, Typeflag: tar.TypeReg, Mode: 0755, Size: int64(len(f.data))})
+		tw.Write(f.data)
+	}
+	tw.Close()
+	return gzipBytes(buf.Bytes())
+}
+
+// buildZip 构造 .zip
+func buildZip(files ...archiveFile) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range files {
+		if f.dir {
+			zw.Create(f.name + "/")
+			continue
+		}
+		w, _ := zw.Create(f.name)
+		w.Write(f.data)
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestExtractUpload(t *testing.T) {
+	binary := []byte("\x7fELF core binary")
+	readme := archiveFile{name: "README.md", data: []byte("readme")}
+
+	tests := []struct {
+		name    string
+		core    string
+		data    []byte
+		want    []byte
+		wantErr string
+	}{
+		{
+			name: "tar.gz",
+			core: "mihomo",
+			data: buildTarGz(readme, archiveFile{name: "mihomo-linux-amd64", data: binary}),
+			want: binary,
+		},
+		{
+			name: "tar.gz 子目录中的 sing-box",
+			core: "singbox",
+			data: buildTarGz(
+				archiveFile{name: "sing-box-1.10.5-linux-amd64", dir: true},
+				archiveFile{name: "sing-box-1.10.5-linux-amd64/LICENSE", data: []byte("license")},
+				archiveFile{name: "sing-box-1.10.5-linux-amd64/sing-box", data: binary},
+			),
+			want: binary,
+		},
+		{
+			name:    "tar.gz 中没有核心",
+			core:    "mihomo",
+			data:    buildTarGz(readme),
+			wantErr: "executable not found",
+		},
+		{
+			name: "zip",
+			core: "mihomo",
+			data: buildZip(archiveFile{name: "dist", dir: true}, readme, archiveFile{name: "dist\\mihomo-windows-amd64.exe", data: binary}),
+			want: binary,
+		},
+		{
+			name:    "zip 中没有核心",
+			core:    "singbox",
+			data:    buildZip(readme, archiveFile{name: "mihomo-linux-amd64", data: binary}),
+			wantErr: "executable not found",
+		},
+		{
+			name: "单文件 gz",
+			core: "mihomo",
+			data: gzipBytes(binary),
+			want: binary,
+		},
+		{
+			name: "未压缩的二进制",
+			core: "mihomo",
+			data: binary,
+			want: binary,
+		},
+		{
+			name:    "损坏的 gz",
+			core:    "mihomo",
+			data:    []byte{0x1f, 0x8b, 0x00},
+			wantErr: "EOF",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "upload")
+			dest := filepath.Join(dir, "core")
+			if err := os.WriteFile(src, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := extractUpload(src, dest, tt.core)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("extractUpload: %v", err)
+			}
+			got, _ := os.ReadFile(dest)
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("extracted = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractUploadSizeLimit(t *testing.T) {
+	old := maxBinarySize
+	maxBinarySize = 1024
+	defer func() { maxBinarySize = old }()
+
+	exact := bytes.Repeat([]byte{0}, 1024)
+	oversized := bytes.Repeat([]byte{0}, 64*1024)
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr bool
+	}{
+		{name: "刚好达到上限", data: exact},
+		{name: "未压缩的二进制超过上限", data: oversized, wantErr: true},
+		{name: "gz 解压后超过上限", data: gzipBytes(oversized), wantErr: true},
+		{name: "tar.gz 解压后超过上限", data: buildTarGz(archiveFile{name: "mihomo", data: oversized}), wantErr: true},
+		{name: "zip 解压后超过上限", data: buildZip(archiveFile{name: "mihomo", data: oversized}), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			src := filepath.Join(dir, "upload")
+			dest := filepath.Join(dir, "core")
+			if err := os.WriteFile(src, tt.data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			err := extractUpload(src, dest, "mihomo")
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("extractUpload: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), "超过") {
+				t.Fatalf("err = %v, want size limit error", err)
+			}
+			if _, err := os.Stat(dest); !os.IsNotExist(err) {
+				t.Errorf("oversized output not removed")
+			}
+		})
+	}
+}
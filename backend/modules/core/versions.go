@@ -0,0 +1,494 @@
+package core
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"p-box/backend/events"
+)
+
+// ============================================================================
+// 核心版本管理：下载校验、多版本保留、切换与自动回滚
+// ============================================================================
+
+// keepVersions 每个核心保留的版本数量（含当前使用的版本）
+const keepVersions = 3
+
+// GitHubAPIBase GitHub API 地址（用于获取版本与校验和）
+var GitHubAPIBase = "https://api.github.com"
+
+// coreRepos 核心对应的 GitHub 仓库
+var coreRepos = map[string]string{
+	"mihomo":  "MetaCubeX/mihomo",
+	"singbox": "SagerNet/sing-box",
+}
+
+// InstalledVersion 已安装的核心版本
+type InstalledVersion struct {
+	Version     string    `json:"version"`
+	Path        string    `json:"path"`
+	SHA256      string    `json:"sha256,omitempty"` // 下载包的 SHA-256
+	Verified    bool      `json:"verified"`         // 是否通过发布方校验和验证
+	Active      bool      `json:"active"`
+	InstalledAt time.Time `json:"installedAt"`
+}
+
+// versionsPath 版本记录文件路径
+func (s *Service) versionsPath() string {
+	return filepath.Join(s.dataDir, "cores", "versions.json")
+}
+
+// loadVersions 加载版本记录
+func (s *Service) loadVersions() {
+	s.installed = make(map[string][]*InstalledVersion)
+	data, err := os.ReadFile(s.versionsPath())
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &s.installed); err != nil {
+		fmt.Printf("⚠️ 读取核心版本记录失败: %v\n", err)
+	}
+}
+
+// saveVersions 保存版本记录（调用者需持有 installMu）
+func (s *Service) saveVersions() error {
+	data, err := json.MarshalIndent(s.installed, "", "  ")
+	if err != nil {
+		return err
+	}
+	os.MkdirAll(filepath.Dir(s.versionsPath()), 0755)
+	return os.WriteFile(s.versionsPath(), data, 0644)
+}
+
+// versionBinaryPath 指定版本的二进制保存路径
+func (s *Service) versionBinaryPath(coreType, version string) string {
+	binName := filepath.Base(s.getCoreBinaryPath(coreType))
+	return filepath.Join(s.dataDir, "cores", "versions", coreType, filepath.Base(version), binName)
+}
+
+// ========== 校验和 ==========
+
+// fetchReleaseChecksum 获取发布方公布的 SHA-256
+// 优先使用 GitHub Release 资产的 digest 字段，其次查找校验和文件（checksums.txt、*.sha256 等）
+func fetchReleaseChecksum(coreType, version, filename string) (string, error) {
+	repo, ok := coreRepos[coreType]
+	if !ok {
+		return "", fmt.Errorf("unknown core type")
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(fmt.Sprintf("%s/repos/%s/releases/tags/v%s", GitHubAPIBase, repo, version))
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("获取发布信息失败: HTTP %d", resp.StatusCode)
+	}
+
+	var release struct {
+		Assets []struct {
+			Name        string `json:"name"`
+			Digest      string `json:"digest"`
+			DownloadURL string `json:"browser_download_url"`
+		} `json:"assets"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
+		return "", err
+	}
+
+	for _, asset := range release.Assets {
+		if asset.Name == filename && strings.HasPrefix(asset.Digest, "sha256:") {
+			return strings.ToLower(strings.TrimPrefix(asset.Digest, "sha256:")), nil
+		}
+	}
+	for _, asset := range release.Assets {
+		name := strings.ToLower(asset.Name)
+		if asset.Name != filename+".sha256" && !strings.Contains(name, "checksum") && !strings.Contains(name, "sha256sum") {
+			continue
+		}
+		if sum, err := fetchChecksumFile(client, asset.DownloadURL, filename, asset.Name == filename+".sha256"); err == nil {
+			return sum, nil
+		}
+	}
+	return "", fmt.Errorf("发布中没有 %s 的校验和", filename)
+}
+
+// checksumFileNames 发布中可能存在的校验和文件（{filename} 为下载包文件名）
+var checksumFileNames = []string{"{filename}.sha256", "checksums.txt", "sha256sums.txt"}
+
+// fetchChecksum 获取发布方公布的 SHA-256：先查询 GitHub API，失败时通过各下载镜像获取校验和文件
+func (s *Service) fetchChecksum(coreType, version, filename string) (string, error) {
+	sum, apiErr := fetchReleaseChecksum(coreType, version, filename)
+	if apiErr == nil {
+		return sum, nil
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	for _, mirror := range s.GetMirrors() {
+		for _, name := range checksumFileNames {
+			single := strings.Contains(name, "{filename}")
+			name = strings.ReplaceAll(name, "{filename}", filename)
+			url := mirror.resolve(coreRepos[coreType], version, name)
+			if sum, err := fetchChecksumFile(client, url, filename, single); err == nil {
+				return sum, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("GitHub API: %v；各镜像也没有 %s 的校验和文件", apiErr, filename)
+}
+
+// fetchChecksumFile 从校验和文件中查找指定文件的 SHA-256
+// 支持 "<hash>  <文件名>" 格式；single 为 true 时（单文件的 .sha256）也接受只有哈希值的行
+func fetchChecksumFile(client *http.Client, url, filename string, single bool) (string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+
+	scanner := bufio.NewScanner(io.LimitReader(resp.Body, 1<<20))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || len(fields[0]) != 64 {
+			continue
+		}
+		if _, err := hex.DecodeString(fields[0]); err != nil {
+			continue
+		}
+		if (single && len(fields) == 1) || strings.TrimPrefix(fields[len(fields)-1], "*") == filename {
+			return strings.ToLower(fields[0]), nil
+		}
+	}
+	return "", fmt.Errorf("校验和文件中没有 %s", filename)
+}
+
+// fileSHA256 计算文件 SHA-256
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ========== 二进制检查 ==========
+
+// probeBinary 运行核心的版本命令，确认二进制可以执行
+func (s *Service) probeBinary(coreType, binPath string) (string, error) {
+	args := []string{"-v"}
+	if coreType == "singbox" {
+		args = []string{"version"}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, binPath, args...).Output()
+	if err != nil {
+		return "", fmt.Errorf("无法执行: %v", err)
+	}
+	return s.parseVersionFromOutput(coreType, string(output)), nil
+}
+
+// validateConfig 使用指定二进制校验当前配置文件，配置文件不存在时跳过
+func (s *Service) validateConfig(coreType, binPath string) error {
+	var args []string
+	switch coreType {
+	case "mihomo":
+		configPath := filepath.Join(s.dataDir, "configs", "config.yaml")
+		if _, err := os.Stat(configPath); err != nil {
+			return nil
+		}
+		args = []string{"-t", "-d", s.dataDir, "-f", configPath}
+	case "singbox":
+		configPath := filepath.Join(s.dataDir, "configs", "singbox-config.json")
+		if _, err := os.Stat(configPath); err != nil {
+			return nil
+		}
+		args = []string{"check", "-D", s.dataDir, "-c", configPath}
+	default:
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, binPath, args...)
+	cmd.Dir = s.dataDir
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%v: %s", err, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// copyBinary 复制二进制文件，先写临时文件再重命名以免留下不完整的文件
+func copyBinary(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	tmp := dst + ".tmp"
+	out, err := os.OpenFile(tmp, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0755)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, dst)
+}
+
+// ========== 安装、切换与回滚 ==========
+
+// findVersion 查找版本记录（调用者需持有 installMu）
+func (s *Service) findVersion(coreType, version string) *InstalledVersion {
+	for _, v := range s.installed[coreType] {
+		if v.Version == version {
+			return v
+		}
+	}
+	return nil
+}
+
+// removeRecord 删除版本记录，不删除文件（调用者需持有 installMu）
+func (s *Service) removeRecord(coreType string, record *InstalledVersion) {
+	list := s.installed[coreType][:0]
+	for _, v := range s.installed[coreType] {
+		if v != record {
+			list = append(list, v)
+		}
+	}
+	s.installed[coreType] = list
+}
+
+// activeVersion 当前使用的版本记录（调用者需持有 installMu）
+func (s *Service) activeVersion(coreType string) *InstalledVersion {
+	for _, v := range s.installed[coreType] {
+		if v.Active {
+			return v
+		}
+	}
+	return nil
+}
+
+// archiveCurrent 将未纳入版本管理的现有二进制保存为一个版本，便于回滚（调用者需持有 installMu）
+func (s *Service) archiveCurrent(coreType string) {
+	if s.activeVersion(coreType) != nil {
+		return
+	}
+	binPath := s.getCoreBinaryPath(coreType)
+	if _, err := os.Stat(binPath); err != nil {
+		return
+	}
+	version, err := s.probeBinary(coreType, binPath)
+	if err != nil || version == "" {
+		version = "unknown-" + time.Now().Format("20060102150405")
+	}
+	if s.findVersion(coreType, version) != nil {
+		return
+	}
+	dest := s.versionBinaryPath(coreType, version)
+	if err := copyBinary(binPath, dest); err != nil {
+		fmt.Printf("⚠️ 备份当前 %s 核心失败: %v\n", coreType, err)
+		return
+	}
+	sum, _ := fileSHA256(dest)
+	s.installed[coreType] = append(s.installed[coreType], &InstalledVersion{
+		Version:     version,
+		Path:        dest,
+		SHA256:      sum,
+		Active:      true,
+		InstalledAt: time.Now(),
+	})
+}
+
+// installVersion 登记新下载的版本并切换到该版本，失败时保留原版本
+func (s *Service) installVersion(coreType string, record *InstalledVersion) error {
+	s.installMu.Lock()
+	defer s.installMu.Unlock()
+	return s.installVersionLocked(coreType, record)
+}
+
+// installVersionLocked 同 installVersion（调用者需持有 installMu）
+func (s *Service) installVersionLocked(coreType string, record *InstalledVersion) error {
+	if _, err := s.probeBinary(coreType, record.Path); err != nil {
+		os.RemoveAll(filepath.Dir(record.Path))
+		if existing := s.findVersion(coreType, record.Version); existing != nil && !existing.Active {
+			// 同版本重新下载时旧文件已被覆盖，记录随之失效
+			s.removeRecord(coreType, existing)
+			s.saveVersions()
+		}
+		return fmt.Errorf("新核心%s，已保留原版本", err)
+	}
+
+	s.archiveCurrent(coreType)
+	if existing := s.findVersion(coreType, record.Version); existing != nil {
+		record.Active = existing.Active
+		s.removeRecord(coreType, existing)
+	}
+	s.installed[coreType] = append(s.installed[coreType], record)
+
+	err := s.activateLocked(coreType, record.Version)
+	s.pruneVersions(coreType)
+	if saveErr := s.saveVersions(); saveErr != nil {
+		fmt.Printf("⚠️ 保存核心版本记录失败: %v\n", saveErr)
+	}
+	return err
+}
+
+// activateLocked 切换到指定版本；新版本无法运行或配置校验失败时自动回滚（调用者需持有 installMu）
+func (s *Service) activateLocked(coreType, version string) error {
+	target := s.findVersion(coreType, version)
+	if target == nil {
+		return fmt.Errorf("未安装版本 %s", version)
+	}
+	previous := s.activeVersion(coreType)
+	binPath := s.getCoreBinaryPath(coreType)
+
+	probed, err := s.probeBinary(coreType, target.Path)
+	if err != nil {
+		return fmt.Errorf("版本 %s %v", version, err)
+	}
+	if err := copyBinary(target.Path, binPath); err != nil {
+		return fmt.Errorf("替换核心失败: %v", err)
+	}
+
+	if err := s.validateConfig(coreType, binPath); err != nil {
+		if previous != nil && previous != target {
+			if restoreErr := copyBinary(previous.Path, binPath); restoreErr != nil {
+				return fmt.Errorf("配置校验失败: %v；回滚到 %s 也失败: %v", err, previous.Version, restoreErr)
+			}
+			fmt.Printf("⚠️ %s %s 配置校验失败，已回滚到 %s\n", coreType, version, previous.Version)
+			s.bus.Publish(events.CoreRollback, events.CoreVersionPayload{Core: coreType, Version: previous.Version, Previous: version, Error: err.Error()})
+			return fmt.Errorf("配置校验失败，已回滚到 %s: %v", previous.Version, err)
+		}
+		return fmt.Errorf("配置校验失败: %v", err)
+	}
+
+	for _, v := range s.installed[coreType] {
+		v.Active = v == target
+	}
+	if probed == "" {
+		probed = version
+	}
+	s.mu.Lock()
+	s.cores[coreType].Installed = true
+	s.cores[coreType].Version = probed
+	s.mu.Unlock()
+	s.saveStatus()
+
+	payload := events.CoreVersionPayload{Core: coreType, Version: version}
+	if previous != nil && previous != target {
+		payload.Previous = previous.Version
+	}
+	s.bus.Publish(events.CoreActivated, payload)
+	fmt.Printf("✅ %s 已切换到 %s\n", coreType, version)
+	return nil
+}
+
+// pruneVersions 只保留最近安装的 keepVersions 个版本，当前版本始终保留（调用者需持有 installMu）
+func (s *Service) pruneVersions(coreType string) {
+	list := s.installed[coreType]
+	sort.Slice(list, func(i, j int) bool { return list[i].InstalledAt.After(list[j].InstalledAt) })
+
+	kept := make([]*InstalledVersion, 0, keepVersions)
+	for i, v := range list {
+		if v.Active || i < keepVersions {
+			kept = append(kept, v)
+			continue
+		}
+		os.RemoveAll(filepath.Dir(v.Path))
+	}
+	s.installed[coreType] = kept
+}
+
+// ListInstalled 获取核心的已安装版本（最新安装的在前）
+func (s *Service) ListInstalled(coreType string) ([]InstalledVersion, error) {
+	if _, ok := coreRepos[coreType]; !ok {
+		return nil, fmt.Errorf("unknown core type: %s", coreType)
+	}
+	s.installMu.Lock()
+	defer s.installMu.Unlock()
+
+	list := make([]InstalledVersion, 0, len(s.installed[coreType]))
+	for _, v := range s.installed[coreType] {
+		list = append(list, *v)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].InstalledAt.After(list[j].InstalledAt) })
+	return list, nil
+}
+
+// ActivateVersion 切换到已安装的指定版本
+func (s *Service) ActivateVersion(coreType, version string) error {
+	if _, ok := coreRepos[coreType]; !ok {
+		return fmt.Errorf("unknown core type: %s", coreType)
+	}
+	s.installMu.Lock()
+	defer s.installMu.Unlock()
+
+	s.archiveCurrent(coreType)
+	err := s.activateLocked(coreType, version)
+	if saveErr := s.saveVersions(); saveErr != nil {
+		fmt.Printf("⚠️ 保存核心版本记录失败: %v\n", saveErr)
+	}
+	return err
+}
+
+// Rollback 回滚到当前版本之前安装的最近一个版本
+func (s *Service) Rollback(coreType string) (string, error) {
+	if _, ok := coreRepos[coreType]; !ok {
+		return "", fmt.Errorf("unknown core type: %s", coreType)
+	}
+	s.installMu.Lock()
+	defer s.installMu.Unlock()
+
+	s.archiveCurrent(coreType)
+	active := s.activeVersion(coreType)
+	var target *InstalledVersion
+	for _, v := range s.installed[coreType] {
+		if v == active || (active != nil && !v.InstalledAt.Before(active.InstalledAt)) {
+			continue
+		}
+		if target == nil || v.InstalledAt.After(target.InstalledAt) {
+			target = v
+		}
+	}
+	if target == nil {
+		return "", fmt.Errorf("没有可回滚的版本")
+	}
+
+	err := s.activateLocked(coreType, target.Version)
+	if saveErr := s.saveVersions(); saveErr != nil {
+		fmt.Printf("⚠️ 保存核心版本记录失败: %v\n", saveErr)
+	}
+	return target.Version, err
+}
@@ -0,0 +1,344 @@
+package core
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"p-box/backend/events"
+)
+
+// stubCore 生成模拟 mihomo 的脚本：-v 输出版本号，-t 校验配置
+func stubCore(version string, probeOK, checkOK bool) []byte {
+	exit := func(ok bool) int {
+		if ok {
+			return 0
+		}
+		return 1
+	}
+	return []byte(fmt.Sprintf(`#!/bin/sh
+case "$1" in
+-v) echo "Mihomo Meta v%s linux amd64"; exit %d ;;
+-t) echo "config check"; exit %d ;;
+esac
+exit 0
+`, version, exit(probeOK), exit(checkOK)))
+}
+
+// newTestService 在临时目录创建核心服务
+func newTestService(t *testing.T) (*Service, *events.Bus) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("需要 /bin/sh 运行模拟核心")
+	}
+	s := NewService(t.TempDir())
+	bus := events.NewBus()
+	s.SetEventBus(bus)
+	return s, bus
+}
+
+// installStub 写入模拟核心并登记为新版本
+func installStub(t *testing.T, s *Service, version string, script []byte, installedAt time.Time) error {
+	t.Helper()
+	path := s.versionBinaryPath("mihomo", version)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, script, 0755); err != nil {
+		t.Fatal(err)
+	}
+	return s.installVersion("mihomo", &InstalledVersion{Version: version, Path: path, InstalledAt: installedAt})
+}
+
+// writeConfig 写入配置文件，使切换版本时执行配置校验
+func writeConfig(t *testing.T, s *Service) {
+	t.Helper()
+	path := filepath.Join(s.dataDir, "configs", "config.yaml")
+	os.MkdirAll(filepath.Dir(path), 0755)
+	if err := os.WriteFile(path, []byte("mode: rule\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// activeVersionOf 当前使用的版本号
+func activeVersionOf(s *Service) string {
+	s.installMu.Lock()
+	defer s.installMu.Unlock()
+	if v := s.activeVersion("mihomo"); v != nil {
+		return v.Version
+	}
+	return ""
+}
+
+// assertActiveBinary 检查正在使用的二进制与指定脚本一致
+func assertActiveBinary(t *testing.T, s *Service, want []byte) {
+	t.Helper()
+	got, err := os.ReadFile(s.getCoreBinaryPath("mihomo"))
+	if err != nil {
+		t.Fatalf("read active binary: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("active binary = %q, want %q", got, want)
+	}
+}
+
+func TestFetchChecksumFile(t *testing.T) {
+	const filename = "mihomo-linux-amd64-v1.2.3.gz"
+	sum := strings.Repeat("ab", 32)
+
+	tests := []struct {
+		name    string
+		body    string
+		status  int
+		single  bool
+		want    string
+		wantErr bool
+	}{
+		{name: "校验和列表", body: strings.Repeat("cd", 32) + "  other.gz\n" + sum + "  " + filename + "\n", want: sum},
+		{name: "二进制模式标记", body: sum + " *" + filename + "\n", want: sum},
+		{name: "大写哈希", body: strings.ToUpper(sum) + "  " + filename + "\n", want: sum},
+		{name: "单文件只有哈希", body: sum + "\n", single: true, want: sum},
+		{name: "列表中只有哈希不接受", body: sum + "\n", wantErr: true},
+		{name: "非十六进制哈希", body: strings.Repeat("zz", 32) + "  " + filename + "\n", wantErr: true},
+		{name: "没有该文件", body: sum + "  other.gz\n", wantErr: true},
+		{name: "HTTP 错误", status: http.StatusNotFound, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.status != 0 {
+					w.WriteHeader(tt.status)
+				}
+				w.Write([]byte(tt.body))
+			}))
+			defer srv.Close()
+
+			got, err := fetchChecksumFile(srv.Client(), srv.URL, filename, tt.single)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("fetchChecksumFile = %q, %v; want %q, err %v", got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDownloadCoreVerifiesChecksum(t *testing.T) {
+	const version = "1.2.3"
+	script := stubCore(version, true, true)
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Write(script)
+	zw.Close()
+	raw := sha256.Sum256(gz.Bytes())
+	sum := hex.EncodeToString(raw[:])
+	wrong := strings.Repeat("0", 64)
+
+	tests := []struct {
+		name            string
+		digest          string // GitHub API 返回的 digest，为空时 API 不可用
+		checksumFile    string // 镜像上 checksums.txt 中的哈希，为空时不存在
+		allowUnverified bool
+		wantErr         string
+		wantVerified    bool
+	}{
+		{name: "digest 一致", digest: "sha256:" + sum, wantVerified: true},
+		{name: "digest 不一致", digest: "sha256:" + wrong, wantErr: "校验和不匹配"},
+		{name: "API 不可用时使用镜像校验和文件", checksumFile: sum, wantVerified: true},
+		{name: "镜像校验和不一致", checksumFile: wrong, wantErr: "校验和不匹配"},
+		{name: "没有校验和时拒绝安装", wantErr: "已取消安装"},
+		{name: "允许跳过校验", allowUnverified: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestService(t)
+			s.cores["mihomo"].LatestVersion = version
+			_, filename, _ := s.getCoreDownloadURLs("mihomo")
+
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch r.URL.Path {
+				case "/repos/MetaCubeX/mihomo/releases/tags/v" + version:
+					if tt.digest == "" {
+						w.WriteHeader(http.StatusForbidden)
+						return
+					}
+					fmt.Fprintf(w, `{"assets":[{"name":%q,"digest":%q}]}`, filename, tt.digest)
+				case "/download/" + filename:
+					w.Write(gz.Bytes())
+				case "/download/checksums.txt":
+					if tt.checksumFile == "" {
+						http.NotFound(w, r)
+						return
+					}
+					fmt.Fprintf(w, "%s  %s\n", tt.checksumFile, filename)
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer srv.Close()
+
+			oldBase := GitHubAPIBase
+			GitHubAPIBase = srv.URL
+			defer func() { GitHubAPIBase = oldBase }()
+			if err := s.SetMirrors([]Mirror{{Name: "test", URL: srv.URL + "/download/{filename}"}}); err != nil {
+				t.Fatal(err)
+			}
+
+			err := s.DownloadCore("mihomo", tt.allowUnverified)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				if v := activeVersionOf(s); v != "" {
+					t.Errorf("active version = %q after rejected download", v)
+				}
+				if _, err := os.Stat(s.getCoreBinaryPath("mihomo")); !os.IsNotExist(err) {
+					t.Errorf("core binary installed after rejected download")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("DownloadCore: %v", err)
+			}
+			list, _ := s.ListInstalled("mihomo")
+			if len(list) != 1 || list[0].Version != version || !list[0].Active || list[0].Verified != tt.wantVerified {
+				t.Errorf("installed = %+v, want active %s verified %v", list, version, tt.wantVerified)
+			}
+			assertActiveBinary(t, s, script)
+		})
+	}
+}
+
+func TestInstallKeepsOldVersionWhenProbeFails(t *testing.T) {
+	s, _ := newTestService(t)
+	now := time.Now()
+	good := stubCore("1.0.0", true, true)
+	if err := installStub(t, s, "1.0.0", good, now); err != nil {
+		t.Fatalf("install 1.0.0: %v", err)
+	}
+
+	err := installStub(t, s, "2.0.0", stubCore("2.0.0", false, true), now.Add(time.Hour))
+	if err == nil || !strings.Contains(err.Error(), "已保留原版本") {
+		t.Fatalf("err = %v, want probe failure", err)
+	}
+	if v := activeVersionOf(s); v != "1.0.0" {
+		t.Errorf("active version = %q, want 1.0.0", v)
+	}
+	assertActiveBinary(t, s, good)
+	if _, err := os.Stat(filepath.Dir(s.versionBinaryPath("mihomo", "2.0.0"))); !os.IsNotExist(err) {
+		t.Errorf("failed version directory not removed")
+	}
+	if list, _ := s.ListInstalled("mihomo"); len(list) != 1 {
+		t.Errorf("installed = %+v, want only 1.0.0", list)
+	}
+}
+
+func TestActivateRollsBackOnConfigCheckFailure(t *testing.T) {
+	s, bus := newTestService(t)
+	writeConfig(t, s)
+	now := time.Now()
+	good := stubCore("1.0.0", true, true)
+	if err := installStub(t, s, "1.0.0", good, now); err != nil {
+		t.Fatalf("install 1.0.0: %v", err)
+	}
+
+	err := installStub(t, s, "2.0.0", stubCore("2.0.0", true, false), now.Add(time.Hour))
+	if err == nil || !strings.Contains(err.Error(), "已回滚到 1.0.0") {
+		t.Fatalf("err = %v, want rollback to 1.0.0", err)
+	}
+	if v := activeVersionOf(s); v != "1.0.0" {
+		t.Errorf("active version = %q, want 1.0.0", v)
+	}
+	assertActiveBinary(t, s, good)
+
+	rollbacks := bus.Since(0, events.CoreRollback)
+	if len(rollbacks) != 1 {
+		t.Fatalf("rollback events = %d, want 1", len(rollbacks))
+	}
+	payload, _ := rollbacks[0].Data.(events.CoreVersionPayload)
+	if payload.Version != "1.0.0" || payload.Previous != "2.0.0" {
+		t.Errorf("rollback payload = %+v", payload)
+	}
+}
+
+func TestPruneVersionsKeepsActive(t *testing.T) {
+	base := time.Now()
+	tests := []struct {
+		name   string
+		active int // 当前版本的序号（0 为最早安装）
+		want   []string
+	}{
+		{name: "当前为最新版本", active: 4, want: []string{"5", "4", "3"}},
+		{name: "当前为最早版本", active: 0, want: []string{"5", "4", "3", "1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _ := newTestService(t)
+			for i := 0; i < 5; i++ {
+				version := fmt.Sprint(i + 1)
+				path := s.versionBinaryPath("mihomo", version)
+				os.MkdirAll(filepath.Dir(path), 0755)
+				os.WriteFile(path, stubCore(version, true, true), 0755)
+				s.installed["mihomo"] = append(s.installed["mihomo"], &InstalledVersion{
+					Version:     version,
+					Path:        path,
+					Active:      i == tt.active,
+					InstalledAt: base.Add(time.Duration(i) * time.Hour),
+				})
+			}
+
+			s.pruneVersions("mihomo")
+
+			var got []string
+			for _, v := range s.installed["mihomo"] {
+				got = append(got, v.Version)
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("kept = %v, want %v", got, tt.want)
+			}
+			for i := 0; i < 5; i++ {
+				version := fmt.Sprint(i + 1)
+				kept := strings.Contains(","+strings.Join(tt.want, ",")+",", ","+version+",")
+				_, err := os.Stat(s.versionBinaryPath("mihomo", version))
+				if kept != (err == nil) {
+					t.Errorf("version %s file exists = %v, want %v", version, err == nil, kept)
+				}
+			}
+		})
+	}
+}
+
+func TestRollbackChoosesPreviousInstall(t *testing.T) {
+	s, _ := newTestService(t)
+	writeConfig(t, s)
+	base := time.Now()
+	scripts := map[string][]byte{}
+	for i, version := range []string{"1.0.0", "2.0.0", "3.0.0"} {
+		scripts[version] = stubCore(version, true, true)
+		if err := installStub(t, s, version, scripts[version], base.Add(time.Duration(i)*time.Hour)); err != nil {
+			t.Fatalf("install %s: %v", version, err)
+		}
+	}
+
+	for _, want := range []string{"2.0.0", "1.0.0"} {
+		got, err := s.Rollback("mihomo")
+		if err != nil || got != want {
+			t.Fatalf("Rollback = %q, %v; want %q", got, err, want)
+		}
+		if v := activeVersionOf(s); v != want {
+			t.Errorf("active version = %q, want %q", v, want)
+		}
+		assertActiveBinary(t, s, scripts[want])
+	}
+	if _, err := s.Rollback("mihomo"); err == nil {
+		t.Error("Rollback from oldest version succeeded, want error")
+	}
+}
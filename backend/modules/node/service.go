@@ -227,6 +227,54 @@ func (s *Service) AddManualAdvanced(name, nodeType, server string, port int, con
 	return node, s.saveManualNodes()
 }
 
+// ManualNodeInput 批量添加手动节点的参数
+type ManualNodeInput struct {
+	Name   string
+	Type   string
+	Server string
+	Port   int
+	Config map[string]interface{}
+}
+
+// AddManualBatch 批量添加手动节点，全部成功或全部不添加
+func (s *Service) AddManualBatch(inputs []ManualNodeInput) ([]*Node, error) {
+	nodes := make([]*Node, 0, len(inputs))
+	for _, input := range inputs {
+		configJSON, err := json.Marshal(input.Config)
+		if err != nil {
+			return nil, fmt.Errorf("节点 %s 配置序列化失败: %w", input.Name, err)
+		}
+		nodes = append(nodes, &Node{
+			ID:         uuid.New().String(),
+			Name:       input.Name,
+			Type:       input.Type,
+			Server:     input.Server,
+			ServerPort: input.Port,
+			IsManual:   true,
+			Enabled:    true,
+			Delay:      -1,
+			Config:     string(configJSON),
+		})
+	}
+
+	s.mu.Lock()
+	for _, node := range nodes {
+		s.manualNodes[node.ID] = node
+	}
+	s.mu.Unlock()
+
+	if err := s.saveManualNodes(); err != nil {
+		// 保存失败时撤销本次添加
+		s.mu.Lock()
+		for _, node := range nodes {
+			delete(s.manualNodes, node.ID)
+		}
+		s.mu.Unlock()
+		return nil, err
+	}
+	return nodes, nil
+}
+
 // generateShareURLFromConfig 根据配置生成分享链接
 func (s *Service) generateShareURLFromConfig(node *Node, config map[string]interface{}) string {
 	// 基于节点类型生成对应的分享链接
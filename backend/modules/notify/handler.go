@@ -0,0 +1,157 @@
+package notify
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Handler 通知处理器
+type Handler struct {
+	service *Service
+}
+
+// NewHandler 创建通知处理器
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// RegisterRoutes 注册路由
+func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/events", h.ListEvents)
+	r.GET("/settings", h.GetSettings)
+	r.PUT("/settings", h.UpdateSettings)
+	r.GET("/history", h.GetHistory)
+
+	r.GET("/channels", h.ListChannels)
+	r.POST("/channels", h.CreateChannel)
+	r.PUT("/channels/:id", h.UpdateChannel)
+	r.DELETE("/channels/:id", h.DeleteChannel)
+	r.POST("/channels/:id/test", h.TestChannel)
+
+	r.GET("/rules", h.ListRules)
+	r.POST("/rules", h.CreateRule)
+	r.PUT("/rules/:id", h.UpdateRule)
+	r.DELETE("/rules/:id", h.DeleteRule)
+}
+
+// respond 统一响应
+func respond(c *gin.Context, data interface{}, err error) {
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    data,
+	})
+}
+
+// bindError 参数错误响应
+func bindError(c *gin.Context, err error) {
+	c.JSON(http.StatusBadRequest, gin.H{
+		"code":    1,
+		"message": "参数错误: " + err.Error(),
+	})
+}
+
+// ListEvents 获取默认通知的事件
+func (h *Handler) ListEvents(c *gin.Context) {
+	respond(c, AlertEvents, nil)
+}
+
+// GetSettings 获取通知设置
+func (h *Handler) GetSettings(c *gin.Context) {
+	respond(c, h.service.GetSettings(), nil)
+}
+
+// UpdateSettings 更新通知设置
+func (h *Handler) UpdateSettings(c *gin.Context) {
+	var settings Settings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		bindError(c, err)
+		return
+	}
+	respond(c, settings, h.service.UpdateSettings(settings))
+}
+
+// GetHistory 获取发送记录
+func (h *Handler) GetHistory(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	respond(c, h.service.History(limit), nil)
+}
+
+// ListChannels 获取渠道列表
+func (h *Handler) ListChannels(c *gin.Context) {
+	respond(c, h.service.ListChannels(), nil)
+}
+
+// CreateChannel 创建渠道
+func (h *Handler) CreateChannel(c *gin.Context) {
+	var ch Channel
+	if err := c.ShouldBindJSON(&ch); err != nil {
+		bindError(c, err)
+		return
+	}
+	created, err := h.service.CreateChannel(&ch)
+	respond(c, created, err)
+}
+
+// UpdateChannel 更新渠道
+func (h *Handler) UpdateChannel(c *gin.Context) {
+	var ch Channel
+	if err := c.ShouldBindJSON(&ch); err != nil {
+		bindError(c, err)
+		return
+	}
+	updated, err := h.service.UpdateChannel(c.Param("id"), &ch)
+	respond(c, updated, err)
+}
+
+// DeleteChannel 删除渠道
+func (h *Handler) DeleteChannel(c *gin.Context) {
+	respond(c, nil, h.service.DeleteChannel(c.Param("id")))
+}
+
+// TestChannel 发送测试通知，发送失败时 data.error 为失败原因
+func (h *Handler) TestChannel(c *gin.Context) {
+	delivery, err := h.service.TestChannel(c.Param("id"))
+	respond(c, delivery, err)
+}
+
+// ListRules 获取规则列表
+func (h *Handler) ListRules(c *gin.Context) {
+	respond(c, h.service.ListRules(), nil)
+}
+
+// CreateRule 创建规则
+func (h *Handler) CreateRule(c *gin.Context) {
+	var rule Rule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		bindError(c, err)
+		return
+	}
+	created, err := h.service.CreateRule(&rule)
+	respond(c, created, err)
+}
+
+// UpdateRule 更新规则
+func (h *Handler) UpdateRule(c *gin.Context) {
+	var rule Rule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		bindError(c, err)
+		return
+	}
+	updated, err := h.service.UpdateRule(c.Param("id"), &rule)
+	respond(c, updated, err)
+}
+
+// DeleteRule 删除规则
+func (h *Handler) DeleteRule(c *gin.Context) {
+	respond(c, nil, h.service.DeleteRule(c.Param("id")))
+}
@@ -0,0 +1,219 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// ============================================================================
+// 通知发送：Webhook、Telegram Bot、SMTP 邮件
+// ============================================================================
+
+// sendTimeout 单次发送超时
+const sendTimeout = 10 * time.Second
+
+// defaultWebhookTemplate 未配置模板时的请求体
+const defaultWebhookTemplate = `{"event":{{json .Event}},"title":{{json .Title}},"text":{{json .Text}},"time":{{json .Time}},"data":{{json .Data}}}`
+
+// templateFuncs 模板函数：json 将值编码为 JSON
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+}
+
+// send 按渠道类型发送
+func send(ch *Channel, msg Message) error {
+	switch ch.Type {
+	case ChannelWebhook:
+		return sendWebhook(ch.Webhook, msg)
+	case ChannelTelegram:
+		return sendTelegram(ch.Telegram, msg)
+	case ChannelSMTP:
+		return sendSMTP(ch.SMTP, msg)
+	}
+	return fmt.Errorf("不支持的通知渠道类型: %s", ch.Type)
+}
+
+// renderWebhookBody 渲染 Webhook 请求体
+func renderWebhookBody(tpl string, msg Message) ([]byte, error) {
+	if strings.TrimSpace(tpl) == "" {
+		tpl = defaultWebhookTemplate
+	}
+	t, err := template.New("webhook").Funcs(templateFuncs).Parse(tpl)
+	if err != nil {
+		return nil, fmt.Errorf("模板解析失败: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, msg); err != nil {
+		return nil, fmt.Errorf("模板渲染失败: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// sendWebhook 发送 Webhook
+func sendWebhook(cfg *WebhookConfig, msg Message) error {
+	body, err := renderWebhookBody(cfg.Template, msg)
+	if err != nil {
+		return err
+	}
+	method := strings.ToUpper(cfg.Method)
+	if method == "" {
+		method = http.MethodPost
+	}
+	req, err := http.NewRequest(method, cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range cfg.Headers {
+		req.Header.Set(key, value)
+	}
+
+	client := &http.Client{Timeout: sendTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		data, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("HTTP %d: %s", resp.StatusCode, strings.TrimSpace(string(data)))
+	}
+	return nil
+}
+
+// sendTelegram 通过 Telegram Bot API 发送消息
+func sendTelegram(cfg *TelegramConfig, msg Message) error {
+	apiBase := strings.TrimSuffix(cfg.APIBase, "/")
+	if apiBase == "" {
+		apiBase = "https://api.telegram.org"
+	}
+	payload, _ := json.Marshal(map[string]interface{}{
+		"chat_id":                  cfg.ChatID,
+		"text":                     msg.Title + "\n\n" + msg.Text,
+		"disable_web_page_preview": true,
+	})
+
+	client := &http.Client{Timeout: sendTimeout}
+	resp, err := client.Post(apiBase+"/bot"+cfg.BotToken+"/sendMessage", "application/json", bytes.NewReader(payload))
+	if err != nil {
+		// 错误信息中的 URL 包含 Bot Token，不直接返回
+		return fmt.Errorf("请求 Telegram API 失败")
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		OK          bool   `json:"ok"`
+		Description string `json:"description"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return fmt.Errorf("HTTP %d: 响应解析失败", resp.StatusCode)
+	}
+	if !result.OK {
+		return fmt.Errorf("Telegram API 错误: %s", result.Description)
+	}
+	return nil
+}
+
+// sendSMTP 发送邮件
+// Security: tls 为直接 TLS（通常 465 端口），starttls 要求 STARTTLS，留空时服务器支持则使用 STARTTLS
+func sendSMTP(cfg *SMTPConfig, msg Message) error {
+	port := cfg.Port
+	if port == 0 {
+		port = 25
+		if cfg.Security == "tls" {
+			port = 465
+		}
+	}
+	addr := net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: cfg.Host}
+	dialer := &net.Dialer{Timeout: sendTimeout}
+
+	var conn net.Conn
+	var err error
+	if cfg.Security == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("连接 SMTP 服务器失败: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(2 * sendTimeout))
+
+	c, err := smtp.NewClient(conn, cfg.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if cfg.Security != "tls" {
+		if ok, _ := c.Extension("STARTTLS"); ok {
+			if err := c.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("STARTTLS 失败: %w", err)
+			}
+		} else if cfg.Security == "starttls" {
+			return fmt.Errorf("SMTP 服务器不支持 STARTTLS")
+		}
+	}
+	if cfg.Username != "" {
+		if err := c.Auth(smtp.PlainAuth("", cfg.Username, cfg.Password, cfg.Host)); err != nil {
+			return fmt.Errorf("SMTP 认证失败: %w", err)
+		}
+	}
+
+	if err := c.Mail(cfg.From); err != nil {
+		return err
+	}
+	for _, to := range cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return fmt.Errorf("收件人 %s 被拒绝: %w", to, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(buildMail(cfg, msg)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// buildMail 构造 UTF-8 纯文本邮件
+func buildMail(cfg *SMTPConfig, msg Message) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("From: " + cfg.From + "\r\n")
+	buf.WriteString("To: " + strings.Join(cfg.To, ", ") + "\r\n")
+	buf.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", "[P-BOX] "+msg.Title) + "\r\n")
+	buf.WriteString("Date: " + msg.Time.Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+
+	encoded := base64.StdEncoding.EncodeToString([]byte(msg.Text))
+	for len(encoded) > 76 {
+		buf.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	buf.WriteString(encoded + "\r\n")
+	return buf.Bytes()
+}
@@ -0,0 +1,275 @@
+package notify
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testMessage 测试用通知
+func testMessage() Message {
+	return Message{
+		Event: "core.crashed",
+		Title: "代理核心异常退出",
+		Text:  "mihomo 核心 (PID 42) 意外退出",
+		Time:  time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Data:  map[string]interface{}{"core": "mihomo"},
+	}
+}
+
+func TestSendWebhook(t *testing.T) {
+	var gotMethod, gotHeader, gotType string
+	var gotBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotHeader = r.Header.Get("X-Token")
+		gotType = r.Header.Get("Content-Type")
+		gotBody, _ = io.ReadAll(r.Body)
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name   string
+		cfg    WebhookConfig
+		method string
+		check  func(t *testing.T, body []byte)
+	}{
+		{
+			name:   "默认模板",
+			cfg:    WebhookConfig{URL: srv.URL},
+			method: http.MethodPost,
+			check: func(t *testing.T, body []byte) {
+				var payload map[string]interface{}
+				if err := json.Unmarshal(body, &payload); err != nil {
+					t.Fatalf("body is not JSON: %s", body)
+				}
+				if payload["event"] != "core.crashed" || payload["title"] != "代理核心异常退出" {
+					t.Errorf("payload = %v", payload)
+				}
+				if data, _ := payload["data"].(map[string]interface{}); data["core"] != "mihomo" {
+					t.Errorf("data = %v", payload["data"])
+				}
+			},
+		},
+		{
+			name:   "自定义模板、方法与请求头",
+			cfg:    WebhookConfig{URL: srv.URL, Method: "put", Headers: map[string]string{"X-Token": "abc"}, Template: `{"msg":{{json .Text}}}`},
+			method: http.MethodPut,
+			check: func(t *testing.T, body []byte) {
+				if string(body) != `{"msg":"mihomo 核心 (PID 42) 意外退出"}` {
+					t.Errorf("body = %s", body)
+				}
+				if gotHeader != "abc" {
+					t.Errorf("X-Token = %q", gotHeader)
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotBody, gotHeader = nil, ""
+			if err := sendWebhook(&tt.cfg, testMessage()); err != nil {
+				t.Fatalf("sendWebhook: %v", err)
+			}
+			if gotMethod != tt.method || gotType != "application/json" {
+				t.Errorf("method = %s, content-type = %s", gotMethod, gotType)
+			}
+			tt.check(t, gotBody)
+		})
+	}
+}
+
+func TestSendWebhookError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "bad token", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	err := sendWebhook(&WebhookConfig{URL: srv.URL}, testMessage())
+	if err == nil || !strings.Contains(err.Error(), "HTTP 401") || !strings.Contains(err.Error(), "bad token") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestSendTelegram(t *testing.T) {
+	var gotPath string
+	var gotPayload map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&gotPayload)
+		if gotPayload["chat_id"] == "bad" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"ok":false,"description":"Bad Request: chat not found"}`))
+			return
+		}
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	cfg := &TelegramConfig{BotToken: "123:secret", ChatID: "42", APIBase: srv.URL + "/"}
+	if err := sendTelegram(cfg, testMessage()); err != nil {
+		t.Fatalf("sendTelegram: %v", err)
+	}
+	if gotPath != "/bot123:secret/sendMessage" {
+		t.Errorf("path = %s", gotPath)
+	}
+	if gotPayload["chat_id"] != "42" || gotPayload["text"] != "代理核心异常退出\n\nmihomo 核心 (PID 42) 意外退出" {
+		t.Errorf("payload = %v", gotPayload)
+	}
+
+	cfg.ChatID = "bad"
+	err := sendTelegram(cfg, testMessage())
+	if err == nil || !strings.Contains(err.Error(), "chat not found") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestSendTelegramHidesToken(t *testing.T) {
+	// 连接失败时错误信息不能包含 Bot Token
+	cfg := &TelegramConfig{BotToken: "123:secret", ChatID: "42", APIBase: "http://127.0.0.1:1"}
+	err := sendTelegram(cfg, testMessage())
+	if err == nil || strings.Contains(err.Error(), "secret") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// smtpSession 本地 SMTP 服务器收到的内容
+type smtpSession struct {
+	auth string
+	from string
+	to   []string
+	data string
+}
+
+// startSMTPServer 启动只处理一次会话的本地 SMTP 服务器（不支持 STARTTLS）
+func startSMTPServer(t *testing.T) (int, <-chan smtpSession) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	done := make(chan smtpSession, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) { conn.Write([]byte(s + "\r\n")) }
+
+		var session smtpSession
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0])
+			switch cmd {
+			case "EHLO":
+				reply("250-localhost")
+				reply("250 AUTH PLAIN")
+			case "AUTH":
+				session.auth = strings.TrimPrefix(line, "AUTH PLAIN ")
+				reply("235 2.7.0 Authentication successful")
+			case "MAIL":
+				session.from = line
+				reply("250 OK")
+			case "RCPT":
+				session.to = append(session.to, line)
+				reply("250 OK")
+			case "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				session.data = data.String()
+				reply("250 OK")
+			case "QUIT":
+				reply("221 Bye")
+				done <- session
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+	return ln.Addr().(*net.TCPAddr).Port, done
+}
+
+func TestSendSMTP(t *testing.T) {
+	port, done := startSMTPServer(t)
+	cfg := &SMTPConfig{
+		Host:     "127.0.0.1",
+		Port:     port,
+		Username: "user",
+		Password: "pass",
+		From:     "pbox@example.com",
+		To:       []string{"a@example.com", "b@example.com"},
+	}
+	if err := sendSMTP(cfg, testMessage()); err != nil {
+		t.Fatalf("sendSMTP: %v", err)
+	}
+
+	var session smtpSession
+	select {
+	case session = <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("SMTP server did not receive QUIT")
+	}
+	if auth, _ := base64.StdEncoding.DecodeString(session.auth); string(auth) != "\x00user\x00pass" {
+		t.Errorf("auth = %q", auth)
+	}
+	if session.from != "MAIL FROM:<pbox@example.com>" || len(session.to) != 2 || session.to[1] != "RCPT TO:<b@example.com>" {
+		t.Errorf("from = %q, to = %q", session.from, session.to)
+	}
+	if !strings.Contains(session.data, "To: a@example.com, b@example.com\r\n") ||
+		!strings.Contains(session.data, "Subject: =?UTF-8?b?") {
+		t.Errorf("headers = %q", session.data)
+	}
+	body := session.data[strings.Index(session.data, "\r\n\r\n")+4:]
+	text, _ := base64.StdEncoding.DecodeString(strings.ReplaceAll(body, "\r\n", ""))
+	if string(text) != testMessage().Text {
+		t.Errorf("body = %q", text)
+	}
+}
+
+func TestSendSMTPRequiresStartTLS(t *testing.T) {
+	port, _ := startSMTPServer(t)
+	cfg := &SMTPConfig{Host: "127.0.0.1", Port: port, From: "pbox@example.com", To: []string{"a@example.com"}, Security: "starttls"}
+	err := sendSMTP(cfg, testMessage())
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestSendSMTPConnectError(t *testing.T) {
+	ln, _ := net.Listen("tcp", "127.0.0.1:0")
+	port := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+
+	cfg := &SMTPConfig{Host: "127.0.0.1", Port: port, From: "pbox@example.com", To: []string{"a@example.com"}}
+	if err := sendSMTP(cfg, testMessage()); err == nil || !strings.Contains(err.Error(), strconv.Itoa(port)) {
+		t.Errorf("err = %v", err)
+	}
+}
@@ -0,0 +1,813 @@
+package notify
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"p-box/backend/events"
+)
+
+// ============================================================================
+// 通知：订阅事件总线，把需要关注的状态变化按规则发送到各通知渠道
+// ============================================================================
+
+// 通知渠道类型
+const (
+	ChannelWebhook  = "webhook"
+	ChannelTelegram = "telegram"
+	ChannelSMTP     = "smtp"
+)
+
+// 由通知模块生成的事件（事件总线上没有对应事件）
+const (
+	EventSubFailing  = "subscription.failing"  // 订阅连续更新失败
+	EventSubExpiring = "subscription.expiring" // 订阅即将到期
+	EventTest        = "notify.test"           // 测试发送
+)
+
+// AlertEvents 默认通知的事件（规则未指定事件时使用）
+var AlertEvents = []EventInfo{
+	{Type: events.CoreCrashed, Description: "代理核心意外退出"},
+	{Type: EventSubFailing, Description: "订阅连续更新失败"},
+	{Type: EventSubExpiring, Description: "订阅即将到期"},
+	{Type: events.FailoverDown, Description: "故障转移分组内节点全部不可用"},
+	{Type: events.CoreUpdate, Description: "核心有新版本"},
+}
+
+// alertBusEvents 生成 AlertEvents 所需的总线事件，单独订阅，避免被其他事件挤占缓冲
+var alertBusEvents = []string{
+	events.CoreCrashed,
+	events.SubUpdated,
+	events.FailoverDown,
+	events.CoreUpdate,
+}
+
+// secretMask 接口返回的密钥占位符，更新渠道时原样提交表示保留原值
+const secretMask = "******"
+
+// historyLimit 发送记录保留数量
+const historyLimit = 200
+
+// EventInfo 可通知的事件说明
+type EventInfo struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+}
+
+// Settings 通知设置
+type Settings struct {
+	SubFailThreshold int `json:"subFailThreshold"` // 订阅连续失败多少次后通知
+	ExpiryDays       int `json:"expiryDays"`       // 订阅到期前多少天开始提醒
+	MaxPerHour       int `json:"maxPerHour"`       // 每个渠道每小时最多发送条数，0 表示不限制
+}
+
+// DefaultSettings 默认通知设置
+func DefaultSettings() Settings {
+	return Settings{
+		SubFailThreshold: 3,
+		ExpiryDays:       3,
+		MaxPerHour:       20,
+	}
+}
+
+// WebhookConfig Webhook 配置
+type WebhookConfig struct {
+	URL      string            `json:"url"`
+	Method   string            `json:"method,omitempty"` // 默认 POST
+	Headers  map[string]string `json:"headers,omitempty"`
+	Template string            `json:"template,omitempty"` // Go 模板，可用 .Event .Title .Text .Time .Data 与 json 函数
+}
+
+// TelegramConfig Telegram Bot 配置
+type TelegramConfig struct {
+	BotToken string `json:"botToken"`
+	ChatID   string `json:"chatId"`
+	APIBase  string `json:"apiBase,omitempty"` // 默认 https://api.telegram.org，可填写反代地址
+}
+
+// SMTPConfig 邮件配置
+type SMTPConfig struct {
+	Host     string   `json:"host"`
+	Port     int      `json:"port"`
+	Username string   `json:"username,omitempty"`
+	Password string   `json:"password,omitempty"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+	Security string   `json:"security,omitempty"` // 留空（自动 STARTTLS）、starttls、tls
+}
+
+// Channel 通知渠道
+type Channel struct {
+	ID       string          `json:"id"`
+	Name     string          `json:"name"`
+	Type     string          `json:"type"` // webhook, telegram, smtp
+	Enabled  bool            `json:"enabled"`
+	Webhook  *WebhookConfig  `json:"webhook,omitempty"`
+	Telegram *TelegramConfig `json:"telegram,omitempty"`
+	SMTP     *SMTPConfig     `json:"smtp,omitempty"`
+}
+
+// Rule 通知路由规则
+type Rule struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Enabled  bool     `json:"enabled"`
+	Events   []string `json:"events"`   // 事件类型前缀，为空表示 AlertEvents，* 表示全部事件
+	Channels []string `json:"channels"` // 渠道 ID
+	Cooldown int      `json:"cooldown"` // 同一对象的同类通知最短间隔（秒）
+}
+
+// Message 通知内容
+type Message struct {
+	Event string      `json:"event"`
+	Title string      `json:"title"`
+	Text  string      `json:"text"`
+	Time  time.Time   `json:"time"`
+	Data  interface{} `json:"data,omitempty"`
+	Key   string      `json:"-"` // 限流对象（例如订阅 ID、分组名）
+}
+
+// Delivery 发送记录
+type Delivery struct {
+	Time        time.Time `json:"time"`
+	Event       string    `json:"event"`
+	Title       string    `json:"title"`
+	ChannelID   string    `json:"channelId"`
+	ChannelName string    `json:"channelName"`
+	Success     bool      `json:"success"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// SubscriptionInfo 订阅到期信息（由订阅模块提供）
+type SubscriptionInfo struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	ExpireTime *time.Time `json:"expireTime"`
+}
+
+// SubscriptionProvider 订阅信息提供者
+type SubscriptionProvider func() []SubscriptionInfo
+
+// notifyStore 持久化结构
+type notifyStore struct {
+	Settings Settings   `json:"settings"`
+	Channels []*Channel `json:"channels"`
+	Rules    []*Rule    `json:"rules"`
+	History  []Delivery `json:"history"`
+}
+
+// Service 通知服务
+type Service struct {
+	dataDir       string
+	store         notifyStore
+	subscriptions SubscriptionProvider
+	lastSent      map[string]time.Time   // 规则|事件|对象 -> 上次发送时间
+	channelSends  map[string][]time.Time // 渠道 ID -> 最近一小时的发送时间
+	subFailures   map[string]int         // 订阅 ID -> 连续失败次数
+	expiryNotice  map[string]time.Time   // 订阅 ID -> 上次到期提醒时间
+	mu            sync.Mutex
+	stopChan      chan struct{}
+}
+
+// NewService 创建通知服务并订阅事件总线
+func NewService(dataDir string, bus *events.Bus) *Service {
+	s := &Service{
+		dataDir:      dataDir,
+		store:        notifyStore{Settings: DefaultSettings()},
+		lastSent:     make(map[string]time.Time),
+		channelSends: make(map[string][]time.Time),
+		subFailures:  make(map[string]int),
+		expiryNotice: make(map[string]time.Time),
+		stopChan:     make(chan struct{}),
+	}
+	s.load()
+
+	// 告警事件与其他事件（自定义规则使用）分开订阅，发送异步进行，慢速渠道不会导致事件被丢弃
+	alerts, cancelAlerts := bus.Subscribe(alertBusEvents...)
+	others, cancelOthers := bus.Subscribe()
+	go func() {
+		defer cancelAlerts()
+		defer cancelOthers()
+		for {
+			select {
+			case event := <-alerts:
+				msg := s.messageFor(event)
+				go s.Dispatch(*msg)
+			case event := <-others:
+				if isAlertBusEvent(event.Type) {
+					continue
+				}
+				msg := s.messageFor(event)
+				go s.Dispatch(*msg)
+			case <-s.stopChan:
+				return
+			}
+		}
+	}()
+	go s.expiryLoop()
+	return s
+}
+
+// isAlertBusEvent 判断事件是否已由告警订阅处理
+func isAlertBusEvent(eventType string) bool {
+	for _, prefix := range alertBusEvents {
+		if strings.HasPrefix(eventType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetSubscriptionProvider 设置订阅信息提供者（用于到期提醒）
+func (s *Service) SetSubscriptionProvider(provider SubscriptionProvider) {
+	s.subscriptions = provider
+}
+
+// Stop 停止通知服务
+func (s *Service) Stop() {
+	close(s.stopChan)
+}
+
+// storePath 获取通知配置文件路径
+func (s *Service) storePath() string {
+	return filepath.Join(s.dataDir, "notify.json")
+}
+
+// load 加载通知配置
+func (s *Service) load() {
+	data, err := os.ReadFile(s.storePath())
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &s.store); err != nil {
+		fmt.Printf("⚠️ 读取通知配置失败: %v\n", err)
+	}
+}
+
+// save 保存通知配置（调用者需持有锁）
+func (s *Service) save() error {
+	data, err := json.MarshalIndent(s.store, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.storePath(), data, 0644)
+}
+
+// ========== 事件转换 ==========
+
+// messageFor 把总线事件转换为通知，是否发送由规则决定
+func (s *Service) messageFor(e events.Event) *Message {
+	msg := &Message{Event: e.Type, Time: e.Time, Data: e.Data}
+	switch data := e.Data.(type) {
+	case events.CorePayload:
+		if e.Type != events.CoreCrashed {
+			return s.genericMessage(msg)
+		}
+		msg.Title = "代理核心异常退出"
+		msg.Text = fmt.Sprintf("%s 核心 (PID %d) 意外退出", data.Core, data.PID)
+		if data.Error != "" {
+			msg.Text += ": " + data.Error
+		}
+		msg.Key = data.Core
+	case events.SubscriptionPayload:
+		if !s.trackSubscription(data) {
+			return s.genericMessage(msg)
+		}
+		msg.Event = EventSubFailing
+		msg.Title = "订阅更新连续失败"
+		msg.Text = fmt.Sprintf("订阅「%s」已连续 %d 次更新失败: %s", data.Name, s.GetSettings().SubFailThreshold, data.Error)
+		msg.Key = data.ID
+	case events.FailoverPayload:
+		if e.Type != events.FailoverDown {
+			return s.genericMessage(msg)
+		}
+		msg.Title = "节点全部不可用"
+		msg.Text = fmt.Sprintf("分组「%s」当前节点 %s 不可用，且没有可切换的成员。%s", data.Group, data.From, data.Detail)
+		msg.Key = data.Group
+	case events.CoreUpdatePayload:
+		msg.Title = "核心有新版本"
+		msg.Text = fmt.Sprintf("%s 当前版本 %s，最新版本 %s", data.Core, data.Current, data.Latest)
+		msg.Key = data.Core + "@" + data.Latest
+	default:
+		return s.genericMessage(msg)
+	}
+	return msg
+}
+
+// genericMessage 其他事件使用事件类型作为标题、负载 JSON 作为正文
+func (s *Service) genericMessage(msg *Message) *Message {
+	data, _ := json.Marshal(msg.Data)
+	msg.Title = msg.Event
+	msg.Text = string(data)
+	return msg
+}
+
+// trackSubscription 记录订阅连续失败次数，恰好达到阈值时返回 true
+func (s *Service) trackSubscription(p events.SubscriptionPayload) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if p.Success {
+		delete(s.subFailures, p.ID)
+		return false
+	}
+	s.subFailures[p.ID]++
+	return s.subFailures[p.ID] == s.store.Settings.SubFailThreshold
+}
+
+// expiryLoop 每小时检查一次订阅到期时间
+func (s *Service) expiryLoop() {
+	ticker := time.NewTicker(time.Hour)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.CheckExpiry()
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// CheckExpiry 检查订阅到期时间，每个订阅每天最多提醒一次
+func (s *Service) CheckExpiry() {
+	if s.subscriptions == nil {
+		return
+	}
+	days := s.GetSettings().ExpiryDays
+	if days <= 0 {
+		return
+	}
+
+	now := time.Now()
+	for _, sub := range s.subscriptions() {
+		if sub.ExpireTime == nil || sub.ExpireTime.Sub(now) > time.Duration(days)*24*time.Hour {
+			continue
+		}
+		s.mu.Lock()
+		last, notified := s.expiryNotice[sub.ID]
+		if notified && now.Sub(last) < 24*time.Hour {
+			s.mu.Unlock()
+			continue
+		}
+		s.expiryNotice[sub.ID] = now
+		s.mu.Unlock()
+
+		msg := Message{Event: EventSubExpiring, Title: "订阅即将到期", Time: now, Key: sub.ID, Data: sub}
+		expire := sub.ExpireTime.Format("2006-01-02 15:04")
+		if sub.ExpireTime.Before(now) {
+			msg.Title = "订阅已到期"
+			msg.Text = fmt.Sprintf("订阅「%s」已于 %s 到期", sub.Name, expire)
+		} else {
+			msg.Text = fmt.Sprintf("订阅「%s」将于 %s 到期（剩余 %.1f 天）", sub.Name, expire, sub.ExpireTime.Sub(now).Hours()/24)
+		}
+		s.Dispatch(msg)
+	}
+}
+
+// ========== 路由与发送 ==========
+
+// matchEvent 判断规则是否匹配事件
+func (r *Rule) matchEvent(eventType string) bool {
+	if len(r.Events) == 0 {
+		for _, e := range AlertEvents {
+			if e.Type == eventType {
+				return true
+			}
+		}
+		return false
+	}
+	for _, prefix := range r.Events {
+		if prefix == "*" || strings.HasPrefix(eventType, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Dispatch 按规则发送通知，同一条通知对每个渠道最多发送一次
+func (s *Service) Dispatch(msg Message) []Delivery {
+	now := time.Now()
+	var targets []*Channel
+
+	s.mu.Lock()
+	seen := make(map[string]bool)
+	for _, rule := range s.store.Rules {
+		if !rule.Enabled || !rule.matchEvent(msg.Event) {
+			continue
+		}
+		key := rule.ID + "|" + msg.Event + "|" + msg.Key
+		if last, ok := s.lastSent[key]; ok && now.Sub(last) < time.Duration(rule.Cooldown)*time.Second {
+			continue
+		}
+		s.lastSent[key] = now
+		for _, id := range rule.Channels {
+			ch := s.findChannel(id)
+			if ch == nil || !ch.Enabled || seen[id] {
+				continue
+			}
+			seen[id] = true
+			if !s.allowSend(id, now) {
+				fmt.Printf("⚠️ 通知渠道 %s 超出每小时发送上限，已跳过: %s\n", ch.Name, msg.Title)
+				continue
+			}
+			copied := *ch
+			targets = append(targets, &copied)
+		}
+	}
+	s.mu.Unlock()
+
+	deliveries := make([]Delivery, 0, len(targets))
+	for _, ch := range targets {
+		deliveries = append(deliveries, s.deliver(ch, msg))
+	}
+	return deliveries
+}
+
+// allowSend 检查渠道每小时发送上限并记录本次发送（调用者需持有锁）
+func (s *Service) allowSend(channelID string, now time.Time) bool {
+	limit := s.store.Settings.MaxPerHour
+	recent := s.channelSends[channelID][:0]
+	for _, t := range s.channelSends[channelID] {
+		if now.Sub(t) < time.Hour {
+			recent = append(recent, t)
+		}
+	}
+	if limit > 0 && len(recent) >= limit {
+		s.channelSends[channelID] = recent
+		return false
+	}
+	s.channelSends[channelID] = append(recent, now)
+	return true
+}
+
+// deliver 发送到单个渠道并记录结果
+func (s *Service) deliver(ch *Channel, msg Message) Delivery {
+	d := Delivery{
+		Time:        time.Now(),
+		Event:       msg.Event,
+		Title:       msg.Title,
+		ChannelID:   ch.ID,
+		ChannelName: ch.Name,
+		Success:     true,
+	}
+	if err := send(ch, msg); err != nil {
+		d.Success = false
+		d.Error = err.Error()
+		fmt.Printf("❌ 通知发送失败 [%s]: %v\n", ch.Name, err)
+	}
+
+	s.mu.Lock()
+	s.store.History = append(s.store.History, d)
+	if len(s.store.History) > historyLimit {
+		s.store.History = s.store.History[len(s.store.History)-historyLimit:]
+	}
+	if err := s.save(); err != nil {
+		fmt.Printf("⚠️ 保存通知记录失败: %v\n", err)
+	}
+	s.mu.Unlock()
+	return d
+}
+
+// TestChannel 向渠道发送测试通知（不受规则与限流影响）
+func (s *Service) TestChannel(id string) (Delivery, error) {
+	s.mu.Lock()
+	ch := s.findChannel(id)
+	if ch == nil {
+		s.mu.Unlock()
+		return Delivery{}, fmt.Errorf("通知渠道不存在")
+	}
+	copied := *ch
+	s.mu.Unlock()
+
+	now := time.Now()
+	msg := Message{
+		Event: EventTest,
+		Title: "P-BOX 测试通知",
+		Text:  fmt.Sprintf("这是一条来自 P-BOX 的测试通知（%s）", now.Format("2006-01-02 15:04:05")),
+		Time:  now,
+	}
+	return s.deliver(&copied, msg), nil
+}
+
+// ========== 设置、渠道与规则 ==========
+
+// GetSettings 获取通知设置
+func (s *Service) GetSettings() Settings {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.store.Settings
+}
+
+// UpdateSettings 更新通知设置
+func (s *Service) UpdateSettings(settings Settings) error {
+	if settings.SubFailThreshold < 1 {
+		return fmt.Errorf("订阅失败阈值不能小于 1")
+	}
+	if settings.ExpiryDays < 0 || settings.MaxPerHour < 0 {
+		return fmt.Errorf("参数不能为负数")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.store.Settings = settings
+	return s.save()
+}
+
+// History 获取发送记录（最新的在前）
+func (s *Service) History(limit int) []Delivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.store.History)
+	if limit <= 0 || limit > n {
+		limit = n
+	}
+	result := make([]Delivery, 0, limit)
+	for i := n - 1; i >= n-limit; i-- {
+		result = append(result, s.store.History[i])
+	}
+	return result
+}
+
+// findChannel 查找渠道（调用者需持有锁）
+func (s *Service) findChannel(id string) *Channel {
+	for _, ch := range s.store.Channels {
+		if ch.ID == id {
+			return ch
+		}
+	}
+	return nil
+}
+
+// validateChannel 校验渠道配置
+func validateChannel(ch *Channel) error {
+	if strings.TrimSpace(ch.Name) == "" {
+		return fmt.Errorf("渠道名称不能为空")
+	}
+	switch ch.Type {
+	case ChannelWebhook:
+		if ch.Webhook == nil || ch.Webhook.URL == "" {
+			return fmt.Errorf("Webhook 地址不能为空")
+		}
+		if _, err := renderWebhookBody(ch.Webhook.Template, Message{Time: time.Now()}); err != nil {
+			return err
+		}
+	case ChannelTelegram:
+		if ch.Telegram == nil || ch.Telegram.BotToken == "" || ch.Telegram.ChatID == "" {
+			return fmt.Errorf("Telegram Bot Token 与 Chat ID 不能为空")
+		}
+	case ChannelSMTP:
+		if ch.SMTP == nil || ch.SMTP.Host == "" || ch.SMTP.From == "" || len(ch.SMTP.To) == 0 {
+			return fmt.Errorf("SMTP 服务器、发件人与收件人不能为空")
+		}
+		for _, addr := range append([]string{ch.SMTP.From}, ch.SMTP.To...) {
+			if _, err := mail.ParseAddress(addr); err != nil {
+				return fmt.Errorf("邮箱地址无效: %s", addr)
+			}
+		}
+		switch ch.SMTP.Security {
+		case "", "starttls", "tls":
+		default:
+			return fmt.Errorf("不支持的加密方式: %s", ch.SMTP.Security)
+		}
+	default:
+		return fmt.Errorf("不支持的通知渠道类型: %s", ch.Type)
+	}
+	return nil
+}
+
+// maskChannel 返回隐藏密钥后的渠道副本
+// 隐藏 Webhook 地址中的用户信息与查询参数值、Webhook 请求头的值、Bot Token 与 SMTP 密码
+func maskChannel(ch *Channel) Channel {
+	masked := *ch
+	if ch.Webhook != nil {
+		webhook := *ch.Webhook
+		webhook.URL = maskWebhookURL(ch.Webhook.URL)
+		if len(ch.Webhook.Headers) > 0 {
+			webhook.Headers = make(map[string]string, len(ch.Webhook.Headers))
+			for k, v := range ch.Webhook.Headers {
+				if v != "" {
+					v = secretMask
+				}
+				webhook.Headers[k] = v
+			}
+		}
+		masked.Webhook = &webhook
+	}
+	if ch.Telegram != nil && ch.Telegram.BotToken != "" {
+		telegram := *ch.Telegram
+		telegram.BotToken = secretMask
+		masked.Telegram = &telegram
+	}
+	if ch.SMTP != nil && ch.SMTP.Password != "" {
+		smtpConfig := *ch.SMTP
+		smtpConfig.Password = secretMask
+		masked.SMTP = &smtpConfig
+	}
+	return masked
+}
+
+// maskWebhookURL 隐藏 Webhook 地址中的用户信息与查询参数值（常用于携带 Token），保留参数名便于识别
+func maskWebhookURL(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return secretMask
+	}
+	hasUser := u.User != nil
+	u.User = nil
+	if u.RawQuery != "" {
+		params := strings.Split(u.RawQuery, "&")
+		for i, param := range params {
+			if key, _, ok := strings.Cut(param, "="); ok {
+				params[i] = key + "=" + secretMask
+			}
+		}
+		u.RawQuery = strings.Join(params, "&")
+	}
+	masked := u.String()
+	if hasUser {
+		// url.User 会转义 *，直接拼接占位符
+		masked = strings.Replace(masked, "//", "//"+secretMask+"@", 1)
+	}
+	return masked
+}
+
+// keepSecrets 更新提交的密钥为占位符时沿用原值
+func keepSecrets(update, current *Channel) {
+	if update.Webhook != nil {
+		webhook := current.Webhook
+		if webhook == nil {
+			webhook = &WebhookConfig{}
+		}
+		if update.Webhook.URL != webhook.URL && update.Webhook.URL == maskWebhookURL(webhook.URL) {
+			update.Webhook.URL = webhook.URL
+		}
+		for k, v := range update.Webhook.Headers {
+			if v != secretMask {
+				continue
+			}
+			if original, ok := webhook.Headers[k]; ok {
+				update.Webhook.Headers[k] = original
+			} else {
+				delete(update.Webhook.Headers, k)
+			}
+		}
+	}
+	if update.Telegram != nil && update.Telegram.BotToken == secretMask {
+		update.Telegram.BotToken = ""
+		if current.Telegram != nil {
+			update.Telegram.BotToken = current.Telegram.BotToken
+		}
+	}
+	if update.SMTP != nil && update.SMTP.Password == secretMask {
+		update.SMTP.Password = ""
+		if current.SMTP != nil {
+			update.SMTP.Password = current.SMTP.Password
+		}
+	}
+}
+
+// ListChannels 获取渠道列表（密钥已隐藏）
+func (s *Service) ListChannels() []Channel {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]Channel, 0, len(s.store.Channels))
+	for _, ch := range s.store.Channels {
+		result = append(result, maskChannel(ch))
+	}
+	return result
+}
+
+// CreateChannel 创建渠道，返回隐藏密钥后的渠道
+func (s *Service) CreateChannel(ch *Channel) (*Channel, error) {
+	if err := validateChannel(ch); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch.ID = uuid.New().String()
+	s.store.Channels = append(s.store.Channels, ch)
+	masked := maskChannel(ch)
+	return &masked, s.save()
+}
+
+// UpdateChannel 更新渠道，密钥为占位符时保留原值，返回隐藏密钥后的渠道
+func (s *Service) UpdateChannel(id string, update *Channel) (*Channel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch := s.findChannel(id)
+	if ch == nil {
+		return nil, fmt.Errorf("通知渠道不存在")
+	}
+	keepSecrets(update, ch)
+	if err := validateChannel(update); err != nil {
+		return nil, err
+	}
+	update.ID = id
+	*ch = *update
+	masked := maskChannel(ch)
+	return &masked, s.save()
+}
+
+// DeleteChannel 删除渠道，同时从规则中移除
+func (s *Service) DeleteChannel(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, ch := range s.store.Channels {
+		if ch.ID != id {
+			continue
+		}
+		s.store.Channels = append(s.store.Channels[:i], s.store.Channels[i+1:]...)
+		for _, rule := range s.store.Rules {
+			kept := rule.Channels[:0]
+			for _, cid := range rule.Channels {
+				if cid != id {
+					kept = append(kept, cid)
+				}
+			}
+			rule.Channels = kept
+		}
+		return s.save()
+	}
+	return fmt.Errorf("通知渠道不存在")
+}
+
+// validateRule 校验规则（调用者需持有锁）
+func (s *Service) validateRule(rule *Rule) error {
+	if strings.TrimSpace(rule.Name) == "" {
+		return fmt.Errorf("规则名称不能为空")
+	}
+	if len(rule.Channels) == 0 {
+		return fmt.Errorf("至少选择一个通知渠道")
+	}
+	for _, id := range rule.Channels {
+		if s.findChannel(id) == nil {
+			return fmt.Errorf("通知渠道不存在: %s", id)
+		}
+	}
+	if rule.Cooldown < 0 {
+		return fmt.Errorf("通知间隔不能为负数")
+	}
+	return nil
+}
+
+// ListRules 获取规则列表
+func (s *Service) ListRules() []Rule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]Rule, 0, len(s.store.Rules))
+	for _, rule := range s.store.Rules {
+		result = append(result, *rule)
+	}
+	return result
+}
+
+// CreateRule 创建规则
+func (s *Service) CreateRule(rule *Rule) (*Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.validateRule(rule); err != nil {
+		return nil, err
+	}
+	rule.ID = uuid.New().String()
+	s.store.Rules = append(s.store.Rules, rule)
+	return rule, s.save()
+}
+
+// UpdateRule 更新规则
+func (s *Service) UpdateRule(id string, update *Rule) (*Rule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.validateRule(update); err != nil {
+		return nil, err
+	}
+	for _, rule := range s.store.Rules {
+		if rule.ID == id {
+			update.ID = id
+			*rule = *update
+			return rule, s.save()
+		}
+	}
+	return nil, fmt.Errorf("通知规则不存在")
+}
+
+// DeleteRule 删除规则
+func (s *Service) DeleteRule(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, rule := range s.store.Rules {
+		if rule.ID == id {
+			s.store.Rules = append(s.store.Rules[:i], s.store.Rules[i+1:]...)
+			return s.save()
+		}
+	}
+	return fmt.Errorf("通知规则不存在")
+}
@@ -0,0 +1,199 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ============================================================================
+// 局域网访问控制与按用户分流
+// Mihomo 使用 authentication/skip-auth-prefixes/lan-allowed-ips/lan-disallowed-ips 与 IN-USER 规则，
+// sing-box 使用入站 users、auth_user 路由规则，来源限制转换为 reject 规则
+// ============================================================================
+
+// AccessSettings 访问控制设置
+type AccessSettings struct {
+	Authentication   []AuthUser `json:"authentication"`
+	SkipAuthPrefixes []string   `json:"skipAuthPrefixes"`
+	LanAllowedIPs    []string   `json:"lanAllowedIps"`
+	LanDisallowedIPs []string   `json:"lanDisallowedIps"`
+}
+
+// accessSettings 提取访问控制设置
+func (s *ProxySettings) accessSettings() AccessSettings {
+	return AccessSettings{
+		Authentication:   s.Authentication,
+		SkipAuthPrefixes: s.SkipAuthPrefixes,
+		LanAllowedIPs:    s.LanAllowedIPs,
+		LanDisallowedIPs: s.LanDisallowedIPs,
+	}
+}
+
+// applyAccessSettings 写回访问控制设置
+func (s *ProxySettings) applyAccessSettings(access AccessSettings) {
+	s.Authentication = access.Authentication
+	s.SkipAuthPrefixes = access.SkipAuthPrefixes
+	s.LanAllowedIPs = access.LanAllowedIPs
+	s.LanDisallowedIPs = access.LanDisallowedIPs
+}
+
+// Validate 校验设置中需要组合检查的部分（访问控制与额外入站）
+func (s *ProxySettings) Validate() error {
+	if err := ValidateAccessSettings(s.accessSettings()); err != nil {
+		return err
+	}
+	return ValidateListeners(s.Listeners, s.enabledPorts())
+}
+
+// ValidateAccessSettings 校验访问控制设置
+func ValidateAccessSettings(access AccessSettings) error {
+	for name, prefixes := range map[string][]string{
+		"skip-auth-prefixes": access.SkipAuthPrefixes,
+		"lan-allowed-ips":    access.LanAllowedIPs,
+		"lan-disallowed-ips": access.LanDisallowedIPs,
+	} {
+		for _, prefix := range prefixes {
+			if _, _, err := net.ParseCIDR(prefix); err != nil {
+				return fmt.Errorf("%s 中的 %s 不是有效的网段", name, prefix)
+			}
+		}
+	}
+
+	usernames := make(map[string]bool)
+	for _, u := range access.Authentication {
+		if u.Username == "" || u.Password == "" {
+			return fmt.Errorf("认证用户缺少用户名或密码")
+		}
+		if strings.ContainsAny(u.Username, ":,()") {
+			return fmt.Errorf("用户名 %s 不能包含冒号、逗号或括号", u.Username)
+		}
+		if usernames[u.Username] {
+			return fmt.Errorf("用户 %s 重复", u.Username)
+		}
+		usernames[u.Username] = true
+	}
+	return nil
+}
+
+// enabledAuthUsers 返回启用的认证用户
+func enabledAuthUsers(users []AuthUser) []AuthUser {
+	var enabled []AuthUser
+	for _, u := range users {
+		if u.Enabled {
+			enabled = append(enabled, u)
+		}
+	}
+	return enabled
+}
+
+// applyMihomoAccess 写入认证与局域网访问控制，返回按用户分流规则
+func applyMihomoAccess(config *MihomoConfig, access AccessSettings) []string {
+	users := enabledAuthUsers(access.Authentication)
+	for _, u := range users {
+		config.Authentication = append(config.Authentication, u.Username+":"+u.Password)
+	}
+	if len(users) > 0 {
+		config.SkipAuthPrefixes = access.SkipAuthPrefixes
+	}
+	config.LanAllowedIPs = access.LanAllowedIPs
+	config.LanDisallowedIPs = access.LanDisallowedIPs
+
+	targets := map[string]bool{"DIRECT": true, "REJECT": true}
+	for _, group := range config.ProxyGroups {
+		targets[group.Name] = true
+	}
+	for _, proxy := range config.Proxies {
+		if name, ok := proxy["name"].(string); ok {
+			targets[name] = true
+		}
+	}
+
+	var rules []string
+	for _, u := range users {
+		if u.Target == "" {
+			continue
+		}
+		if !targets[u.Target] {
+			fmt.Printf("⚠️ 用户 %s 的出口 %s 不存在，已跳过\n", u.Username, u.Target)
+			continue
+		}
+		rules = append(rules, fmt.Sprintf("IN-USER,%s,%s", u.Username, u.Target))
+	}
+	return rules
+}
+
+// applySingBoxAccess 为内置代理入站添加认证用户，返回来源限制规则（reject）与按用户分流规则
+// sing-box 没有免认证网段，设置 skip-auth-prefixes 时仅输出提示
+func applySingBoxAccess(config *SingBoxConfig, access AccessSettings) (aclRules, userRules []SBRouteRule) {
+	users := enabledAuthUsers(access.Authentication)
+
+	// 内置代理入站（额外入站有各自的用户）
+	var proxyInbounds, lanInbounds []string
+	for i, in := range config.Inbounds {
+		if in.Type == "tun" {
+			continue
+		}
+		lanInbounds = append(lanInbounds, in.Tag)
+		if in.Tag == "mixed-in" || in.Tag == "http-in" || in.Tag == "socks-in" {
+			proxyInbounds = append(proxyInbounds, in.Tag)
+			for _, u := range users {
+				config.Inbounds[i].Users = append(config.Inbounds[i].Users, SBInboundUser{Username: u.Username, Password: u.Password})
+			}
+		}
+	}
+	if len(users) > 0 && len(access.SkipAuthPrefixes) > 0 {
+		fmt.Printf("⚠️ Sing-Box 不支持免认证网段，skip-auth-prefixes 未生效\n")
+	}
+
+	outbounds := make(map[string]bool, len(config.Outbounds))
+	for _, ob := range config.Outbounds {
+		outbounds[ob.Tag] = true
+	}
+	for _, ep := range config.Endpoints {
+		outbounds[ep.Tag] = true
+	}
+
+	if len(lanInbounds) > 0 {
+		if len(access.LanDisallowedIPs) > 0 {
+			aclRules = append(aclRules, SBRouteRule{Inbound: lanInbounds, SourceIPCIDR: access.LanDisallowedIPs, Action: "reject"})
+		}
+		if len(access.LanAllowedIPs) > 0 {
+			// 与 Mihomo 一致，本机连接不受允许列表限制
+			allowed := append([]string{"127.0.0.0/8", "::1/128"}, access.LanAllowedIPs...)
+			aclRules = append(aclRules, SBRouteRule{Inbound: lanInbounds, SourceIPCIDR: allowed, Invert: true, Action: "reject"})
+		}
+	}
+	for _, u := range users {
+		if u.Target == "" || len(proxyInbounds) == 0 {
+			continue
+		}
+		target := singBoxListenerTarget(u.Target)
+		if !outbounds[target] {
+			fmt.Printf("⚠️ 用户 %s 的出口 %s 不存在，已跳过\n", u.Username, u.Target)
+			continue
+		}
+		userRules = append(userRules, SBRouteRule{Inbound: proxyInbounds, AuthUser: []string{u.Username}, Outbound: target})
+	}
+	return aclRules, userRules
+}
+
+// insertSingBoxRules 将 head 规则插入到最前面，将 rules 插入到嗅探与 DNS 劫持规则之后
+func insertSingBoxRules(route *SBRoute, head, rules []SBRouteRule) {
+	if route == nil || len(head)+len(rules) == 0 {
+		return
+	}
+	insertAt := 0
+	for i, rule := range route.Rules {
+		if rule.Action != "sniff" && rule.Action != "hijack-dns" {
+			break
+		}
+		insertAt = i + 1
+	}
+	merged := make([]SBRouteRule, 0, len(route.Rules)+len(head)+len(rules))
+	merged = append(merged, head...)
+	merged = append(merged, route.Rules[:insertAt]...)
+	merged = append(merged, rules...)
+	merged = append(merged, route.Rules[insertAt:]...)
+	route.Rules = merged
+}
@@ -0,0 +1,286 @@
+package proxy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ============================================================================
+// Clash / Mihomo 配置导入
+// ============================================================================
+
+// clashImportDoc 导入时需要保留原始字段的部分（用于报告无法映射的配置项）
+type clashImportDoc struct {
+	ProxyGroups    []map[string]interface{}          `yaml:"proxy-groups"`
+	RuleProviders  map[string]map[string]interface{} `yaml:"rule-providers"`
+	DNS            map[string]interface{}            `yaml:"dns"`
+	ProxyProviders map[string]interface{}            `yaml:"proxy-providers"`
+}
+
+// clashImportGroup Clash 代理组
+type clashImportGroup struct {
+	Name              string   `yaml:"name"`
+	Type              string   `yaml:"type"`
+	Proxies           []string `yaml:"proxies"`
+	URL               string   `yaml:"url"`
+	Interval          int      `yaml:"interval"`
+	Tolerance         int      `yaml:"tolerance"`
+	Lazy              bool     `yaml:"lazy"`
+	Hidden            bool     `yaml:"hidden"`
+	Filter            string   `yaml:"filter"`
+	IncludeAll        bool     `yaml:"include-all"`
+	IncludeAllProxies bool     `yaml:"include-all-proxies"`
+	Icon              string   `yaml:"icon"`
+}
+
+// clashImportedKeys 会被导入的顶层配置项
+var clashImportedKeys = map[string]bool{
+	"proxies":        true,
+	"proxy-groups":   true,
+	"rules":          true,
+	"rule-providers": true,
+	"sub-rules":      true,
+	"dns":            true,
+}
+
+// ParseClashConfig 解析完整的 Clash/Mihomo YAML 配置
+// proxies 转为手动节点，proxy-groups/rules/rule-providers/sub-rules 转为配置模板，dns 转为 DNS 设置
+func ParseClashConfig(data []byte) (*ImportResult, error) {
+	var config MihomoConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("YAML 解析失败: %w", err)
+	}
+	var doc clashImportDoc
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("YAML 解析失败: %w", err)
+	}
+	var top map[string]interface{}
+	yaml.Unmarshal(data, &top)
+
+	result := &ImportResult{Source: "clash", Template: &ConfigTemplate{}}
+
+	// 未导入的顶层配置项
+	var skipped []string
+	for key := range top {
+		if !clashImportedKeys[key] {
+			skipped = append(skipped, key)
+		}
+	}
+	sort.Strings(skipped)
+	for _, key := range skipped {
+		result.issue(key, key, "配置项 %s 未导入", key)
+	}
+
+	// 节点
+	names := make(map[string]bool)
+	for idx, p := range config.Proxies {
+		path := fmt.Sprintf("proxies[%d]", idx)
+		node, err := newImportedNode(p)
+		if err != nil {
+			result.issue(path, fmt.Sprint(p["name"]), "节点无法导入: %v", err)
+			continue
+		}
+		if names[node.Name] {
+			result.issue(path, node.Name, "节点名称 %s 重复，已跳过", node.Name)
+			continue
+		}
+		names[node.Name] = true
+		result.Nodes = append(result.Nodes, node)
+	}
+	for name := range doc.ProxyProviders {
+		result.issue("proxy-providers."+name, name, "不支持代理集合 %s，请改为订阅导入", name)
+	}
+
+	// 代理组
+	groupKeys := structFieldNames(clashImportGroup{}, "yaml")
+	for idx, raw := range doc.ProxyGroups {
+		path := fmt.Sprintf("proxy-groups[%d]", idx)
+		if group, ok := parseClashGroup(raw, path, groupKeys, result); ok {
+			result.Template.ProxyGroups = append(result.Template.ProxyGroups, group)
+		}
+	}
+
+	// 规则提供者
+	var providerNames []string
+	for name := range doc.RuleProviders {
+		providerNames = append(providerNames, name)
+	}
+	sort.Strings(providerNames)
+	providerKeys := structFieldNames(RuleProvider{}, "yaml")
+	for _, name := range providerNames {
+		path := "rule-providers." + name
+		raw := doc.RuleProviders[name]
+		p := config.RuleProviders[name]
+		if p.Type != "http" && p.Type != "file" {
+			result.issue(path, name, "不支持 %s 类型的规则集，已跳过", p.Type)
+			continue
+		}
+		for _, key := range sortedKeys(raw) {
+			if !providerKeys[key] {
+				result.issue(path+"."+key, name, "规则集字段 %s 未导入", key)
+			}
+		}
+		result.Template.RuleProviders = append(result.Template.RuleProviders, RuleProviderTemplate{
+			Name:     name,
+			Type:     p.Type,
+			Behavior: p.Behavior,
+			URL:      p.URL,
+			Path:     p.Path,
+			Interval: p.Interval,
+			Format:   p.Format,
+		})
+	}
+
+	// 子规则需要先解析，规则校验会用到
+	if len(config.SubRules) > 0 {
+		result.Template.SubRules = make(map[string][]RuleTemplate, len(config.SubRules))
+		for name := range config.SubRules {
+			result.Template.SubRules[name] = nil
+		}
+		for name, rules := range config.SubRules {
+			result.Template.SubRules[name] = parseClashRules(rules, "sub-rules."+name, result)
+		}
+	}
+	result.Template.Rules = parseClashRules(config.Rules, "rules", result)
+
+	// DNS
+	if doc.DNS != nil {
+		dns, err := parseClashDNS(doc.DNS, result)
+		if err != nil {
+			result.issue("dns", "", "DNS 配置无法导入: %v", err)
+		} else {
+			result.DNS = dns
+		}
+	}
+
+	return result, nil
+}
+
+// parseClashGroup 转换单个代理组
+func parseClashGroup(raw map[string]interface{}, path string, known map[string]bool, result *ImportResult) (ProxyGroupTemplate, bool) {
+	var g clashImportGroup
+	if err := remarshalYAML(raw, &g); err != nil || g.Name == "" {
+		result.issue(path, fmt.Sprint(raw["name"]), "代理组格式错误")
+		return ProxyGroupTemplate{}, false
+	}
+
+	switch g.Type {
+	case "select", "url-test", "fallback", "load-balance":
+	default:
+		result.issue(path, g.Name, "不支持 %s 类型的代理组 %s，已跳过", g.Type, g.Name)
+		return ProxyGroupTemplate{}, false
+	}
+
+	for _, key := range sortedKeys(raw) {
+		switch {
+		case key == "use":
+			result.issue(path+".use", g.Name, "代理组 %s 引用的代理集合无法导入，已改为包含全部节点", g.Name)
+		case key == "icon":
+			result.issue(path+".icon", g.Name, "代理组 %s 的图标地址未导入", g.Name)
+		case !known[key]:
+			result.issue(path+"."+key, g.Name, "代理组 %s 的字段 %s 未导入", g.Name, key)
+		}
+	}
+
+	group := ProxyGroupTemplate{
+		Name:      g.Name,
+		Type:      g.Type,
+		Enabled:   true,
+		Proxies:   g.Proxies,
+		URL:       g.URL,
+		Interval:  g.Interval,
+		Tolerance: g.Tolerance,
+		Lazy:      g.Lazy,
+		Hidden:    g.Hidden,
+		Filter:    g.Filter,
+		UseAll:    g.IncludeAll || g.IncludeAllProxies,
+	}
+	// 只引用代理集合的分组改为包含全部节点
+	if _, hasUse := raw["use"]; hasUse && len(group.Proxies) == 0 {
+		group.UseAll = true
+	}
+	if group.Proxies == nil {
+		group.Proxies = []string{}
+	}
+	return group, true
+}
+
+// parseClashRules 解析规则列表，无法识别的规则记录后跳过
+func parseClashRules(lines []string, basePath string, result *ImportResult) []RuleTemplate {
+	var rules []RuleTemplate
+	for idx, line := range lines {
+		path := fmt.Sprintf("%s[%d]", basePath, idx)
+		rule, err := parseClashRule(line)
+		if err == nil {
+			err = ValidateRuleTemplate(rule, result.Template.SubRules)
+		}
+		if err != nil {
+			result.issue(path, line, "规则 %s 无法导入: %v", line, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// parseClashRule 解析单条 Clash 规则，如 DOMAIN-SUFFIX,google.com,节点选择,no-resolve
+func parseClashRule(line string) (RuleTemplate, error) {
+	fields := splitRuleFields(strings.TrimSpace(line))
+	ruleType := strings.ToUpper(fields[0])
+	if ruleType == "MATCH" {
+		if len(fields) < 2 {
+			return RuleTemplate{}, fmt.Errorf("缺少目标策略")
+		}
+		return RuleTemplate{Type: "MATCH", Proxy: fields[1]}, nil
+	}
+	if len(fields) < 3 {
+		return RuleTemplate{}, fmt.Errorf("缺少目标策略")
+	}
+
+	rule := RuleTemplate{Type: ruleType, Payload: fields[1], Proxy: fields[2]}
+	for _, extra := range fields[3:] {
+		if strings.EqualFold(extra, "no-resolve") {
+			rule.NoResolve = true
+			continue
+		}
+		return RuleTemplate{}, fmt.Errorf("不支持规则参数 %s", extra)
+	}
+	return rule, nil
+}
+
+// parseClashDNS 转换 DNS 配置，未设置的字段使用默认值
+func parseClashDNS(raw map[string]interface{}, result *ImportResult) (*DNSSettings, error) {
+	dns := GetDefaultProxySettings().DNS
+	if err := remarshalYAML(raw, &dns); err != nil {
+		return nil, err
+	}
+	known := structFieldNames(DNSSettings{}, "yaml")
+	for _, key := range sortedKeys(raw) {
+		if !known[key] {
+			result.issue("dns."+key, key, "DNS 配置项 %s 未导入", key)
+		}
+	}
+	return &dns, nil
+}
+
+// remarshalYAML 将通用结构重新解码为目标类型
+func remarshalYAML(src interface{}, dst interface{}) error {
+	data, err := yaml.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(data, dst)
+}
+
+// sortedKeys 获取排序后的键（保证报告顺序稳定）
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -27,6 +27,13 @@ type MihomoConfig struct {
 	IPv6               bool   `yaml:"ipv6"`
 	ExternalController string `yaml:"external-controller"`
 	Secret             string `yaml:"secret,omitempty"`
+	ControllerUnix     string `yaml:"external-controller-unix,omitempty"`
+
+	// 认证与局域网访问控制
+	Authentication   []string `yaml:"authentication,omitempty"`
+	SkipAuthPrefixes []string `yaml:"skip-auth-prefixes,omitempty"`
+	LanAllowedIPs    []string `yaml:"lan-allowed-ips,omitempty"`
+	LanDisallowedIPs []string `yaml:"lan-disallowed-ips,omitempty"`
 
 	// 高级配置
 	UnifiedDelay       bool     `yaml:"unified-delay,omitempty"`
@@ -53,11 +60,15 @@ type MihomoConfig struct {
 	TUN     *TUNConfig     `yaml:"tun,omitempty"`
 	Sniffer *SnifferConfig `yaml:"sniffer,omitempty"`
 
+	// 额外入站
+	Listeners []MihomoListener `yaml:"listeners,omitempty"`
+
 	// 代理配置
 	Proxies       []map[string]interface{} `yaml:"proxies"`
 	ProxyGroups   []ProxyGroup             `yaml:"proxy-groups"`
 	RuleProviders map[string]RuleProvider  `yaml:"rule-providers,omitempty"`
 	Rules         []string                 `yaml:"rules"`
+	SubRules      map[string][]string      `yaml:"sub-rules,omitempty"`
 }
 
 // GeoxURL GEO 数据源
@@ -162,13 +173,14 @@ type ProxyGroup struct {
 
 // ProxyNode 代理节点
 type ProxyNode struct {
-	Name       string `json:"name"`
-	Type       string `json:"type"`
-	Server     string `json:"server"`
-	Port       int    `json:"port"`
-	ServerPort int    `json:"serverPort"` // 兼容 node 模块的字段名
-	Config     string `json:"config"`     // JSON 格式的完整配置
-	IsManual   bool   `json:"isManual"`   // 是否手动添加的节点
+	Name           string `json:"name"`
+	Type           string `json:"type"`
+	Server         string `json:"server"`
+	Port           int    `json:"port"`
+	ServerPort     int    `json:"serverPort"`               // 兼容 node 模块的字段名
+	Config         string `json:"config"`                   // JSON 格式的完整配置
+	IsManual       bool   `json:"isManual"`                 // 是否手动添加的节点
+	SubscriptionID string `json:"subscriptionId,omitempty"` // 来源订阅 ID（手动节点为空）
 }
 
 // GetPort 获取端口（兼容两种字段名）
@@ -203,8 +215,9 @@ type ConfigGeneratorOptions struct {
 	Fallback     []string `json:"fallback"`
 
 	// API
-	ExternalController string `json:"externalController"`
-	Secret             string `json:"secret"`
+	ExternalController     string `json:"externalController"`
+	ExternalControllerUnix string `json:"externalControllerUnix"` // 非空时仅通过该 unix socket 提供控制器
+	Secret                 string `json:"secret"`
 
 	// 性能优化设置（从 ProxySettings 读取）
 	UnifiedDelay            bool   `json:"unifiedDelay"`
@@ -229,6 +242,16 @@ type ConfigGeneratorOptions struct {
 
 	// 配置模板（可选，为 nil 时使用默认生成）
 	Template *ConfigTemplate `json:"-"`
+
+	// 统一路由模板编译出的 DNS 策略（可选）
+	DNSPolicy *MihomoDNSPolicy `json:"-"`
+
+	// 额外入站监听器与访问控制（从 ProxySettings 读取）
+	Listeners []ListenerSettings `json:"listeners"`
+	Access    AccessSettings     `json:"access"`
+
+	// 停用的规则集（移除规则集定义及引用它的规则）
+	DisabledRuleSets []string `json:"disabledRuleSets"`
 }
 
 // ConfigGenerator 配置生成器
@@ -287,6 +310,7 @@ func (g *ConfigGenerator) GenerateConfig(nodes []ProxyNode, options ConfigGenera
 		IPv6:               options.IPv6,
 		ExternalController: options.ExternalController,
 		Secret:             options.Secret,
+		ControllerUnix:     options.ExternalControllerUnix,
 
 		// 高级配置 (从代理设置读取)
 		UnifiedDelay:       getOrDefault(options.UnifiedDelay, true),
@@ -316,6 +340,11 @@ func (g *ConfigGenerator) GenerateConfig(nodes []ProxyNode, options ConfigGenera
 		},
 	}
 
+	// 仅通过 unix socket 提供控制器时关闭 TCP 控制器
+	if options.ExternalControllerUnix != "" {
+		config.ExternalController = ""
+	}
+
 	// 透明代理端口 - 只有启用时才设置
 	if options.EnableTProxy {
 		if options.TProxyPort > 0 {
@@ -430,15 +459,77 @@ func (g *ConfigGenerator) GenerateConfig(nodes []ProxyNode, options ConfigGenera
 	}
 	config.ProxyGroups = g.generateProxyGroupsFromTemplate(nodes, template.ProxyGroups)
 
-	// 生成规则提供者
+	// 生成规则提供者（内置规则集 + 模板中的其他规则集）
 	config.RuleProviders = g.generateRuleProviders()
+	for _, p := range template.RuleProviders {
+		if _, exists := config.RuleProviders[p.Name]; exists {
+			continue
+		}
+		config.RuleProviders[p.Name] = RuleProvider{
+			Type:     p.Type,
+			Behavior: p.Behavior,
+			URL:      p.URL,
+			Path:     p.Path,
+			Interval: p.Interval,
+			Format:   p.Format,
+		}
+	}
 
 	// 生成规则（使用模板中的规则）
 	config.Rules = g.generateRulesFromTemplate(template.Rules)
 
+	// 额外入站、访问控制及其分流规则（放在最前面，监听器规则优先于按用户规则）
+	config.Listeners = mihomoListeners(options.Listeners)
+	rules := mihomoListenerRules(options.Listeners, config)
+	rules = append(rules, applyMihomoAccess(config, options.Access)...)
+	if len(rules) > 0 {
+		config.Rules = append(rules, config.Rules...)
+	}
+	if len(template.SubRules) > 0 {
+		config.SubRules = make(map[string][]string, len(template.SubRules))
+		for name, rules := range template.SubRules {
+			config.SubRules[name] = g.generateRulesFromTemplate(rules)
+		}
+	}
+
+	// 停用的规则集
+	removeMihomoRuleSets(config, options.DisabledRuleSets)
+
 	return config, nil
 }
 
+// removeMihomoRuleSets 移除停用的规则集定义，以及直接或在逻辑规则中引用它们的规则
+func removeMihomoRuleSets(config *MihomoConfig, names []string) {
+	if len(names) == 0 {
+		return
+	}
+	refs := make([]string, 0, len(names)*2)
+	for _, name := range names {
+		delete(config.RuleProviders, name)
+		refs = append(refs, "RULE-SET,"+name+",", "(RULE-SET,"+name+")")
+	}
+	filter := func(rules []string) []string {
+		kept := make([]string, 0, len(rules))
+		for _, rule := range rules {
+			matched := false
+			for _, ref := range refs {
+				if strings.Contains(rule, ref) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				kept = append(kept, rule)
+			}
+		}
+		return kept
+	}
+	config.Rules = filter(config.Rules)
+	for name, rules := range config.SubRules {
+		config.SubRules[name] = filter(rules)
+	}
+}
+
 // generateDNSConfig 生成 DNS 配置 (防止 DNS 泄漏 + 性能优化)
 func (g *ConfigGenerator) generateDNSConfig(options ConfigGeneratorOptions) *DNSConfig {
 	dns := &DNSConfig{
@@ -598,6 +689,16 @@ func (g *ConfigGenerator) generateDNSConfig(options ConfigGeneratorOptions) *DNS
 	}
 	// 未匹配的域名会使用 nameserver（海外 DNS），配合 respect-rules 走代理查询
 
+	// 统一路由模板中的 DNS 策略
+	if options.DNSPolicy != nil {
+		for key, servers := range options.DNSPolicy.NameserverPolicy {
+			dns.NameserverPolicy[key] = servers
+		}
+		if dns.EnhancedMode == "fake-ip" {
+			dns.FakeIPFilter = append(dns.FakeIPFilter, options.DNSPolicy.FakeIPFilter...)
+		}
+	}
+
 	return dns
 }
 
@@ -659,6 +760,9 @@ func (g *ConfigGenerator) convertProxies(nodes []ProxyNode) []map[string]interfa
 			proxyType = pt
 		}
 
+		// 端口跳跃、多路复用、ECH 等扩展选项（sing-box 写法 -> Mihomo 写法）
+		convertSingBoxOptionsToMihomo(proxy)
+
 		// 根据协议类型进行字段转换
 		switch proxyType {
 		case "hysteria2", "hy2":
@@ -705,6 +809,35 @@ func (g *ConfigGenerator) convertProxies(nodes []ProxyNode) []map[string]interfa
 
 		case "hysteria", "hy":
 			proxy["type"] = "hysteria"
+			// 处理订阅解析器的字段 (auth_str/up_mbps/down_mbps/tls -> Mihomo 格式)
+			if authStr, ok := proxy["auth_str"].(string); ok {
+				proxy["auth-str"] = authStr
+				delete(proxy, "auth_str")
+			}
+			if upMbps, ok := proxy["up_mbps"].(float64); ok && upMbps > 0 {
+				proxy["up"] = fmt.Sprintf("%d Mbps", int(upMbps))
+				delete(proxy, "up_mbps")
+			}
+			if downMbps, ok := proxy["down_mbps"].(float64); ok && downMbps > 0 {
+				proxy["down"] = fmt.Sprintf("%d Mbps", int(downMbps))
+				delete(proxy, "down_mbps")
+			}
+			if tls, ok := proxy["tls"].(map[string]interface{}); ok {
+				if sni, ok := tls["server_name"].(string); ok && sni != "" {
+					proxy["sni"] = sni
+				}
+				if insecure, ok := tls["insecure"].(bool); ok {
+					proxy["skip-cert-verify"] = insecure
+				}
+				if alpn, ok := tls["alpn"].([]interface{}); ok && len(alpn) > 0 {
+					proxy["alpn"] = alpn
+				}
+				delete(proxy, "tls")
+			}
+			if sni, ok := proxy["server_name"].(string); ok {
+				proxy["sni"] = sni
+				delete(proxy, "server_name")
+			}
 			// Hysteria 默认启用 UDP 和 fast-open
 			if _, ok := proxy["udp"]; !ok {
 				proxy["udp"] = true
@@ -1231,45 +1364,50 @@ func (g *ConfigGenerator) generateProxyGroups(nodes []ProxyNode) []ProxyGroup {
 	return groups
 }
 
+// ruleProviderSource 默认规则提供者来源
+type ruleProviderSource struct {
+	name     string
+	behavior string
+	urlPath  string
+}
+
+// defaultRuleProviderSources 默认规则提供者定义（urlPath 对应 meta-rules-dat 的 geosite/geoip 分类）
+var defaultRuleProviderSources = []ruleProviderSource{
+	{"private-domain", "domain", "/geosite/private.mrs"},
+	{"private-ip", "ipcidr", "/geoip/private.mrs"},
+	{"ai-domain", "domain", "/geosite/openai.mrs"},
+	{"youtube-domain", "domain", "/geosite/youtube.mrs"},
+	{"google-domain", "domain", "/geosite/google.mrs"},
+	{"google-ip", "ipcidr", "/geoip/google.mrs"},
+	{"telegram-domain", "domain", "/geosite/telegram.mrs"},
+	{"telegram-ip", "ipcidr", "/geoip/telegram.mrs"},
+	{"twitter-domain", "domain", "/geosite/twitter.mrs"},
+	{"twitter-ip", "ipcidr", "/geoip/twitter.mrs"},
+	{"facebook-domain", "domain", "/geosite/facebook.mrs"},
+	{"facebook-ip", "ipcidr", "/geoip/facebook.mrs"},
+	{"github-domain", "domain", "/geosite/github.mrs"},
+	{"apple-domain", "domain", "/geosite/apple.mrs"},
+	{"apple-cn-domain", "domain", "/geosite/apple-cn.mrs"},
+	{"microsoft-domain", "domain", "/geosite/microsoft.mrs"},
+	{"netflix-domain", "domain", "/geosite/netflix.mrs"},
+	{"netflix-ip", "ipcidr", "/geoip/netflix.mrs"},
+	{"spotify-domain", "domain", "/geosite/spotify.mrs"},
+	{"tiktok-domain", "domain", "/geosite/tiktok.mrs"},
+	{"bilibili-domain", "domain", "/geosite/bilibili.mrs"},
+	{"steam-domain", "domain", "/geosite/steam.mrs"},
+	{"epic-domain", "domain", "/geosite/epicgames.mrs"},
+	{"cn-domain", "domain", "/geosite/cn.mrs"},
+	{"cn-ip", "ipcidr", "/geoip/cn.mrs"},
+	{"geolocation-!cn", "domain", "/geosite/geolocation-!cn.mrs"},
+	{"ads-domain", "domain", "/geosite/category-ads-all.mrs"},
+}
+
 // generateRuleProviders 生成规则提供者（优先使用本地文件，使用绝对路径）
 func (g *ConfigGenerator) generateRuleProviders() map[string]RuleProvider {
 	rulesetDir := filepath.Join(g.dataDir, "ruleset")
 	baseURL := "https://testingcf.jsdelivr.net/gh/MetaCubeX/meta-rules-dat@meta/geo"
 
-	// 规则定义
-	rules := []struct {
-		name     string
-		behavior string
-		urlPath  string
-	}{
-		{"private-domain", "domain", "/geosite/private.mrs"},
-		{"private-ip", "ipcidr", "/geoip/private.mrs"},
-		{"ai-domain", "domain", "/geosite/openai.mrs"},
-		{"youtube-domain", "domain", "/geosite/youtube.mrs"},
-		{"google-domain", "domain", "/geosite/google.mrs"},
-		{"google-ip", "ipcidr", "/geoip/google.mrs"},
-		{"telegram-domain", "domain", "/geosite/telegram.mrs"},
-		{"telegram-ip", "ipcidr", "/geoip/telegram.mrs"},
-		{"twitter-domain", "domain", "/geosite/twitter.mrs"},
-		{"twitter-ip", "ipcidr", "/geoip/twitter.mrs"},
-		{"facebook-domain", "domain", "/geosite/facebook.mrs"},
-		{"facebook-ip", "ipcidr", "/geoip/facebook.mrs"},
-		{"github-domain", "domain", "/geosite/github.mrs"},
-		{"apple-domain", "domain", "/geosite/apple.mrs"},
-		{"apple-cn-domain", "domain", "/geosite/apple-cn.mrs"},
-		{"microsoft-domain", "domain", "/geosite/microsoft.mrs"},
-		{"netflix-domain", "domain", "/geosite/netflix.mrs"},
-		{"netflix-ip", "ipcidr", "/geoip/netflix.mrs"},
-		{"spotify-domain", "domain", "/geosite/spotify.mrs"},
-		{"tiktok-domain", "domain", "/geosite/tiktok.mrs"},
-		{"bilibili-domain", "domain", "/geosite/bilibili.mrs"},
-		{"steam-domain", "domain", "/geosite/steam.mrs"},
-		{"epic-domain", "domain", "/geosite/epicgames.mrs"},
-		{"cn-domain", "domain", "/geosite/cn.mrs"},
-		{"cn-ip", "ipcidr", "/geoip/cn.mrs"},
-		{"geolocation-!cn", "domain", "/geosite/geolocation-!cn.mrs"},
-		{"ads-domain", "domain", "/geosite/category-ads-all.mrs"},
-	}
+	rules := defaultRuleProviderSources
 
 	providers := make(map[string]RuleProvider)
 
@@ -1438,6 +1576,12 @@ func (g *ConfigGenerator) SaveConfig(config *MihomoConfig, filename string) (str
 		return "", err
 	}
 
+	// 合并用户覆写
+	data, err = applyMihomoOverride(g.dataDir, data)
+	if err != nil {
+		return "", fmt.Errorf("应用覆写失败: %w", err)
+	}
+
 	// 解码 Unicode 转义序列 (如 \U0001F1ED -> 🇭🇰)
 	yamlStr := decodeUnicodeEscapes(string(data))
 
@@ -1579,16 +1723,7 @@ func (g *ConfigGenerator) generateRulesFromTemplate(templates []RuleTemplate) []
 	var rules []string
 
 	for _, t := range templates {
-		var rule string
-		// MATCH 规则不需要 Payload
-		if t.Type == "MATCH" {
-			rule = t.Type + "," + t.Proxy
-		} else if t.NoResolve {
-			rule = t.Type + "," + t.Payload + "," + t.Proxy + ",no-resolve"
-		} else {
-			rule = t.Type + "," + t.Payload + "," + t.Proxy
-		}
-		rules = append(rules, rule)
+		rules = append(rules, formatMihomoRule(t))
 	}
 
 	return rules
@@ -0,0 +1,237 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ============================================================================
+// 外部配置导入：将已有的客户端配置转换为手动节点、配置模板与 DNS 设置
+// ============================================================================
+
+// 导入内容分类
+const (
+	ImportSectionNodes    = "nodes"
+	ImportSectionTemplate = "template"
+	ImportSectionDNS      = "dns"
+)
+
+// ImportIssue 导入时无法映射的内容
+type ImportIssue struct {
+	Path    string `json:"path"`             // 原配置中的位置，如 proxy-groups[2].use
+	Target  string `json:"target,omitempty"` // 相关名称
+	Message string `json:"message"`
+}
+
+// ImportResult 解析结果（尚未写入）
+type ImportResult struct {
+	Source          string           `json:"source"` // clash, singbox, subconverter
+	Nodes           []ProxyNode      `json:"nodes"`
+	Template        *ConfigTemplate  `json:"template,omitempty"`
+	SingBoxTemplate *SingBoxTemplate `json:"singboxTemplate,omitempty"`
+	DNS             *DNSSettings     `json:"dns,omitempty"`
+	Issues          []ImportIssue    `json:"issues"`
+}
+
+// issue 记录一条无法映射的内容
+func (r *ImportResult) issue(path, target, format string, args ...interface{}) {
+	r.Issues = append(r.Issues, ImportIssue{Path: path, Target: target, Message: fmt.Sprintf(format, args...)})
+}
+
+// ImportOptions 导入选项
+type ImportOptions struct {
+	DryRun   bool     `json:"dryRun"`             // 只解析不写入
+	Sections []string `json:"sections,omitempty"` // 要写入的内容（nodes/template/dns），为空时全部写入
+}
+
+// includes 判断是否需要写入指定内容
+func (o ImportOptions) includes(section string) bool {
+	return len(o.Sections) == 0 || containsString(o.Sections, section)
+}
+
+// ImportSummary 导入结果汇总
+type ImportSummary struct {
+	*ImportResult
+	NodesAdded   int      `json:"nodesAdded"`
+	NodesSkipped []string `json:"nodesSkipped,omitempty"` // 已存在同名节点而跳过
+	Applied      bool     `json:"applied"`
+}
+
+// NodeSink 手动节点批量写入接口（由节点管理模块提供），须全部写入或全部不写入
+type NodeSink func(nodes []ProxyNode) error
+
+// ConfigImporter 配置导入器
+type ConfigImporter struct {
+	service  *Service
+	settings *SettingsHandler
+	nodeSink NodeSink
+}
+
+// NewConfigImporter 创建配置导入器
+func NewConfigImporter(service *Service, settings *SettingsHandler) *ConfigImporter {
+	return &ConfigImporter{service: service, settings: settings}
+}
+
+// SetNodeSink 设置手动节点写入接口
+func (i *ConfigImporter) SetNodeSink(sink NodeSink) {
+	i.nodeSink = sink
+}
+
+// Apply 写入解析结果：先校验全部内容，再依次写入模板、DNS 设置与节点，失败时恢复已写入的内容
+func (i *ConfigImporter) Apply(result *ImportResult, opts ImportOptions) (*ImportSummary, error) {
+	summary := &ImportSummary{ImportResult: result}
+
+	// 已存在的节点名称（同名节点不重复导入）
+	existing := make(map[string]bool)
+	nodes := i.service.listNodes()
+	for _, n := range nodes {
+		existing[n.Name] = true
+	}
+	var newNodes []ProxyNode
+	if opts.includes(ImportSectionNodes) {
+		for _, n := range result.Nodes {
+			if existing[n.Name] {
+				summary.NodesSkipped = append(summary.NodesSkipped, n.Name)
+				continue
+			}
+			newNodes = append(newNodes, n)
+		}
+	}
+
+	// 模板按导入后的节点检查，未导入节点时检查会跳过节点相关项
+	var lintNodes []ProxyNode
+	if i.service.nodeProvider != nil {
+		lintNodes = append(append([]ProxyNode{}, nodes...), newNodes...)
+	}
+	writeTemplate := result.Template != nil && opts.includes(ImportSectionTemplate)
+	if writeTemplate {
+		if err := lintResult(LintConfigTemplate(result.Template, lintNodes)); err != nil {
+			return nil, err
+		}
+	}
+	writeSingBox := result.SingBoxTemplate != nil && opts.includes(ImportSectionTemplate)
+	if writeSingBox {
+		if err := lintResult(LintSingBoxTemplate(result.SingBoxTemplate, lintNodes)); err != nil {
+			return nil, err
+		}
+	}
+
+	// 写入前校验 DNS 设置，避免部分内容写入后才失败
+	var newSettings *ProxySettings
+	if result.DNS != nil && opts.includes(ImportSectionDNS) && i.settings != nil {
+		newSettings = i.settings.GetCurrentSettings()
+		newSettings.DNS = *result.DNS
+		if err := newSettings.Validate(); err != nil {
+			return nil, fmt.Errorf("DNS 设置无效: %w", err)
+		}
+	}
+
+	if opts.DryRun {
+		summary.NodesAdded = len(newNodes)
+		return summary, nil
+	}
+	if len(newNodes) > 0 && i.nodeSink == nil {
+		return nil, fmt.Errorf("节点管理模块未就绪，无法导入节点")
+	}
+
+	// 依次写入，任一步失败时恢复已写入的内容
+	var rollbacks []func()
+	fail := func(err error) (*ImportSummary, error) {
+		for idx := len(rollbacks) - 1; idx >= 0; idx-- {
+			rollbacks[idx]()
+		}
+		return nil, err
+	}
+
+	if writeTemplate {
+		var previous ConfigTemplate
+		cloneJSON(i.service.GetConfigTemplate(), &previous)
+		if err := i.service.ReplaceConfigTemplate(result.Template); err != nil {
+			return fail(fmt.Errorf("保存模板失败: %w", err))
+		}
+		rollbacks = append(rollbacks, func() { i.service.ReplaceConfigTemplate(&previous) })
+	}
+
+	if writeSingBox {
+		previous := LoadSingBoxTemplate(i.service.dataDir)
+		if err := SaveSingBoxTemplate(i.service.dataDir, result.SingBoxTemplate); err != nil {
+			return fail(fmt.Errorf("保存 Sing-Box 模板失败: %w", err))
+		}
+		rollbacks = append(rollbacks, func() { SaveSingBoxTemplate(i.service.dataDir, previous) })
+	}
+
+	if newSettings != nil {
+		previous := i.settings.GetCurrentSettings()
+		if err := i.settings.ReplaceSettings(newSettings); err != nil {
+			return fail(fmt.Errorf("保存 DNS 设置失败: %w", err))
+		}
+		rollbacks = append(rollbacks, func() { i.settings.ReplaceSettings(previous) })
+	}
+
+	// 节点批量写入，全部成功或全部不写入
+	if len(newNodes) > 0 {
+		if err := i.nodeSink(newNodes); err != nil {
+			return fail(fmt.Errorf("导入节点失败: %w", err))
+		}
+		summary.NodesAdded = len(newNodes)
+	}
+
+	summary.Applied = true
+	fmt.Printf("📥 已导入 %s 配置: %d 个节点, %d 项未能映射\n", result.Source, summary.NodesAdded, len(result.Issues))
+	i.service.regenerateIfRunning()
+	return summary, nil
+}
+
+// newImportedNode 由 Clash 格式的节点配置创建手动节点
+func newImportedNode(proxy map[string]interface{}) (ProxyNode, error) {
+	name, _ := proxy["name"].(string)
+	nodeType, _ := proxy["type"].(string)
+	server, _ := proxy["server"].(string)
+	if name == "" || nodeType == "" || server == "" {
+		return ProxyNode{}, fmt.Errorf("缺少 name/type/server")
+	}
+
+	var port int
+	switch v := proxy["port"].(type) {
+	case int:
+		port = v
+	case float64:
+		port = int(v)
+	case string:
+		fmt.Sscanf(v, "%d", &port)
+	}
+	if port <= 0 {
+		return ProxyNode{}, fmt.Errorf("端口无效")
+	}
+
+	config, err := json.Marshal(proxy)
+	if err != nil {
+		return ProxyNode{}, err
+	}
+	return ProxyNode{
+		Name:     name,
+		Type:     nodeType,
+		Server:   server,
+		Port:     port,
+		Config:   string(config),
+		IsManual: true,
+	}, nil
+}
+
+// structFieldNames 获取结构体指定标签（yaml/json）的字段名，用于找出无法映射的配置项
+func structFieldNames(v interface{}, tagKey string) map[string]bool {
+	names := make(map[string]bool)
+	t := reflect.TypeOf(v)
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	for idx := 0; idx < t.NumField(); idx++ {
+		tag := strings.Split(t.Field(idx).Tag.Get(tagKey), ",")[0]
+		if tag != "" && tag != "-" {
+			names[tag] = true
+		}
+	}
+	return names
+}
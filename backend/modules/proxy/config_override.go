@@ -0,0 +1,283 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// 覆写文件名（位于 dataDir 下）
+const (
+	MihomoOverrideFile  = "override_mihomo.yaml"
+	SingBoxOverrideFile = "override_singbox.json"
+)
+
+// 覆写键后缀说明：
+//   key    映射与映射递归合并，其他类型直接替换
+//   key!   整体替换，不做递归合并；值为 null 时删除该键
+//   key+   追加到原列表末尾
+//   key^   插入到原列表开头
+// 使用后缀而不是前缀，是为了不与 "+.example.com" 这类域名通配键冲突
+
+// GetOverridePath 获取指定核心的覆写文件路径
+func GetOverridePath(dataDir, coreType string) (string, error) {
+	switch coreType {
+	case "mihomo", "clash":
+		return filepath.Join(dataDir, MihomoOverrideFile), nil
+	case "singbox", "sing-box":
+		return filepath.Join(dataDir, SingBoxOverrideFile), nil
+	}
+	return "", fmt.Errorf("不支持的核心类型: %s", coreType)
+}
+
+// LoadOverride 读取覆写文件原始内容，文件不存在时返回空字符串
+func LoadOverride(dataDir, coreType string) (string, error) {
+	path, err := GetOverridePath(dataDir, coreType)
+	if err != nil {
+		return "", err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	return string(data), nil
+}
+
+// SaveOverride 校验并保存覆写文件，内容为空时删除文件
+func SaveOverride(dataDir, coreType, content string) error {
+	path, err := GetOverridePath(dataDir, coreType)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(content) == "" {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if _, err := parseOverrideDocument([]byte(content)); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(content), 0644)
+}
+
+// applyMihomoOverride 将 Mihomo 覆写合并到生成的 YAML 中
+func applyMihomoOverride(dataDir string, data []byte) ([]byte, error) {
+	override, err := loadOverrideNode(dataDir, "mihomo")
+	if err != nil || override == nil {
+		return data, err
+	}
+
+	base, err := parseOverrideDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	mergeOverrideNode(base, override)
+	return yaml.Marshal(base)
+}
+
+// applySingBoxOverride 将 sing-box 覆写合并到生成的 JSON 中（保持原有字段顺序）
+func applySingBoxOverride(dataDir string, data []byte) ([]byte, error) {
+	override, err := loadOverrideNode(dataDir, "singbox")
+	if err != nil || override == nil {
+		return data, err
+	}
+
+	base, err := parseOverrideDocument(data)
+	if err != nil {
+		return nil, err
+	}
+	mergeOverrideNode(base, override)
+	return json.MarshalIndent(overrideNodeValue(base), "", "  ")
+}
+
+// loadOverrideNode 读取并解析覆写文件，未配置覆写时返回 nil
+func loadOverrideNode(dataDir, coreType string) (*yaml.Node, error) {
+	content, err := LoadOverride(dataDir, coreType)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(content) == "" {
+		return nil, nil
+	}
+	node, err := parseOverrideDocument([]byte(content))
+	if err != nil {
+		return nil, fmt.Errorf("覆写文件解析失败: %w", err)
+	}
+	return node, nil
+}
+
+// parseOverrideDocument 解析 YAML/JSON 文档，要求顶层为映射
+// JSON 是 YAML 的子集，因此两种格式共用同一解析逻辑
+func parseOverrideDocument(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return nil, fmt.Errorf("覆写内容为空")
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("覆写内容顶层必须是对象")
+	}
+	return root, nil
+}
+
+// mergeOverrideNode 将 override 映射合并到 base 映射
+func mergeOverrideNode(base, override *yaml.Node) {
+	for i := 0; i+1 < len(override.Content); i += 2 {
+		key := override.Content[i].Value
+		value := override.Content[i+1]
+
+		switch {
+		case strings.HasSuffix(key, "!") && len(key) > 1:
+			name := strings.TrimSuffix(key, "!")
+			if value.Tag == "!!null" {
+				deleteMappingKey(base, name)
+			} else {
+				setMappingValue(base, name, value)
+			}
+		case strings.HasSuffix(key, "+") && len(key) > 1:
+			concatMappingList(base, strings.TrimSuffix(key, "+"), value, false)
+		case strings.HasSuffix(key, "^") && len(key) > 1:
+			concatMappingList(base, strings.TrimSuffix(key, "^"), value, true)
+		default:
+			existing := findMappingValue(base, key)
+			if existing != nil && existing.Kind == yaml.MappingNode && value.Kind == yaml.MappingNode {
+				mergeOverrideNode(existing, value)
+			} else {
+				setMappingValue(base, key, value)
+			}
+		}
+	}
+}
+
+// concatMappingList 将列表插入到 base[key] 的开头或末尾
+func concatMappingList(base *yaml.Node, key string, value *yaml.Node, prepend bool) {
+	items := []*yaml.Node{value}
+	if value.Kind == yaml.SequenceNode {
+		items = value.Content
+	}
+
+	existing := findMappingValue(base, key)
+	if existing == nil || existing.Kind != yaml.SequenceNode {
+		setMappingValue(base, key, &yaml.Node{Kind: yaml.SequenceNode, Tag: "!!seq", Content: items})
+		return
+	}
+
+	merged := make([]*yaml.Node, 0, len(existing.Content)+len(items))
+	if prepend {
+		merged = append(merged, items...)
+		merged = append(merged, existing.Content...)
+	} else {
+		merged = append(merged, existing.Content...)
+		merged = append(merged, items...)
+	}
+	existing.Content = merged
+}
+
+// findMappingValue 查找映射中的键值
+func findMappingValue(m *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			return m.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// setMappingValue 设置映射中的键值，不存在时追加到末尾
+func setMappingValue(m *yaml.Node, key string, value *yaml.Node) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content[i+1] = value
+			return
+		}
+	}
+	m.Content = append(m.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key},
+		value,
+	)
+}
+
+// deleteMappingKey 删除映射中的键
+func deleteMappingKey(m *yaml.Node, key string) {
+	for i := 0; i+1 < len(m.Content); i += 2 {
+		if m.Content[i].Value == key {
+			m.Content = append(m.Content[:i], m.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// orderedObject 保持键顺序的 JSON 对象
+type orderedObject []orderedField
+
+type orderedField struct {
+	Key   string
+	Value interface{}
+}
+
+// MarshalJSON 按原有顺序输出字段
+func (o orderedObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(field.Key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		value, err := json.Marshal(field.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// overrideNodeValue 将 YAML 节点转换为可按顺序序列化的 JSON 值
+func overrideNodeValue(n *yaml.Node) interface{} {
+	switch n.Kind {
+	case yaml.DocumentNode:
+		if len(n.Content) > 0 {
+			return overrideNodeValue(n.Content[0])
+		}
+		return nil
+	case yaml.AliasNode:
+		return overrideNodeValue(n.Alias)
+	case yaml.MappingNode:
+		obj := make(orderedObject, 0, len(n.Content)/2)
+		for i := 0; i+1 < len(n.Content); i += 2 {
+			obj = append(obj, orderedField{Key: n.Content[i].Value, Value: overrideNodeValue(n.Content[i+1])})
+		}
+		return obj
+	case yaml.SequenceNode:
+		list := make([]interface{}, 0, len(n.Content))
+		for _, item := range n.Content {
+			list = append(list, overrideNodeValue(item))
+		}
+		return list
+	}
+
+	var v interface{}
+	if err := n.Decode(&v); err != nil {
+		return n.Value
+	}
+	return v
+}
@@ -19,9 +19,9 @@ type ProxyGroupTemplate struct {
 
 // RuleTemplate 规则模板
 type RuleTemplate struct {
-	Type        string `json:"type"`        // DOMAIN, DOMAIN-SUFFIX, DOMAIN-KEYWORD, IP-CIDR, GEOIP, RULE-SET, MATCH
-	Payload     string `json:"payload"`     // 规则内容
-	Proxy       string `json:"proxy"`       // 代理组名称
+	Type        string `json:"type"`        // DOMAIN, DOMAIN-SUFFIX, DOMAIN-KEYWORD, IP-CIDR, GEOIP, RULE-SET, AND/OR/NOT, SUB-RULE, MATCH 等
+	Payload     string `json:"payload"`     // 规则内容，逻辑规则为 ((DOMAIN,a.com),(NETWORK,UDP)) 形式
+	Proxy       string `json:"proxy"`       // 代理组名称（SUB-RULE 为子规则名称）
 	NoResolve   bool   `json:"noResolve"`   // 不解析域名
 	Description string `json:"description"` // 说明
 }
@@ -40,9 +40,10 @@ type RuleProviderTemplate struct {
 
 // ConfigTemplate 完整配置模板
 type ConfigTemplate struct {
-	ProxyGroups   []ProxyGroupTemplate   `json:"proxyGroups"`
-	Rules         []RuleTemplate         `json:"rules"`
-	RuleProviders []RuleProviderTemplate `json:"ruleProviders"`
+	ProxyGroups   []ProxyGroupTemplate      `json:"proxyGroups"`
+	Rules         []RuleTemplate            `json:"rules"`
+	RuleProviders []RuleProviderTemplate    `json:"ruleProviders"`
+	SubRules      map[string][]RuleTemplate `json:"subRules,omitempty"` // SUB-RULE 引用的子规则
 }
 
 // GetDefaultProxyGroups 获取默认代理组
@@ -0,0 +1,171 @@
+package proxy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"p-box/backend/clashapi"
+)
+
+// ============================================================================
+// 连接管理：基于控制器 /connections 的查询、过滤与关闭
+// ============================================================================
+
+// ConnectionFilter 连接过滤条件（字段为空表示不过滤，文本匹配不区分大小写）
+type ConnectionFilter struct {
+	SourceIP string `json:"sourceIp" form:"sourceIp"` // 来源 IP（精确匹配）
+	Host     string `json:"host" form:"host"`         // 目标域名或 IP（包含匹配）
+	Rule     string `json:"rule" form:"rule"`         // 规则类型或规则内容（包含匹配）
+	Chain    string `json:"chain" form:"chain"`       // 代理链中的任一节点或分组（精确匹配）
+	Process  string `json:"process" form:"process"`   // 进程名或进程路径（包含匹配）
+	Network  string `json:"network" form:"network"`   // tcp, udp
+	Inbound  string `json:"inbound" form:"inbound"`   // 入站名称
+}
+
+// IsEmpty 是否未设置任何条件
+func (f ConnectionFilter) IsEmpty() bool {
+	return f == ConnectionFilter{}
+}
+
+// Match 判断连接是否满足过滤条件
+func (f ConnectionFilter) Match(conn clashapi.Connection) bool {
+	m := conn.Metadata
+	if f.SourceIP != "" && m.SourceIP != f.SourceIP {
+		return false
+	}
+	if f.Host != "" && !containsFold(m.Host, f.Host) && !containsFold(m.DestinationIP, f.Host) && !containsFold(m.SniffHost, f.Host) {
+		return false
+	}
+	if f.Rule != "" && !containsFold(conn.Rule, f.Rule) && !containsFold(conn.RulePayload, f.Rule) {
+		return false
+	}
+	if f.Chain != "" {
+		found := false
+		for _, name := range conn.Chains {
+			if name == f.Chain {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.Process != "" && !containsFold(m.Process, f.Process) && !containsFold(m.ProcessPath, f.Process) {
+		return false
+	}
+	if f.Network != "" && !strings.EqualFold(m.Network, f.Network) {
+		return false
+	}
+	if f.Inbound != "" && m.InboundName != f.Inbound {
+		return false
+	}
+	return true
+}
+
+// containsFold 不区分大小写的包含匹配
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
+
+// connectionSorters 支持的排序字段
+var connectionSorters = map[string]func(a, b clashapi.Connection) bool{
+	"start":    func(a, b clashapi.Connection) bool { return a.Start.Before(b.Start) },
+	"upload":   func(a, b clashapi.Connection) bool { return a.Upload < b.Upload },
+	"download": func(a, b clashapi.Connection) bool { return a.Download < b.Download },
+	"total":    func(a, b clashapi.Connection) bool { return a.Upload+a.Download < b.Upload+b.Download },
+	"host":     func(a, b clashapi.Connection) bool { return a.Metadata.Host < b.Metadata.Host },
+	"source":   func(a, b clashapi.Connection) bool { return a.Metadata.SourceIP < b.Metadata.SourceIP },
+}
+
+// ConnectionList 连接查询结果
+type ConnectionList struct {
+	DownloadTotal int64                 `json:"downloadTotal"`
+	UploadTotal   int64                 `json:"uploadTotal"`
+	Total         int                   `json:"total"` // 过滤前的连接数
+	Connections   []clashapi.Connection `json:"connections"`
+}
+
+// ListConnections 查询活动连接，sortBy 为空时按开始时间倒序
+func (s *Service) ListConnections(filter ConnectionFilter, sortBy string, desc bool) (*ConnectionList, error) {
+	if sortBy == "" {
+		sortBy, desc = "start", true
+	}
+	less, ok := connectionSorters[sortBy]
+	if !ok {
+		return nil, fmt.Errorf("不支持的排序字段: %s", sortBy)
+	}
+
+	snapshot, err := s.Controller().Connections()
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ConnectionList{
+		DownloadTotal: snapshot.DownloadTotal,
+		UploadTotal:   snapshot.UploadTotal,
+		Total:         len(snapshot.Connections),
+		Connections:   []clashapi.Connection{},
+	}
+	for _, conn := range snapshot.Connections {
+		if filter.Match(conn) {
+			result.Connections = append(result.Connections, conn)
+		}
+	}
+	sort.SliceStable(result.Connections, func(i, j int) bool {
+		if desc {
+			return less(result.Connections[j], result.Connections[i])
+		}
+		return less(result.Connections[i], result.Connections[j])
+	})
+	return result, nil
+}
+
+// CloseAllConnections 关闭全部连接，返回关闭数量
+func (s *Service) CloseAllConnections() (int, error) {
+	controller := s.Controller()
+	snapshot, err := controller.Connections()
+	if err != nil {
+		return 0, err
+	}
+	if err := controller.CloseAllConnections(); err != nil {
+		return 0, err
+	}
+	return len(snapshot.Connections), nil
+}
+
+// CloseConnection 关闭单个连接
+func (s *Service) CloseConnection(id string) error {
+	return s.Controller().CloseConnection(id)
+}
+
+// CloseConnections 关闭满足条件的连接，返回关闭数量
+// 条件不能为空，关闭全部连接需调用 CloseAllConnections
+func (s *Service) CloseConnections(filter ConnectionFilter) (int, error) {
+	if filter.IsEmpty() {
+		return 0, fmt.Errorf("未指定过滤条件")
+	}
+	controller := s.Controller()
+	snapshot, err := controller.Connections()
+	if err != nil {
+		return 0, err
+	}
+
+	closed := 0
+	var lastErr error
+	for _, conn := range snapshot.Connections {
+		if !filter.Match(conn) {
+			continue
+		}
+		if err := controller.CloseConnection(conn.ID); err != nil {
+			lastErr = err
+			continue
+		}
+		closed++
+	}
+	if closed == 0 && lastErr != nil {
+		return 0, lastErr
+	}
+	return closed, nil
+}
@@ -0,0 +1,103 @@
+package proxy
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListConnections 查询活动连接
+// 过滤参数：sourceIp, host, rule, chain, process, network, inbound；排序：sort=start|upload|download|total|host|source，order=asc|desc
+func (h *Handler) ListConnections(c *gin.Context) {
+	var filter ConnectionFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	sortBy := c.Query("sort")
+	desc := c.DefaultQuery("order", "desc") == "desc"
+	list, err := h.service.ListConnections(filter, sortBy, desc)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    list,
+	})
+}
+
+// CloseConnection 关闭单个连接
+func (h *Handler) CloseConnection(c *gin.Context) {
+	if err := h.service.CloseConnection(c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// CloseConnections 关闭满足过滤条件的连接，例如 chain=节点名 关闭经过该节点的所有连接
+// 关闭全部连接需显式指定 all=true，未指定条件时返回 400（避免参数拼写错误时误关全部连接）
+func (h *Handler) CloseConnections(c *gin.Context) {
+	var filter ConnectionFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	all := c.Query("all") == "true"
+	if all && !filter.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "all=true 不能与过滤条件同时使用",
+		})
+		return
+	}
+	if !all && filter.IsEmpty() {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "未指定过滤条件，关闭全部连接请使用 all=true",
+		})
+		return
+	}
+
+	var closed int
+	var err error
+	if all {
+		closed, err = h.service.CloseAllConnections()
+	} else {
+		closed, err = h.service.CloseConnections(filter)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    gin.H{"closed": closed},
+	})
+}
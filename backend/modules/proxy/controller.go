@@ -0,0 +1,108 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"path/filepath"
+	"runtime"
+
+	"p-box/backend/clashapi"
+)
+
+// ============================================================================
+// 核心控制器访问
+// 控制器密钥自动生成并注入两种核心的配置；Mihomo 可改为仅通过 unix socket 提供控制器
+// ============================================================================
+
+// Controller 获取核心控制器客户端（地址与密钥取自当前配置）
+// 客户端会被缓存复用，避免定时轮询时每次新建连接池
+func (s *Service) Controller() *clashapi.Client {
+	s.mu.RLock()
+	unix := s.useControllerUnix()
+	addr := s.config.ExternalController
+	if unix {
+		addr = s.controllerSocketPath()
+	}
+	secret := s.config.Secret
+	s.mu.RUnlock()
+
+	key := fmt.Sprintf("%t|%s|%s", unix, addr, secret)
+	s.controllerMu.Lock()
+	defer s.controllerMu.Unlock()
+	if s.controller != nil && s.controllerKey == key {
+		return s.controller
+	}
+	if s.controller != nil {
+		s.controller.CloseIdleConnections()
+	}
+	if unix {
+		s.controller = clashapi.NewUnix(addr, secret)
+	} else {
+		s.controller = clashapi.New(addr, secret)
+	}
+	s.controllerKey = key
+	return s.controller
+}
+
+// controllerSocketPath 控制器 unix socket 路径
+func (s *Service) controllerSocketPath() string {
+	return filepath.Join(s.dataDir, "runtime", "controller.sock")
+}
+
+// useControllerUnix 是否仅通过 unix socket 提供控制器（调用者需持有锁）
+// Sing-Box 的 clash_api 不支持 unix socket，Windows 下同样使用 TCP
+func (s *Service) useControllerUnix() bool {
+	return s.config.ControllerUnix && s.coreType != "singbox" && runtime.GOOS != "windows"
+}
+
+// generateControllerSecret 生成随机控制器密钥
+func generateControllerSecret() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("生成控制器密钥失败: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// ensureControllerSecret 未设置控制器密钥时自动生成并保存
+func (s *Service) ensureControllerSecret() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.config.Secret == "" {
+		secret, err := generateControllerSecret()
+		if err != nil {
+			fmt.Printf("⚠️ %v\n", err)
+			return
+		}
+		s.config.Secret = secret
+		if err := s.saveConfig(); err != nil {
+			fmt.Printf("⚠️ 保存控制器密钥失败: %v\n", err)
+		}
+	}
+
+	if host, _, err := net.SplitHostPort(s.config.ExternalController); err == nil {
+		if ip := net.ParseIP(host); host == "" || (ip != nil && !ip.IsLoopback()) {
+			fmt.Printf("⚠️ 控制器监听在 %s，局域网设备可访问（已启用密钥）\n", s.config.ExternalController)
+		}
+	}
+}
+
+// RotateControllerSecret 重新生成控制器密钥，运行中时重新生成配置并重启
+func (s *Service) RotateControllerSecret() error {
+	secret, err := generateControllerSecret()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.config.Secret = secret
+	err = s.saveConfig()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	s.regenerateIfRunning()
+	return nil
+}
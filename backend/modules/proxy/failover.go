@@ -0,0 +1,474 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"p-box/backend/clashapi"
+	"p-box/backend/events"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// 选择器故障转移与出口轮换
+// 核心的 url-test/fallback 只作用于自身管理的分组，手动选择器由看门狗通过控制器探测并切换
+// ============================================================================
+
+// 故障转移策略
+const (
+	FailoverPolicyLowestDelay = "lowest-delay" // 切换到延迟最低的成员
+	FailoverPolicySameRegion  = "same-region"  // 优先切换到同地区延迟最低的成员
+	FailoverPolicyRoundRobin  = "round-robin"  // 按顺序切换到下一个可用成员
+)
+
+// failoverEventLimit 切换记录保留条数
+const failoverEventLimit = 200
+
+// failoverProbeWorkers 并发测速数
+const failoverProbeWorkers = 8
+
+// FailoverSettings 故障转移设置
+type FailoverSettings struct {
+	Enabled       bool            `json:"enabled"`
+	Interval      int             `json:"interval"`      // 检查间隔（秒）
+	TestURL       string          `json:"testUrl"`       // 测速地址
+	Timeout       int             `json:"timeout"`       // 测速超时（毫秒）
+	FailThreshold int             `json:"failThreshold"` // 连续失败多少次后切换
+	Groups        []FailoverGroup `json:"groups"`
+}
+
+// FailoverGroup 受看门狗管理的选择器分组
+type FailoverGroup struct {
+	Name           string   `json:"name"`
+	Enabled        bool     `json:"enabled"`
+	Policy         string   `json:"policy"`                   // lowest-delay, same-region, round-robin
+	MaxDelay       int      `json:"maxDelay,omitempty"`       // 延迟超过该值（毫秒）视为不可用，0 表示不限制
+	Exclude        []string `json:"exclude,omitempty"`        // 不参与切换的成员
+	RotateInterval int      `json:"rotateInterval,omitempty"` // 出口轮换间隔（分钟），0 表示不轮换
+	CloseOld       bool     `json:"closeOld,omitempty"`       // 切换后关闭经过原选中项的连接
+}
+
+// FailoverEvent 切换记录
+type FailoverEvent struct {
+	Time   time.Time `json:"time"`
+	Group  string    `json:"group"`
+	From   string    `json:"from"`
+	To     string    `json:"to"`
+	Reason string    `json:"reason"` // failover, rotate
+	Delay  int       `json:"delay,omitempty"`
+	Detail string    `json:"detail"`
+}
+
+// failoverStore 故障转移持久化结构
+type failoverStore struct {
+	Settings FailoverSettings `json:"settings"`
+	Events   []FailoverEvent  `json:"events"`
+}
+
+// failoverState 分组运行状态（不持久化）
+type failoverState struct {
+	failures   int
+	lastSwitch time.Time
+}
+
+// Watchdog 选择器看门狗
+type Watchdog struct {
+	dataDir  string
+	service  *Service
+	store    failoverStore
+	states   map[string]*failoverState
+	mu       sync.Mutex
+	checkMu  sync.Mutex
+	stopChan chan struct{}
+}
+
+// DefaultFailoverSettings 默认故障转移设置
+func DefaultFailoverSettings() FailoverSettings {
+	return FailoverSettings{
+		Enabled:       false,
+		Interval:      60,
+		TestURL:       "https://www.gstatic.com/generate_204",
+		Timeout:       5000,
+		FailThreshold: 2,
+		Groups:        []FailoverGroup{},
+	}
+}
+
+// NewWatchdog 创建看门狗并启动检查循环
+func NewWatchdog(dataDir string, service *Service) *Watchdog {
+	w := &Watchdog{
+		dataDir:  dataDir,
+		service:  service,
+		store:    failoverStore{Settings: DefaultFailoverSettings()},
+		states:   make(map[string]*failoverState),
+		stopChan: make(chan struct{}),
+	}
+	w.load()
+	go w.loop()
+	return w
+}
+
+// Stop 停止检查循环
+func (w *Watchdog) Stop() {
+	close(w.stopChan)
+}
+
+// storePath 获取故障转移文件路径
+func (w *Watchdog) storePath() string {
+	return filepath.Join(w.dataDir, "failover.json")
+}
+
+// load 加载故障转移设置
+func (w *Watchdog) load() {
+	data, err := os.ReadFile(w.storePath())
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &w.store); err != nil {
+		fmt.Printf("⚠️ 读取故障转移设置失败: %v\n", err)
+	}
+}
+
+// save 保存故障转移设置（调用者需持有锁）
+func (w *Watchdog) save() error {
+	data, err := json.MarshalIndent(w.store, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(w.storePath(), data, 0644)
+}
+
+// GetSettings 获取故障转移设置
+func (w *Watchdog) GetSettings() FailoverSettings {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.store.Settings
+}
+
+// UpdateSettings 更新故障转移设置
+func (w *Watchdog) UpdateSettings(settings FailoverSettings) error {
+	if err := ValidateFailoverSettings(settings); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if settings.Groups == nil {
+		settings.Groups = []FailoverGroup{}
+	}
+	w.store.Settings = settings
+	w.states = make(map[string]*failoverState)
+	return w.save()
+}
+
+// ValidateFailoverSettings 校验故障转移设置
+func ValidateFailoverSettings(settings FailoverSettings) error {
+	if settings.Interval < 10 {
+		return fmt.Errorf("检查间隔不能小于 10 秒")
+	}
+	if settings.TestURL == "" {
+		return fmt.Errorf("测速地址不能为空")
+	}
+	if settings.Timeout <= 0 {
+		return fmt.Errorf("测速超时必须大于 0")
+	}
+	if settings.FailThreshold <= 0 {
+		return fmt.Errorf("失败次数阈值必须大于 0")
+	}
+	names := make(map[string]bool)
+	for _, g := range settings.Groups {
+		if g.Name == "" {
+			return fmt.Errorf("分组名称不能为空")
+		}
+		if names[g.Name] {
+			return fmt.Errorf("分组 %s 重复", g.Name)
+		}
+		names[g.Name] = true
+		switch g.Policy {
+		case FailoverPolicyLowestDelay, FailoverPolicySameRegion, FailoverPolicyRoundRobin:
+		default:
+			return fmt.Errorf("分组 %s 的策略 %s 不受支持", g.Name, g.Policy)
+		}
+		if g.MaxDelay < 0 || g.RotateInterval < 0 {
+			return fmt.Errorf("分组 %s 的延迟上限或轮换间隔无效", g.Name)
+		}
+	}
+	return nil
+}
+
+// Events 获取切换记录（最新的在前）
+func (w *Watchdog) Events(limit int) []FailoverEvent {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	events := make([]FailoverEvent, 0, len(w.store.Events))
+	for i := len(w.store.Events) - 1; i >= 0; i-- {
+		events = append(events, w.store.Events[i])
+		if limit > 0 && len(events) >= limit {
+			break
+		}
+	}
+	return events
+}
+
+// loop 按设置的间隔检查分组
+func (w *Watchdog) loop() {
+	for {
+		interval := time.Duration(w.GetSettings().Interval) * time.Second
+		if interval < 10*time.Second {
+			interval = 10 * time.Second
+		}
+		select {
+		case <-time.After(interval):
+			settings := w.GetSettings()
+			if settings.Enabled && w.service.GetStatus().Running {
+				w.CheckAll()
+			}
+		case <-w.stopChan:
+			return
+		}
+	}
+}
+
+// CheckAll 立即检查所有启用的分组，返回本次产生的切换记录
+func (w *Watchdog) CheckAll() []FailoverEvent {
+	w.checkMu.Lock()
+	defer w.checkMu.Unlock()
+
+	settings := w.GetSettings()
+	var events []FailoverEvent
+	for _, g := range settings.Groups {
+		if !g.Enabled {
+			continue
+		}
+		event, err := w.checkGroup(settings, g)
+		if err != nil {
+			fmt.Printf("⚠️ 故障转移检查 %s 失败: %v\n", g.Name, err)
+			continue
+		}
+		if event != nil {
+			events = append(events, *event)
+		}
+	}
+	return events
+}
+
+// state 获取分组运行状态
+func (w *Watchdog) state(group string) *failoverState {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	st, ok := w.states[group]
+	if !ok {
+		st = &failoverState{lastSwitch: time.Now()}
+		w.states[group] = st
+	}
+	return st
+}
+
+// checkGroup 探测分组当前选中，不可用或到达轮换时间时切换
+func (w *Watchdog) checkGroup(settings FailoverSettings, group FailoverGroup) (*FailoverEvent, error) {
+	controller := w.service.Controller()
+	info, err := controller.Proxy(group.Name)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsSelector() {
+		return nil, fmt.Errorf("%s 不是选择器分组", group.Name)
+	}
+
+	st := w.state(group.Name)
+	delay, err := controller.ProxyDelay(info.Now, settings.TestURL, settings.Timeout)
+	healthy := err == nil && (group.MaxDelay == 0 || delay <= group.MaxDelay)
+	if healthy {
+		st.failures = 0
+	} else {
+		st.failures++
+	}
+
+	reason := ""
+	switch {
+	case st.failures >= settings.FailThreshold:
+		reason = "failover"
+	case group.RotateInterval > 0 && time.Since(st.lastSwitch) >= time.Duration(group.RotateInterval)*time.Minute:
+		reason = "rotate"
+	default:
+		return nil, nil
+	}
+
+	candidates := failoverCandidates(info, group)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("%s 没有可切换的成员", group.Name)
+	}
+	delays := probeFailoverCandidates(controller, candidates, settings, group)
+	policy := group.Policy
+	if reason == "rotate" {
+		policy = FailoverPolicyRoundRobin
+	}
+	target := pickFailoverTarget(policy, info.Now, info.All, candidates, delays)
+	if target == "" {
+		if reason == "failover" {
+			w.service.bus.Publish(events.FailoverDown, events.FailoverPayload{
+				Group:  group.Name,
+				From:   info.Now,
+				Detail: fmt.Sprintf("当前节点与 %d 个候选成员均不可用", len(candidates)),
+			})
+		}
+		return nil, fmt.Errorf("%s 没有可用的成员", group.Name)
+	}
+
+	if err := w.service.SelectProxy(group.Name, target); err != nil {
+		return nil, err
+	}
+	st.failures = 0
+	st.lastSwitch = time.Now()
+	if group.CloseOld {
+		if _, err := w.service.CloseConnections(ConnectionFilter{Chain: info.Now}); err != nil {
+			fmt.Printf("⚠️ 关闭 %s 的连接失败: %v\n", info.Now, err)
+		}
+	}
+
+	event := FailoverEvent{
+		Time:   time.Now(),
+		Group:  group.Name,
+		From:   info.Now,
+		To:     target,
+		Reason: reason,
+		Delay:  delays[target],
+	}
+	if reason == "failover" {
+		if err != nil {
+			event.Detail = fmt.Sprintf("%s 不可用: %v", info.Now, err)
+		} else {
+			event.Detail = fmt.Sprintf("%s 延迟 %dms 超过上限 %dms", info.Now, delay, group.MaxDelay)
+		}
+	} else {
+		event.Detail = fmt.Sprintf("定时轮换（每 %d 分钟）", group.RotateInterval)
+	}
+	fmt.Printf("🔄 %s: %s -> %s (%s)\n", group.Name, info.Now, target, event.Detail)
+	w.record(event)
+	w.service.bus.Publish(events.FailoverSwitch, events.FailoverPayload{
+		Group:  event.Group,
+		From:   event.From,
+		To:     event.To,
+		Reason: event.Reason,
+		Detail: event.Detail,
+	})
+	return &event, nil
+}
+
+// record 记录切换事件
+func (w *Watchdog) record(event FailoverEvent) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.store.Events = append(w.store.Events, event)
+	if len(w.store.Events) > failoverEventLimit {
+		w.store.Events = w.store.Events[len(w.store.Events)-failoverEventLimit:]
+	}
+	if err := w.save(); err != nil {
+		fmt.Printf("⚠️ 保存故障转移记录失败: %v\n", err)
+	}
+}
+
+// failoverCandidates 可切换的成员（排除当前选中、内置策略与排除列表）
+func failoverCandidates(info *clashapi.Proxy, group FailoverGroup) []string {
+	excluded := map[string]bool{
+		info.Now: true, "DIRECT": true, "REJECT": true, "REJECT-DROP": true, "PASS": true,
+		"COMPATIBLE": true, "direct": true, "block": true,
+	}
+	for _, name := range group.Exclude {
+		excluded[name] = true
+	}
+	var candidates []string
+	for _, name := range info.All {
+		if !excluded[name] {
+			candidates = append(candidates, name)
+		}
+	}
+	return candidates
+}
+
+// probeFailoverCandidates 并发测试候选成员延迟，不可用的成员不在结果中
+func probeFailoverCandidates(controller *clashapi.Client, candidates []string, settings FailoverSettings, group FailoverGroup) map[string]int {
+	delays := make(map[string]int)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, failoverProbeWorkers)
+	for _, name := range candidates {
+		wg.Add(1)
+		go func(name string) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			delay, err := controller.ProxyDelay(name, settings.TestURL, settings.Timeout)
+			if err != nil || (group.MaxDelay > 0 && delay > group.MaxDelay) {
+				return
+			}
+			mu.Lock()
+			delays[name] = delay
+			mu.Unlock()
+		}(name)
+	}
+	wg.Wait()
+	return delays
+}
+
+// pickFailoverTarget 按策略从可用成员中选择目标
+func pickFailoverTarget(policy, current string, members, candidates []string, delays map[string]int) string {
+	lowest := func(names []string) string {
+		best := ""
+		for _, name := range names {
+			if d, ok := delays[name]; ok && (best == "" || d < delays[best]) {
+				best = name
+			}
+		}
+		return best
+	}
+
+	switch policy {
+	case FailoverPolicySameRegion:
+		if region := nodeRegion(current); region != "" {
+			var same []string
+			for _, name := range candidates {
+				if nodeRegion(name) == region {
+					same = append(same, name)
+				}
+			}
+			if best := lowest(same); best != "" {
+				return best
+			}
+		}
+		return lowest(candidates)
+	case FailoverPolicyRoundRobin:
+		// 从当前选中之后按分组顺序查找第一个可用成员
+		start := 0
+		for i, name := range members {
+			if name == current {
+				start = i + 1
+				break
+			}
+		}
+		for i := 0; i < len(members); i++ {
+			name := members[(start+i)%len(members)]
+			if _, ok := delays[name]; ok {
+				return name
+			}
+		}
+		return ""
+	}
+	return lowest(candidates)
+}
+
+// nodeRegion 根据默认地区过滤器识别节点地区
+func nodeRegion(name string) string {
+	for _, filter := range GetDefaultRegionFilters() {
+		for _, keyword := range filter.Keywords {
+			if strings.Contains(name, keyword) {
+				return filter.Tag
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,92 @@
+package proxy
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// FailoverHandler 故障转移处理器
+type FailoverHandler struct {
+	watchdog *Watchdog
+}
+
+// NewFailoverHandler 创建故障转移处理器
+func NewFailoverHandler(watchdog *Watchdog) *FailoverHandler {
+	return &FailoverHandler{watchdog: watchdog}
+}
+
+// RegisterRoutes 注册路由
+func (h *FailoverHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/failover", h.GetFailoverSettings)
+	r.PUT("/failover", h.UpdateFailoverSettings)
+	r.GET("/failover/events", h.GetFailoverEvents)
+	r.POST("/failover/check", h.CheckNow)
+}
+
+// GetFailoverSettings 获取故障转移设置
+func (h *FailoverHandler) GetFailoverSettings(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.watchdog.GetSettings(),
+	})
+}
+
+// UpdateFailoverSettings 更新故障转移设置
+func (h *FailoverHandler) UpdateFailoverSettings(c *gin.Context) {
+	var settings FailoverSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.watchdog.UpdateSettings(settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.watchdog.GetSettings(),
+	})
+}
+
+// GetFailoverEvents 获取切换记录，limit 默认 50
+func (h *FailoverHandler) GetFailoverEvents(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 0 {
+		limit = 50
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.watchdog.Events(limit),
+	})
+}
+
+// CheckNow 立即检查所有分组
+func (h *FailoverHandler) CheckNow(c *gin.Context) {
+	if !h.watchdog.service.GetStatus().Running {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "核心未运行",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.watchdog.CheckAll(),
+	})
+}
@@ -0,0 +1,613 @@
+package proxy
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GEO 数据离线读取，用于规则模拟等不依赖核心运行的场景
+// 支持 geosite.dat / geoip.dat (v2ray protobuf 格式) 与 country.mmdb (MaxMind DB 格式)
+
+// geoSiteDomain geosite 域名条目
+type geoSiteDomain struct {
+	Type  int // 0: 关键字 1: 正则 2: 域名后缀 3: 完整域名
+	Value string
+}
+
+// GeoDataReader GEO 数据读取器（按文件修改时间缓存）
+type GeoDataReader struct {
+	dataDir string
+	mu      sync.Mutex
+	sites   map[string][]geoSiteDomain
+	ips     map[string][]*net.IPNet
+	siteMod time.Time
+	ipMod   time.Time
+	mmdb    *mmdbReader
+	mmdbMod time.Time
+}
+
+// NewGeoDataReader 创建 GEO 数据读取器
+func NewGeoDataReader(dataDir string) *GeoDataReader {
+	return &GeoDataReader{
+		dataDir: dataDir,
+		sites:   make(map[string][]geoSiteDomain),
+		ips:     make(map[string][]*net.IPNet),
+	}
+}
+
+// MatchGeoSite 判断域名是否属于 geosite 分类（忽略 @attr 属性过滤）
+func (r *GeoDataReader) MatchGeoSite(code, domain string) (bool, error) {
+	code = strings.ToLower(strings.SplitN(code, "@", 2)[0])
+	domains, err := r.loadGeoSite(code)
+	if err != nil {
+		return false, err
+	}
+
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	for _, d := range domains {
+		value := strings.ToLower(d.Value)
+		switch d.Type {
+		case 0:
+			if strings.Contains(domain, value) {
+				return true, nil
+			}
+		case 1:
+			if re, err := regexp.Compile(d.Value); err == nil && re.MatchString(domain) {
+				return true, nil
+			}
+		case 2:
+			if domain == value || strings.HasSuffix(domain, "."+value) {
+				return true, nil
+			}
+		case 3:
+			if domain == value {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// MatchGeoIP 判断 IP 是否属于 geoip 分类
+// 优先使用 geoip.dat，不存在时回退到 country.mmdb
+func (r *GeoDataReader) MatchGeoIP(code string, ip net.IP) (bool, error) {
+	code = strings.ToLower(code)
+	if code == "private" || code == "lan" {
+		return ip.IsPrivate() || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsUnspecified(), nil
+	}
+
+	if _, err := os.Stat(filepath.Join(r.dataDir, "geoip.dat")); err == nil {
+		nets, err := r.loadGeoIP(code)
+		if err != nil {
+			return false, err
+		}
+		for _, n := range nets {
+			if n.Contains(ip) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+
+	codes, err := r.LookupCountry(ip)
+	if err != nil {
+		return false, err
+	}
+	for _, c := range codes {
+		if strings.ToLower(c) == code {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// LookupCountry 使用 country.mmdb 查询 IP 所属国家代码
+func (r *GeoDataReader) LookupCountry(ip net.IP) ([]string, error) {
+	db, err := r.loadMMDB()
+	if err != nil {
+		return nil, err
+	}
+	record, err := db.lookup(ip)
+	if err != nil || record == nil {
+		return nil, err
+	}
+
+	switch v := record.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		codes := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				codes = append(codes, s)
+			}
+		}
+		return codes, nil
+	case map[string]interface{}:
+		if country, ok := v["country"].(map[string]interface{}); ok {
+			if iso, ok := country["iso_code"].(string); ok {
+				return []string{iso}, nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// loadGeoSite 加载 geosite.dat 中的指定分类
+func (r *GeoDataReader) loadGeoSite(code string) ([]geoSiteDomain, error) {
+	path := filepath.Join(r.dataDir, "geosite.dat")
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("geosite.dat 不存在")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !info.ModTime().Equal(r.siteMod) {
+		r.sites = make(map[string][]geoSiteDomain)
+		r.siteMod = info.ModTime()
+	}
+	if domains, ok := r.sites[code]; ok {
+		return domains, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var domains []geoSiteDomain
+	err = walkProtoFields(data, func(field int, entry []byte, _ uint64) error {
+		if field != 1 || !strings.EqualFold(protoStringField(entry, 1), code) {
+			return nil
+		}
+		return walkProtoFields(entry, func(field int, raw []byte, _ uint64) error {
+			if field != 2 {
+				return nil
+			}
+			d := geoSiteDomain{}
+			walkProtoFields(raw, func(field int, value []byte, num uint64) error {
+				switch field {
+				case 1:
+					d.Type = int(num)
+				case 2:
+					d.Value = string(value)
+				}
+				return nil
+			})
+			domains = append(domains, d)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("geosite.dat 解析失败: %w", err)
+	}
+
+	r.sites[code] = domains
+	return domains, nil
+}
+
+// loadGeoIP 加载 geoip.dat 中的指定分类
+func (r *GeoDataReader) loadGeoIP(code string) ([]*net.IPNet, error) {
+	path := filepath.Join(r.dataDir, "geoip.dat")
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("geoip.dat 不存在")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !info.ModTime().Equal(r.ipMod) {
+		r.ips = make(map[string][]*net.IPNet)
+		r.ipMod = info.ModTime()
+	}
+	if nets, ok := r.ips[code]; ok {
+		return nets, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var nets []*net.IPNet
+	err = walkProtoFields(data, func(field int, entry []byte, _ uint64) error {
+		if field != 1 || !strings.EqualFold(protoStringField(entry, 1), code) {
+			return nil
+		}
+		return walkProtoFields(entry, func(field int, raw []byte, _ uint64) error {
+			if field != 2 {
+				return nil
+			}
+			var ip []byte
+			var prefix int
+			walkProtoFields(raw, func(field int, value []byte, num uint64) error {
+				switch field {
+				case 1:
+					ip = value
+				case 2:
+					prefix = int(num)
+				}
+				return nil
+			})
+			if len(ip) == net.IPv4len || len(ip) == net.IPv6len {
+				nets = append(nets, &net.IPNet{IP: net.IP(ip), Mask: net.CIDRMask(prefix, len(ip)*8)})
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("geoip.dat 解析失败: %w", err)
+	}
+
+	r.ips[code] = nets
+	return nets, nil
+}
+
+// loadMMDB 加载 country.mmdb
+func (r *GeoDataReader) loadMMDB() (*mmdbReader, error) {
+	path := filepath.Join(r.dataDir, "country.mmdb")
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("country.mmdb 不存在")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.mmdb != nil && info.ModTime().Equal(r.mmdbMod) {
+		return r.mmdb, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	db, err := openMMDB(data)
+	if err != nil {
+		return nil, fmt.Errorf("country.mmdb 解析失败: %w", err)
+	}
+	r.mmdb = db
+	r.mmdbMod = info.ModTime()
+	return db, nil
+}
+
+// ========== protobuf 最小解析 ==========
+
+// walkProtoFields 遍历 protobuf 消息字段
+// 长度分隔字段通过 value 返回，varint 字段通过 num 返回
+func walkProtoFields(data []byte, fn func(field int, value []byte, num uint64) error) error {
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return fmt.Errorf("invalid field key")
+		}
+		data = data[n:]
+		field := int(key >> 3)
+
+		switch key & 0x7 {
+		case 0:
+			num, n := binary.Uvarint(data)
+			if n <= 0 {
+				return fmt.Errorf("invalid varint")
+			}
+			data = data[n:]
+			if err := fn(field, nil, num); err != nil {
+				return err
+			}
+		case 1:
+			if len(data) < 8 {
+				return fmt.Errorf("unexpected EOF")
+			}
+			data = data[8:]
+		case 2:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < size {
+				return fmt.Errorf("invalid length")
+			}
+			value := data[n : n+int(size)]
+			data = data[n+int(size):]
+			if err := fn(field, value, 0); err != nil {
+				return err
+			}
+		case 5:
+			if len(data) < 4 {
+				return fmt.Errorf("unexpected EOF")
+			}
+			data = data[4:]
+		default:
+			return fmt.Errorf("unsupported wire type %d", key&0x7)
+		}
+	}
+	return nil
+}
+
+// protoStringField 读取消息中的字符串字段
+func protoStringField(data []byte, target int) string {
+	var result string
+	walkProtoFields(data, func(field int, value []byte, _ uint64) error {
+		if field == target && value != nil {
+			result = string(value)
+			return errStopWalk
+		}
+		return nil
+	})
+	return result
+}
+
+var errStopWalk = fmt.Errorf("stop")
+
+// ========== MaxMind DB 最小解析 ==========
+
+var mmdbMetadataMarker = []byte("\xAB\xCD\xEFMaxMind.com")
+
+// mmdbMaxDepth 指针与嵌套 map/array 的最大解析深度，防止损坏或自引用的数据库导致栈溢出
+const mmdbMaxDepth = 32
+
+type mmdbReader struct {
+	data       []byte
+	nodeCount  uint
+	recordSize uint
+	ipVersion  uint
+	dataStart  uint
+	ipv4Start  uint
+}
+
+// openMMDB 解析 MaxMind DB 元数据
+func openMMDB(data []byte) (*mmdbReader, error) {
+	idx := bytes.LastIndex(data, mmdbMetadataMarker)
+	if idx < 0 {
+		return nil, fmt.Errorf("metadata not found")
+	}
+	metaStart := uint(idx + len(mmdbMetadataMarker))
+	meta, _, err := (&mmdbReader{data: data, dataStart: metaStart}).decode(metaStart, 0)
+	if err != nil {
+		return nil, err
+	}
+	metaMap, ok := meta.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("invalid metadata")
+	}
+
+	db := &mmdbReader{
+		data:       data,
+		nodeCount:  mmdbUint(metaMap["node_count"]),
+		recordSize: mmdbUint(metaMap["record_size"]),
+		ipVersion:  mmdbUint(metaMap["ip_version"]),
+	}
+	if db.recordSize != 24 && db.recordSize != 28 && db.recordSize != 32 {
+		return nil, fmt.Errorf("unsupported record size %d", db.recordSize)
+	}
+	db.dataStart = db.nodeCount*db.recordSize/4 + 16
+	if db.dataStart > uint(len(data)) {
+		return nil, fmt.Errorf("invalid search tree size")
+	}
+
+	// IPv6 数据库中 IPv4 地址位于 ::/96 子树
+	if db.ipVersion == 6 {
+		node := uint(0)
+		for i := 0; i < 96 && node < db.nodeCount; i++ {
+			node = db.readRecord(node, 0)
+		}
+		db.ipv4Start = node
+	}
+	return db, nil
+}
+
+func mmdbUint(v interface{}) uint {
+	switch n := v.(type) {
+	case uint64:
+		return uint(n)
+	case uint32:
+		return uint(n)
+	case uint16:
+		return uint(n)
+	}
+	return 0
+}
+
+// readRecord 读取搜索树节点的左(0)/右(1)记录
+func (db *mmdbReader) readRecord(node uint, bit uint) uint {
+	switch db.recordSize {
+	case 24:
+		off := node*6 + bit*3
+		b := db.data[off : off+3]
+		return uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+	case 28:
+		off := node * 7
+		b := db.data[off : off+7]
+		if bit == 0 {
+			return uint(b[3]&0xF0)<<20 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])
+		}
+		return uint(b[3]&0x0F)<<24 | uint(b[4])<<16 | uint(b[5])<<8 | uint(b[6])
+	default:
+		off := node*8 + bit*4
+		return uint(binary.BigEndian.Uint32(db.data[off : off+4]))
+	}
+}
+
+// lookup 查询 IP 对应的数据记录
+func (db *mmdbReader) lookup(ip net.IP) (interface{}, error) {
+	node := uint(0)
+	addr := ip.To16()
+	bits := 128
+	if v4 := ip.To4(); v4 != nil {
+		addr = v4
+		bits = 32
+		if db.ipVersion == 6 {
+			node = db.ipv4Start
+		}
+	} else if db.ipVersion == 4 {
+		return nil, nil
+	}
+
+	for i := 0; i < bits && node < db.nodeCount; i++ {
+		bit := uint(addr[i>>3]>>(7-uint(i&7))) & 1
+		node = db.readRecord(node, bit)
+	}
+
+	if node == db.nodeCount {
+		return nil, nil
+	}
+	if node < db.nodeCount {
+		return nil, fmt.Errorf("invalid search tree")
+	}
+	value, _, err := db.decode(db.dataStart+(node-db.nodeCount)-16, 0)
+	return value, err
+}
+
+// decode 解析 offset 处的数据字段，返回值与下一个字段偏移
+// depth 为当前指针与嵌套层数，超过 mmdbMaxDepth 时返回错误
+func (db *mmdbReader) decode(offset uint, depth int) (interface{}, uint, error) {
+	if depth > mmdbMaxDepth {
+		return nil, 0, fmt.Errorf("data nested too deep")
+	}
+	if offset >= uint(len(db.data)) {
+		return nil, 0, fmt.Errorf("offset out of range")
+	}
+	ctrl := db.data[offset]
+	offset++
+	typeNum := uint(ctrl >> 5)
+
+	if typeNum == 1 {
+		pointer, next, err := db.decodePointer(ctrl, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		value, _, err := db.decode(db.dataStart+pointer, depth+1)
+		return value, next, err
+	}
+
+	if typeNum == 0 {
+		if offset >= uint(len(db.data)) {
+			return nil, 0, fmt.Errorf("offset out of range")
+		}
+		typeNum = 7 + uint(db.data[offset])
+		offset++
+	}
+
+	size := uint(ctrl & 0x1f)
+	if size >= 29 {
+		extra := size - 28
+		if offset+extra > uint(len(db.data)) {
+			return nil, 0, fmt.Errorf("offset out of range")
+		}
+		var n uint
+		for _, b := range db.data[offset : offset+extra] {
+			n = n<<8 | uint(b)
+		}
+		offset += extra
+		switch size {
+		case 29:
+			size = 29 + n
+		case 30:
+			size = 285 + n
+		default:
+			size = 65821 + n
+		}
+	}
+
+	switch typeNum {
+	case 7: // map
+		// 每个条目至少占 1 字节，容量不超过剩余数据长度
+		m := make(map[string]interface{}, min(size, uint(len(db.data))-offset))
+		for i := uint(0); i < size; i++ {
+			key, next, err := db.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			value, next, err := db.decode(next, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			if k, ok := key.(string); ok {
+				m[k] = value
+			}
+			offset = next
+		}
+		return m, offset, nil
+	case 11: // array
+		list := make([]interface{}, 0, min(size, uint(len(db.data))-offset))
+		for i := uint(0); i < size; i++ {
+			value, next, err := db.decode(offset, depth+1)
+			if err != nil {
+				return nil, 0, err
+			}
+			list = append(list, value)
+			offset = next
+		}
+		return list, offset, nil
+	case 14: // boolean
+		return size != 0, offset, nil
+	}
+
+	if offset+size > uint(len(db.data)) {
+		return nil, 0, fmt.Errorf("offset out of range")
+	}
+	raw := db.data[offset : offset+size]
+	next := offset + size
+
+	switch typeNum {
+	case 2:
+		return string(raw), next, nil
+	case 3:
+		if size != 8 {
+			return nil, 0, fmt.Errorf("invalid double size")
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(raw)), next, nil
+	case 4:
+		return raw, next, nil
+	case 5, 6, 9, 10:
+		var n uint64
+		for _, b := range raw {
+			n = n<<8 | uint64(b)
+		}
+		return n, next, nil
+	case 8:
+		var n int32
+		for _, b := range raw {
+			n = n<<8 | int32(b)
+		}
+		return n, next, nil
+	case 15:
+		if size != 4 {
+			return nil, 0, fmt.Errorf("invalid float size")
+		}
+		return math.Float32frombits(binary.BigEndian.Uint32(raw)), next, nil
+	}
+	return nil, next, nil
+}
+
+// decodePointer 解析指针字段，返回数据段内偏移
+func (db *mmdbReader) decodePointer(ctrl byte, offset uint) (uint, uint, error) {
+	size := uint((ctrl>>3)&0x3) + 1
+	if offset+size > uint(len(db.data)) {
+		return 0, 0, fmt.Errorf("offset out of range")
+	}
+	b := db.data[offset : offset+size]
+	vvv := uint(ctrl & 0x7)
+
+	var pointer uint
+	switch size {
+	case 1:
+		pointer = vvv<<8 | uint(b[0])
+	case 2:
+		pointer = (vvv<<16 | uint(b[0])<<8 | uint(b[1])) + 2048
+	case 3:
+		pointer = (vvv<<24 | uint(b[0])<<16 | uint(b[1])<<8 | uint(b[2])) + 526336
+	default:
+		pointer = uint(binary.BigEndian.Uint32(b))
+	}
+	return pointer, offset + size, nil
+}
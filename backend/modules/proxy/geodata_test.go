@@ -0,0 +1,268 @@
+package proxy
+
+import (
+	"encoding/binary"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// ========== protobuf 测试数据 ==========
+
+// protoBytes 编码长度分隔字段
+func protoBytes(field int, value []byte) []byte {
+	out := binary.AppendUvarint(nil, uint64(field<<3|2))
+	out = binary.AppendUvarint(out, uint64(len(value)))
+	return append(out, value...)
+}
+
+// protoVarint 编码 varint 字段
+func protoVarint(field int, value uint64) []byte {
+	out := binary.AppendUvarint(nil, uint64(field<<3))
+	return binary.AppendUvarint(out, value)
+}
+
+// buildGeoSite 构造 geosite.dat：分类 -> 域名条目
+func buildGeoSite(entries map[string][]geoSiteDomain) []byte {
+	var out []byte
+	for code, domains := range entries {
+		site := protoBytes(1, []byte(code))
+		for _, d := range domains {
+			domain := append(protoVarint(1, uint64(d.Type)), protoBytes(2, []byte(d.Value))...)
+			site = append(site, protoBytes(2, domain)...)
+		}
+		out = append(out, protoBytes(1, site)...)
+	}
+	return out
+}
+
+// buildGeoIP 构造 geoip.dat：分类 -> CIDR 列表
+func buildGeoIP(entries map[string][]string) []byte {
+	var out []byte
+	for code, cidrs := range entries {
+		entry := protoBytes(1, []byte(code))
+		for _, cidr := range cidrs {
+			_, ipnet, _ := net.ParseCIDR(cidr)
+			ip := ipnet.IP
+			if v4 := ip.To4(); v4 != nil {
+				ip = v4
+			}
+			prefix, _ := ipnet.Mask.Size()
+			entry = append(entry, protoBytes(2, append(protoBytes(1, ip), protoVarint(2, uint64(prefix))...))...)
+		}
+		out = append(out, protoBytes(1, entry)...)
+	}
+	return out
+}
+
+// ========== MaxMind DB 测试数据 ==========
+
+// mmdbString 编码 MMDB 字符串（长度小于 29）
+func mmdbString(s string) []byte {
+	return append([]byte{0x40 | byte(len(s))}, s...)
+}
+
+// mmdbMap 编码 MMDB map（条目数小于 29），kv 为交替的已编码键值
+func mmdbMap(kv ...[]byte) []byte {
+	out := []byte{0xE0 | byte(len(kv)/2)}
+	for _, item := range kv {
+		out = append(out, item...)
+	}
+	return out
+}
+
+// buildMMDB 构造只有一个节点的 IPv4 数据库（24 位记录）
+// 首位为 0 的地址（0.0.0.0/1）指向 data 开头，其余地址未收录
+func buildMMDB(data []byte) []byte {
+	const nodeCount = 1
+	left := nodeCount + 16 // 数据段偏移 0
+	tree := []byte{byte(left >> 16), byte(left >> 8), byte(left), 0, 0, nodeCount}
+
+	out := append(tree, make([]byte, 16)...)
+	out = append(out, data...)
+	out = append(out, mmdbMetadataMarker...)
+	out = append(out, mmdbMap(
+		mmdbString("node_count"), []byte{0xC1, nodeCount},
+		mmdbString("record_size"), []byte{0xA1, 24},
+		mmdbString("ip_version"), []byte{0xA1, 4},
+	)...)
+	return out
+}
+
+// countryRecord 编码 {"country":{"iso_code":code}}
+func countryRecord(code string) []byte {
+	return mmdbMap(mmdbString("country"), mmdbMap(mmdbString("iso_code"), mmdbString(code)))
+}
+
+// newGeoReader 在临时目录写入 GEO 数据文件并创建读取器
+func newGeoReader(t *testing.T, files map[string][]byte) *GeoDataReader {
+	t.Helper()
+	dir := t.TempDir()
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return NewGeoDataReader(dir)
+}
+
+func TestMatchGeoSite(t *testing.T) {
+	valid := buildGeoSite(map[string][]geoSiteDomain{
+		"CN": {
+			{Type: 0, Value: "baidu"},
+			{Type: 1, Value: `^cdn\d+\.example\.net$`},
+			{Type: 2, Value: "qq.com"},
+			{Type: 3, Value: "full.example.org"},
+		},
+		"google": {{Type: 2, Value: "google.com"}},
+	})
+
+	tests := []struct {
+		name    string
+		data    []byte
+		code    string
+		domain  string
+		want    bool
+		wantErr bool
+	}{
+		{name: "关键字", data: valid, code: "cn", domain: "www.baidu.com", want: true},
+		{name: "正则", data: valid, code: "cn", domain: "cdn12.example.net", want: true},
+		{name: "后缀", data: valid, code: "cn", domain: "im.QQ.com.", want: true},
+		{name: "后缀本身", data: valid, code: "cn", domain: "qq.com", want: true},
+		{name: "完整域名", data: valid, code: "cn", domain: "full.example.org", want: true},
+		{name: "完整域名不匹配子域名", data: valid, code: "cn", domain: "a.full.example.org"},
+		{name: "忽略属性", data: valid, code: "google@ads", domain: "ads.google.com", want: true},
+		{name: "未命中", data: valid, code: "cn", domain: "google.com"},
+		{name: "分类不存在", data: valid, code: "jp", domain: "qq.com"},
+		{name: "文件截断", data: valid[:len(valid)-3], code: "cn", domain: "qq.com", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newGeoReader(t, map[string][]byte{"geosite.dat": tt.data})
+			got, err := r.MatchGeoSite(tt.code, tt.domain)
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("MatchGeoSite(%q, %q) = %v, %v; want %v, err %v", tt.code, tt.domain, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatchGeoIPDat(t *testing.T) {
+	valid := buildGeoIP(map[string][]string{
+		"cn": {"1.2.0.0/16", "2400:da00::/32"},
+	})
+
+	tests := []struct {
+		name    string
+		data    []byte
+		code    string
+		ip      string
+		want    bool
+		wantErr bool
+	}{
+		{name: "IPv4 命中", data: valid, code: "CN", ip: "1.2.3.4", want: true},
+		{name: "IPv6 命中", data: valid, code: "cn", ip: "2400:da00::1", want: true},
+		{name: "未命中", data: valid, code: "cn", ip: "8.8.8.8"},
+		{name: "私有地址不读取文件", data: nil, code: "private", ip: "192.168.1.1", want: true},
+		{name: "文件截断", data: valid[:len(valid)-2], code: "cn", ip: "1.2.3.4", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newGeoReader(t, map[string][]byte{"geoip.dat": tt.data})
+			got, err := r.MatchGeoIP(tt.code, net.ParseIP(tt.ip))
+			if (err != nil) != tt.wantErr || got != tt.want {
+				t.Errorf("MatchGeoIP(%q, %s) = %v, %v; want %v, err %v", tt.code, tt.ip, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLookupCountryMMDB(t *testing.T) {
+	valid := buildMMDB(countryRecord("CN"))
+
+	// 数据段指针指向自身
+	selfLoop := buildMMDB([]byte{0x20, 0x00})
+	// 两个指针互相引用
+	mutualLoop := buildMMDB([]byte{0x20, 0x02, 0x20, 0x00})
+	// 指针指向真实记录
+	viaPointer := buildMMDB(append([]byte{0x20, 0x02}, countryRecord("JP")...))
+
+	tests := []struct {
+		name    string
+		data    []byte
+		ip      string
+		want    string
+		wantErr string
+	}{
+		{name: "命中", data: valid, ip: "1.2.3.4", want: "CN"},
+		{name: "未命中", data: valid, ip: "200.1.1.1"},
+		{name: "IPv4 数据库不收录 IPv6", data: valid, ip: "2400:da00::1"},
+		{name: "通过指针命中", data: viaPointer, ip: "1.2.3.4", want: "JP"},
+		{name: "指针指向自身", data: selfLoop, ip: "1.2.3.4", wantErr: "too deep"},
+		{name: "指针互相引用", data: mutualLoop, ip: "1.2.3.4", wantErr: "too deep"},
+		{name: "元数据截断", data: valid[:len(valid)-4], ip: "1.2.3.4", wantErr: "out of range"},
+		{name: "缺少元数据", data: valid[:30], ip: "1.2.3.4", wantErr: "metadata not found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newGeoReader(t, map[string][]byte{"country.mmdb": tt.data})
+			codes, err := r.LookupCountry(net.ParseIP(tt.ip))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LookupCountry: %v", err)
+			}
+			got := strings.Join(codes, ",")
+			if got != tt.want {
+				t.Errorf("LookupCountry(%s) = %q, want %q", tt.ip, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatchGeoIPFallsBackToMMDB(t *testing.T) {
+	r := newGeoReader(t, map[string][]byte{"country.mmdb": buildMMDB(countryRecord("CN"))})
+	if ok, err := r.MatchGeoIP("cn", net.ParseIP("1.2.3.4")); err != nil || !ok {
+		t.Errorf("MatchGeoIP = %v, %v; want true", ok, err)
+	}
+	if ok, err := r.MatchGeoIP("cn", net.ParseIP("200.1.1.1")); err != nil || ok {
+		t.Errorf("MatchGeoIP = %v, %v; want false", ok, err)
+	}
+}
+
+func TestWalkProtoFields(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    []byte
+		fields  []int
+		wantErr bool
+	}{
+		{name: "varint 与长度分隔字段", data: append(protoVarint(1, 150), protoBytes(2, []byte("abc"))...), fields: []int{1, 2}},
+		{name: "跳过 64 位与 32 位字段", data: append(append([]byte{0x09}, make([]byte, 8)...), append([]byte{0x15, 0, 0, 0, 0}, protoVarint(3, 1)...)...), fields: []int{3}},
+		{name: "长度超出数据", data: []byte{0x12, 0x05, 'a'}, wantErr: true},
+		{name: "64 位字段截断", data: []byte{0x09, 0x00}, wantErr: true},
+		{name: "不支持的类型", data: []byte{0x0B}, wantErr: true},
+		{name: "键不完整", data: []byte{0x80}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var fields []int
+			err := walkProtoFields(tt.data, func(field int, _ []byte, _ uint64) error {
+				fields = append(fields, field)
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && len(fields) != len(tt.fields) {
+				t.Errorf("fields = %v, want %v", fields, tt.fields)
+			}
+		})
+	}
+}
@@ -1,16 +1,15 @@
 package proxy
 
 import (
-	"bytes"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
 	"os/exec"
+	"p-box/backend/clashapi"
 	"path/filepath"
 	"strconv"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -49,23 +48,55 @@ func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 	r.PUT("/template/groups", h.UpdateProxyGroups)
 	r.PUT("/template/rules", h.UpdateRules)
 	r.PUT("/template/providers", h.UpdateRuleProviders)
+	r.PUT("/template/sub-rules", h.UpdateSubRules)
 	r.POST("/template/reset", h.ResetTemplate)
+	r.GET("/template/lint", h.LintTemplate)
+
+	// 统一路由模板（同时驱动 Mihomo 与 Sing-Box）
+	r.GET("/routing", h.GetRoutingTemplate)
+	r.PUT("/routing", h.UpdateRoutingTemplate)
+	r.DELETE("/routing", h.DisableRoutingTemplate)
+	r.POST("/routing/migrate", h.MigrateRoutingTemplate)
+	r.GET("/routing/report", h.GetRoutingReport)
+
+	// 规则匹配模拟（离线）
+	r.POST("/rules/simulate", h.SimulateRule)
 
 	// Sing-Box 配置生成
 	r.POST("/singbox/generate", h.GenerateSingBoxConfig)
 	r.GET("/singbox/preview", h.GetSingBoxConfigPreview)
 	r.GET("/singbox/download", h.DownloadSingBoxConfig)
+	r.GET("/singbox/nodes/report", h.GetSingBoxNodeReport)
 
 	// Sing-Box 模板管理
 	r.GET("/singbox/template", h.GetSingBoxTemplate)
 	r.PUT("/singbox/template", h.UpdateSingBoxTemplate)
 	r.POST("/singbox/template/reset", h.ResetSingBoxTemplate)
+	r.GET("/singbox/template/lint", h.LintSingBoxTemplate)
+
+	// 用户覆写（生成配置后深度合并）
+	r.GET("/override/:core", h.GetOverride)
+	r.PUT("/override/:core", h.UpdateOverride)
 
 	// Mihomo API 代理 (避免 CORS 问题)
 	r.GET("/mihomo/proxies", h.ProxyMihomoGetProxies)
 	r.GET("/mihomo/proxies/:name", h.ProxyMihomoGetProxy)
 	r.PUT("/mihomo/proxies/:name", h.ProxyMihomoSelectProxy)
 	r.GET("/mihomo/proxies/:name/delay", h.ProxyMihomoTestDelay)
+
+	// 选择器选中记录（核心启动后自动恢复）
+	r.GET("/selections", h.GetSelections)
+	r.DELETE("/selections", h.ClearSelections)
+
+	// 连接管理
+	r.GET("/connections", h.ListConnections)
+	r.DELETE("/connections", h.CloseConnections)
+	r.DELETE("/connections/:id", h.CloseConnection)
+
+	// 核心控制器（密钥由 P-BOX 管理，前端通过转发访问）
+	r.GET("/controller", h.GetControllerInfo)
+	r.POST("/controller/secret", h.RotateControllerSecret)
+	r.Any("/core-api/*path", h.ForwardController)
 }
 
 func (h *Handler) GetStatus(c *gin.Context) {
@@ -361,16 +392,16 @@ func (h *Handler) UpdateProxyGroups(c *gin.Context) {
 	}
 
 	if err := h.service.UpdateProxyGroups(groups); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		h.respondTemplateError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
+		"data": gin.H{
+			"issues": h.service.LintConfigTemplate(),
+		},
 	})
 }
 
@@ -386,16 +417,41 @@ func (h *Handler) UpdateRules(c *gin.Context) {
 	}
 
 	if err := h.service.UpdateRules(rules); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
+		h.respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"issues": h.service.LintConfigTemplate(),
+		},
+	})
+}
+
+// UpdateSubRules 更新子规则
+func (h *Handler) UpdateSubRules(c *gin.Context) {
+	var subRules map[string][]RuleTemplate
+	if err := c.ShouldBindJSON(&subRules); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
 			"code":    1,
 			"message": err.Error(),
 		})
 		return
 	}
 
+	if err := h.service.UpdateSubRules(subRules); err != nil {
+		h.respondTemplateError(c, err)
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
+		"data": gin.H{
+			"issues": h.service.LintConfigTemplate(),
+		},
 	})
 }
 
@@ -411,16 +467,16 @@ func (h *Handler) UpdateRuleProviders(c *gin.Context) {
 	}
 
 	if err := h.service.UpdateRuleProviders(providers); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": err.Error(),
-		})
+		h.respondTemplateError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
+		"data": gin.H{
+			"issues": h.service.LintConfigTemplate(),
+		},
 	})
 }
 
@@ -433,110 +489,207 @@ func (h *Handler) ResetTemplate(c *gin.Context) {
 	})
 }
 
-// ========== Mihomo API 代理 (避免 CORS 问题) ==========
-
-// ProxyMihomoGetProxies 代理获取所有代理组
-func (h *Handler) ProxyMihomoGetProxies(c *gin.Context) {
-	apiAddr := h.service.GetConfig().ExternalController
-	if apiAddr == "" {
-		apiAddr = "127.0.0.1:9090"
-	}
+// LintTemplate 检查配置模板的引用完整性
+func (h *Handler) LintTemplate(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"issues": h.service.LintConfigTemplate(),
+		},
+	})
+}
 
-	resp, err := http.Get("http://" + apiAddr + "/proxies")
-	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
+// respondTemplateError 返回模板更新错误，检查失败时附带问题列表
+func (h *Handler) respondTemplateError(c *gin.Context, err error) {
+	var lintErr *TemplateLintError
+	if errors.As(err, &lintErr) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
 			"code":    1,
-			"message": "Mihomo API 不可用: " + err.Error(),
+			"message": err.Error(),
+			"data": gin.H{
+				"issues": lintErr.Issues,
+			},
 		})
 		return
 	}
-	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
-	c.Data(resp.StatusCode, "application/json", body)
+	c.JSON(http.StatusInternalServerError, gin.H{
+		"code":    1,
+		"message": "保存失败: " + err.Error(),
+	})
 }
 
-// ProxyMihomoGetProxy 代理获取单个代理组
-func (h *Handler) ProxyMihomoGetProxy(c *gin.Context) {
-	name := c.Param("name")
-	apiAddr := h.service.GetConfig().ExternalController
-	if apiAddr == "" {
-		apiAddr = "127.0.0.1:9090"
+// SimulateRule 离线模拟域名/IP 命中的规则与出口
+func (h *Handler) SimulateRule(c *gin.Context) {
+	var req RuleMatchRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
 	}
 
-	resp, err := http.Get("http://" + apiAddr + "/proxies/" + name)
+	result, err := h.service.SimulateRuleMatch(req)
 	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
+		c.JSON(http.StatusBadRequest, gin.H{
 			"code":    1,
-			"message": "Mihomo API 不可用: " + err.Error(),
+			"message": err.Error(),
 		})
 		return
 	}
-	defer resp.Body.Close()
 
-	body, _ := io.ReadAll(resp.Body)
-	c.Data(resp.StatusCode, "application/json", body)
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    result,
+	})
+}
+
+// ========== Mihomo API 代理 (避免 CORS 问题) ==========
+
+// ProxyMihomoGetProxies 代理获取所有代理组
+func (h *Handler) ProxyMihomoGetProxies(c *gin.Context) {
+	proxies, err := h.service.Controller().Proxies()
+	if err != nil {
+		respondControllerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"proxies": proxies})
+}
+
+// ProxyMihomoGetProxy 代理获取单个代理组
+func (h *Handler) ProxyMihomoGetProxy(c *gin.Context) {
+	proxy, err := h.service.Controller().Proxy(c.Param("name"))
+	if err != nil {
+		respondControllerError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, proxy)
 }
 
 // ProxyMihomoSelectProxy 代理切换节点
 func (h *Handler) ProxyMihomoSelectProxy(c *gin.Context) {
-	name := c.Param("name")
-	apiAddr := h.service.GetConfig().ExternalController
-	if apiAddr == "" {
-		apiAddr = "127.0.0.1:9090"
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Name == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "缺少 name 参数"})
+		return
 	}
 
-	body, _ := io.ReadAll(c.Request.Body)
-	req, _ := http.NewRequest("PUT", "http://"+apiAddr+"/proxies/"+name, bytes.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
+	// closeConnections=true 时切换后关闭经过原选中项的连接
+	group := c.Param("name")
+	previous := ""
+	if c.Query("closeConnections") == "true" {
+		if info, err := h.service.Controller().Proxy(group); err == nil {
+			previous = info.Now
+		}
+	}
 
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Do(req)
-	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
+	// 切换成功后记录选中项
+	if err := h.service.SelectProxy(group, req.Name); err != nil {
+		respondControllerError(c, err)
+		return
+	}
+	if previous != "" && previous != req.Name {
+		if _, err := h.service.CloseConnections(ConnectionFilter{Chain: previous}); err != nil {
+			fmt.Printf("⚠️ 关闭 %s 的连接失败: %v\n", previous, err)
+		}
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetSelections 获取选择器选中记录
+func (h *Handler) GetSelections(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.service.GetSelections(),
+	})
+}
+
+// ClearSelections 清除选中记录，指定 group 时仅清除该分组
+func (h *Handler) ClearSelections(c *gin.Context) {
+	if err := h.service.ClearSelections(c.Query("group")); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    1,
-			"message": "Mihomo API 不可用: " + err.Error(),
+			"message": err.Error(),
 		})
 		return
 	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
-	c.Data(resp.StatusCode, "application/json", respBody)
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
 }
 
 // ProxyMihomoTestDelay 代理测试节点延迟
 func (h *Handler) ProxyMihomoTestDelay(c *gin.Context) {
-	name := c.Param("name")
-	url := c.Query("url")
-	timeout := c.Query("timeout")
-
-	if url == "" {
-		url = "http://www.gstatic.com/generate_204"
-	}
-	if timeout == "" {
-		timeout = "5000"
+	testURL := c.DefaultQuery("url", "http://www.gstatic.com/generate_204")
+	timeout, err := strconv.Atoi(c.DefaultQuery("timeout", "5000"))
+	if err != nil || timeout <= 0 {
+		timeout = 5000
 	}
 
-	apiAddr := h.service.GetConfig().ExternalController
-	if apiAddr == "" {
-		apiAddr = "127.0.0.1:9090"
+	delay, err := h.service.Controller().ProxyDelay(c.Param("name"), testURL, timeout)
+	if err != nil {
+		respondControllerError(c, err)
+		return
 	}
+	c.JSON(http.StatusOK, gin.H{"delay": delay})
+}
 
-	targetURL := fmt.Sprintf("http://%s/proxies/%s/delay?url=%s&timeout=%s", apiAddr, name, url, timeout)
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Get(targetURL)
-	if err != nil {
-		c.JSON(http.StatusServiceUnavailable, gin.H{
+// GetControllerInfo 获取控制器访问方式（不返回密钥）
+func (h *Handler) GetControllerInfo(c *gin.Context) {
+	config := h.service.GetConfig()
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"address":        h.service.Controller().Address(),
+			"controllerUnix": config.ControllerUnix,
+			"secretSet":      config.Secret != "",
+		},
+	})
+}
+
+// RotateControllerSecret 重新生成控制器密钥
+func (h *Handler) RotateControllerSecret(c *gin.Context) {
+	if err := h.service.RotateControllerSecret(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
 			"code":    1,
-			"message": "Mihomo API 不可用: " + err.Error(),
+			"message": err.Error(),
 		})
 		return
 	}
-	defer resp.Body.Close()
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// ForwardController 转发请求到核心控制器并附加密钥
+func (h *Handler) ForwardController(c *gin.Context) {
+	rawPath := c.Request.URL.EscapedPath()
+	if i := strings.Index(rawPath, "/core-api/"); i >= 0 {
+		rawPath = rawPath[i+len("/core-api"):]
+	}
+	h.service.Controller().Forward(c.Writer, c.Request, rawPath)
+}
 
-	body, _ := io.ReadAll(resp.Body)
-	c.Data(resp.StatusCode, "application/json", body)
+// respondControllerError 返回控制器错误，保留控制器的状态码
+func respondControllerError(c *gin.Context, err error) {
+	var apiErr *clashapi.APIError
+	if errors.As(err, &apiErr) {
+		c.JSON(apiErr.StatusCode, gin.H{"message": apiErr.Message})
+		return
+	}
+	c.JSON(http.StatusServiceUnavailable, gin.H{
+		"code":    1,
+		"message": "核心 API 不可用: " + err.Error(),
+	})
 }
 
 // ========== Sing-Box 1.12+ 配置生成 ==========
@@ -592,6 +745,9 @@ func (h *Handler) GenerateSingBoxConfig(c *gin.Context) {
 		Sniff:                    req.Sniff,
 		SniffOverrideDestination: req.SniffOverrideDestination,
 	}
+	if opts.ClashAPISecret == "" {
+		opts.ClashAPISecret = h.service.GetConfig().Secret
+	}
 
 	// 获取所有节点
 	nodes, err := h.service.GetAllNodes()
@@ -644,6 +800,7 @@ func (h *Handler) GenerateSingBoxConfig(c *gin.Context) {
 					"nodeCount":       len(nodes),
 					"mode":            opts.Mode,
 					"validationError": errorMsg,
+					"skipped":         SingBoxNodeReport(nodes),
 				},
 			})
 			return
@@ -657,10 +814,28 @@ func (h *Handler) GenerateSingBoxConfig(c *gin.Context) {
 			"configPath": filePath,
 			"nodeCount":  len(nodes),
 			"mode":       opts.Mode,
+			"skipped":    SingBoxNodeReport(nodes),
 		},
 	})
 }
 
+// GetSingBoxNodeReport 获取无法用于 Sing-Box 而被跳过的节点及原因
+func (h *Handler) GetSingBoxNodeReport(c *gin.Context) {
+	skipped, err := h.service.SingBoxNodeReport()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    skipped,
+	})
+}
+
 // GetSingBoxConfigPreview 获取 Sing-Box 配置预览
 func (h *Handler) GetSingBoxConfigPreview(c *gin.Context) {
 	content, err := h.service.GetSingBoxConfigContent()
@@ -722,16 +897,27 @@ func (h *Handler) UpdateSingBoxTemplate(c *gin.Context) {
 	}
 
 	if err := h.service.UpdateSingBoxTemplate(&template); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"code":    1,
-			"message": "保存失败: " + err.Error(),
-		})
+		h.respondTemplateError(c, err)
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"code":    0,
 		"message": "success",
+		"data": gin.H{
+			"issues": h.service.LintSingBoxTemplate(),
+		},
+	})
+}
+
+// LintSingBoxTemplate 检查 Sing-Box 模板的引用完整性
+func (h *Handler) LintSingBoxTemplate(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"issues": h.service.LintSingBoxTemplate(),
+		},
 	})
 }
 
@@ -744,3 +930,147 @@ func (h *Handler) ResetSingBoxTemplate(c *gin.Context) {
 		"data":    h.service.GetSingBoxTemplate(),
 	})
 }
+
+// GetRoutingTemplate 获取统一路由模板（未启用时返回默认模板供编辑）
+func (h *Handler) GetRoutingTemplate(c *gin.Context) {
+	routing := h.service.GetRoutingTemplate()
+	enabled := routing != nil
+	if !enabled {
+		routing = GetDefaultRoutingTemplate()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"enabled":  enabled,
+			"template": routing,
+			"issues":   h.service.RoutingReport(),
+		},
+	})
+}
+
+// UpdateRoutingTemplate 保存并启用统一路由模板
+func (h *Handler) UpdateRoutingTemplate(c *gin.Context) {
+	var routing RoutingTemplate
+	if err := c.ShouldBindJSON(&routing); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	issues, err := h.service.UpdateRoutingTemplate(&routing)
+	if err != nil {
+		h.respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"issues": issues,
+		},
+	})
+}
+
+// DisableRoutingTemplate 停用统一路由模板
+func (h *Handler) DisableRoutingTemplate(c *gin.Context) {
+	if err := h.service.DisableRoutingTemplate(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// MigrateRoutingTemplate 由现有 Mihomo 与 Sing-Box 模板生成统一路由模板
+func (h *Handler) MigrateRoutingTemplate(c *gin.Context) {
+	var req struct {
+		DryRun bool `json:"dryRun"`
+	}
+	c.ShouldBindJSON(&req)
+
+	routing, issues, err := h.service.MigrateRoutingTemplate(req.DryRun)
+	if err != nil {
+		h.respondTemplateError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"template": routing,
+			"issues":   issues,
+		},
+	})
+}
+
+// GetRoutingReport 获取统一路由模板无法翻译到各核心的功能
+func (h *Handler) GetRoutingReport(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"issues": h.service.RoutingReport(),
+		},
+	})
+}
+
+// GetOverride 获取用户覆写内容
+func (h *Handler) GetOverride(c *gin.Context) {
+	content, err := h.service.GetOverride(c.Param("core"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"content": content,
+		},
+	})
+}
+
+// UpdateOverride 更新用户覆写内容并重新生成配置
+func (h *Handler) UpdateOverride(c *gin.Context) {
+	var req struct {
+		Content string `json:"content"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.service.UpdateOverride(c.Param("core"), req.Content); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "覆写内容无效: " + err.Error(),
+		})
+		return
+	}
+
+	// 重新生成配置，使预览立即反映覆写结果（无节点时忽略）
+	h.service.RegenerateConfig()
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
@@ -0,0 +1,159 @@
+package proxy
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportHandler 配置导入处理器
+type ImportHandler struct {
+	importer *ConfigImporter
+}
+
+// NewImportHandler 创建配置导入处理器
+func NewImportHandler(importer *ConfigImporter) *ImportHandler {
+	return &ImportHandler{importer: importer}
+}
+
+// RegisterRoutes 注册路由
+func (h *ImportHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.POST("/import/clash", h.ImportClash)
+	r.POST("/import/singbox", h.ImportSingBox)
+	r.POST("/import/subconverter", h.ImportSubconverter)
+}
+
+// importRequest 导入请求
+type importRequest struct {
+	ImportOptions
+	Content string `json:"content"` // 配置文件内容
+	URL     string `json:"url"`     // 配置文件地址（未提供内容时下载）
+}
+
+// ImportClash 导入 Clash/Mihomo 配置文件
+func (h *ImportHandler) ImportClash(c *gin.Context) {
+	h.handleImport(c, ParseClashConfig)
+}
+
+// ImportSingBox 导入 sing-box 配置文件
+func (h *ImportHandler) ImportSingBox(c *gin.Context) {
+	h.handleImport(c, ParseSingBoxConfig)
+}
+
+// ImportSubconverter 导入 subconverter/ACL4SSR 外部配置（.ini）
+func (h *ImportHandler) ImportSubconverter(c *gin.Context) {
+	h.handleImport(c, ParseSubconverterConfig)
+}
+
+// handleImport 解析并写入导入内容
+func (h *ImportHandler) handleImport(c *gin.Context, parse func([]byte) (*ImportResult, error)) {
+	var req importRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	content := []byte(req.Content)
+	if len(content) == 0 {
+		if req.URL == "" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    1,
+				"message": "请提供配置内容或地址",
+			})
+			return
+		}
+		data, err := downloadImportContent(req.URL)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    1,
+				"message": "下载配置失败: " + err.Error(),
+			})
+			return
+		}
+		content = data
+	}
+
+	result, err := parse(content)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	summary, err := h.importer.Apply(result, req.ImportOptions)
+	if err != nil {
+		var lintErr *TemplateLintError
+		if errors.As(err, &lintErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"code":    1,
+				"message": err.Error(),
+				"data": gin.H{
+					"issues":   lintErr.Issues,
+					"unmapped": result.Issues,
+				},
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    1,
+			"message": "导入失败: " + err.Error(),
+			"data":    summary,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    summary,
+	})
+}
+
+// downloadImportContent 下载待导入的配置文件
+// 仅允许 http/https，连接时按实际解析出的 IP 拒绝本机与链路本地地址（包括重定向后的地址）
+func downloadImportContent(rawURL string) ([]byte, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return nil, fmt.Errorf("仅支持 http/https 地址")
+	}
+
+	dialer := &net.Dialer{Timeout: 10 * time.Second, Control: rejectInternalAddress}
+	client := &http.Client{
+		Timeout:   30 * time.Second,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+	}
+	resp, err := client.Get(u.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP %d", resp.StatusCode)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+}
+
+// rejectInternalAddress 拒绝连接本机、链路本地与未指定地址
+func rejectInternalAddress(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("不允许访问本机或链路本地地址: %s", host)
+	}
+	return nil
+}
@@ -0,0 +1,297 @@
+package proxy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ============================================================================
+// 额外入站监听器
+// 设置中的监听器生成 Mihomo listeners 与 sing-box inbounds，目标分组生成 IN-NAME/IN-USER 规则
+// ============================================================================
+
+// listenerTypes 支持的监听器类型
+var listenerTypes = map[string]bool{
+	"mixed": true, "socks": true, "http": true, "shadowsocks": true, "vmess": true,
+}
+
+// MihomoListener Mihomo listeners 配置项
+type MihomoListener struct {
+	Name     string               `yaml:"name"`
+	Type     string               `yaml:"type"`
+	Port     int                  `yaml:"port"`
+	Listen   string               `yaml:"listen"`
+	UDP      bool                 `yaml:"udp,omitempty"`
+	Cipher   string               `yaml:"cipher,omitempty"`
+	Password string               `yaml:"password,omitempty"`
+	Users    []MihomoListenerUser `yaml:"users,omitempty"`
+}
+
+// MihomoListenerUser Mihomo 监听器用户，VMess 使用 uuid
+type MihomoListenerUser struct {
+	Username string `yaml:"username"`
+	Password string `yaml:"password,omitempty"`
+	UUID     string `yaml:"uuid,omitempty"`
+	AlterID  int    `yaml:"alterId,omitempty"`
+}
+
+// ValidateListeners 校验监听器设置，ports 为已启用的内置端口
+func ValidateListeners(listeners []ListenerSettings, ports []int) error {
+	used := make(map[int]string)
+	for _, port := range ports {
+		if port > 0 {
+			used[port] = "内置入站"
+		}
+	}
+	names := make(map[string]bool)
+	for i, l := range listeners {
+		if l.Name == "" {
+			return fmt.Errorf("第 %d 个监听器缺少名称", i+1)
+		}
+		if strings.ContainsAny(l.Name, ",()") {
+			return fmt.Errorf("监听器名称 %s 不能包含逗号或括号", l.Name)
+		}
+		if names[l.Name] {
+			return fmt.Errorf("监听器名称 %s 重复", l.Name)
+		}
+		names[l.Name] = true
+
+		if !listenerTypes[l.Type] {
+			return fmt.Errorf("监听器 %s 的类型 %s 不受支持", l.Name, l.Type)
+		}
+		if l.Port <= 0 || l.Port > 65535 {
+			return fmt.Errorf("监听器 %s 的端口 %d 无效", l.Name, l.Port)
+		}
+		if l.Enabled {
+			if owner, exists := used[l.Port]; exists {
+				return fmt.Errorf("监听器 %s 的端口 %d 与%s冲突", l.Name, l.Port, owner)
+			}
+			used[l.Port] = "监听器 " + l.Name
+		}
+
+		switch l.Type {
+		case "shadowsocks":
+			if l.Cipher == "" || l.Password == "" {
+				return fmt.Errorf("Shadowsocks 监听器 %s 缺少加密方式或密码", l.Name)
+			}
+			if len(l.Users) > 0 {
+				return fmt.Errorf("Shadowsocks 监听器 %s 不支持多用户", l.Name)
+			}
+		case "vmess":
+			if len(l.Users) == 0 {
+				return fmt.Errorf("VMess 监听器 %s 至少需要一个用户", l.Name)
+			}
+		}
+
+		usernames := make(map[string]bool)
+		for _, u := range l.Users {
+			if u.Username == "" || u.Password == "" {
+				return fmt.Errorf("监听器 %s 的用户缺少用户名或密码", l.Name)
+			}
+			if strings.ContainsAny(u.Username, ",()") {
+				return fmt.Errorf("监听器 %s 的用户名 %s 不能包含逗号或括号", l.Name, u.Username)
+			}
+			if usernames[u.Username] {
+				return fmt.Errorf("监听器 %s 的用户 %s 重复", l.Name, u.Username)
+			}
+			usernames[u.Username] = true
+		}
+	}
+	return nil
+}
+
+// enabledPorts 已启用的内置入站端口
+func (s *ProxySettings) enabledPorts() []int {
+	var ports []int
+	for _, p := range []struct {
+		enabled bool
+		port    int
+	}{
+		{s.MixedPortEnabled, s.MixedPort},
+		{s.SocksPortEnabled, s.SocksPort},
+		{s.HTTPPortEnabled, s.HTTPPort},
+		{s.RedirPortEnabled, s.RedirPort},
+		{s.TProxyPortEnabled, s.TProxyPort},
+	} {
+		if p.enabled {
+			ports = append(ports, p.port)
+		}
+	}
+	return ports
+}
+
+// listenAddress 监听地址，为空时监听所有地址
+func listenAddress(l ListenerSettings) string {
+	if l.Listen == "" {
+		return "0.0.0.0"
+	}
+	return l.Listen
+}
+
+// mihomoListeners 生成 Mihomo listeners
+func mihomoListeners(listeners []ListenerSettings) []MihomoListener {
+	var result []MihomoListener
+	for _, l := range listeners {
+		if !l.Enabled {
+			continue
+		}
+		item := MihomoListener{
+			Name:   l.Name,
+			Type:   l.Type,
+			Port:   l.Port,
+			Listen: listenAddress(l),
+			UDP:    l.UDP && l.Type != "http",
+		}
+		if l.Type == "shadowsocks" {
+			item.Cipher = l.Cipher
+			item.Password = l.Password
+		}
+		for _, u := range l.Users {
+			if l.Type == "vmess" {
+				item.Users = append(item.Users, MihomoListenerUser{Username: u.Username, UUID: u.Password})
+			} else {
+				item.Users = append(item.Users, MihomoListenerUser{Username: u.Username, Password: u.Password})
+			}
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+// mihomoListenerRules 生成监听器的分流规则，用户规则在监听器规则之前
+// 目标不是已生成的代理组、节点或 DIRECT/REJECT 时跳过该规则
+func mihomoListenerRules(listeners []ListenerSettings, config *MihomoConfig) []string {
+	targets := map[string]bool{"DIRECT": true, "REJECT": true}
+	for _, group := range config.ProxyGroups {
+		targets[group.Name] = true
+	}
+	for _, proxy := range config.Proxies {
+		if name, ok := proxy["name"].(string); ok {
+			targets[name] = true
+		}
+	}
+
+	var rules []string
+	for _, l := range listeners {
+		if !l.Enabled {
+			continue
+		}
+		for _, u := range l.Users {
+			if u.Target == "" || u.Target == l.Target {
+				continue
+			}
+			if !targets[u.Target] {
+				fmt.Printf("⚠️ 监听器 %s 用户 %s 的目标 %s 不存在，已跳过\n", l.Name, u.Username, u.Target)
+				continue
+			}
+			rules = append(rules, fmt.Sprintf("AND,((IN-NAME,%s),(IN-USER,%s)),%s", l.Name, u.Username, u.Target))
+		}
+		if l.Target == "" {
+			continue
+		}
+		if !targets[l.Target] {
+			fmt.Printf("⚠️ 监听器 %s 的目标 %s 不存在，已跳过\n", l.Name, l.Target)
+			continue
+		}
+		rules = append(rules, fmt.Sprintf("IN-NAME,%s,%s", l.Name, l.Target))
+	}
+	return rules
+}
+
+// singBoxListenerInbound 生成 sing-box 入站
+func singBoxListenerInbound(l ListenerSettings) SBInbound {
+	inbound := SBInbound{
+		Tag:        l.Name,
+		Type:       l.Type,
+		Listen:     listenAddress(l),
+		ListenPort: l.Port,
+	}
+	switch l.Type {
+	case "shadowsocks":
+		inbound.Method = l.Cipher
+		inbound.Password = l.Password
+		if !l.UDP {
+			inbound.Network = "tcp"
+		}
+	case "vmess":
+		for _, u := range l.Users {
+			inbound.Users = append(inbound.Users, SBInboundUser{Name: u.Username, UUID: u.Password})
+		}
+	default:
+		for _, u := range l.Users {
+			inbound.Users = append(inbound.Users, SBInboundUser{Username: u.Username, Password: u.Password})
+		}
+	}
+	return inbound
+}
+
+// singBoxListenerTarget 将目标分组转换为 sing-box 出站标签
+func singBoxListenerTarget(target string) string {
+	switch target {
+	case "DIRECT":
+		return "direct"
+	case "REJECT":
+		return "block"
+	}
+	return target
+}
+
+// applySingBoxListeners 添加额外入站并加入嗅探，返回监听器的分流规则
+func applySingBoxListeners(config *SingBoxConfig, listeners []ListenerSettings) []SBRouteRule {
+	outbounds := make(map[string]bool, len(config.Outbounds))
+	for _, ob := range config.Outbounds {
+		outbounds[ob.Tag] = true
+	}
+	for _, ep := range config.Endpoints {
+		outbounds[ep.Tag] = true
+	}
+	inbounds := make(map[string]bool, len(config.Inbounds))
+	for _, in := range config.Inbounds {
+		inbounds[in.Tag] = true
+	}
+
+	var tags []string
+	var rules []SBRouteRule
+	for _, l := range listeners {
+		if !l.Enabled {
+			continue
+		}
+		if inbounds[l.Name] {
+			fmt.Printf("⚠️ 监听器 %s 与内置入站同名，已跳过\n", l.Name)
+			continue
+		}
+		config.Inbounds = append(config.Inbounds, singBoxListenerInbound(l))
+		tags = append(tags, l.Name)
+
+		for _, u := range l.Users {
+			if u.Target == "" || u.Target == l.Target {
+				continue
+			}
+			target := singBoxListenerTarget(u.Target)
+			if !outbounds[target] {
+				fmt.Printf("⚠️ 监听器 %s 用户 %s 的目标 %s 不存在，已跳过\n", l.Name, u.Username, u.Target)
+				continue
+			}
+			rules = append(rules, SBRouteRule{Inbound: []string{l.Name}, AuthUser: []string{u.Username}, Outbound: target})
+		}
+		if l.Target == "" {
+			continue
+		}
+		target := singBoxListenerTarget(l.Target)
+		if !outbounds[target] {
+			fmt.Printf("⚠️ 监听器 %s 的目标 %s 不存在，已跳过\n", l.Name, l.Target)
+			continue
+		}
+		rules = append(rules, SBRouteRule{Inbound: []string{l.Name}, Outbound: target})
+	}
+
+	// 额外入站同样需要嗅探
+	if config.Route != nil && len(tags) > 0 {
+		for i, rule := range config.Route.Rules {
+			if rule.Action == "sniff" && len(rule.Inbound) > 0 {
+				config.Route.Rules[i].Inbound = append(append([]string{}, rule.Inbound...), tags...)
+			}
+		}
+	}
+	return rules
+}
@@ -0,0 +1,202 @@
+package proxy
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProfileHandler 配置方案处理器
+type ProfileHandler struct {
+	manager *ProfileManager
+}
+
+// NewProfileHandler 创建配置方案处理器
+func NewProfileHandler(manager *ProfileManager) *ProfileHandler {
+	return &ProfileHandler{manager: manager}
+}
+
+// RegisterRoutes 注册路由
+func (h *ProfileHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/profiles", h.ListProfiles)
+	r.POST("/profiles", h.CreateProfile)
+	r.GET("/profiles/:id", h.GetProfile)
+	r.PUT("/profiles/:id", h.UpdateProfile)
+	r.DELETE("/profiles/:id", h.DeleteProfile)
+	r.POST("/profiles/:id/clone", h.CloneProfile)
+	r.POST("/profiles/:id/save-current", h.SaveCurrent)
+	r.POST("/profiles/:id/activate", h.ActivateProfile)
+}
+
+// ListProfiles 获取配置方案列表
+func (h *ProfileHandler) ListProfiles(c *gin.Context) {
+	profiles, active := h.manager.List()
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"active":   active,
+			"profiles": profiles,
+		},
+	})
+}
+
+// GetProfile 获取配置方案详情
+func (h *ProfileHandler) GetProfile(c *gin.Context) {
+	profile, err := h.manager.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    profile,
+	})
+}
+
+// CreateProfile 创建配置方案（未提供的设置与模板取当前生效值）
+func (h *ProfileHandler) CreateProfile(c *gin.Context) {
+	var profile Profile
+	if err := c.ShouldBindJSON(&profile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	created, err := h.manager.Create(&profile)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    created,
+	})
+}
+
+// UpdateProfile 更新配置方案
+func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
+	var profile Profile
+	if err := c.ShouldBindJSON(&profile); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	updated, err := h.manager.Update(c.Param("id"), &profile)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    updated,
+	})
+}
+
+// DeleteProfile 删除配置方案
+func (h *ProfileHandler) DeleteProfile(c *gin.Context) {
+	if err := h.manager.Delete(c.Param("id")); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// CloneProfile 复制配置方案
+func (h *ProfileHandler) CloneProfile(c *gin.Context) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	c.ShouldBindJSON(&req)
+
+	cloned, err := h.manager.Clone(c.Param("id"), req.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    cloned,
+	})
+}
+
+// SaveCurrent 将当前生效的设置与模板保存到方案
+func (h *ProfileHandler) SaveCurrent(c *gin.Context) {
+	profile, err := h.manager.SaveCurrent(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    profile,
+	})
+}
+
+// ActivateProfile 激活配置方案（失败时自动回滚）
+func (h *ProfileHandler) ActivateProfile(c *gin.Context) {
+	if err := h.manager.Activate(c.Param("id")); err != nil {
+		var lintErr *TemplateLintError
+		if errors.As(err, &lintErr) {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{
+				"code":    1,
+				"message": err.Error(),
+				"data": gin.H{
+					"issues": lintErr.Issues,
+				},
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    1,
+			"message": "切换方案失败: " + err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data": gin.H{
+			"active": c.Param("id"),
+		},
+	})
+}
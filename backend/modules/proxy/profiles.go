@@ -0,0 +1,466 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NodeFilter 节点过滤条件（配置方案使用）
+type NodeFilter struct {
+	Subscriptions []string `json:"subscriptions,omitempty"` // 选中的订阅 ID，为空表示全部订阅
+	ExcludeManual bool     `json:"excludeManual,omitempty"` // 排除手动节点
+	Include       string   `json:"include,omitempty"`       // 节点名包含正则
+	Exclude       string   `json:"exclude,omitempty"`       // 节点名排除正则
+}
+
+// Apply 按过滤条件筛选节点（nil 过滤条件直接返回原列表）
+func (f *NodeFilter) Apply(nodes []ProxyNode) []ProxyNode {
+	if f == nil {
+		return nodes
+	}
+
+	subs := make(map[string]bool, len(f.Subscriptions))
+	for _, id := range f.Subscriptions {
+		subs[id] = true
+	}
+	include, _ := compileOptional(f.Include)
+	exclude, _ := compileOptional(f.Exclude)
+
+	result := make([]ProxyNode, 0, len(nodes))
+	for _, n := range nodes {
+		if n.IsManual {
+			if f.ExcludeManual {
+				continue
+			}
+		} else if len(subs) > 0 && !subs[n.SubscriptionID] {
+			continue
+		}
+		if include != nil && !include.MatchString(n.Name) {
+			continue
+		}
+		if exclude != nil && exclude.MatchString(n.Name) {
+			continue
+		}
+		result = append(result, n)
+	}
+	return result
+}
+
+// Validate 校验过滤正则
+func (f *NodeFilter) Validate() error {
+	if f == nil {
+		return nil
+	}
+	if _, err := compileOptional(f.Include); err != nil {
+		return fmt.Errorf("包含正则无效: %w", err)
+	}
+	if _, err := compileOptional(f.Exclude); err != nil {
+		return fmt.Errorf("排除正则无效: %w", err)
+	}
+	return nil
+}
+
+// compileOptional 编译可选正则（空字符串返回 nil）
+func compileOptional(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+	return regexp.Compile(pattern)
+}
+
+// Profile 配置方案：打包代理设置、模板、订阅选择和节点过滤
+type Profile struct {
+	ID              string           `json:"id"`
+	Name            string           `json:"name"`
+	Description     string           `json:"description"`
+	Icon            string           `json:"icon"`
+	Settings        *ProxySettings   `json:"settings"`
+	Template        *ConfigTemplate  `json:"template"`
+	SingBoxTemplate *SingBoxTemplate `json:"singboxTemplate"`
+	NodeFilter      *NodeFilter      `json:"nodeFilter,omitempty"`
+	CreatedAt       time.Time        `json:"createdAt"`
+	UpdatedAt       time.Time        `json:"updatedAt"`
+}
+
+// profileStore 配置方案持久化结构
+type profileStore struct {
+	Active   string     `json:"active"`
+	Profiles []*Profile `json:"profiles"`
+}
+
+// ProfileManager 配置方案管理
+type ProfileManager struct {
+	dataDir  string
+	service  *Service
+	settings *SettingsHandler
+	store    profileStore
+	mu       sync.Mutex
+}
+
+// NewProfileManager 创建配置方案管理器，首次运行时生成网关/桌面两个种子方案
+func NewProfileManager(dataDir string, service *Service, settings *SettingsHandler) *ProfileManager {
+	m := &ProfileManager{
+		dataDir:  dataDir,
+		service:  service,
+		settings: settings,
+	}
+	if err := m.load(); err != nil {
+		if os.IsNotExist(err) {
+			m.seed()
+			m.save()
+		} else {
+			// 文件损坏时保留原文件，不用种子方案覆盖用户的方案
+			fmt.Printf("⚠️ 读取配置方案失败，已保留原文件 %s: %v\n", m.storePath(), err)
+			m.backupCorrupt()
+			m.store = profileStore{}
+		}
+	}
+
+	// 恢复当前方案的节点过滤
+	if p := m.find(m.store.Active); p != nil {
+		service.SetNodeFilter(p.NodeFilter)
+	}
+	return m
+}
+
+// storePath 获取方案文件路径
+func (m *ProfileManager) storePath() string {
+	return filepath.Join(m.dataDir, "profiles.json")
+}
+
+// load 加载方案
+func (m *ProfileManager) load() error {
+	data, err := os.ReadFile(m.storePath())
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, &m.store)
+}
+
+// backupCorrupt 备份无法解析的方案文件，避免之后的保存覆盖它
+func (m *ProfileManager) backupCorrupt() {
+	data, err := os.ReadFile(m.storePath())
+	if err != nil {
+		return
+	}
+	backup := m.storePath() + ".corrupt-" + time.Now().Format("20060102-150405")
+	if err := os.WriteFile(backup, data, 0644); err == nil {
+		fmt.Printf("📦 已备份损坏的配置方案文件: %s\n", backup)
+	}
+}
+
+// save 保存方案
+func (m *ProfileManager) save() error {
+	data, err := json.MarshalIndent(m.store, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.storePath(), data, 0644)
+}
+
+// seed 生成种子方案（原内置预设，现可编辑），模板取自当前生效的模板以免覆盖用户的自定义模板
+func (m *ProfileManager) seed() {
+	now := time.Now()
+	current := m.Capture()
+	seeds := []*Profile{
+		{
+			ID:          "gateway",
+			Name:        "Linux 网关",
+			Description: "软路由/旁路由模式，TUN+DNS 最佳性能",
+			Icon:        "server",
+			Settings:    getGatewayPreset(),
+		},
+		{
+			ID:          "desktop",
+			Name:        "桌面客户端",
+			Description: "Windows/macOS 系统代理模式",
+			Icon:        "monitor",
+			Settings:    getDesktopPreset(),
+		},
+	}
+	for _, p := range seeds {
+		cloneJSON(current.Template, &p.Template)
+		cloneJSON(current.SingBoxTemplate, &p.SingBoxTemplate)
+		p.CreatedAt = now
+		p.UpdatedAt = now
+	}
+	m.store = profileStore{Profiles: seeds}
+}
+
+// find 按 ID 查找方案（调用者需持有锁或处于初始化阶段）
+func (m *ProfileManager) find(id string) *Profile {
+	for _, p := range m.store.Profiles {
+		if p.ID == id {
+			return p
+		}
+	}
+	return nil
+}
+
+// List 获取方案列表与当前方案 ID
+func (m *ProfileManager) List() ([]*Profile, string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	profiles := make([]*Profile, 0, len(m.store.Profiles))
+	for _, p := range m.store.Profiles {
+		profiles = append(profiles, cloneProfile(p))
+	}
+	return profiles, m.store.Active
+}
+
+// Get 获取单个方案
+func (m *ProfileManager) Get(id string) (*Profile, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := m.find(id)
+	if p == nil {
+		return nil, fmt.Errorf("方案不存在")
+	}
+	return cloneProfile(p), nil
+}
+
+// Create 创建方案，未提供的部分使用当前生效的设置与模板
+func (m *ProfileManager) Create(p *Profile) (*Profile, error) {
+	if p.Name == "" {
+		return nil, fmt.Errorf("方案名称不能为空")
+	}
+	current := m.Capture()
+	if p.Settings == nil {
+		p.Settings = current.Settings
+	}
+	if p.Template == nil {
+		p.Template = current.Template
+	}
+	if p.SingBoxTemplate == nil {
+		p.SingBoxTemplate = current.SingBoxTemplate
+	}
+	if err := p.NodeFilter.Validate(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p.ID = uuid.New().String()
+	p.CreatedAt = time.Now()
+	p.UpdatedAt = p.CreatedAt
+	m.store.Profiles = append(m.store.Profiles, p)
+	return cloneProfile(p), m.save()
+}
+
+// Clone 复制方案
+func (m *ProfileManager) Clone(id, name string) (*Profile, error) {
+	m.mu.Lock()
+	src := m.find(id)
+	m.mu.Unlock()
+	if src == nil {
+		return nil, fmt.Errorf("方案不存在")
+	}
+
+	p := cloneProfile(src)
+	if name == "" {
+		name = src.Name + " 副本"
+	}
+	p.Name = name
+	return m.Create(p)
+}
+
+// Update 更新方案内容（不会自动激活）
+func (m *ProfileManager) Update(id string, update *Profile) (*Profile, error) {
+	if err := update.NodeFilter.Validate(); err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := m.find(id)
+	if p == nil {
+		return nil, fmt.Errorf("方案不存在")
+	}
+	if update.Name != "" {
+		p.Name = update.Name
+	}
+	p.Description = update.Description
+	p.Icon = update.Icon
+	if update.Settings != nil {
+		p.Settings = update.Settings
+	}
+	if update.Template != nil {
+		p.Template = update.Template
+	}
+	if update.SingBoxTemplate != nil {
+		p.SingBoxTemplate = update.SingBoxTemplate
+	}
+	p.NodeFilter = update.NodeFilter
+	p.UpdatedAt = time.Now()
+	return cloneProfile(p), m.save()
+}
+
+// Delete 删除方案（不能删除当前方案）
+func (m *ProfileManager) Delete(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if id == m.store.Active {
+		return fmt.Errorf("不能删除当前使用的方案")
+	}
+	for i, p := range m.store.Profiles {
+		if p.ID == id {
+			m.store.Profiles = append(m.store.Profiles[:i], m.store.Profiles[i+1:]...)
+			return m.save()
+		}
+	}
+	return fmt.Errorf("方案不存在")
+}
+
+// SaveCurrent 将当前生效的设置与模板写回方案
+func (m *ProfileManager) SaveCurrent(id string) (*Profile, error) {
+	current := m.Capture()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := m.find(id)
+	if p == nil {
+		return nil, fmt.Errorf("方案不存在")
+	}
+	p.Settings = current.Settings
+	p.Template = current.Template
+	p.SingBoxTemplate = current.SingBoxTemplate
+	p.NodeFilter = current.NodeFilter
+	p.UpdatedAt = time.Now()
+	return cloneProfile(p), m.save()
+}
+
+// Capture 获取当前生效的设置、模板与节点过滤快照
+func (m *ProfileManager) Capture() *Profile {
+	snapshot := &Profile{
+		Settings:        m.settings.GetCurrentSettings(),
+		SingBoxTemplate: m.service.GetSingBoxTemplate(),
+		NodeFilter:      m.service.GetNodeFilter(),
+	}
+	cloneJSON(m.service.GetConfigTemplate(), &snapshot.Template)
+	return snapshot
+}
+
+// Activate 激活方案：应用设置与模板、重新生成配置，运行中则重启核心
+// 任一步骤失败时回滚到激活前的状态
+func (m *ProfileManager) Activate(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := m.find(id)
+	if p == nil {
+		return fmt.Errorf("方案不存在")
+	}
+	target := cloneProfile(p)
+	if target.Template != nil {
+		if err := lintResult(LintConfigTemplate(target.Template, nil)); err != nil {
+			return err
+		}
+	}
+
+	previous := m.Capture()
+	if err := m.apply(target); err != nil {
+		fmt.Printf("⚠️ 方案 %s 激活失败，正在回滚: %v\n", target.Name, err)
+		if rollbackErr := m.apply(previous); rollbackErr != nil {
+			fmt.Printf("❌ 方案回滚失败: %v\n", rollbackErr)
+		}
+		return err
+	}
+
+	m.store.Active = id
+	fmt.Printf("✅ 已切换到配置方案: %s\n", target.Name)
+	return m.save()
+}
+
+// ApplySettings 仅应用方案中的代理设置（设置预设使用），不替换模板与节点过滤，也不切换当前方案
+func (m *ProfileManager) ApplySettings(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := m.find(id)
+	if p == nil {
+		return fmt.Errorf("方案不存在")
+	}
+	if p.Settings == nil {
+		return fmt.Errorf("方案没有代理设置")
+	}
+
+	current := m.Capture()
+	target := &Profile{NodeFilter: current.NodeFilter}
+	cloneJSON(p.Settings, &target.Settings)
+	if err := m.apply(target); err != nil {
+		fmt.Printf("⚠️ 预设 %s 应用失败，正在回滚: %v\n", p.Name, err)
+		if rollbackErr := m.apply(&Profile{Settings: current.Settings, NodeFilter: current.NodeFilter}); rollbackErr != nil {
+			fmt.Printf("❌ 设置回滚失败: %v\n", rollbackErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// apply 将方案内容写入设置与模板，重新生成配置并按需重启
+func (m *ProfileManager) apply(p *Profile) error {
+	if p.Settings != nil {
+		if err := m.settings.ReplaceSettings(p.Settings); err != nil {
+			return fmt.Errorf("应用设置失败: %w", err)
+		}
+	}
+	if p.Template != nil {
+		if err := m.service.ReplaceConfigTemplate(p.Template); err != nil {
+			return fmt.Errorf("应用模板失败: %w", err)
+		}
+	}
+	if p.SingBoxTemplate != nil {
+		if err := SaveSingBoxTemplate(m.dataDir, p.SingBoxTemplate); err != nil {
+			return fmt.Errorf("应用 Sing-Box 模板失败: %w", err)
+		}
+	}
+	m.service.SetNodeFilter(p.NodeFilter)
+
+	// 尚未导入节点时只保存设置与模板，待有节点后再生成配置
+	if _, err := m.service.RegenerateConfig(); err != nil && !errors.Is(err, ErrNoNodes) {
+		return fmt.Errorf("生成配置失败: %w", err)
+	}
+	if m.service.GetStatus().Running {
+		if err := m.service.Restart(); err != nil {
+			return fmt.Errorf("重启核心失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// ActiveID 获取当前方案 ID
+func (m *ProfileManager) ActiveID() string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.store.Active
+}
+
+// cloneProfile 深拷贝方案
+func cloneProfile(p *Profile) *Profile {
+	var copy Profile
+	cloneJSON(p, &copy)
+	return &copy
+}
+
+// cloneJSON 通过 JSON 序列化深拷贝
+func cloneJSON(src, dst interface{}) {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return
+	}
+	json.Unmarshal(data, dst)
+}
@@ -0,0 +1,174 @@
+package proxy
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// 节点扩展选项：端口跳跃、多路复用、ECH 与 TLS 分片
+// 节点 Config 中可能是 Mihomo 写法（ports/hop-interval/smux/ech-opts），
+// 也可能是订阅解析器输出的 sing-box 写法（server_ports/hop_interval/multiplex/tls.ech），两种内核生成时互相转换
+// ============================================================================
+
+// mihomoPortsToSingBox 转换端口跳跃范围，"20000-30000,443" -> ["20000:30000", "443:443"]
+func mihomoPortsToSingBox(ports string) []string {
+	var ranges []string
+	for _, part := range strings.Split(ports, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		start, end, isRange := strings.Cut(strings.ReplaceAll(part, ":", "-"), "-")
+		if !isRange {
+			end = start
+		}
+		if _, err := strconv.Atoi(start); err != nil {
+			continue
+		}
+		if _, err := strconv.Atoi(end); err != nil {
+			continue
+		}
+		ranges = append(ranges, start+":"+end)
+	}
+	return ranges
+}
+
+// singBoxPortsToMihomo 转换端口跳跃范围，["20000:30000", "443:443"] -> "20000-30000,443"
+func singBoxPortsToMihomo(ranges []string) string {
+	parts := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		start, end, ok := strings.Cut(r, ":")
+		if !ok || start == end {
+			parts = append(parts, start)
+			continue
+		}
+		parts = append(parts, start+"-"+end)
+	}
+	return strings.Join(parts, ",")
+}
+
+// hopIntervalSeconds 读取端口跳跃间隔（秒），兼容 30 与 "30s"
+func hopIntervalSeconds(v interface{}) int {
+	switch val := v.(type) {
+	case float64:
+		return int(val)
+	case int:
+		return val
+	case string:
+		if n, err := strconv.Atoi(val); err == nil {
+			return n
+		}
+		if d, err := time.ParseDuration(val); err == nil {
+			return int(d.Seconds())
+		}
+	}
+	return 0
+}
+
+// configPorts 读取端口跳跃范围（sing-box 格式），兼容 server_ports 列表与 Mihomo 的 ports 字符串
+func configPorts(config map[string]interface{}) []string {
+	if ranges := configStrings(config, "server_ports"); len(ranges) > 0 {
+		return mihomoPortsToSingBox(strings.Join(ranges, ","))
+	}
+	if ports, ok := config["ports"].(string); ok {
+		return mihomoPortsToSingBox(ports)
+	}
+	return nil
+}
+
+// parsePortHopping 解析 Hysteria/Hysteria2 的端口跳跃，sing-box 中 server_ports 与 server_port 互斥
+func parsePortHopping(config map[string]interface{}, out *SBOutbound) {
+	out.ServerPorts = configPorts(config)
+	if len(out.ServerPorts) == 0 {
+		return
+	}
+	out.ServerPort = 0
+	if seconds := hopIntervalSeconds(firstValue(config, "hop-interval", "hop_interval")); seconds > 0 {
+		out.HopInterval = fmt.Sprintf("%ds", seconds)
+	}
+}
+
+// firstValue 按顺序返回第一个存在的字段值
+func firstValue(config map[string]interface{}, keys ...string) interface{} {
+	for _, key := range keys {
+		if v, ok := config[key]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+// echConfigBase64 将 PEM 格式的 ECH 配置还原为 Mihomo 使用的 base64
+func echConfigBase64(lines []string) string {
+	var parts []string
+	for _, line := range lines {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "-----") {
+			continue
+		}
+		parts = append(parts, line)
+	}
+	return strings.Join(parts, "")
+}
+
+// convertSingBoxOptionsToMihomo 将 sing-box 写法的扩展选项转换为 Mihomo 写法（在 tls 对象展开前调用）
+// TLS 分片在 Mihomo 中没有对应的节点选项，直接丢弃
+func convertSingBoxOptionsToMihomo(proxy map[string]interface{}) {
+	// 端口跳跃
+	if ranges := configStrings(proxy, "server_ports"); len(ranges) > 0 {
+		if _, exists := proxy["ports"]; !exists {
+			proxy["ports"] = singBoxPortsToMihomo(mihomoPortsToSingBox(strings.Join(ranges, ",")))
+		}
+		delete(proxy, "server_ports")
+	}
+	if v, ok := proxy["hop_interval"]; ok {
+		if seconds := hopIntervalSeconds(v); seconds > 0 {
+			proxy["hop-interval"] = seconds
+		}
+		delete(proxy, "hop_interval")
+	}
+
+	// 多路复用
+	if mux, ok := proxy["multiplex"].(map[string]interface{}); ok {
+		if configBool(mux, "enabled") {
+			smux := map[string]interface{}{"enabled": true}
+			if protocol := configString(mux, "protocol"); protocol != "" {
+				smux["protocol"] = protocol
+			}
+			for from, to := range map[string]string{
+				"max_connections": "max-connections",
+				"min_streams":     "min-streams",
+				"max_streams":     "max-streams",
+			} {
+				if n := configInt(mux, from); n > 0 {
+					smux[to] = n
+				}
+			}
+			if configBool(mux, "padding") {
+				smux["padding"] = true
+			}
+			proxy["smux"] = smux
+		}
+		delete(proxy, "multiplex")
+	}
+
+	// ECH
+	if tls, ok := proxy["tls"].(map[string]interface{}); ok {
+		if ech, ok := tls["ech"].(map[string]interface{}); ok && configBool(ech, "enabled") {
+			opts := map[string]interface{}{"enable": true}
+			if config := echConfigBase64(configStrings(ech, "config")); config != "" {
+				opts["config"] = config
+			}
+			proxy["ech-opts"] = opts
+		}
+		delete(tls, "ech")
+		delete(tls, "fragment")
+		delete(tls, "record_fragment")
+		delete(tls, "fragment_fallback_delay")
+	}
+	delete(proxy, "tls-fragment")
+	delete(proxy, "tls-record-fragment")
+}
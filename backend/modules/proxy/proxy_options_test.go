@@ -0,0 +1,452 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"p-box/backend/modules/subscription"
+)
+
+// nodeFromShareLink 通过订阅解析器将分享链接转换为节点
+func nodeFromShareLink(t *testing.T, link string) ProxyNode {
+	t.Helper()
+	parsed, err := subscription.ParseURL(link)
+	if err != nil {
+		t.Fatalf("ParseURL: %v", err)
+	}
+	return ProxyNode{
+		Name:       parsed.Name,
+		Type:       parsed.Type,
+		Server:     parsed.Server,
+		ServerPort: parsed.ServerPort,
+		Config:     parsed.Config,
+	}
+}
+
+func TestExtraOptionsEndToEnd(t *testing.T) {
+	tests := []struct {
+		name    string
+		node    func(t *testing.T) ProxyNode
+		mihomo  map[string]string // 生成的 Mihomo 代理字段 -> fmt.Sprint 后的值
+		absent  []string          // Mihomo 代理中不应出现的字段
+		singbox func(t *testing.T, out *SBOutbound)
+	}{
+		{
+			name: "hysteria2 端口跳跃（分享链接）",
+			node: func(t *testing.T) ProxyNode {
+				return nodeFromShareLink(t, "hysteria2://pw@example.com:443,20000-30000?sni=a.example.com&hop_interval=30#hy2")
+			},
+			mihomo: map[string]string{
+				"type":         "hysteria2",
+				"ports":        "443,20000-30000",
+				"hop-interval": "30",
+			},
+			absent: []string{"server_ports", "hop_interval"},
+			singbox: func(t *testing.T, out *SBOutbound) {
+				if fmt.Sprint(out.ServerPorts) != "[443:443 20000:30000]" || out.HopInterval != "30s" {
+					t.Errorf("server_ports = %v, hop_interval = %q", out.ServerPorts, out.HopInterval)
+				}
+				if out.ServerPort != 0 {
+					t.Errorf("server_port = %d, want 0 when server_ports is set", out.ServerPort)
+				}
+			},
+		},
+		{
+			name: "hysteria2 端口跳跃（Mihomo 配置）",
+			node: func(t *testing.T) ProxyNode {
+				return ProxyNode{Name: "hy2", Type: "hysteria2", Server: "example.com", Port: 443,
+					Config: `{"name":"hy2","type":"hysteria2","server":"example.com","port":443,"password":"pw","ports":"20000-30000","hop-interval":15}`}
+			},
+			mihomo: map[string]string{
+				"ports":        "20000-30000",
+				"hop-interval": "15",
+			},
+			singbox: func(t *testing.T, out *SBOutbound) {
+				if fmt.Sprint(out.ServerPorts) != "[20000:30000]" || out.HopInterval != "15s" {
+					t.Errorf("server_ports = %v, hop_interval = %q", out.ServerPorts, out.HopInterval)
+				}
+			},
+		},
+		{
+			name: "vless smux 与 ECH（分享链接）",
+			node: func(t *testing.T) ProxyNode {
+				return nodeFromShareLink(t, "vless://uuid@example.com:443?security=tls&sni=a.example.com&type=tcp&mux=smux&mux_max_streams=8&ech=AEXabc#vless")
+			},
+			mihomo: map[string]string{
+				"smux":     "map[enabled:true max-streams:8 protocol:smux]",
+				"ech-opts": "map[config:AEXabc enable:true]",
+			},
+			absent: []string{"multiplex"},
+			singbox: func(t *testing.T, out *SBOutbound) {
+				if out.Multiplex == nil || out.Multiplex.Protocol != "smux" || out.Multiplex.MaxStreams != 8 {
+					t.Errorf("multiplex = %+v", out.Multiplex)
+				}
+				if out.TLS == nil || out.TLS.ECH == nil || !out.TLS.ECH.Enabled || len(out.TLS.ECH.Config) != 3 || out.TLS.ECH.Config[1] != "AEXabc" {
+					t.Errorf("tls.ech = %+v", out.TLS)
+				}
+			},
+		},
+		{
+			name: "trojan h2mux 与 TLS 分片（分享链接）",
+			node: func(t *testing.T) ProxyNode {
+				return nodeFromShareLink(t, "trojan://pw@example.com:443?sni=a.example.com&mux=h2mux&mux_padding=1&fragment=1&record_fragment=1#trojan")
+			},
+			mihomo: map[string]string{
+				"smux": "map[enabled:true padding:true protocol:h2mux]",
+			},
+			// Mihomo 没有 TLS 分片选项
+			absent: []string{"tls-fragment", "fragment", "multiplex"},
+			singbox: func(t *testing.T, out *SBOutbound) {
+				if out.Multiplex == nil || out.Multiplex.Protocol != "h2mux" || !out.Multiplex.Padding {
+					t.Errorf("multiplex = %+v", out.Multiplex)
+				}
+				if out.TLS == nil || !out.TLS.Fragment || !out.TLS.RecordFragment {
+					t.Errorf("tls fragment = %+v", out.TLS)
+				}
+			},
+		},
+		{
+			name: "trojan smux 与 ECH（Mihomo 配置）",
+			node: func(t *testing.T) ProxyNode {
+				return ProxyNode{Name: "trojan", Type: "trojan", Server: "example.com", Port: 443,
+					Config: `{"name":"trojan","type":"trojan","server":"example.com","port":443,"password":"pw","sni":"a.example.com",` +
+						`"smux":{"enabled":true,"protocol":"yamux","max-connections":4},"ech-opts":{"enable":true,"config":"AEXabc"},"tls-fragment":true}`}
+			},
+			mihomo: map[string]string{
+				"smux":     "map[enabled:true max-connections:4 protocol:yamux]",
+				"ech-opts": "map[config:AEXabc enable:true]",
+			},
+			absent: []string{"tls-fragment"},
+			singbox: func(t *testing.T, out *SBOutbound) {
+				if out.Multiplex == nil || out.Multiplex.Protocol != "yamux" || out.Multiplex.MaxConnections != 4 {
+					t.Errorf("multiplex = %+v", out.Multiplex)
+				}
+				if out.TLS == nil || out.TLS.ECH == nil || out.TLS.ECH.Config[1] != "AEXabc" || !out.TLS.Fragment {
+					t.Errorf("tls = %+v", out.TLS)
+				}
+			},
+		},
+		{
+			name: "shadowsocks 多路复用（分享链接）",
+			node: func(t *testing.T) ProxyNode {
+				return nodeFromShareLink(t, "ss://YWVzLTEyOC1nY206cHc@example.com:8388?mux=1#ss")
+			},
+			mihomo: map[string]string{
+				"smux": "map[enabled:true]",
+			},
+			singbox: func(t *testing.T, out *SBOutbound) {
+				if out.Multiplex == nil || !out.Multiplex.Enabled || out.Multiplex.Protocol != "" {
+					t.Errorf("multiplex = %+v", out.Multiplex)
+				}
+			},
+		},
+		{
+			name: "hysteria（Mihomo 配置）",
+			node: func(t *testing.T) ProxyNode {
+				return ProxyNode{Name: "hy", Type: "hysteria", Server: "example.com", Port: 443,
+					Config: `{"name":"hy","type":"hysteria","server":"example.com","port":443,"auth-str":"secret","obfs":"ob","up":"30 Mbps","down":100,` +
+						`"sni":"a.example.com","alpn":["h3"],"recv-window-conn":12582912}`}
+			},
+			mihomo: map[string]string{"type": "hysteria"},
+			singbox: func(t *testing.T, out *SBOutbound) {
+				if out.Type != "hysteria" || out.AuthStr != "secret" || out.Obfs == nil || out.Obfs.Password != "ob" {
+					t.Errorf("outbound = %+v", out)
+				}
+				if out.UpMbps != 30 || out.DownMbps != 100 || out.RecvWindowConn != 12582912 {
+					t.Errorf("up = %d, down = %d, recv_window_conn = %d", out.UpMbps, out.DownMbps, out.RecvWindowConn)
+				}
+				if out.TLS == nil || !out.TLS.Enabled || out.TLS.ServerName != "a.example.com" || fmt.Sprint(out.TLS.ALPN) != "[h3]" {
+					t.Errorf("tls = %+v", out.TLS)
+				}
+			},
+		},
+		{
+			name: "shadowtls（订阅配置）",
+			node: func(t *testing.T) ProxyNode {
+				return ProxyNode{Name: "stls", Type: "shadowtls", Server: "example.com", Port: 443,
+					Config: `{"type":"shadowtls","server":"example.com","port":443,"password":"pw","sni":"cloud.example.com","client-fingerprint":"firefox"}`}
+			},
+			mihomo: map[string]string{"type": "shadowtls"},
+			singbox: func(t *testing.T, out *SBOutbound) {
+				if out.Type != "shadowtls" || out.Version != 3 || out.Password != "pw" {
+					t.Errorf("outbound = %+v", out)
+				}
+				if out.TLS == nil || out.TLS.ServerName != "cloud.example.com" || out.TLS.UTLS == nil || out.TLS.UTLS.Fingerprint != "firefox" {
+					t.Errorf("tls = %+v", out.TLS)
+				}
+			},
+		},
+		{
+			name: "ssh（Mihomo 配置）",
+			node: func(t *testing.T) ProxyNode {
+				return ProxyNode{Name: "ssh", Type: "ssh", Server: "example.com", Port: 22,
+					Config: `{"name":"ssh","type":"ssh","server":"example.com","port":22,"username":"admin","private-key":"KEY",` +
+						`"host-key":["ssh-ed25519 AAAA"],"host-key-algorithms":"ssh-ed25519, rsa-sha2-512"}`}
+			},
+			mihomo: map[string]string{"type": "ssh"},
+			singbox: func(t *testing.T, out *SBOutbound) {
+				if out.User != "admin" || out.PrivateKey != "KEY" || out.Password != "" {
+					t.Errorf("outbound = %+v", out)
+				}
+				if fmt.Sprint(out.HostKey) != "[ssh-ed25519 AAAA]" || fmt.Sprint(out.HostKeyAlgorithms) != "[ssh-ed25519 rsa-sha2-512]" {
+					t.Errorf("host_key = %v, host_key_algorithms = %v", out.HostKey, out.HostKeyAlgorithms)
+				}
+			},
+		},
+		{
+			name: "naive（订阅配置）",
+			node: func(t *testing.T) ProxyNode {
+				return ProxyNode{Name: "naive", Type: "naive", Server: "example.com", Port: 443,
+					Config: `{"type":"naive","server":"example.com","port":443,"username":"u","password":"p","quic":true,` +
+						`"extra_headers":{"X-Pad":"1"},"server_name":"a.example.com"}`}
+			},
+			mihomo: map[string]string{"type": "naive"},
+			singbox: func(t *testing.T, out *SBOutbound) {
+				if out.Username != "u" || out.Password != "p" || !out.QUIC || out.ExtraHeaders["X-Pad"] != "1" {
+					t.Errorf("outbound = %+v", out)
+				}
+				if out.TLS == nil || !out.TLS.Enabled || out.TLS.ServerName != "a.example.com" {
+					t.Errorf("tls = %+v", out.TLS)
+				}
+			},
+		},
+		{
+			name: "wireguard（Mihomo 配置）",
+			node: func(t *testing.T) ProxyNode {
+				return ProxyNode{Name: "wg", Type: "wireguard", Server: "example.com", Port: 51820,
+					Config: `{"name":"wg","type":"wireguard","server":"example.com","port":51820,"ip":"172.16.0.2","ipv6":"fd01::2",` +
+						`"private-key":"PRIV","public-key":"PUB","reserved":[1,2,3],"mtu":1280}`}
+			},
+			mihomo: map[string]string{"type": "wireguard"},
+			singbox: func(t *testing.T, out *SBOutbound) {
+				if out.PrivateKey != "PRIV" || out.PeerPublicKey != "PUB" || out.MTU != 1280 {
+					t.Errorf("outbound = %+v", out)
+				}
+				if fmt.Sprint(out.LocalAddress) != "[172.16.0.2/32 fd01::2/128]" || fmt.Sprint(out.Reserved) != "[1 2 3]" {
+					t.Errorf("local_address = %v, reserved = %v", out.LocalAddress, out.Reserved)
+				}
+				endpoint := wireGuardEndpoint(*out)
+				if len(endpoint.Peers) != 1 || endpoint.Peers[0].Address != "example.com" || endpoint.Peers[0].Port != 51820 ||
+					fmt.Sprint(endpoint.Peers[0].AllowedIPs) != "[0.0.0.0/0 ::/0]" {
+					t.Errorf("endpoint = %+v", endpoint)
+				}
+			},
+		},
+		{
+			name: "wireguard 对端列表（Mihomo 配置）",
+			node: func(t *testing.T) ProxyNode {
+				return ProxyNode{Name: "wg", Type: "wireguard", Server: "example.com", Port: 51820,
+					Config: `{"name":"wg","type":"wireguard","server":"example.com","port":51820,"ip":"172.16.0.2/24","private-key":"PRIV",` +
+						`"peers":[{"server":"peer.example.com","port":2408,"public-key":"PUB","reserved":"AQID","allowed-ips":["10.0.0.0/8"]}]}`}
+			},
+			mihomo: map[string]string{"type": "wireguard"},
+			singbox: func(t *testing.T, out *SBOutbound) {
+				if out.Server != "peer.example.com" || out.ServerPort != 2408 || out.PeerPublicKey != "PUB" {
+					t.Errorf("outbound = %+v", out)
+				}
+				if fmt.Sprint(out.LocalAddress) != "[172.16.0.2/24]" || fmt.Sprint(out.Reserved) != "[1 2 3]" {
+					t.Errorf("local_address = %v, reserved = %v", out.LocalAddress, out.Reserved)
+				}
+				if endpoint := wireGuardEndpoint(*out); fmt.Sprint(endpoint.Peers[0].AllowedIPs) != "[10.0.0.0/8]" {
+					t.Errorf("allowed_ips = %v", endpoint.Peers[0].AllowedIPs)
+				}
+			},
+		},
+		{
+			name: "socks5（Mihomo 配置）",
+			node: func(t *testing.T) ProxyNode {
+				return ProxyNode{Name: "socks", Type: "socks5", Server: "example.com", Port: 1080,
+					Config: `{"name":"socks","type":"socks5","server":"example.com","port":1080,"username":"u","password":"p","udp-over-tcp":true}`}
+			},
+			mihomo: map[string]string{"type": "socks5"},
+			singbox: func(t *testing.T, out *SBOutbound) {
+				if out.Type != "socks" || out.Username != "u" || out.Password != "p" || !out.UDPOverTCP {
+					t.Errorf("outbound = %+v", out)
+				}
+			},
+		},
+		{
+			name: "http TLS（Mihomo 配置）",
+			node: func(t *testing.T) ProxyNode {
+				return ProxyNode{Name: "http", Type: "http", Server: "example.com", Port: 443,
+					Config: `{"name":"http","type":"http","server":"example.com","port":443,"username":"u","password":"p","tls":true,` +
+						`"sni":"a.example.com","skip-cert-verify":true,"headers":{"X-Token":"t"}}`}
+			},
+			mihomo: map[string]string{"type": "http"},
+			singbox: func(t *testing.T, out *SBOutbound) {
+				if out.Username != "u" || out.Password != "p" || out.Headers["X-Token"] != "t" {
+					t.Errorf("outbound = %+v", out)
+				}
+				if out.TLS == nil || !out.TLS.Enabled || out.TLS.ServerName != "a.example.com" || !out.TLS.Insecure {
+					t.Errorf("tls = %+v", out.TLS)
+				}
+			},
+		},
+	}
+
+	g := NewConfigGenerator(t.TempDir())
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node := tt.node(t)
+
+			proxies := g.convertProxies([]ProxyNode{node})
+			if len(proxies) != 1 {
+				t.Fatalf("convertProxies returned %d proxies", len(proxies))
+			}
+			proxy := proxies[0]
+			for key, want := range tt.mihomo {
+				if got, ok := proxy[key]; !ok || fmt.Sprint(got) != want {
+					data, _ := json.Marshal(proxy)
+					t.Errorf("mihomo %s = %v, want %s (%s)", key, got, want, data)
+				}
+			}
+			for _, key := range tt.absent {
+				if got, ok := proxy[key]; ok {
+					t.Errorf("mihomo %s = %v, want absent", key, got)
+				}
+			}
+
+			out, err := ParseNodeToSingBox(node)
+			if err != nil {
+				t.Fatalf("ParseNodeToSingBox: %v", err)
+			}
+			tt.singbox(t, out)
+		})
+	}
+}
+
+func TestPortRangeConversion(t *testing.T) {
+	tests := []struct {
+		mihomo  string
+		singbox string
+		back    string
+	}{
+		{"20000-30000", "[20000:30000]", "20000-30000"},
+		{"443,20000-30000", "[443:443 20000:30000]", "443,20000-30000"},
+		{"443, bad, 1-x", "[443:443]", "443"},
+	}
+	for _, tt := range tests {
+		ranges := mihomoPortsToSingBox(tt.mihomo)
+		if got := fmt.Sprint(ranges); got != tt.singbox {
+			t.Errorf("mihomoPortsToSingBox(%q) = %s, want %s", tt.mihomo, got, tt.singbox)
+		}
+		if got := singBoxPortsToMihomo(ranges); got != tt.back {
+			t.Errorf("singBoxPortsToMihomo(%v) = %q, want %q", ranges, got, tt.back)
+		}
+	}
+}
+
+func TestHopIntervalSeconds(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want int
+	}{
+		{float64(30), 30},
+		{"30", 30},
+		{"30s", 30},
+		{"1m", 60},
+		{"bad", 0},
+		{nil, 0},
+	}
+	for _, tt := range tests {
+		if got := hopIntervalSeconds(tt.in); got != tt.want {
+			t.Errorf("hopIntervalSeconds(%v) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseWireGuardReserved(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      interface{}
+		want    string
+		wantErr bool
+	}{
+		{name: "数组", in: []interface{}{float64(1), float64(2), float64(3)}, want: "[1 2 3]"},
+		{name: "逗号分隔", in: "1, 2, 3", want: "[1 2 3]"},
+		{name: "base64", in: "AQID", want: "[1 2 3]"},
+		{name: "未设置", in: nil, want: "[]"},
+		{name: "空字符串", in: "", want: "[]"},
+		{name: "数组元素不是数字", in: []interface{}{"a"}, wantErr: true},
+		{name: "逗号分隔含非数字", in: "1,b", wantErr: true},
+		{name: "不是 base64", in: "!!", wantErr: true},
+		{name: "不支持的类型", in: float64(1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseWireGuardReserved(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && fmt.Sprint(got) != tt.want {
+				t.Errorf("parseWireGuardReserved(%v) = %v, want %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConvertNodesV112(t *testing.T) {
+	ss := func(name, extra string) ProxyNode {
+		return ProxyNode{Name: name, Type: "ss", Server: "example.com", Port: 8388,
+			Config: `{"type":"ss","server":"example.com","port":8388,"cipher":"aes-128-gcm","password":"pw"` + extra + `}`}
+	}
+	shadowTLS := `,"plugin":"shadow-tls","plugin-opts":{"host":"cloud.example.com","password":"stls","version":3}`
+
+	nodes := []ProxyNode{
+		ss("ss-1", ""),
+		ss("ss-1", ""),
+		ss("ss-2", shadowTLS),
+		ss("ss-3-shadowtls", ""),
+		ss("ss-3", shadowTLS),
+		ss("ss-4", `,"plugin":"shadow-tls","plugin-opts":{"host":"cloud.example.com","version":3}`),
+		{Name: "hy", Type: "hysteria", Server: "example.com", Port: 443, Config: `{"protocol":"faketcp","auth-str":"a"}`},
+		{Name: "wg", Type: "wireguard", Server: "example.com", Port: 51820, Config: `{"private-key":"PRIV","ip":"172.16.0.2"}`},
+		{Name: "socks", Type: "socks5", Server: "example.com", Port: 1080, Config: `{"tls":true}`},
+		{Name: "ssh", Type: "ssh", Server: "example.com", Port: 22, Config: `{"username":"root"}`},
+		{Name: "naive", Type: "naive", Server: "example.com", Port: 443},
+		{Name: "bad", Type: "vmess", Server: "example.com", Port: 443, Config: `{`},
+	}
+	wantSkipped := []struct{ name, reason string }{
+		{"ss-1", "节点名称重复"},
+		{"ss-3", "前置出站 ss-3-shadowtls 与其他节点重名"},
+		{"ss-4", "shadow-tls 插件"},
+		{"hy", "只支持 udp"},
+		{"wg", "缺少 WireGuard 私钥或对端公钥"},
+		{"socks", "不支持 TLS"},
+		{"ssh", "需要密码或私钥"},
+		{"naive", "缺少协议配置"},
+		{"bad", "failed to parse config JSON"},
+	}
+
+	result := convertNodesV112(nodes)
+
+	var tags []string
+	for _, ob := range result.Nodes {
+		tags = append(tags, ob.Tag)
+	}
+	if fmt.Sprint(tags) != "[ss-1 ss-2 ss-3-shadowtls]" {
+		t.Errorf("nodes = %v", tags)
+	}
+	if len(result.Detours) != 1 || result.Detours[0].Tag != "ss-2-shadowtls" || result.Detours[0].Password != "stls" {
+		t.Errorf("detours = %+v", result.Detours)
+	}
+	if ss2 := result.Nodes[1]; ss2.Detour != "ss-2-shadowtls" || ss2.Plugin != "" {
+		t.Errorf("ss-2 detour = %q, plugin = %q", ss2.Detour, ss2.Plugin)
+	}
+
+	if len(result.Skipped) != len(wantSkipped) {
+		t.Fatalf("skipped = %+v, want %d entries", result.Skipped, len(wantSkipped))
+	}
+	for i, want := range wantSkipped {
+		got := result.Skipped[i]
+		if got.Name != want.name || !strings.Contains(got.Reason, want.reason) {
+			t.Errorf("skipped[%d] = %+v, want %s: %s", i, got, want.name, want.reason)
+		}
+	}
+
+	if report := SingBoxNodeReport(nodes[:1]); report == nil || len(report) != 0 {
+		t.Errorf("SingBoxNodeReport = %#v, want empty list", report)
+	}
+}
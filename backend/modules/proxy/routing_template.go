@@ -0,0 +1,790 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// 统一路由模板：代理组、规则、规则集与 DNS 策略，同时编译为 Mihomo 与 sing-box 配置
+// ============================================================================
+
+// RoutingTemplateFile 统一路由模板文件名（存在时作为两个核心的路由来源）
+const RoutingTemplateFile = "routing_template.json"
+
+// DNS 策略服务器
+const (
+	DNSServerDirect = "direct" // 国内 DNS，返回真实 IP
+	DNSServerProxy  = "proxy"  // 海外 DNS
+	DNSServerFakeIP = "fakeip" // FakeIP（非 FakeIP 模式下等同 proxy）
+	DNSServerReject = "reject" // 拒绝解析
+)
+
+// 规则集来源地址
+const (
+	mihomoGeoBaseURL     = "https://testingcf.jsdelivr.net/gh/MetaCubeX/meta-rules-dat@meta/geo"
+	singBoxGeositeURL    = "https://raw.githubusercontent.com/SagerNet/sing-geosite/rule-set"
+	singBoxGeoIPURL      = "https://raw.githubusercontent.com/SagerNet/sing-geoip/rule-set"
+	defaultRuleSetUpdate = 86400
+)
+
+// Mihomo DNS 策略使用的服务器
+var (
+	mihomoDirectDNS = []string{"https://doh.pub/dns-query", "https://dns.alidns.com/dns-query"}
+	mihomoProxyDNS  = []string{"https://dns.google/dns-query", "https://cloudflare-dns.com/dns-query"}
+)
+
+// RoutingRuleSet 核心无关的规则集
+// Geo 引用官方 GEO 分类，两个核心都使用各自的官方规则集；否则分别指定各核心的下载地址
+type RoutingRuleSet struct {
+	Name         string `json:"name"`
+	Behavior     string `json:"behavior"`               // domain, ipcidr, classical
+	Geo          string `json:"geo,omitempty"`          // geosite:google, geoip:cn
+	MihomoURL    string `json:"mihomoUrl,omitempty"`    // Mihomo 规则集地址
+	MihomoFormat string `json:"mihomoFormat,omitempty"` // mrs, yaml, text
+	MihomoType   string `json:"mihomoType,omitempty"`   // http（默认）、file
+	MihomoPath   string `json:"mihomoPath,omitempty"`   // Mihomo 规则集保存路径，留空时为 ./ruleset/<名称>
+	SingBoxURL   string `json:"singboxUrl,omitempty"`   // sing-box 规则集地址（.srs 或 .json）
+	Interval     int    `json:"interval,omitempty"`
+	Description  string `json:"description,omitempty"`
+}
+
+// RoutingDNSRule DNS 分流策略
+type RoutingDNSRule struct {
+	Type        string `json:"type"`    // GEOSITE, DOMAIN, DOMAIN-SUFFIX, RULE-SET
+	Payload     string `json:"payload"` // 匹配内容
+	Server      string `json:"server"`  // direct, proxy, fakeip, reject
+	Description string `json:"description,omitempty"`
+}
+
+// RoutingDNSPolicy DNS 策略（追加在各核心内置 DNS 规则之前）
+type RoutingDNSPolicy struct {
+	Rules []RoutingDNSRule `json:"rules"`
+}
+
+// RoutingTemplate 核心无关的路由模板
+// 代理组与规则沿用 Mihomo 模板的结构，规则类型作为两个核心共同的描述语言
+type RoutingTemplate struct {
+	Groups   []ProxyGroupTemplate      `json:"groups"`
+	Rules    []RuleTemplate            `json:"rules"`
+	SubRules map[string][]RuleTemplate `json:"subRules,omitempty"`
+	RuleSets []RoutingRuleSet          `json:"ruleSets"`
+	DNS      RoutingDNSPolicy          `json:"dns"`
+}
+
+// RoutingIssue 无法翻译到某个核心的功能
+type RoutingIssue struct {
+	Core    string `json:"core"` // mihomo, singbox, migration
+	Path    string `json:"path"`
+	Target  string `json:"target,omitempty"`
+	Message string `json:"message"`
+}
+
+// LoadRoutingTemplate 读取统一路由模板，未启用时返回 nil
+func LoadRoutingTemplate(dataDir string) (*RoutingTemplate, error) {
+	data, err := os.ReadFile(filepath.Join(dataDir, RoutingTemplateFile))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var t RoutingTemplate
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// SaveRoutingTemplate 保存统一路由模板
+func SaveRoutingTemplate(dataDir string, t *RoutingTemplate) error {
+	data, err := json.MarshalIndent(t, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dataDir, RoutingTemplateFile), data, 0644)
+}
+
+// RemoveRoutingTemplate 删除统一路由模板，恢复使用各核心独立模板
+func RemoveRoutingTemplate(dataDir string) error {
+	err := os.Remove(filepath.Join(dataDir, RoutingTemplateFile))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// GetDefaultRoutingTemplate 由默认的两个核心模板迁移得到默认统一模板
+func GetDefaultRoutingTemplate() *RoutingTemplate {
+	t, _ := MigrateRoutingTemplate(GetDefaultConfigTemplate(), GetDefaultSingBoxTemplate())
+	return t
+}
+
+// parseGeoRef 解析 geosite:xxx / geoip:xxx
+func parseGeoRef(geo string) (kind, code string, ok bool) {
+	idx := strings.Index(geo, ":")
+	if idx <= 0 {
+		return "", "", false
+	}
+	kind, code = strings.ToLower(geo[:idx]), strings.ToLower(geo[idx+1:])
+	if (kind != "geosite" && kind != "geoip") || code == "" {
+		return "", "", false
+	}
+	return kind, code, true
+}
+
+// findRuleSet 按名称查找规则集
+func (t *RoutingTemplate) findRuleSet(name string) *RoutingRuleSet {
+	for i := range t.RuleSets {
+		if t.RuleSets[i].Name == name {
+			return &t.RuleSets[i]
+		}
+	}
+	return nil
+}
+
+// ============================================================================
+// 编译到 Mihomo
+// ============================================================================
+
+// MihomoDNSPolicy 编译后的 Mihomo DNS 策略
+type MihomoDNSPolicy struct {
+	NameserverPolicy map[string][]string
+	FakeIPFilter     []string
+}
+
+// CompileMihomo 编译为 Mihomo 配置模板与 DNS 策略
+func (t *RoutingTemplate) CompileMihomo() (*ConfigTemplate, *MihomoDNSPolicy, []RoutingIssue) {
+	var issues []RoutingIssue
+	issue := func(path, target, format string, args ...interface{}) {
+		issues = append(issues, RoutingIssue{Core: "mihomo", Path: path, Target: target, Message: fmt.Sprintf(format, args...)})
+	}
+
+	builtin := make(map[string]bool)
+	for _, source := range defaultRuleProviderSources {
+		builtin[source.name] = true
+	}
+
+	compiled := &ConfigTemplate{
+		ProxyGroups: append([]ProxyGroupTemplate(nil), t.Groups...),
+		Rules:       append([]RuleTemplate(nil), t.Rules...),
+		SubRules:    t.SubRules,
+	}
+
+	for i, rs := range t.RuleSets {
+		path := fmt.Sprintf("ruleSets[%d]", i)
+		if builtin[rs.Name] {
+			continue // 生成器内置的规则集
+		}
+		interval := rs.Interval
+		if interval == 0 {
+			interval = defaultRuleSetUpdate
+		}
+		provider := RuleProviderTemplate{
+			Name:        rs.Name,
+			Type:        "http",
+			Behavior:    rs.Behavior,
+			Interval:    interval,
+			Description: rs.Description,
+		}
+		if kind, code, ok := parseGeoRef(rs.Geo); ok {
+			provider.URL = fmt.Sprintf("%s/%s/%s.mrs", mihomoGeoBaseURL, kind, code)
+			provider.Format = "mrs"
+			if provider.Behavior == "" {
+				provider.Behavior = map[string]string{"geosite": "domain", "geoip": "ipcidr"}[kind]
+			}
+		} else if rs.MihomoType == "file" && rs.MihomoPath != "" {
+			// 本地文件规则集没有下载地址
+			provider.Type = "file"
+			provider.Format = rs.MihomoFormat
+			if provider.Format == "" {
+				provider.Format = ruleSetFormatFromURL(rs.MihomoPath)
+			}
+		} else if rs.MihomoURL != "" {
+			provider.URL = rs.MihomoURL
+			provider.Format = rs.MihomoFormat
+			if provider.Format == "" {
+				provider.Format = ruleSetFormatFromURL(rs.MihomoURL)
+			}
+		} else {
+			issue(path, rs.Name, "规则集 %s 没有 Mihomo 可用的来源", rs.Name)
+			continue
+		}
+		provider.Path = rs.MihomoPath
+		if provider.Path == "" {
+			provider.Path = defaultRuleSetPath(rs.Name, provider.Format)
+		}
+		compiled.RuleProviders = append(compiled.RuleProviders, provider)
+	}
+
+	policy := &MihomoDNSPolicy{NameserverPolicy: make(map[string][]string)}
+	for i, r := range t.DNS.Rules {
+		path := fmt.Sprintf("dns.rules[%d]", i)
+		var key string
+		switch strings.ToUpper(r.Type) {
+		case "GEOSITE":
+			key = "geosite:" + strings.ToLower(r.Payload)
+		case "DOMAIN":
+			key = r.Payload
+		case "DOMAIN-SUFFIX":
+			key = "+." + strings.TrimPrefix(r.Payload, ".")
+		case "RULE-SET":
+			key = "rule-set:" + r.Payload
+		default:
+			issue(path, r.Payload, "Mihomo DNS 策略不支持 %s 匹配", r.Type)
+			continue
+		}
+		switch r.Server {
+		case DNSServerDirect:
+			policy.NameserverPolicy[key] = mihomoDirectDNS
+			policy.FakeIPFilter = append(policy.FakeIPFilter, key)
+		case DNSServerProxy:
+			policy.NameserverPolicy[key] = mihomoProxyDNS
+		case DNSServerFakeIP:
+			// Mihomo 在 fake-ip 模式下默认返回 FakeIP
+		case DNSServerReject:
+			issue(path, r.Payload, "Mihomo DNS 策略无法直接拒绝解析，请使用 REJECT 路由规则")
+		default:
+			issue(path, r.Server, "未知的 DNS 服务器 %s", r.Server)
+		}
+	}
+
+	return compiled, policy, issues
+}
+
+// ruleSetFormatFromURL 根据扩展名推断 Mihomo 规则集格式
+func ruleSetFormatFromURL(url string) string {
+	switch {
+	case strings.HasSuffix(url, ".mrs"):
+		return "mrs"
+	case strings.HasSuffix(url, ".yaml"), strings.HasSuffix(url, ".yml"):
+		return "yaml"
+	}
+	return "text"
+}
+
+// ============================================================================
+// 编译到 sing-box
+// ============================================================================
+
+// singBoxRouting 编译后的 sing-box 路由
+type singBoxRouting struct {
+	Groups   []SBOutbound
+	Rules    []SBRouteRule
+	RuleSets []SBRuleSet
+	DNSRules []SBDNSRule
+	Final    string
+}
+
+// CompileSingBox 编译为 sing-box 出站组、路由规则、规则集与 DNS 规则
+// nodeTags 为全部节点标签，manualTags 为手动节点标签
+func (t *RoutingTemplate) CompileSingBox(nodeTags, manualTags []string, fakeIP bool) (*singBoxRouting, []RoutingIssue) {
+	var issues []RoutingIssue
+	issue := func(path, target, format string, args ...interface{}) {
+		issues = append(issues, RoutingIssue{Core: "singbox", Path: path, Target: target, Message: fmt.Sprintf(format, args...)})
+	}
+	compiled := &singBoxRouting{}
+
+	nodes := make(map[string]bool, len(nodeTags))
+	for _, tag := range nodeTags {
+		nodes[tag] = true
+	}
+
+	// 代理组：与 Mihomo 生成器保持一致，禁用且有说明的分组不生成
+	emitted := make(map[string]bool)
+	var groups []ProxyGroupTemplate
+	for i, g := range t.Groups {
+		path := fmt.Sprintf("groups[%d]", i)
+		if !g.Enabled && g.Description != "" {
+			continue
+		}
+		if g.Name == "GLOBAL" {
+			continue // sing-box 的 Clash API 自动提供 GLOBAL
+		}
+		switch g.Type {
+		case "select", "url-test":
+		case "fallback", "load-balance":
+			issue(path, g.Name, "sing-box 不支持 %s 分组，%s 将按 urltest 生成", g.Type, g.Name)
+		default:
+			issue(path, g.Name, "sing-box 不支持 %s 分组，已跳过 %s", g.Type, g.Name)
+			continue
+		}
+		emitted[g.Name] = true
+		groups = append(groups, g)
+	}
+
+	for _, g := range groups {
+		outbound := SBOutbound{Tag: g.Name, Type: "selector"}
+		if g.Type != "select" {
+			outbound.Type = "urltest"
+			outbound.URL = g.URL
+			if g.Interval > 0 {
+				outbound.Interval = strconv.Itoa(g.Interval) + "s"
+			}
+			outbound.Tolerance = g.Tolerance
+		}
+
+		if g.UseAll {
+			outbound.Outbounds = filterGroupMembers(g.Filter, nodeTags, manualTags)
+		} else {
+			for _, member := range g.Proxies {
+				switch {
+				case member == "DIRECT":
+					outbound.Outbounds = append(outbound.Outbounds, "direct")
+				case member == "REJECT" || member == "REJECT-DROP":
+					outbound.Outbounds = append(outbound.Outbounds, "block")
+				case emitted[member] || nodes[member]:
+					outbound.Outbounds = append(outbound.Outbounds, member)
+				}
+			}
+		}
+		if len(outbound.Outbounds) == 0 {
+			outbound.Outbounds = []string{"direct"}
+		}
+		compiled.Groups = append(compiled.Groups, outbound)
+	}
+
+	// 规则集
+	for i, rs := range t.RuleSets {
+		if ruleSet, ok := singBoxRuleSetFromRouting(rs); ok {
+			compiled.RuleSets = append(compiled.RuleSets, ruleSet)
+		} else {
+			issue(fmt.Sprintf("ruleSets[%d]", i), rs.Name, "规则集 %s 没有 sing-box 可用的来源", rs.Name)
+		}
+	}
+
+	converter := &singBoxRuleConverter{subRules: t.SubRules, ruleSetTag: t.singBoxRuleSetTag}
+	targetOK := func(rule SBRouteRule) bool {
+		return rule.Outbound == "" || rule.Outbound == "direct" || emitted[rule.Outbound] || nodes[rule.Outbound]
+	}
+
+	// 路由规则：MATCH 对应 route.final
+	for i, r := range t.Rules {
+		path := fmt.Sprintf("rules[%d]", i)
+		if strings.EqualFold(r.Type, "MATCH") {
+			final := SBRouteRule{}
+			if err := setSingBoxRuleTarget(&final, r.Proxy); err != nil || !targetOK(final) {
+				issue(path, r.Proxy, "兜底出站 %s 在 sing-box 中不存在", r.Proxy)
+				break
+			}
+			compiled.Final = final.Outbound
+			if final.Action == "reject" {
+				compiled.Final = "block"
+			}
+			break
+		}
+
+		rules, err := converter.convert(r, 0)
+		if err == nil {
+			for _, rule := range rules {
+				if !targetOK(rule) {
+					err = fmt.Errorf("出站 %s 在 sing-box 中不存在", rule.Outbound)
+					break
+				}
+			}
+		}
+		if err != nil {
+			issue(path, r.Payload, "规则 %s 无法用于 sing-box: %v", formatMihomoRule(r), err)
+			continue
+		}
+		compiled.Rules = append(compiled.Rules, rules...)
+	}
+
+	// DNS 策略
+	proxyServer, directServer := "proxyDns", "localDns"
+	if fakeIP {
+		proxyServer, directServer = "google", "local"
+	}
+	for i, r := range t.DNS.Rules {
+		path := fmt.Sprintf("dns.rules[%d]", i)
+		var rule SBDNSRule
+		switch strings.ToUpper(r.Type) {
+		case "GEOSITE":
+			rule.RuleSet = "geosite-" + strings.ToLower(r.Payload)
+		case "DOMAIN":
+			rule.Domain = []string{r.Payload}
+		case "DOMAIN-SUFFIX":
+			rule.DomainSuffix = []string{strings.TrimPrefix(r.Payload, ".")}
+		case "RULE-SET":
+			tag, ok := t.singBoxRuleSetTag(r.Payload)
+			if !ok {
+				issue(path, r.Payload, "规则集 %s 没有 sing-box 可用的来源", r.Payload)
+				continue
+			}
+			rule.RuleSet = tag
+		default:
+			issue(path, r.Payload, "sing-box DNS 规则不支持 %s 匹配", r.Type)
+			continue
+		}
+		switch r.Server {
+		case DNSServerDirect:
+			rule.Server = directServer
+		case DNSServerProxy:
+			rule.Server = proxyServer
+		case DNSServerFakeIP:
+			rule.Server = proxyServer
+			if fakeIP {
+				rule.Server = "fakeip"
+				rule.QueryType = []string{"A", "AAAA"}
+			}
+		case DNSServerReject:
+			rule.Action = "reject"
+		default:
+			issue(path, r.Server, "未知的 DNS 服务器 %s", r.Server)
+			continue
+		}
+		compiled.DNSRules = append(compiled.DNSRules, rule)
+	}
+
+	return compiled, issues
+}
+
+// singBoxRuleSetTag 规则集名称到 sing-box 标签：模板中有 sing-box 来源时使用同名标签，否则回退到内置映射
+func (t *RoutingTemplate) singBoxRuleSetTag(name string) (string, bool) {
+	if rs := t.findRuleSet(name); rs != nil {
+		_, ok := singBoxRuleSetFromRouting(*rs)
+		return name, ok
+	}
+	return singBoxRuleSetTag(name)
+}
+
+// singBoxRuleSetFromRouting 生成 sing-box 规则集定义（优先使用本地文件）
+func singBoxRuleSetFromRouting(rs RoutingRuleSet) (SBRuleSet, bool) {
+	if kind, code, ok := parseGeoRef(rs.Geo); ok {
+		file := kind + "-" + code + ".srs"
+		if localPath := GetSingBoxRulesetDir() + "/" + file; fileExists(localPath) {
+			return SBRuleSet{Tag: rs.Name, Type: "local", Format: "binary", Path: localPath}, true
+		}
+		baseURL := singBoxGeositeURL
+		if kind == "geoip" {
+			baseURL = singBoxGeoIPURL
+		}
+		return SBRuleSet{Tag: rs.Name, Type: "remote", Format: "binary", URL: baseURL + "/" + file}, true
+	}
+	if rs.SingBoxURL != "" {
+		format := "binary"
+		if strings.HasSuffix(rs.SingBoxURL, ".json") {
+			format = "source"
+		}
+		return SBRuleSet{Tag: rs.Name, Type: "remote", Format: format, URL: rs.SingBoxURL}, true
+	}
+	return SBRuleSet{}, false
+}
+
+// filterGroupMembers 按 Mihomo 分组的 Filter 语义筛选节点
+func filterGroupMembers(filter string, nodeTags, manualTags []string) []string {
+	if filter == "__MANUAL__" {
+		return append([]string(nil), manualTags...)
+	}
+	if filter == "" {
+		return append([]string(nil), nodeTags...)
+	}
+	re, err := regexp.Compile(filter)
+	if err != nil {
+		return append([]string(nil), nodeTags...)
+	}
+	var members []string
+	for _, tag := range nodeTags {
+		if re.MatchString(tag) {
+			members = append(members, tag)
+		}
+	}
+	// 与 Mihomo 一致：没匹配到任何节点时使用全部节点
+	if len(members) == 0 {
+		members = append(members, nodeTags...)
+	}
+	return members
+}
+
+// TranslationReport 汇总两个核心都无法翻译的功能
+func (t *RoutingTemplate) TranslationReport(nodes []ProxyNode, fakeIP bool) []RoutingIssue {
+	var nodeTags, manualTags []string
+	for _, n := range nodes {
+		nodeTags = append(nodeTags, n.Name)
+		if n.IsManual {
+			manualTags = append(manualTags, n.Name)
+		}
+	}
+	_, _, issues := t.CompileMihomo()
+	_, singBoxIssues := t.CompileSingBox(nodeTags, manualTags, fakeIP)
+	return append(issues, singBoxIssues...)
+}
+
+// syncFromConfigTemplate 将 Mihomo 模板的编辑同步回统一模板
+// 内置规则集保持不变，其他规则集按规则提供者更新，已有的 sing-box 来源会被保留
+func (t *RoutingTemplate) syncFromConfigTemplate(c *ConfigTemplate) {
+	t.Groups = append([]ProxyGroupTemplate(nil), c.ProxyGroups...)
+	t.Rules = append([]RuleTemplate(nil), c.Rules...)
+	t.SubRules = c.SubRules
+
+	builtin := make(map[string]bool)
+	for _, source := range defaultRuleProviderSources {
+		builtin[source.name] = true
+	}
+	var ruleSets []RoutingRuleSet
+	for _, rs := range t.RuleSets {
+		if builtin[rs.Name] {
+			ruleSets = append(ruleSets, rs)
+		}
+	}
+	for _, p := range c.RuleProviders {
+		if builtin[p.Name] {
+			continue
+		}
+		updated := routingRuleSetFromProvider(p)
+		if existing := t.findRuleSet(p.Name); existing != nil && updated.Geo == "" {
+			updated.SingBoxURL = existing.SingBoxURL
+		}
+		ruleSets = append(ruleSets, updated)
+	}
+	t.RuleSets = ruleSets
+}
+
+// ============================================================================
+// 从现有模板迁移
+// ============================================================================
+
+// mihomoGeoURLPattern 识别 meta-rules-dat 的 GEO 规则集地址
+var mihomoGeoURLPattern = regexp.MustCompile(`/(geosite|geoip)/([^/]+)\.mrs$`)
+
+// routingRuleSetFromProvider 将 Mihomo 规则提供者转换为统一规则集（识别 GEO 规则集地址）
+func routingRuleSetFromProvider(p RuleProviderTemplate) RoutingRuleSet {
+	rs := RoutingRuleSet{
+		Name:        p.Name,
+		Behavior:    p.Behavior,
+		Interval:    p.Interval,
+		Description: p.Description,
+	}
+	if m := mihomoGeoURLPattern.FindStringSubmatch(p.URL); p.Type != "file" && m != nil {
+		rs.Geo = m[1] + ":" + m[2]
+	} else {
+		rs.MihomoURL = p.URL
+		rs.MihomoFormat = p.Format
+		if p.Type == "file" {
+			rs.MihomoType = "file"
+		}
+	}
+	// 只保存自定义路径，默认路径在编译时按名称与格式生成
+	format := p.Format
+	switch {
+	case rs.Geo != "":
+		format = "mrs"
+	case format == "" && p.Type == "file":
+		format = ruleSetFormatFromURL(p.Path)
+	case format == "":
+		format = ruleSetFormatFromURL(p.URL)
+	}
+	if p.Path != "" && p.Path != defaultRuleSetPath(p.Name, format) {
+		rs.MihomoPath = p.Path
+	}
+	return rs
+}
+
+// defaultRuleSetPath Mihomo 规则集的默认保存路径
+func defaultRuleSetPath(name, format string) string {
+	ext := map[string]string{"mrs": ".mrs", "yaml": ".yaml", "text": ".list"}[format]
+	return "./ruleset/" + name + ext
+}
+
+// MigrateRoutingTemplate 由现有 Mihomo 模板与 sing-box 模板生成统一模板
+// Mihomo 模板为主，sing-box 模板中独有的代理组、规则与规则集会被合并进来
+func MigrateRoutingTemplate(mihomo *ConfigTemplate, singbox *SingBoxTemplate) (*RoutingTemplate, []RoutingIssue) {
+	var issues []RoutingIssue
+	note := func(path, target, format string, args ...interface{}) {
+		issues = append(issues, RoutingIssue{Core: "migration", Path: path, Target: target, Message: fmt.Sprintf(format, args...)})
+	}
+
+	t := &RoutingTemplate{
+		Groups:   append([]ProxyGroupTemplate(nil), mihomo.ProxyGroups...),
+		Rules:    append([]RuleTemplate(nil), mihomo.Rules...),
+		SubRules: mihomo.SubRules,
+	}
+
+	// 规则集：模板中的规则提供者 + 生成器内置规则集
+	for _, p := range mihomo.RuleProviders {
+		t.RuleSets = append(t.RuleSets, routingRuleSetFromProvider(p))
+	}
+	for _, source := range defaultRuleProviderSources {
+		if t.findRuleSet(source.name) != nil {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(strings.TrimSuffix(source.urlPath, ".mrs"), "/"), "/")
+		if len(parts) != 2 {
+			continue
+		}
+		t.RuleSets = append(t.RuleSets, RoutingRuleSet{Name: source.name, Behavior: source.behavior, Geo: parts[0] + ":" + parts[1]})
+	}
+
+	if singbox == nil {
+		return t, issues
+	}
+
+	// sing-box 规则集：GEO 规则集与已有规则集合并，其他规则集按标签新增
+	aliases := make(map[string]string) // sing-box 标签 -> 统一模板规则集名称
+	singBoxOnly := make(map[string]bool)
+	for i, rs := range singbox.RuleSets {
+		geo := ""
+		if strings.HasPrefix(rs.Tag, "geosite-") || strings.HasPrefix(rs.Tag, "geoip-") {
+			idx := strings.Index(rs.Tag, "-")
+			geo = rs.Tag[:idx] + ":" + rs.Tag[idx+1:]
+		}
+		if geo != "" {
+			for _, existing := range t.RuleSets {
+				if existing.Geo == geo {
+					aliases[rs.Tag] = existing.Name
+					break
+				}
+			}
+			if aliases[rs.Tag] != "" {
+				continue
+			}
+		}
+		if existing := t.findRuleSet(rs.Tag); existing != nil {
+			aliases[rs.Tag] = existing.Name
+			if existing.SingBoxURL == "" && existing.Geo == "" {
+				existing.SingBoxURL = rs.URL
+			}
+			continue
+		}
+		added := RoutingRuleSet{Name: rs.Tag, Geo: geo}
+		if geo == "" {
+			added.SingBoxURL = rs.URL
+			note(fmt.Sprintf("singbox.ruleSets[%d]", i), rs.Tag, "规则集 %s 仅有 sing-box 来源", rs.Tag)
+		}
+		if strings.HasPrefix(rs.Tag, "geoip-") {
+			added.Behavior = "ipcidr"
+		} else {
+			added.Behavior = "domain"
+		}
+		t.RuleSets = append(t.RuleSets, added)
+		aliases[rs.Tag] = rs.Tag
+		singBoxOnly[rs.Tag] = true
+	}
+
+	// sing-box 出站名称映射到 Mihomo 名称
+	mapOutbound := func(name string) string {
+		switch name {
+		case "direct":
+			return "DIRECT"
+		case "block":
+			return "REJECT"
+		}
+		if mapped, ok := singBoxGroupNames[name]; ok {
+			return mapped
+		}
+		return name
+	}
+
+	// sing-box 独有的代理组
+	groupNames := make(map[string]bool)
+	for _, g := range t.Groups {
+		groupNames[g.Name] = true
+	}
+	for i, g := range singbox.ProxyGroups {
+		name := mapOutbound(g.Tag)
+		if !g.Enabled || groupNames[name] || name == "DIRECT" || name == "REJECT" {
+			continue
+		}
+		group := ProxyGroupTemplate{
+			Name:      name,
+			Type:      "select",
+			Icon:      g.Icon,
+			Enabled:   true,
+			URL:       g.URL,
+			Tolerance: g.Tolerance,
+			UseAll:    len(g.Outbounds) == 0,
+		}
+		if g.Type == "urltest" {
+			group.Type = "url-test"
+			if d, err := time.ParseDuration(g.Interval); err == nil {
+				group.Interval = int(d.Seconds())
+			}
+		}
+		for _, member := range g.Outbounds {
+			group.Proxies = append(group.Proxies, mapOutbound(member))
+		}
+		t.Groups = append(t.Groups, group)
+		groupNames[name] = true
+		note(fmt.Sprintf("singbox.proxyGroups[%d]", i), name, "合并了仅存在于 sing-box 模板的出站组 %s", name)
+	}
+
+	// sing-box 独有的规则：插入到 MATCH 之前
+	covered := make(map[string]bool)
+	for _, r := range t.Rules {
+		switch strings.ToUpper(r.Type) {
+		case "RULE-SET":
+			covered[r.Payload] = true
+			if rs := t.findRuleSet(r.Payload); rs != nil && rs.Geo != "" {
+				covered[rs.Geo] = true
+			}
+		case "GEOSITE", "GEOIP":
+			covered[strings.ToLower(r.Type+":"+r.Payload)] = true
+		}
+	}
+	var extra []RuleTemplate
+	for i, r := range singbox.Rules {
+		if r.Outbound == "" {
+			note(fmt.Sprintf("singbox.rules[%d]", i), r.Action, "sing-box 动作规则 %s 无法迁移", r.Action)
+			continue
+		}
+		for _, tag := range singBoxRuleSetRefs(r.RuleSet) {
+			name := aliases[tag]
+			if name == "" {
+				note(fmt.Sprintf("singbox.rules[%d]", i), tag, "规则引用的规则集 %s 不存在", tag)
+				continue
+			}
+			rs := t.findRuleSet(name)
+			if covered[name] || (rs != nil && rs.Geo != "" && covered[rs.Geo]) {
+				continue
+			}
+			covered[name] = true
+			extra = append(extra, RuleTemplate{Type: "RULE-SET", Payload: name, Proxy: mapOutbound(r.Outbound), Description: "迁移自 sing-box 模板"})
+			note(fmt.Sprintf("singbox.rules[%d]", i), name, "合并了仅存在于 sing-box 模板的规则 %s", name)
+		}
+	}
+	if len(extra) > 0 {
+		insertAt := len(t.Rules)
+		for i, r := range t.Rules {
+			if strings.EqualFold(r.Type, "MATCH") {
+				insertAt = i
+				break
+			}
+		}
+		rules := make([]RuleTemplate, 0, len(t.Rules)+len(extra))
+		rules = append(rules, t.Rules[:insertAt]...)
+		rules = append(rules, extra...)
+		rules = append(rules, t.Rules[insertAt:]...)
+		t.Rules = rules
+	}
+
+	// 仅存在于 sing-box 模板且未被任何规则引用的规则集不迁移
+	referenced := make(map[string]bool)
+	for _, r := range t.Rules {
+		for _, name := range ruleProviderRefs(r) {
+			referenced[name] = true
+		}
+	}
+	for _, rules := range t.SubRules {
+		for _, r := range rules {
+			for _, name := range ruleProviderRefs(r) {
+				referenced[name] = true
+			}
+		}
+	}
+	ruleSets := t.RuleSets[:0]
+	for _, rs := range t.RuleSets {
+		if singBoxOnly[rs.Name] && !referenced[rs.Name] {
+			continue
+		}
+		ruleSets = append(ruleSets, rs)
+	}
+	t.RuleSets = ruleSets
+
+	return t, issues
+}
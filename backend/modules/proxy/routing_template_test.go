@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRuleProviderRoundTrip(t *testing.T) {
+	tests := []struct {
+		name     string
+		provider RuleProviderTemplate
+	}{
+		{
+			name:     "http 默认路径",
+			provider: RuleProviderTemplate{Name: "ads", Type: "http", Behavior: "domain", URL: "https://example.com/ads.yaml", Path: "./ruleset/ads.yaml", Format: "yaml", Interval: 3600},
+		},
+		{
+			name:     "http 自定义路径",
+			provider: RuleProviderTemplate{Name: "ads", Type: "http", Behavior: "domain", URL: "https://example.com/ads.txt", Path: "./custom/ads.txt", Format: "text", Interval: 3600},
+		},
+		{
+			name:     "file 规则集",
+			provider: RuleProviderTemplate{Name: "local", Type: "file", Behavior: "classical", Path: "./rules/local.yaml", Format: "yaml", Interval: 3600},
+		},
+		{
+			name:     "file 规则集未指定格式",
+			provider: RuleProviderTemplate{Name: "local", Type: "file", Behavior: "domain", Path: "./rules/local.list", Interval: 3600},
+		},
+		{
+			name:     "GEO 规则集",
+			provider: RuleProviderTemplate{Name: "cn", Type: "http", Behavior: "domain", URL: mihomoGeoBaseURL + "/geosite/cn.mrs", Path: "./ruleset/cn.mrs", Format: "mrs", Interval: 3600},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routing := &RoutingTemplate{}
+			routing.syncFromConfigTemplate(&ConfigTemplate{RuleProviders: []RuleProviderTemplate{tt.provider}})
+			compiled, _, issues := routing.CompileMihomo()
+			for _, issue := range issues {
+				if issue.Target == tt.provider.Name {
+					t.Errorf("issue: %s", issue.Message)
+				}
+			}
+			var got *RuleProviderTemplate
+			for i := range compiled.RuleProviders {
+				if compiled.RuleProviders[i].Name == tt.provider.Name {
+					got = &compiled.RuleProviders[i]
+				}
+			}
+			if got == nil {
+				t.Fatalf("provider %s dropped", tt.provider.Name)
+			}
+			want := tt.provider
+			if want.Format == "" {
+				want.Format = "text"
+			}
+			if got.Type != want.Type || got.Path != want.Path || got.URL != want.URL || got.Format != want.Format {
+				t.Errorf("provider = %+v, want %+v", *got, want)
+			}
+		})
+	}
+}
+
+func TestRoutingSyncFailureKeepsTemplate(t *testing.T) {
+	dir := t.TempDir()
+	s := NewService(dir)
+	routing, _ := MigrateRoutingTemplate(s.GetConfigTemplate(), nil)
+	if _, err := s.UpdateRoutingTemplate(routing); err != nil {
+		t.Fatalf("UpdateRoutingTemplate: %v", err)
+	}
+	before := len(s.GetConfigTemplate().Rules)
+
+	// 用目录占据模板文件路径，使保存失败
+	path := filepath.Join(dir, RoutingTemplateFile)
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(path, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	rules := []RuleTemplate{{Type: "MATCH", Proxy: "DIRECT"}}
+	if err := s.UpdateRules(rules); err == nil {
+		t.Fatal("UpdateRules succeeded, want save error")
+	}
+	if got := len(s.GetConfigTemplate().Rules); got != before {
+		t.Errorf("rules = %d after failed save, want %d", got, before)
+	}
+	if got := len(s.GetRoutingTemplate().Rules); got != before {
+		t.Errorf("routing rules = %d after failed save, want %d", got, before)
+	}
+}
@@ -0,0 +1,410 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 逻辑规则类型
+var logicalRuleTypes = map[string]bool{
+	"AND": true, "OR": true, "NOT": true,
+}
+
+// maxRuleDepth 逻辑规则/子规则最大嵌套深度
+const maxRuleDepth = 8
+
+// defaultRuleKeys 获取默认规则的 Type:Payload 集合
+func defaultRuleKeys() map[string]bool {
+	keys := make(map[string]bool)
+	for _, r := range GetDefaultRules() {
+		keys[r.Type+":"+r.Payload] = true
+	}
+	return keys
+}
+
+// isCustomRule 判断是否为需要转换给 sing-box 的用户自定义规则
+// 默认规则与 Mihomo 规则集规则由 sing-box 内置路由负责
+func isCustomRule(r RuleTemplate, defaults map[string]bool) bool {
+	return !defaults[r.Type+":"+r.Payload] && !strings.EqualFold(r.Type, "RULE-SET")
+}
+
+// customRuleTemplates 提取用户自定义规则
+func customRuleTemplates(rules []RuleTemplate) []RuleTemplate {
+	defaults := defaultRuleKeys()
+	var custom []RuleTemplate
+	for _, r := range rules {
+		if isCustomRule(r, defaults) {
+			custom = append(custom, r)
+		}
+	}
+	return custom
+}
+
+// formatMihomoRule 将规则模板转换为 Mihomo 规则字符串
+func formatMihomoRule(t RuleTemplate) string {
+	ruleType := strings.ToUpper(t.Type)
+	switch {
+	case ruleType == "MATCH":
+		// MATCH 规则不需要 Payload
+		return t.Type + "," + t.Proxy
+	case logicalRuleTypes[ruleType] || ruleType == "SUB-RULE":
+		// 逻辑规则的 no-resolve 写在子条件中
+		return ruleType + "," + strings.TrimSpace(t.Payload) + "," + t.Proxy
+	case t.NoResolve:
+		return t.Type + "," + t.Payload + "," + t.Proxy + ",no-resolve"
+	}
+	return t.Type + "," + t.Payload + "," + t.Proxy
+}
+
+// parseLogicalPayload 解析逻辑规则载荷，如 ((DOMAIN,a.com),(NETWORK,UDP))
+// 返回的子条件不含目标策略
+func parseLogicalPayload(payload string) ([]RuleTemplate, error) {
+	inner, err := unwrapParens(payload)
+	if err != nil {
+		return nil, err
+	}
+	if inner == "" {
+		return nil, fmt.Errorf("逻辑规则缺少子条件")
+	}
+
+	var conditions []RuleTemplate
+	for _, item := range splitRuleFields(inner) {
+		condition, err := parseRuleCondition(item)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, condition)
+	}
+	return conditions, nil
+}
+
+// parseRuleCondition 解析单个括号条件，如 (DOMAIN,a.com) 或 (AND,((...),(...)))
+func parseRuleCondition(item string) (RuleTemplate, error) {
+	inner, err := unwrapParens(item)
+	if err != nil {
+		return RuleTemplate{}, err
+	}
+	fields := splitRuleFields(inner)
+	if len(fields) < 2 || fields[0] == "" {
+		return RuleTemplate{}, fmt.Errorf("条件格式错误: %s", item)
+	}
+	condition := RuleTemplate{Type: strings.ToUpper(fields[0]), Payload: fields[1]}
+	for _, extra := range fields[2:] {
+		if strings.EqualFold(extra, "no-resolve") {
+			condition.NoResolve = true
+		}
+	}
+	return condition, nil
+}
+
+// unwrapParens 去除最外层括号，并校验括号配对
+func unwrapParens(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if len(s) < 2 || s[0] != '(' || s[len(s)-1] != ')' {
+		return "", fmt.Errorf("条件必须用括号包裹: %s", s)
+	}
+	depth := 0
+	for i, ch := range s {
+		switch ch {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 && i != len(s)-1 {
+				return "", fmt.Errorf("括号不匹配: %s", s)
+			}
+		}
+	}
+	if depth != 0 {
+		return "", fmt.Errorf("括号不匹配: %s", s)
+	}
+	return strings.TrimSpace(s[1 : len(s)-1]), nil
+}
+
+// ValidateRuleTemplate 校验规则模板能否被 Mihomo 正确解析
+// 未知规则类型原样输出给核心，不在此处报错
+func ValidateRuleTemplate(t RuleTemplate, subRules map[string][]RuleTemplate) error {
+	ruleType := strings.ToUpper(t.Type)
+	switch ruleType {
+	case "MATCH":
+		return nil
+	case "SUB-RULE":
+		if _, ok := subRules[t.Proxy]; !ok {
+			return fmt.Errorf("子规则 %s 不存在", t.Proxy)
+		}
+		condition, err := parseRuleCondition(t.Payload)
+		if err != nil {
+			return err
+		}
+		return validateRuleCondition(condition, 0)
+	}
+	return validateRuleCondition(RuleTemplate{Type: ruleType, Payload: t.Payload}, 0)
+}
+
+// validateRuleCondition 校验规则条件（不含目标策略）
+func validateRuleCondition(t RuleTemplate, depth int) error {
+	if depth > maxRuleDepth {
+		return fmt.Errorf("逻辑规则嵌套过深")
+	}
+	ruleType := strings.ToUpper(t.Type)
+	payload := strings.TrimSpace(t.Payload)
+
+	switch ruleType {
+	case "AND", "OR", "NOT":
+		conditions, err := parseLogicalPayload(payload)
+		if err != nil {
+			return fmt.Errorf("%s 规则: %w", ruleType, err)
+		}
+		if ruleType == "NOT" && len(conditions) != 1 {
+			return fmt.Errorf("NOT 规则只能包含一个条件")
+		}
+		if ruleType != "NOT" && len(conditions) < 2 {
+			return fmt.Errorf("%s 规则至少需要两个条件", ruleType)
+		}
+		for _, condition := range conditions {
+			if err := validateRuleCondition(condition, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case "MATCH", "SUB-RULE":
+		if depth > 0 {
+			return fmt.Errorf("%s 不能作为逻辑规则的条件", ruleType)
+		}
+		return nil
+	}
+
+	if payload == "" {
+		return fmt.Errorf("%s 规则缺少内容", ruleType)
+	}
+	switch ruleType {
+	case "IP-CIDR", "IP-CIDR6", "SRC-IP-CIDR":
+		if _, _, err := net.ParseCIDR(payload); err != nil {
+			return fmt.Errorf("%s 规则的 CIDR 无效: %s", ruleType, payload)
+		}
+	case "DST-PORT", "SRC-PORT", "IN-PORT":
+		if _, _, err := parsePortSpec(payload); err != nil {
+			return fmt.Errorf("%s 规则: %w", ruleType, err)
+		}
+	case "NETWORK":
+		if n := strings.ToLower(payload); n != "tcp" && n != "udp" {
+			return fmt.Errorf("NETWORK 规则只支持 tcp 或 udp")
+		}
+	case "DOMAIN-REGEX":
+		if _, err := regexp.Compile(payload); err != nil {
+			return fmt.Errorf("DOMAIN-REGEX 正则无效: %v", err)
+		}
+	}
+	return nil
+}
+
+// parsePortSpec 解析端口表达式（80、1000-2000、80/443 组合）
+// 返回单个端口列表与 sing-box 格式的端口范围（1000:2000）
+func parsePortSpec(spec string) ([]int, []string, error) {
+	var ports []int
+	var ranges []string
+	for _, part := range strings.Split(spec, "/") {
+		part = strings.TrimSpace(part)
+		if idx := strings.Index(part, "-"); idx > 0 {
+			start, err1 := strconv.Atoi(part[:idx])
+			end, err2 := strconv.Atoi(part[idx+1:])
+			if err1 != nil || err2 != nil || start < 1 || end > 65535 || start > end {
+				return nil, nil, fmt.Errorf("端口范围无效: %s", part)
+			}
+			ranges = append(ranges, fmt.Sprintf("%d:%d", start, end))
+			continue
+		}
+		port, err := strconv.Atoi(part)
+		if err != nil || port < 1 || port > 65535 {
+			return nil, nil, fmt.Errorf("端口无效: %s", part)
+		}
+		ports = append(ports, port)
+	}
+	return ports, ranges, nil
+}
+
+// singBoxRuleConverter Mihomo 规则模板到 sing-box 路由规则的转换器
+type singBoxRuleConverter struct {
+	subRules   map[string][]RuleTemplate
+	ruleSetTag func(name string) (string, bool) // RULE-SET 名称到 sing-box 规则集标签
+}
+
+// ConvertRuleToSingBox 将 Mihomo 规则模板转换为 sing-box 路由规则
+// SUB-RULE 会展开为多条 "条件 AND 子规则" 的逻辑规则；MATCH 对应 route.final，不在此转换
+func ConvertRuleToSingBox(t RuleTemplate, subRules map[string][]RuleTemplate) ([]SBRouteRule, error) {
+	c := &singBoxRuleConverter{subRules: subRules, ruleSetTag: singBoxRuleSetTag}
+	return c.convert(t, 0)
+}
+
+// convert 转换单条规则
+func (c *singBoxRuleConverter) convert(t RuleTemplate, depth int) ([]SBRouteRule, error) {
+	if depth > maxRuleDepth {
+		return nil, fmt.Errorf("子规则嵌套过深")
+	}
+	ruleType := strings.ToUpper(t.Type)
+	switch ruleType {
+	case "MATCH":
+		return nil, fmt.Errorf("MATCH 规则对应 sing-box 的 route.final，不能作为路由规则")
+	case "SUB-RULE":
+		return c.convertSubRule(t, depth)
+	}
+
+	rule, err := c.condition(RuleTemplate{Type: ruleType, Payload: t.Payload}, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := setSingBoxRuleTarget(&rule, t.Proxy); err != nil {
+		return nil, err
+	}
+	return []SBRouteRule{rule}, nil
+}
+
+// convertSubRule 展开 SUB-RULE：每条子规则与外层条件组成 AND 逻辑规则
+func (c *singBoxRuleConverter) convertSubRule(t RuleTemplate, depth int) ([]SBRouteRule, error) {
+	children, ok := c.subRules[t.Proxy]
+	if !ok {
+		return nil, fmt.Errorf("子规则 %s 不存在", t.Proxy)
+	}
+	condition, err := parseRuleCondition(t.Payload)
+	if err != nil {
+		return nil, err
+	}
+	outer, err := c.condition(condition, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []SBRouteRule
+	for _, child := range children {
+		if strings.EqualFold(child.Type, "MATCH") {
+			rule := outer
+			if err := setSingBoxRuleTarget(&rule, child.Proxy); err != nil {
+				return nil, err
+			}
+			rules = append(rules, rule)
+			break
+		}
+		converted, err := c.convert(child, depth+1)
+		if err != nil {
+			return nil, fmt.Errorf("子规则 %s: %w", t.Proxy, err)
+		}
+		for _, inner := range converted {
+			rule := SBRouteRule{Type: "logical", Mode: "and", Action: inner.Action, Outbound: inner.Outbound}
+			inner.Action, inner.Outbound = "", ""
+			rule.Rules = []SBRouteRule{outer, inner}
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+// condition 将规则条件转换为 sing-box 路由规则（不含动作）
+func (c *singBoxRuleConverter) condition(t RuleTemplate, depth int) (SBRouteRule, error) {
+	if err := validateRuleCondition(t, depth); err != nil {
+		return SBRouteRule{}, err
+	}
+	ruleType := strings.ToUpper(t.Type)
+	payload := strings.TrimSpace(t.Payload)
+
+	var rule SBRouteRule
+	switch ruleType {
+	case "DOMAIN":
+		rule.Domain = []string{payload}
+	case "DOMAIN-SUFFIX":
+		rule.DomainSuffix = []string{payload}
+	case "DOMAIN-KEYWORD":
+		rule.DomainKeyword = []string{payload}
+	case "DOMAIN-REGEX":
+		rule.DomainRegex = []string{payload}
+	case "IP-CIDR", "IP-CIDR6":
+		rule.IPCIDR = []string{payload}
+	case "SRC-IP-CIDR":
+		rule.SourceIPCIDR = []string{payload}
+	case "DST-PORT":
+		ports, ranges, _ := parsePortSpec(payload)
+		if len(ports) > 0 {
+			rule.Port = ports
+		}
+		rule.PortRange = ranges
+	case "SRC-PORT":
+		rule.SourcePort, rule.SourcePortRange, _ = parsePortSpec(payload)
+	case "NETWORK":
+		rule.Network = strings.ToLower(payload)
+	case "PROCESS-NAME":
+		rule.ProcessName = []string{payload}
+	case "PROCESS-PATH":
+		rule.ProcessPath = []string{payload}
+	case "GEOSITE":
+		rule.RuleSet = "geosite-" + strings.ToLower(payload)
+	case "GEOIP":
+		code := strings.ToLower(payload)
+		if code == "private" || code == "lan" {
+			rule.IPIsPrivate = true
+		} else {
+			rule.RuleSet = "geoip-" + code
+		}
+	case "RULE-SET":
+		tag, ok := c.ruleSetTag(payload)
+		if !ok {
+			return SBRouteRule{}, fmt.Errorf("规则集 %s 没有 sing-box 可用的来源", payload)
+		}
+		rule.RuleSet = tag
+	case "AND", "OR", "NOT":
+		conditions, _ := parseLogicalPayload(payload)
+		if ruleType == "NOT" {
+			inner, err := c.condition(conditions[0], depth+1)
+			if err != nil {
+				return SBRouteRule{}, err
+			}
+			return SBRouteRule{Type: "logical", Mode: "and", Rules: []SBRouteRule{inner}, Invert: true}, nil
+		}
+		rule = SBRouteRule{Type: "logical", Mode: strings.ToLower(ruleType)}
+		for _, condition := range conditions {
+			inner, err := c.condition(condition, depth+1)
+			if err != nil {
+				return SBRouteRule{}, err
+			}
+			rule.Rules = append(rule.Rules, inner)
+		}
+	case "IN-PORT":
+		return SBRouteRule{}, fmt.Errorf("sing-box 路由规则不支持按入站端口匹配（IN-PORT），请改用入站标签")
+	default:
+		return SBRouteRule{}, fmt.Errorf("sing-box 不支持 %s 规则", ruleType)
+	}
+	return rule, nil
+}
+
+// singBoxRuleSetTag 将内置 Mihomo 规则集映射为 sing-box 规则集标签（geosite-xxx / geoip-xxx）
+func singBoxRuleSetTag(name string) (string, bool) {
+	for _, source := range defaultRuleProviderSources {
+		if source.name != name {
+			continue
+		}
+		parts := strings.Split(strings.TrimPrefix(source.urlPath, "/"), "/")
+		if len(parts) != 2 {
+			return "", false
+		}
+		return parts[0] + "-" + strings.TrimSuffix(parts[1], ".mrs"), true
+	}
+	return "", false
+}
+
+// setSingBoxRuleTarget 设置 sing-box 规则的动作/出站
+func setSingBoxRuleTarget(rule *SBRouteRule, target string) error {
+	switch strings.ToUpper(target) {
+	case "DIRECT":
+		rule.Outbound = "direct"
+	case "REJECT", "REJECT-DROP":
+		rule.Action = "reject"
+	case "PASS", "COMPATIBLE":
+		return fmt.Errorf("sing-box 不支持 %s 策略", target)
+	case "":
+		return fmt.Errorf("规则缺少目标策略")
+	default:
+		rule.Outbound = target
+	}
+	return nil
+}
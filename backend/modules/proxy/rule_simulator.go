@@ -0,0 +1,584 @@
+package proxy
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RuleMatchRequest 规则匹配模拟请求
+type RuleMatchRequest struct {
+	Domain  string `json:"domain"`
+	IP      string `json:"ip"`
+	Port    int    `json:"port"`
+	SrcPort int    `json:"srcPort"` // 来源端口（用于 SRC-PORT）
+	InPort  int    `json:"inPort"`  // 入站端口（用于 IN-PORT）
+	Process string `json:"process"` // 进程名或完整路径
+	Network string `json:"network"` // tcp, udp（默认 tcp）
+	SrcIP   string `json:"srcIp"`   // 来源 IP（用于 SRC-IP-CIDR）
+	Resolve bool   `json:"resolve"` // 遇到 IP 规则且未提供 IP 时是否解析域名
+}
+
+// RuleMatchResult 规则匹配模拟结果
+type RuleMatchResult struct {
+	Mode            string           `json:"mode"`
+	Matched         bool             `json:"matched"`
+	RuleIndex       int              `json:"ruleIndex"` // 命中规则序号，-1 表示未命中
+	Rule            string           `json:"rule,omitempty"`
+	SubRule         string           `json:"subRule,omitempty"` // SUB-RULE 中命中的子规则
+	Target          string           `json:"target"`
+	Selection       string           `json:"selection,omitempty"`       // 目标组当前选中
+	SelectionSource string           `json:"selectionSource,omitempty"` // controller, config
+	Chain           []string         `json:"chain"`                     // 目标组到最终出口的链路
+	ResolvedIP      string           `json:"resolvedIp,omitempty"`
+	Skipped         []RuleSkipReason `json:"skipped,omitempty"` // 无法离线判断而跳过的规则
+}
+
+// RuleSkipReason 规则跳过原因
+type RuleSkipReason struct {
+	Index  int    `json:"index"`
+	Rule   string `json:"rule"`
+	Reason string `json:"reason"`
+}
+
+// ruleSimulator 离线规则匹配器
+type ruleSimulator struct {
+	dataDir   string
+	geo       *GeoDataReader
+	providers map[string]RuleProvider
+	sets      map[string]*ruleProviderSet
+	req       RuleMatchRequest
+	domain    string
+	ip        net.IP
+	srcIP     net.IP
+	procName  string
+	result    *RuleMatchResult
+}
+
+// ruleProviderSet 已加载的规则提供者内容
+type ruleProviderSet struct {
+	behavior string
+	entries  []string
+	geoCode  string // 回退到 GEO 数据库时的分类名
+	err      error
+}
+
+// SimulateRuleMatch 使用当前模板生成规则并离线模拟匹配（无需核心运行）
+func (s *Service) SimulateRuleMatch(req RuleMatchRequest) (*RuleMatchResult, error) {
+	if req.Domain == "" && req.IP == "" {
+		return nil, fmt.Errorf("domain 和 ip 至少需要提供一个")
+	}
+
+	nodes := s.listNodes()
+
+	config, err := s.configGenerator.GenerateConfig(nodes, s.buildGeneratorOptions())
+	if err != nil {
+		return nil, err
+	}
+	// 与实际生成的配置保持一致，合并用户覆写
+	if data, err := yaml.Marshal(config); err == nil {
+		if merged, err := applyMihomoOverride(s.dataDir, data); err == nil {
+			var overridden MihomoConfig
+			if yaml.Unmarshal(merged, &overridden) == nil {
+				config = &overridden
+			}
+		}
+	}
+
+	sim := &ruleSimulator{
+		dataDir:   s.dataDir,
+		geo:       s.geoData,
+		providers: config.RuleProviders,
+		sets:      make(map[string]*ruleProviderSet),
+		req:       req,
+		domain:    strings.ToLower(strings.TrimSuffix(strings.TrimSpace(req.Domain), ".")),
+		result:    &RuleMatchResult{Mode: config.Mode, RuleIndex: -1},
+	}
+	if req.IP != "" {
+		if sim.ip = net.ParseIP(strings.TrimSpace(req.IP)); sim.ip == nil {
+			return nil, fmt.Errorf("无效的 IP: %s", req.IP)
+		}
+	} else if ip := net.ParseIP(sim.domain); ip != nil {
+		sim.ip = ip
+		sim.domain = ""
+	}
+	if req.SrcIP != "" {
+		sim.srcIP = net.ParseIP(req.SrcIP)
+	}
+	if req.Process != "" {
+		sim.procName = filepath.Base(strings.ReplaceAll(req.Process, "\\", "/"))
+	}
+
+	result := sim.result
+	switch config.Mode {
+	case "global":
+		result.Target = "GLOBAL"
+	case "direct":
+		result.Target = "DIRECT"
+	default:
+		for i, rule := range config.Rules {
+			matched, err := sim.matchRule(rule, 0)
+			if err != nil {
+				result.Skipped = append(result.Skipped, RuleSkipReason{Index: i, Rule: rule, Reason: err.Error()})
+				continue
+			}
+			if !matched {
+				continue
+			}
+			target := ruleTarget(rule)
+			if strings.EqualFold(splitRuleFields(rule)[0], "SUB-RULE") {
+				subRule, subTarget, err := sim.matchSubRules(config.SubRules, target, 0)
+				if err != nil {
+					result.Skipped = append(result.Skipped, RuleSkipReason{Index: i, Rule: rule, Reason: err.Error()})
+					continue
+				}
+				if subRule == "" {
+					continue // 子规则均未命中，继续匹配后续规则
+				}
+				result.SubRule = subRule
+				target = subTarget
+			}
+			result.Matched = true
+			result.RuleIndex = i
+			result.Rule = rule
+			result.Target = target
+			break
+		}
+		if !result.Matched {
+			result.Target = "DIRECT"
+		}
+	}
+	if sim.ip != nil && req.IP == "" && sim.domain != "" {
+		result.ResolvedIP = sim.ip.String()
+	}
+
+	s.resolveSelectionChain(config, result)
+	return result, nil
+}
+
+// resolveSelectionChain 沿代理组选中项追溯最终出口
+// 核心运行时读取控制器中的实际选中，否则使用配置中的默认（第一个成员）
+func (s *Service) resolveSelectionChain(config *MihomoConfig, result *RuleMatchResult) {
+	groups := make(map[string]ProxyGroup, len(config.ProxyGroups))
+	for _, g := range config.ProxyGroups {
+		groups[g.Name] = g
+	}
+
+	s.mu.RLock()
+	running := s.running
+	s.mu.RUnlock()
+	controller := s.Controller()
+
+	current := result.Target
+	result.Chain = []string{current}
+	for depth := 0; depth < 16; depth++ {
+		group, ok := groups[current]
+		if !ok || len(group.Proxies) == 0 {
+			break
+		}
+
+		next, source := "", "config"
+		if running {
+			if info, err := controller.Proxy(current); err == nil && info.Now != "" {
+				next, source = info.Now, "controller"
+			}
+		}
+		if next == "" {
+			next = group.Proxies[0]
+		}
+
+		if depth == 0 {
+			result.Selection = next
+			result.SelectionSource = source
+		}
+		result.Chain = append(result.Chain, next)
+		current = next
+	}
+}
+
+// ruleTarget 获取规则的目标策略
+func ruleTarget(rule string) string {
+	parts := splitRuleFields(rule)
+	if len(parts) == 0 {
+		return ""
+	}
+	if strings.EqualFold(parts[0], "MATCH") {
+		if len(parts) > 1 {
+			return parts[1]
+		}
+		return ""
+	}
+	if len(parts) < 3 {
+		return ""
+	}
+	return parts[2]
+}
+
+// splitRuleFields 按逗号拆分规则，忽略括号内的逗号
+func splitRuleFields(rule string) []string {
+	var fields []string
+	depth, start := 0, 0
+	for i, ch := range rule {
+		switch ch {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				fields = append(fields, strings.TrimSpace(rule[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	return append(fields, strings.TrimSpace(rule[start:]))
+}
+
+// matchRule 判断单条规则是否命中（规则集中的 classical 条目可以没有目标）
+func (sim *ruleSimulator) matchRule(rule string, depth int) (bool, error) {
+	parts := splitRuleFields(rule)
+	ruleType := strings.ToUpper(parts[0])
+	if ruleType == "MATCH" || ruleType == "FINAL" {
+		return true, nil
+	}
+	if len(parts) < 2 {
+		return false, fmt.Errorf("规则格式错误")
+	}
+	return sim.matchCondition(ruleType, parts[1], hasNoResolve(parts[2:]), depth)
+}
+
+// hasNoResolve 判断规则选项中是否包含 no-resolve
+func hasNoResolve(options []string) bool {
+	for _, option := range options {
+		if strings.EqualFold(option, "no-resolve") {
+			return true
+		}
+	}
+	return false
+}
+
+// matchCondition 判断规则条件是否命中
+// noResolve 为 true 时 IP 类规则不解析域名，与 Mihomo 的 no-resolve 行为一致
+func (sim *ruleSimulator) matchCondition(ruleType, payload string, noResolve bool, depth int) (bool, error) {
+	switch ruleType {
+	case "DOMAIN":
+		return sim.domain != "" && sim.domain == strings.ToLower(payload), nil
+	case "DOMAIN-SUFFIX":
+		suffix := strings.ToLower(payload)
+		return sim.domain != "" && (sim.domain == suffix || strings.HasSuffix(sim.domain, "."+suffix)), nil
+	case "DOMAIN-KEYWORD":
+		return sim.domain != "" && strings.Contains(sim.domain, strings.ToLower(payload)), nil
+	case "DOMAIN-REGEX":
+		re, err := regexp.Compile(payload)
+		if err != nil {
+			return false, fmt.Errorf("正则无效: %v", err)
+		}
+		return sim.domain != "" && re.MatchString(sim.domain), nil
+	case "GEOSITE":
+		if sim.domain == "" {
+			return false, nil
+		}
+		return sim.geo.MatchGeoSite(payload, sim.domain)
+	case "GEOIP":
+		ip, err := sim.destinationIP(noResolve)
+		if ip == nil {
+			return false, err
+		}
+		return sim.geo.MatchGeoIP(payload, ip)
+	case "IP-CIDR", "IP-CIDR6":
+		ip, err := sim.destinationIP(noResolve)
+		if ip == nil {
+			return false, err
+		}
+		return cidrContains(payload, ip), nil
+	case "SRC-IP-CIDR":
+		return sim.srcIP != nil && cidrContains(payload, sim.srcIP), nil
+	case "DST-PORT":
+		return sim.req.Port > 0 && portMatches(payload, sim.req.Port), nil
+	case "SRC-PORT":
+		return sim.req.SrcPort > 0 && portMatches(payload, sim.req.SrcPort), nil
+	case "IN-PORT":
+		return sim.req.InPort > 0 && portMatches(payload, sim.req.InPort), nil
+	case "AND", "OR", "NOT":
+		return sim.matchLogical(ruleType, payload, depth)
+	case "SUB-RULE":
+		condition, err := parseRuleCondition(payload)
+		if err != nil {
+			return false, err
+		}
+		return sim.matchCondition(condition.Type, condition.Payload, condition.NoResolve, depth)
+	case "NETWORK":
+		network := strings.ToLower(sim.req.Network)
+		if network == "" {
+			network = "tcp"
+		}
+		return strings.EqualFold(payload, network), nil
+	case "PROCESS-NAME":
+		return sim.procName != "" && strings.EqualFold(sim.procName, payload), nil
+	case "PROCESS-PATH":
+		return sim.req.Process != "" && sim.req.Process == payload, nil
+	case "RULE-SET":
+		if depth > 0 {
+			return false, fmt.Errorf("规则集不支持嵌套")
+		}
+		return sim.matchRuleSet(payload, noResolve)
+	}
+	return false, fmt.Errorf("离线模拟暂不支持 %s 规则", ruleType)
+}
+
+// matchSubRules 在子规则中查找命中的规则，返回命中规则与目标
+func (sim *ruleSimulator) matchSubRules(subRules map[string][]string, name string, depth int) (string, string, error) {
+	if depth > maxRuleDepth {
+		return "", "", fmt.Errorf("子规则嵌套过深")
+	}
+	rules, ok := subRules[name]
+	if !ok {
+		return "", "", fmt.Errorf("子规则 %s 不存在", name)
+	}
+	for _, rule := range rules {
+		matched, err := sim.matchRule(rule, 0)
+		if err != nil {
+			return "", "", fmt.Errorf("子规则 %s: %v", name, err)
+		}
+		if !matched {
+			continue
+		}
+		target := ruleTarget(rule)
+		if strings.EqualFold(splitRuleFields(rule)[0], "SUB-RULE") {
+			return sim.matchSubRules(subRules, target, depth+1)
+		}
+		return rule, target, nil
+	}
+	return "", "", nil
+}
+
+// matchLogical 判断逻辑规则是否命中
+func (sim *ruleSimulator) matchLogical(ruleType, payload string, depth int) (bool, error) {
+	conditions, err := parseLogicalPayload(payload)
+	if err != nil {
+		return false, err
+	}
+	if ruleType == "NOT" {
+		if len(conditions) != 1 {
+			return false, fmt.Errorf("NOT 规则只能包含一个条件")
+		}
+		matched, err := sim.matchCondition(conditions[0].Type, conditions[0].Payload, conditions[0].NoResolve, depth)
+		return !matched, err
+	}
+
+	// 已能确定结果时忽略其他条件的错误
+	var firstErr error
+	for _, condition := range conditions {
+		matched, err := sim.matchCondition(condition.Type, condition.Payload, condition.NoResolve, depth)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if ruleType == "AND" && !matched {
+			return false, nil
+		}
+		if ruleType == "OR" && matched {
+			return true, nil
+		}
+	}
+	if firstErr != nil {
+		return false, firstErr
+	}
+	return ruleType == "AND", nil
+}
+
+// destinationIP 获取目标 IP，按需解析域名（noResolve 时只使用已知 IP）
+func (sim *ruleSimulator) destinationIP(noResolve bool) (net.IP, error) {
+	if sim.ip != nil {
+		return sim.ip, nil
+	}
+	if noResolve || sim.domain == "" || !sim.req.Resolve {
+		return nil, nil
+	}
+	ips, err := net.LookupIP(sim.domain)
+	if err != nil || len(ips) == 0 {
+		return nil, fmt.Errorf("域名解析失败: %v", err)
+	}
+	sim.ip = ips[0]
+	return sim.ip, nil
+}
+
+// matchRuleSet 判断是否命中规则集
+func (sim *ruleSimulator) matchRuleSet(name string, noResolve bool) (bool, error) {
+	set := sim.loadRuleSet(name)
+	if set.err != nil {
+		return false, set.err
+	}
+
+	// .mrs 二进制规则集无法离线读取，使用同源 GEO 数据库判断
+	if set.geoCode != "" {
+		if set.behavior == "ipcidr" {
+			ip, err := sim.destinationIP(noResolve)
+			if ip == nil {
+				return false, err
+			}
+			return sim.geo.MatchGeoIP(set.geoCode, ip)
+		}
+		if sim.domain == "" {
+			return false, nil
+		}
+		return sim.geo.MatchGeoSite(set.geoCode, sim.domain)
+	}
+
+	switch set.behavior {
+	case "domain":
+		if sim.domain == "" {
+			return false, nil
+		}
+		for _, entry := range set.entries {
+			if domainEntryMatches(strings.ToLower(entry), sim.domain) {
+				return true, nil
+			}
+		}
+	case "ipcidr":
+		ip, err := sim.destinationIP(noResolve)
+		if ip == nil {
+			return false, err
+		}
+		for _, entry := range set.entries {
+			if cidrContains(entry, ip) {
+				return true, nil
+			}
+		}
+	default:
+		for _, entry := range set.entries {
+			if matched, err := sim.matchRule(entry, 1); err == nil && matched {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// loadRuleSet 加载规则提供者内容（yaml/text）
+func (sim *ruleSimulator) loadRuleSet(name string) *ruleProviderSet {
+	if set, ok := sim.sets[name]; ok {
+		return set
+	}
+	set := &ruleProviderSet{}
+	sim.sets[name] = set
+
+	provider, ok := sim.providers[name]
+	if !ok {
+		set.err = fmt.Errorf("规则集 %s 未定义", name)
+		return set
+	}
+	set.behavior = provider.Behavior
+
+	path := provider.Path
+	if path != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(sim.dataDir, path)
+	}
+	if path == "" {
+		path = filepath.Join(sim.dataDir, "ruleset", name+".yaml")
+	}
+
+	candidates := []string{path}
+	if provider.Format == "mrs" || strings.HasSuffix(path, ".mrs") {
+		base := strings.TrimSuffix(path, filepath.Ext(path))
+		candidates = []string{base + ".yaml", base + ".yml", base + ".txt", base + ".list"}
+	}
+
+	for _, candidate := range candidates {
+		data, err := os.ReadFile(candidate)
+		if err != nil {
+			continue
+		}
+		set.entries = parseRuleSetEntries(data, candidate, provider.Format)
+		return set
+	}
+
+	for _, source := range defaultRuleProviderSources {
+		if source.name == name {
+			set.geoCode = strings.TrimSuffix(filepath.Base(source.urlPath), ".mrs")
+			return set
+		}
+	}
+
+	set.err = fmt.Errorf("规则集 %s 无可读取的本地文件（mrs 格式需提供同名 yaml/text 文件）", name)
+	return set
+}
+
+// parseRuleSetEntries 解析规则集文件内容
+func parseRuleSetEntries(data []byte, path, format string) []string {
+	if format == "yaml" || strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml") {
+		var doc struct {
+			Payload []string `yaml:"payload"`
+		}
+		if err := yaml.Unmarshal(data, &doc); err == nil && doc.Payload != nil {
+			return doc.Payload
+		}
+	}
+
+	var entries []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		entries = append(entries, strings.Trim(line, "'\""))
+	}
+	return entries
+}
+
+// domainEntryMatches 按 Mihomo domain 规则集语义匹配
+// +.a.com 匹配自身及所有子域，.a.com 仅匹配子域，*.a.com 匹配一级子域
+func domainEntryMatches(entry, domain string) bool {
+	switch {
+	case strings.HasPrefix(entry, "+."):
+		root := entry[2:]
+		return domain == root || strings.HasSuffix(domain, "."+root)
+	case strings.HasPrefix(entry, "*."):
+		root := entry[2:]
+		if !strings.HasSuffix(domain, "."+root) {
+			return false
+		}
+		return !strings.Contains(strings.TrimSuffix(domain, "."+root), ".")
+	case strings.HasPrefix(entry, "."):
+		return strings.HasSuffix(domain, entry)
+	}
+	return domain == entry
+}
+
+// cidrContains 判断 IP 是否在网段内（支持单个 IP）
+func cidrContains(cidr string, ip net.IP) bool {
+	cidr = strings.TrimSpace(cidr)
+	if !strings.Contains(cidr, "/") {
+		other := net.ParseIP(cidr)
+		return other != nil && other.Equal(ip)
+	}
+	_, network, err := net.ParseCIDR(cidr)
+	return err == nil && network.Contains(ip)
+}
+
+// portMatches 判断端口是否命中（支持 80、80-90、80/443 组合）
+func portMatches(payload string, port int) bool {
+	for _, part := range strings.FieldsFunc(payload, func(r rune) bool { return r == '/' || r == ',' }) {
+		part = strings.TrimSpace(part)
+		if idx := strings.Index(part, "-"); idx > 0 {
+			start, err1 := strconv.Atoi(part[:idx])
+			end, err2 := strconv.Atoi(part[idx+1:])
+			if err1 == nil && err2 == nil && port >= start && port <= end {
+				return true
+			}
+			continue
+		}
+		if n, err := strconv.Atoi(part); err == nil && n == port {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,542 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ============================================================================
+// 定时策略：按 cron 表达式切换运行模式、选择器节点、配置方案、规则集，或启停核心
+// ============================================================================
+
+// 定时任务动作
+const (
+	ScheduleActionMode    = "mode"    // 切换运行模式（运行中通过控制器热切换）
+	ScheduleActionSelect  = "select"  // 通过控制器切换选择器分组的节点
+	ScheduleActionProfile = "profile" // 激活配置方案
+	ScheduleActionRuleSet = "ruleset" // 启用或停用规则集
+	ScheduleActionStart   = "start"   // 启动核心
+	ScheduleActionStop    = "stop"    // 停止核心
+	ScheduleActionRestart = "restart" // 重启核心
+)
+
+// scheduleLogLimit 执行记录保留条数
+const scheduleLogLimit = 200
+
+// ScheduleRule 定时任务
+type ScheduleRule struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Enabled bool   `json:"enabled"`
+	Cron    string `json:"cron"`   // 分 时 日 月 周，支持 * , - / 以及 @hourly/@daily/@weekly
+	Action  string `json:"action"` // mode, select, profile, ruleset, start, stop, restart
+
+	Mode      string `json:"mode,omitempty"`      // mode: rule/global/direct
+	Group     string `json:"group,omitempty"`     // select: 选择器分组
+	Proxy     string `json:"proxy,omitempty"`     // select: 目标节点或分组
+	ProfileID string `json:"profileId,omitempty"` // profile: 方案 ID
+	RuleSet   string `json:"ruleSet,omitempty"`   // ruleset: 规则集名称（Mihomo 规则提供者名称或 Sing-Box 规则集标签）
+	Enable    bool   `json:"enable,omitempty"`    // ruleset: true 启用，false 停用
+
+	LastRun *time.Time `json:"lastRun,omitempty"`
+	NextRun *time.Time `json:"nextRun,omitempty"` // 仅用于展示，不持久化
+}
+
+// ScheduleExecution 定时任务执行记录
+type ScheduleExecution struct {
+	RuleID   string    `json:"ruleId"`
+	RuleName string    `json:"ruleName"`
+	Action   string    `json:"action"`
+	Time     time.Time `json:"time"`
+	Manual   bool      `json:"manual,omitempty"` // 手动触发
+	Success  bool      `json:"success"`
+	Message  string    `json:"message"`
+}
+
+// scheduleStore 定时任务持久化结构
+type scheduleStore struct {
+	Rules []*ScheduleRule     `json:"rules"`
+	Logs  []ScheduleExecution `json:"logs"`
+}
+
+// Scheduler 定时任务调度器
+type Scheduler struct {
+	dataDir  string
+	service  *Service
+	profiles *ProfileManager
+	store    scheduleStore
+	mu       sync.Mutex
+	stopChan chan struct{}
+}
+
+// NewScheduler 创建调度器并启动调度循环
+func NewScheduler(dataDir string, service *Service, profiles *ProfileManager) *Scheduler {
+	s := &Scheduler{
+		dataDir:  dataDir,
+		service:  service,
+		profiles: profiles,
+		stopChan: make(chan struct{}),
+	}
+	s.load()
+	go s.loop()
+	return s
+}
+
+// Stop 停止调度循环
+func (s *Scheduler) Stop() {
+	close(s.stopChan)
+}
+
+// storePath 获取定时任务文件路径
+func (s *Scheduler) storePath() string {
+	return filepath.Join(s.dataDir, "schedules.json")
+}
+
+// load 加载定时任务
+func (s *Scheduler) load() {
+	data, err := os.ReadFile(s.storePath())
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &s.store); err != nil {
+		fmt.Printf("⚠️ 读取定时任务失败: %v\n", err)
+	}
+}
+
+// save 保存定时任务（调用者需持有锁）
+func (s *Scheduler) save() error {
+	rules := make([]*ScheduleRule, 0, len(s.store.Rules))
+	for _, r := range s.store.Rules {
+		item := *r
+		item.NextRun = nil
+		rules = append(rules, &item)
+	}
+	data, err := json.MarshalIndent(scheduleStore{Rules: rules, Logs: s.store.Logs}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.storePath(), data, 0644)
+}
+
+// loop 每分钟整点检查一次需要执行的任务
+func (s *Scheduler) loop() {
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Minute).Add(time.Minute)
+		select {
+		case <-time.After(next.Sub(now)):
+			s.runDue(next)
+		case <-s.stopChan:
+			return
+		}
+	}
+}
+
+// runDue 执行当前分钟匹配的任务，同一分钟内每个任务只执行一次
+func (s *Scheduler) runDue(at time.Time) {
+	s.mu.Lock()
+	var due []ScheduleRule
+	for _, r := range s.store.Rules {
+		if !r.Enabled {
+			continue
+		}
+		if r.LastRun != nil && !r.LastRun.Truncate(time.Minute).Before(at) {
+			continue
+		}
+		schedule, err := parseCron(r.Cron)
+		if err != nil || !schedule.matches(at) {
+			continue
+		}
+		due = append(due, *r)
+	}
+	s.mu.Unlock()
+
+	for _, r := range due {
+		s.execute(r, at, false)
+	}
+}
+
+// execute 执行任务并记录结果
+func (s *Scheduler) execute(rule ScheduleRule, at time.Time, manual bool) ScheduleExecution {
+	message, err := s.perform(rule)
+	entry := ScheduleExecution{
+		RuleID:   rule.ID,
+		RuleName: rule.Name,
+		Action:   rule.Action,
+		Time:     at,
+		Manual:   manual,
+		Success:  err == nil,
+		Message:  message,
+	}
+	if err != nil {
+		entry.Message = err.Error()
+		fmt.Printf("⚠️ 定时任务 %s 执行失败: %v\n", rule.Name, err)
+	} else {
+		fmt.Printf("⏰ 定时任务 %s: %s\n", rule.Name, message)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if r := s.find(rule.ID); r != nil {
+		lastRun := at
+		r.LastRun = &lastRun
+	}
+	s.store.Logs = append(s.store.Logs, entry)
+	if len(s.store.Logs) > scheduleLogLimit {
+		s.store.Logs = s.store.Logs[len(s.store.Logs)-scheduleLogLimit:]
+	}
+	if err := s.save(); err != nil {
+		fmt.Printf("⚠️ 保存定时任务失败: %v\n", err)
+	}
+	return entry
+}
+
+// perform 执行任务动作，返回执行说明
+func (s *Scheduler) perform(rule ScheduleRule) (string, error) {
+	switch rule.Action {
+	case ScheduleActionMode:
+		if err := s.service.ApplyMode(rule.Mode); err != nil {
+			return "", err
+		}
+		return "运行模式已切换为 " + rule.Mode, nil
+	case ScheduleActionSelect:
+		if err := s.service.SelectProxy(rule.Group, rule.Proxy); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s 已切换到 %s", rule.Group, rule.Proxy), nil
+	case ScheduleActionProfile:
+		if s.profiles == nil {
+			return "", fmt.Errorf("配置方案不可用")
+		}
+		if err := s.profiles.Activate(rule.ProfileID); err != nil {
+			return "", err
+		}
+		return "已激活配置方案 " + rule.ProfileID, nil
+	case ScheduleActionRuleSet:
+		if err := s.service.SetRuleSetEnabled(rule.RuleSet, rule.Enable); err != nil {
+			return "", err
+		}
+		if rule.Enable {
+			return "已启用规则集 " + rule.RuleSet, nil
+		}
+		return "已停用规则集 " + rule.RuleSet, nil
+	case ScheduleActionStart:
+		if s.service.GetStatus().Running {
+			return "核心已在运行", nil
+		}
+		if err := s.service.Start(); err != nil {
+			return "", err
+		}
+		return "核心已启动", nil
+	case ScheduleActionStop:
+		if !s.service.GetStatus().Running {
+			return "核心未运行", nil
+		}
+		if err := s.service.Stop(); err != nil {
+			return "", err
+		}
+		return "核心已停止", nil
+	case ScheduleActionRestart:
+		if err := s.service.Restart(); err != nil {
+			return "", err
+		}
+		return "核心已重启", nil
+	}
+	return "", fmt.Errorf("不支持的动作: %s", rule.Action)
+}
+
+// validate 校验任务
+func (s *Scheduler) validate(rule *ScheduleRule) error {
+	if rule.Name == "" {
+		return fmt.Errorf("任务名称不能为空")
+	}
+	if _, err := parseCron(rule.Cron); err != nil {
+		return err
+	}
+	switch rule.Action {
+	case ScheduleActionMode:
+		if rule.Mode != "rule" && rule.Mode != "global" && rule.Mode != "direct" {
+			return fmt.Errorf("无效的运行模式: %s", rule.Mode)
+		}
+	case ScheduleActionSelect:
+		if rule.Group == "" || rule.Proxy == "" {
+			return fmt.Errorf("切换节点需要指定分组与目标")
+		}
+	case ScheduleActionProfile:
+		if s.profiles == nil {
+			return fmt.Errorf("配置方案不可用")
+		}
+		if _, err := s.profiles.Get(rule.ProfileID); err != nil {
+			return err
+		}
+	case ScheduleActionRuleSet:
+		if rule.RuleSet == "" {
+			return fmt.Errorf("需要指定规则集名称")
+		}
+	case ScheduleActionStart, ScheduleActionStop, ScheduleActionRestart:
+	default:
+		return fmt.Errorf("不支持的动作: %s", rule.Action)
+	}
+	return nil
+}
+
+// find 按 ID 查找任务（调用者需持有锁）
+func (s *Scheduler) find(id string) *ScheduleRule {
+	for _, r := range s.store.Rules {
+		if r.ID == id {
+			return r
+		}
+	}
+	return nil
+}
+
+// withNextRun 返回带下次执行时间的副本
+func withNextRun(r *ScheduleRule, now time.Time) *ScheduleRule {
+	item := *r
+	item.NextRun = nil
+	if !r.Enabled {
+		return &item
+	}
+	if schedule, err := parseCron(r.Cron); err == nil {
+		if next, ok := schedule.next(now); ok {
+			item.NextRun = &next
+		}
+	}
+	return &item
+}
+
+// List 获取任务列表
+func (s *Scheduler) List() []*ScheduleRule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	rules := make([]*ScheduleRule, 0, len(s.store.Rules))
+	for _, r := range s.store.Rules {
+		rules = append(rules, withNextRun(r, now))
+	}
+	return rules
+}
+
+// Create 创建任务
+func (s *Scheduler) Create(rule *ScheduleRule) (*ScheduleRule, error) {
+	if err := s.validate(rule); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rule.ID = uuid.New().String()
+	rule.LastRun = nil
+	s.store.Rules = append(s.store.Rules, rule)
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return withNextRun(rule, time.Now()), nil
+}
+
+// Update 更新任务
+func (s *Scheduler) Update(id string, update *ScheduleRule) (*ScheduleRule, error) {
+	if err := s.validate(update); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.find(id)
+	if r == nil {
+		return nil, fmt.Errorf("定时任务不存在")
+	}
+	update.ID = id
+	update.LastRun = r.LastRun
+	*r = *update
+	if err := s.save(); err != nil {
+		return nil, err
+	}
+	return withNextRun(r, time.Now()), nil
+}
+
+// Delete 删除任务
+func (s *Scheduler) Delete(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, r := range s.store.Rules {
+		if r.ID == id {
+			s.store.Rules = append(s.store.Rules[:i], s.store.Rules[i+1:]...)
+			return s.save()
+		}
+	}
+	return fmt.Errorf("定时任务不存在")
+}
+
+// RunNow 立即执行任务
+func (s *Scheduler) RunNow(id string) (ScheduleExecution, error) {
+	s.mu.Lock()
+	r := s.find(id)
+	if r == nil {
+		s.mu.Unlock()
+		return ScheduleExecution{}, fmt.Errorf("定时任务不存在")
+	}
+	rule := *r
+	s.mu.Unlock()
+
+	return s.execute(rule, time.Now(), true), nil
+}
+
+// Logs 获取执行记录（最新的在前）
+func (s *Scheduler) Logs(limit int) []ScheduleExecution {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	logs := make([]ScheduleExecution, 0, len(s.store.Logs))
+	for i := len(s.store.Logs) - 1; i >= 0; i-- {
+		logs = append(logs, s.store.Logs[i])
+		if limit > 0 && len(logs) >= limit {
+			break
+		}
+	}
+	return logs
+}
+
+// ============================================================================
+// cron 表达式
+// ============================================================================
+
+// cronSchedule 解析后的 cron 表达式
+type cronSchedule struct {
+	minute, hour, dom, month, dow map[int]bool
+	domAny, dowAny                bool
+}
+
+// cronMacros 常用表达式别名
+var cronMacros = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+}
+
+// parseCron 解析五段式 cron 表达式：分 时 日 月 周（周日为 0 或 7）
+func parseCron(expr string) (*cronSchedule, error) {
+	expr = strings.TrimSpace(expr)
+	if macro, ok := cronMacros[expr]; ok {
+		expr = macro
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron 表达式需要 5 段（分 时 日 月 周）: %s", expr)
+	}
+
+	bounds := [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+	sets := make([]map[int]bool, 5)
+	for i, field := range fields {
+		set, err := parseCronField(field, bounds[i][0], bounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("cron 表达式第 %d 段 %s 无效: %w", i+1, field, err)
+		}
+		sets[i] = set
+	}
+	if sets[4][7] {
+		sets[4][0] = true
+	}
+	return &cronSchedule{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}, nil
+}
+
+// parseCronField 解析单段表达式，支持 *、a-b、*/n、a-b/n 与逗号列表
+func parseCronField(field string, min, max int) (map[int]bool, error) {
+	set := make(map[int]bool)
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return nil, fmt.Errorf("步长 %s 无效", stepPart)
+			}
+			step = n
+		}
+
+		start, end := min, max
+		if rangePart != "*" {
+			from, to, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if start, err = strconv.Atoi(from); err != nil {
+				return nil, fmt.Errorf("%s 不是数字", from)
+			}
+			end = start
+			if isRange {
+				if end, err = strconv.Atoi(to); err != nil {
+					return nil, fmt.Errorf("%s 不是数字", to)
+				}
+			} else if hasStep {
+				end = max
+			}
+		}
+		if start < min || end > max || start > end {
+			return nil, fmt.Errorf("超出范围 %d-%d", min, max)
+		}
+		for v := start; v <= end; v += step {
+			set[v] = true
+		}
+	}
+	return set, nil
+}
+
+// matches 判断时间是否匹配（日与周都有限制时满足其一即可，与标准 cron 一致）
+func (c *cronSchedule) matches(t time.Time) bool {
+	if !c.minute[t.Minute()] || !c.hour[t.Hour()] || !c.month[int(t.Month())] {
+		return false
+	}
+	domMatch := c.dom[t.Day()]
+	dowMatch := c.dow[int(t.Weekday())]
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dowMatch
+	case c.dowAny:
+		return domMatch
+	}
+	return domMatch || dowMatch
+}
+
+// next 计算下次执行时间（最多向后查找一年）
+func (c *cronSchedule) next(after time.Time) (time.Time, bool) {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(1, 0, 0)
+	for t.Before(limit) {
+		if !c.month[int(t.Month())] {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.hour[t.Hour()] {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.matches(t) {
+			return t, true
+		}
+		t = t.Add(time.Minute)
+	}
+	return time.Time{}, false
+}
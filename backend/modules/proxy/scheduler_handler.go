@@ -0,0 +1,139 @@
+package proxy
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SchedulerHandler 定时任务处理器
+type SchedulerHandler struct {
+	scheduler *Scheduler
+}
+
+// NewSchedulerHandler 创建定时任务处理器
+func NewSchedulerHandler(scheduler *Scheduler) *SchedulerHandler {
+	return &SchedulerHandler{scheduler: scheduler}
+}
+
+// RegisterRoutes 注册路由
+func (h *SchedulerHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/schedules", h.ListSchedules)
+	r.POST("/schedules", h.CreateSchedule)
+	r.GET("/schedules/logs", h.GetScheduleLogs)
+	r.PUT("/schedules/:id", h.UpdateSchedule)
+	r.DELETE("/schedules/:id", h.DeleteSchedule)
+	r.POST("/schedules/:id/run", h.RunSchedule)
+}
+
+// ListSchedules 获取定时任务列表
+func (h *SchedulerHandler) ListSchedules(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.scheduler.List(),
+	})
+}
+
+// CreateSchedule 创建定时任务
+func (h *SchedulerHandler) CreateSchedule(c *gin.Context) {
+	var rule ScheduleRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	created, err := h.scheduler.Create(&rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    created,
+	})
+}
+
+// UpdateSchedule 更新定时任务
+func (h *SchedulerHandler) UpdateSchedule(c *gin.Context) {
+	var rule ScheduleRule
+	if err := c.ShouldBindJSON(&rule); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	updated, err := h.scheduler.Update(c.Param("id"), &rule)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    updated,
+	})
+}
+
+// DeleteSchedule 删除定时任务
+func (h *SchedulerHandler) DeleteSchedule(c *gin.Context) {
+	if err := h.scheduler.Delete(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// RunSchedule 立即执行定时任务
+func (h *SchedulerHandler) RunSchedule(c *gin.Context) {
+	entry, err := h.scheduler.RunNow(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    entry,
+	})
+}
+
+// GetScheduleLogs 获取执行记录，limit 默认 50
+func (h *SchedulerHandler) GetScheduleLogs(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "50"))
+	if err != nil || limit < 0 {
+		limit = 50
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.scheduler.Logs(limit),
+	})
+}
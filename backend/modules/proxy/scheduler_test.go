@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+// cronValues 将取值集合转换为有序列表，便于比较
+func cronValues(set map[int]bool) string {
+	var values []int
+	for v := range set {
+		values = append(values, v)
+	}
+	sort.Ints(values)
+	return fmt.Sprint(values)
+}
+
+func TestParseCronField(t *testing.T) {
+	tests := []struct {
+		name     string
+		field    string
+		min, max int
+		want     string
+		wantErr  bool
+	}{
+		{name: "单个值", field: "5", min: 0, max: 59, want: "[5]"},
+		{name: "任意值", field: "*", min: 1, max: 12, want: "[1 2 3 4 5 6 7 8 9 10 11 12]"},
+		{name: "步长", field: "*/15", min: 0, max: 59, want: "[0 15 30 45]"},
+		{name: "范围", field: "9-12", min: 0, max: 23, want: "[9 10 11 12]"},
+		{name: "范围加步长", field: "1-10/3", min: 1, max: 31, want: "[1 4 7 10]"},
+		{name: "起点加步长", field: "20/10", min: 0, max: 59, want: "[20 30 40 50]"},
+		{name: "列表", field: "1,15,30", min: 1, max: 31, want: "[1 15 30]"},
+		{name: "列表混合范围", field: "1-3,10,*/20", min: 0, max: 59, want: "[0 1 2 3 10 20 40]"},
+		{name: "超出上限", field: "60", min: 0, max: 59, wantErr: true},
+		{name: "低于下限", field: "0", min: 1, max: 31, wantErr: true},
+		{name: "范围颠倒", field: "10-5", min: 0, max: 59, wantErr: true},
+		{name: "步长为 0", field: "*/0", min: 0, max: 59, wantErr: true},
+		{name: "步长不是数字", field: "*/x", min: 0, max: 59, wantErr: true},
+		{name: "不是数字", field: "mon", min: 0, max: 7, wantErr: true},
+		{name: "空列表项", field: "1,", min: 0, max: 59, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			set, err := parseCronField(tt.field, tt.min, tt.max)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && cronValues(set) != tt.want {
+				t.Errorf("parseCronField(%q) = %s, want %s", tt.field, cronValues(set), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCron(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		dow     string
+		wantErr bool
+	}{
+		{name: "周日写作 7", expr: "0 0 * * 7", dow: "[0 7]"},
+		{name: "周日写作 0", expr: "0 0 * * 0", dow: "[0]"},
+		{name: "周范围包含 7", expr: "0 0 * * 5-7", dow: "[0 5 6 7]"},
+		{name: "别名", expr: " @weekly ", dow: "[0]"},
+		{name: "段数不足", expr: "0 0 * *", wantErr: true},
+		{name: "段数过多", expr: "0 0 * * * *", wantErr: true},
+		{name: "周超出范围", expr: "0 0 * * 8", wantErr: true},
+		{name: "小时超出范围", expr: "0 24 * * *", wantErr: true},
+		{name: "未知别名", expr: "@yearly", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := parseCron(tt.expr)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && cronValues(schedule.dow) != tt.dow {
+				t.Errorf("dow = %s, want %s", cronValues(schedule.dow), tt.dow)
+			}
+		})
+	}
+}
+
+func TestCronScheduleMatches(t *testing.T) {
+	// 2026-03-01 为周日，2026-03-13 为周五
+	tests := []struct {
+		name string
+		expr string
+		at   string
+		want bool
+	}{
+		{name: "只限制日", expr: "0 0 13 * *", at: "2026-03-13 00:00", want: true},
+		{name: "只限制日不匹配", expr: "0 0 13 * *", at: "2026-03-14 00:00"},
+		{name: "只限制周", expr: "0 0 * * 5", at: "2026-03-06 00:00", want: true},
+		{name: "只限制周不匹配", expr: "0 0 * * 5", at: "2026-03-07 00:00"},
+		{name: "日与周都限制时日匹配即可", expr: "0 0 1 * 5", at: "2026-03-01 00:00", want: true},
+		{name: "日与周都限制时周匹配即可", expr: "0 0 1 * 5", at: "2026-03-06 00:00", want: true},
+		{name: "日与周都不匹配", expr: "0 0 1 * 5", at: "2026-03-07 00:00"},
+		{name: "周日写作 7", expr: "30 8 * * 7", at: "2026-03-01 08:30", want: true},
+		{name: "月份不匹配", expr: "0 0 13 4 *", at: "2026-03-13 00:00"},
+		{name: "分钟不匹配", expr: "0 0 13 * *", at: "2026-03-13 00:01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("parseCron: %v", err)
+			}
+			at, _ := time.ParseInLocation("2006-01-02 15:04", tt.at, time.Local)
+			if got := schedule.matches(at); got != tt.want {
+				t.Errorf("matches(%s) = %v, want %v", tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	tests := []struct {
+		name  string
+		expr  string
+		after string
+		want  string // 为空表示一年内没有匹配
+	}{
+		{name: "下一分钟", expr: "* * * * *", after: "2026-03-13 10:00:30", want: "2026-03-13 10:01"},
+		{name: "不包含当前分钟", expr: "0 * * * *", after: "2026-03-13 10:00:00", want: "2026-03-13 11:00"},
+		{name: "步长", expr: "*/15 * * * *", after: "2026-03-13 10:16:00", want: "2026-03-13 10:30"},
+		{name: "跨天", expr: "30 2 * * *", after: "2026-03-13 03:00:00", want: "2026-03-14 02:30"},
+		{name: "跨月", expr: "0 0 1 * *", after: "2026-01-31 12:00:00", want: "2026-02-01 00:00"},
+		{name: "跳过没有 31 日的月份", expr: "0 0 31 * *", after: "2026-03-31 00:00:00", want: "2026-05-31 00:00"},
+		{name: "跨年", expr: "0 0 1 1 *", after: "2026-12-31 23:59:00", want: "2027-01-01 00:00"},
+		{name: "年末最后一分钟", expr: "59 23 31 12 *", after: "2026-12-31 23:58:00", want: "2026-12-31 23:59"},
+		{name: "日与周满足其一", expr: "0 0 13 * 1", after: "2026-03-10 00:00:00", want: "2026-03-13 00:00"},
+		{name: "周日写作 7", expr: "0 9 * * 7", after: "2026-03-02 00:00:00", want: "2026-03-08 09:00"},
+		{name: "闰年二月 29 日", expr: "0 0 29 2 *", after: "2027-03-01 00:00:00", want: "2028-02-29 00:00"},
+		{name: "一年内没有匹配", expr: "0 0 29 2 *", after: "2025-03-01 00:00:00"},
+		{name: "不存在的日期", expr: "0 0 30 2 *", after: "2026-01-01 00:00:00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("parseCron: %v", err)
+			}
+			after, _ := time.ParseInLocation("2006-01-02 15:04:05", tt.after, time.UTC)
+			got, ok := schedule.next(after)
+			if tt.want == "" {
+				if ok {
+					t.Errorf("next(%s) = %s, want none", tt.after, got)
+				}
+				return
+			}
+			if !ok || got.Format("2006-01-02 15:04") != tt.want {
+				t.Errorf("next(%s) = %s, %v; want %s", tt.after, got.Format("2006-01-02 15:04"), ok, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"p-box/backend/clashapi"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ============================================================================
+// 选择器选中记录
+// 通过 P-BOX 切换的选中项持久化保存，核心每次启动后通过控制器恢复；
+// 节点按 类型+地址+端口 识别，订阅更名后仍能找回原节点
+// ============================================================================
+
+// SelectionRecord 选择器选中记录
+type SelectionRecord struct {
+	Group     string    `json:"group"`
+	Name      string    `json:"name"`
+	Identity  string    `json:"identity,omitempty"` // 节点标识，选中项为分组或内置策略时为空
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// selectionStore 选中记录存储
+type selectionStore struct {
+	path    string
+	records map[string]SelectionRecord
+	mu      sync.Mutex
+}
+
+// newSelectionStore 创建选中记录存储
+func newSelectionStore(dataDir string) *selectionStore {
+	store := &selectionStore{
+		path:    filepath.Join(dataDir, "selections.json"),
+		records: make(map[string]SelectionRecord),
+	}
+	if data, err := os.ReadFile(store.path); err == nil {
+		if err := json.Unmarshal(data, &store.records); err != nil {
+			fmt.Printf("⚠️ 读取选中记录失败: %v\n", err)
+		}
+	}
+	return store
+}
+
+// save 保存选中记录（调用者需持有锁）
+func (st *selectionStore) save() error {
+	data, err := json.MarshalIndent(st.records, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(st.path, data, 0644)
+}
+
+// nodeIdentity 节点稳定标识
+func nodeIdentity(n ProxyNode) string {
+	return strings.ToLower(n.Type) + "|" + strings.ToLower(n.Server) + "|" + strconv.Itoa(n.GetPort())
+}
+
+// RecordSelection 记录选择器选中项
+func (s *Service) RecordSelection(group, name string) {
+	record := SelectionRecord{Group: group, Name: name, UpdatedAt: time.Now()}
+	if s.nodeProvider != nil {
+		for _, n := range s.nodeProvider() {
+			if n.Name == name {
+				record.Identity = nodeIdentity(n)
+				break
+			}
+		}
+	}
+
+	st := s.selections
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	st.records[group] = record
+	if err := st.save(); err != nil {
+		fmt.Printf("⚠️ 保存选中记录失败: %v\n", err)
+	}
+}
+
+// GetSelections 获取所有选中记录
+func (s *Service) GetSelections() []SelectionRecord {
+	st := s.selections
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	records := make([]SelectionRecord, 0, len(st.records))
+	for _, r := range st.records {
+		records = append(records, r)
+	}
+	return records
+}
+
+// ClearSelections 清除选中记录，group 为空时清除全部
+func (s *Service) ClearSelections(group string) error {
+	st := s.selections
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if group == "" {
+		st.records = make(map[string]SelectionRecord)
+	} else {
+		delete(st.records, group)
+	}
+	return st.save()
+}
+
+// restoreSelections 等待控制器就绪后恢复选中项
+func (s *Service) restoreSelections() {
+	records := s.GetSelections()
+	if len(records) == 0 {
+		return
+	}
+
+	// 等待核心控制器就绪
+	controller := s.Controller()
+	var proxies map[string]*clashapi.Proxy
+	for i := 0; i < 20 && proxies == nil; i++ {
+		time.Sleep(500 * time.Millisecond)
+		if !s.GetStatus().Running {
+			return
+		}
+		proxies, _ = controller.Proxies()
+	}
+	if proxies == nil {
+		fmt.Printf("⚠️ 控制器未就绪，未恢复选中项\n")
+		return
+	}
+
+	var nodes []ProxyNode
+	if s.nodeProvider != nil {
+		nodes = s.nodeProvider()
+	}
+
+	restored := 0
+	for _, r := range records {
+		group, ok := proxies[r.Group]
+		if !ok || !group.IsSelector() {
+			continue
+		}
+		target := resolveSelection(r, group.All, nodes)
+		if target == "" {
+			fmt.Printf("⚠️ %s 的选中项 %s 已不存在\n", r.Group, r.Name)
+			continue
+		}
+		if target != group.Now {
+			if err := controller.SelectProxy(r.Group, target); err != nil {
+				fmt.Printf("⚠️ 恢复 %s 的选中项失败: %v\n", r.Group, err)
+				continue
+			}
+			restored++
+		}
+		if target != r.Name {
+			// 节点已更名，更新记录
+			s.RecordSelection(r.Group, target)
+		}
+	}
+	if restored > 0 {
+		fmt.Printf("✅ 已恢复 %d 个选择器的选中项\n", restored)
+	}
+}
+
+// resolveSelection 在分组成员中查找记录对应的选中项，名称不存在时按节点标识查找
+func resolveSelection(r SelectionRecord, members []string, nodes []ProxyNode) string {
+	inGroup := make(map[string]bool, len(members))
+	for _, m := range members {
+		inGroup[m] = true
+	}
+	if inGroup[r.Name] {
+		return r.Name
+	}
+	if r.Identity == "" {
+		return ""
+	}
+	for _, n := range nodes {
+		if inGroup[n.Name] && nodeIdentity(n) == r.Identity {
+			return n.Name
+		}
+	}
+	return ""
+}
@@ -3,11 +3,14 @@ package proxy
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
 	"os/exec"
+	"p-box/backend/clashapi"
+	"p-box/backend/events"
 	"p-box/backend/modules/system"
 	"path/filepath"
 	"runtime"
@@ -16,6 +19,9 @@ import (
 	"time"
 )
 
+// ErrNoNodes 没有可用节点，无法生成配置
+var ErrNoNodes = errors.New("没有可用节点")
+
 type ProxyMode string
 
 const (
@@ -50,11 +56,15 @@ type ProxyConfig struct {
 	Mode               string `json:"mode" yaml:"mode"`
 	LogLevel           string `json:"logLevel" yaml:"log-level"`
 	ExternalController string `json:"externalController" yaml:"external-controller"`
+	Secret             string `json:"secret,omitempty" yaml:"secret,omitempty"`                  // 控制器密钥（为空时自动生成）
+	ControllerUnix     bool   `json:"controllerUnix,omitempty" yaml:"controller-unix,omitempty"` // 仅通过 unix socket 提供控制器（仅 Mihomo）
 	TunEnabled         bool   `json:"tunEnabled" yaml:"tun-enabled"`
 	TunStack           string `json:"tunStack" yaml:"tun-stack"`               // system, gvisor, mixed
 	TransparentMode    string `json:"transparentMode" yaml:"transparent-mode"` // off, tun, tproxy, redirect
 	AutoStart          bool   `json:"autoStart" yaml:"auto-start"`             // 开机自动启动
 	AutoStartDelay     int    `json:"autoStartDelay" yaml:"auto-start-delay"`  // 自动启动延迟（秒）
+
+	DisabledRuleSets []string `json:"disabledRuleSets,omitempty" yaml:"disabled-rule-sets,omitempty"` // 停用的规则集（生成配置时移除其规则）
 }
 
 // NodeProvider 节点提供者接口
@@ -83,11 +93,29 @@ type Service struct {
 	settingsProvider SettingsProvider
 
 	// 日志收集
-	logs  []string
-	logMu sync.RWMutex
+	logs    []string
+	logMu   sync.RWMutex
+	logSink func(string)
+
+	// 事件总线（核心启动、停止、崩溃）
+	bus *events.Bus
+
+	// GEO 数据读取（规则模拟使用）
+	geoData *GeoDataReader
+
+	// 节点过滤（由当前配置方案设置）
+	nodeFilter *NodeFilter
 
-	// 启动回调（用于通知其他模块 VPN 已启动）
-	onStartCallback func()
+	// 统一路由模板（非空时两个核心的路由均由其编译生成）
+	routingTemplate *RoutingTemplate
+
+	// 选择器选中记录（启动后恢复）
+	selections *selectionStore
+
+	// 控制器客户端缓存（地址、socket 或密钥变化时重建）
+	controller    *clashapi.Client
+	controllerKey string
+	controllerMu  sync.Mutex
 }
 
 func NewService(dataDir string) *Service {
@@ -123,9 +151,13 @@ func NewService(dataDir string) *Service {
 		configGenerator:  NewConfigGenerator(dataDir),
 		singboxGenerator: NewSingboxGenerator(dataDir),
 		configTemplate:   GetDefaultConfigTemplate(),
+		geoData:          NewGeoDataReader(dataDir),
+		selections:       newSelectionStore(dataDir),
 	}
 	s.loadConfig()
+	s.ensureControllerSecret()
 	s.loadConfigTemplate()
+	s.loadRoutingTemplate()
 	return s
 }
 
@@ -323,13 +355,25 @@ func (s *Service) Start() error {
 	s.startTime = time.Now()
 	s.configPath = configPath
 
-	// 监控进程
+	// 监控进程，非 Stop 引起的退出视为崩溃
+	process := s.process
+	coreType := s.coreType
 	go func() {
-		s.process.Wait()
+		err := process.Wait()
 		s.mu.Lock()
-		s.running = false
-		s.process = nil
+		crashed := s.running && s.process == process
+		if crashed {
+			s.running = false
+			s.process = nil
+		}
 		s.mu.Unlock()
+		if crashed {
+			payload := events.CorePayload{Core: coreType, PID: process.Process.Pid}
+			if err != nil {
+				payload.Error = err.Error()
+			}
+			s.bus.Publish(events.CoreCrashed, payload)
+		}
 	}()
 
 	// 根据透明代理模式自动设置系统代理（macOS/Windows）
@@ -345,10 +389,11 @@ func (s *Service) Start() error {
 		go s.configureAllBrowsers()
 	}
 
-	// 调用启动回调（通知其他模块 VPN 已启动）
-	if s.onStartCallback != nil {
-		s.onStartCallback()
-	}
+	// 通知其他模块核心已启动
+	s.bus.Publish(events.CoreStarted, events.CorePayload{Core: s.coreType, PID: s.process.Process.Pid})
+
+	// 恢复选择器选中项
+	go s.restoreSelections()
 
 	return nil
 }
@@ -373,6 +418,7 @@ func (s *Service) Stop() error {
 	}
 
 	wasTunEnabled := s.config.TunEnabled || s.config.TransparentMode == "tun"
+	coreType := s.coreType
 
 	if s.process != nil && s.process.Process != nil {
 		if err := s.process.Process.Kill(); err != nil {
@@ -386,6 +432,8 @@ func (s *Service) Stop() error {
 	s.process = nil
 	s.mu.Unlock()
 
+	s.bus.Publish(events.CoreStopped, events.CorePayload{Core: coreType})
+
 	// 恢复系统环境（在锁外执行）
 	if wasTunEnabled {
 		s.restoreSystemAfterTUN()
@@ -419,8 +467,10 @@ func (s *Service) collectLogs(reader io.Reader) {
 	for scanner.Scan() {
 		line := scanner.Text()
 		s.addLog(line)
-		// 同时输出到控制台
-		fmt.Println(line)
+		// 未接入日志文件时直接输出到控制台
+		if s.logSink == nil {
+			fmt.Println(line)
+		}
 	}
 }
 
@@ -434,6 +484,15 @@ func (s *Service) addLog(line string) {
 	if len(s.logs) > 1000 {
 		s.logs = s.logs[len(s.logs)-1000:]
 	}
+
+	if s.logSink != nil {
+		s.logSink(line)
+	}
+}
+
+// SetLogSink 设置核心日志输出（写入日志文件），需在启动核心前设置
+func (s *Service) SetLogSink(sink func(string)) {
+	s.logSink = sink
 }
 
 // GetLogs 获取日志
@@ -469,6 +528,33 @@ func (s *Service) SetNodeProvider(provider NodeProvider) {
 	s.nodeProvider = provider
 }
 
+// SetNodeFilter 设置节点过滤条件（nil 表示不过滤）
+func (s *Service) SetNodeFilter(filter *NodeFilter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nodeFilter = filter
+}
+
+// GetNodeFilter 获取当前节点过滤条件
+func (s *Service) GetNodeFilter() *NodeFilter {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.nodeFilter
+}
+
+// listNodes 从节点提供者获取节点并应用过滤条件
+func (s *Service) listNodes() []ProxyNode {
+	if s.nodeProvider == nil {
+		return nil
+	}
+	nodes := s.nodeProvider()
+
+	s.mu.RLock()
+	filter := s.nodeFilter
+	s.mu.RUnlock()
+	return filter.Apply(nodes)
+}
+
 // SetSettingsProvider 设置代理设置提供者
 func (s *Service) SetSettingsProvider(provider SettingsProvider) {
 	s.mu.Lock()
@@ -476,11 +562,11 @@ func (s *Service) SetSettingsProvider(provider SettingsProvider) {
 	s.settingsProvider = provider
 }
 
-// SetOnStartCallback 设置启动回调（VPN 启动后调用）
-func (s *Service) SetOnStartCallback(callback func()) {
+// SetEventBus 设置事件总线（发布核心启动、停止与崩溃事件）
+func (s *Service) SetEventBus(bus *events.Bus) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.onStartCallback = callback
+	s.bus = bus
 }
 
 // RegenerateConfig 从节点管理模块获取过滤后的节点并生成配置（公开方法）
@@ -491,15 +577,13 @@ func (s *Service) RegenerateConfig() (string, error) {
 // regenerateConfig 从节点管理模块获取过滤后的节点并生成配置
 // 注意：调用此方法时不能持有 s.mu 锁
 func (s *Service) regenerateConfig() (string, error) {
-	provider := s.nodeProvider // nodeProvider 在初始化后不会改变，无需加锁
-
-	if provider == nil {
+	if s.nodeProvider == nil { // nodeProvider 在初始化后不会改变，无需加锁
 		return "", fmt.Errorf("节点提供者未设置")
 	}
 
-	allNodes := provider()
+	allNodes := s.listNodes()
 	if len(allNodes) == 0 {
-		return "", fmt.Errorf("没有可用节点")
+		return "", ErrNoNodes
 	}
 
 	fmt.Printf("🔄 重新生成配置，共 %d 个节点\n", len(allNodes))
@@ -530,6 +614,57 @@ func (s *Service) SetMode(mode string) error {
 	return nil
 }
 
+// ApplyMode 切换运行模式并保存，核心运行中时通过控制器热切换
+func (s *Service) ApplyMode(mode string) error {
+	if err := s.SetMode(mode); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	err := s.saveConfig()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if !s.GetStatus().Running {
+		return nil
+	}
+	return s.Controller().SetMode(mode)
+}
+
+// SelectProxy 通过控制器切换选择器分组的节点
+func (s *Service) SelectProxy(group, proxy string) error {
+	if !s.GetStatus().Running {
+		return fmt.Errorf("核心未运行，无法切换 %s", group)
+	}
+	if err := s.Controller().SelectProxy(group, proxy); err != nil {
+		return err
+	}
+	s.RecordSelection(group, proxy)
+	return nil
+}
+
+// SetRuleSetEnabled 启用或停用规则集，运行中时重新生成配置并重启
+func (s *Service) SetRuleSetEnabled(name string, enabled bool) error {
+	s.mu.Lock()
+	disabled := make([]string, 0, len(s.config.DisabledRuleSets)+1)
+	for _, n := range s.config.DisabledRuleSets {
+		if n != name {
+			disabled = append(disabled, n)
+		}
+	}
+	if !enabled {
+		disabled = append(disabled, name)
+	}
+	s.config.DisabledRuleSets = disabled
+	err := s.saveConfig()
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	s.regenerateIfRunning()
+	return nil
+}
+
 // SetTunEnabled 设置 TUN 模式开关 (兼容旧接口)
 func (s *Service) SetTunEnabled(enabled bool) error {
 	s.mu.Lock()
@@ -625,6 +760,16 @@ func (s *Service) PatchConfig(updates map[string]interface{}) error {
 			s.config.ExternalController = val
 		}
 	}
+	if v, ok := updates["secret"]; ok {
+		if val, ok := v.(string); ok && val != "" {
+			s.config.Secret = val
+		}
+	}
+	if v, ok := updates["controllerUnix"]; ok {
+		if val, ok := v.(bool); ok {
+			s.config.ControllerUnix = val
+		}
+	}
 	if v, ok := updates["tunEnabled"]; ok {
 		if val, ok := v.(bool); ok {
 			s.config.TunEnabled = val
@@ -694,51 +839,7 @@ func (s *Service) findCorePath() string {
 
 // GenerateConfig 生成配置文件
 func (s *Service) GenerateConfig(nodes []ProxyNode) (string, error) {
-	// 根据透明代理模式设置
-	enableTUN := s.config.TransparentMode == "tun"
-	enableTProxy := s.config.TransparentMode == "tproxy" || s.config.TransparentMode == "redirect"
-
-	options := ConfigGeneratorOptions{
-		MixedPort:          s.config.MixedPort,
-		AllowLan:           s.config.AllowLan,
-		Mode:               s.config.Mode,
-		LogLevel:           s.config.LogLevel,
-		IPv6:               s.config.IPv6,
-		ExternalController: s.config.ExternalController,
-		EnableDNS:          true,
-		EnhancedMode:       "fake-ip",
-		EnableTUN:          enableTUN,
-		EnableTProxy:       enableTProxy,
-		TProxyPort:         s.config.TProxyPort,
-		Template:           s.configTemplate, // 使用配置模板
-	}
-
-	// 从代理设置获取优化配置
-	if s.settingsProvider != nil {
-		settings := s.settingsProvider()
-		if settings != nil {
-			// 性能优化
-			options.UnifiedDelay = settings.UnifiedDelay
-			options.TCPConcurrent = settings.TCPConcurrent
-			options.FindProcessMode = settings.FindProcessMode
-			options.GlobalClientFingerprint = settings.GlobalClientFingerprint
-			options.KeepAliveInterval = settings.KeepAliveInterval
-			options.KeepAliveIdle = settings.KeepAliveIdle
-			options.DisableKeepAlive = settings.DisableKeepAlive
-
-			// GEO 数据
-			options.GeodataMode = settings.GeodataMode
-			options.GeodataLoader = settings.GeodataLoader
-			options.GeositeMatcher = settings.GeositeMatcher
-			options.GeoAutoUpdate = settings.GeoAutoUpdate
-			options.GeoUpdateInterval = settings.GeoUpdateInterval
-			options.GlobalUA = settings.GlobalUA
-			options.ETagSupport = settings.ETagSupport
-
-			// TUN 设置
-			options.TUNSettings = &settings.TUN
-		}
-	}
+	options := s.buildGeneratorOptions()
 
 	var configPath string
 
@@ -751,6 +852,17 @@ func (s *Service) GenerateConfig(nodes []ProxyNode) (string, error) {
 			LogLevel:                 options.LogLevel,
 			Sniff:                    true,
 			SniffOverrideDestination: true,
+			Listeners:                options.Listeners,
+			Access:                   options.Access,
+			DisabledRuleSets:         options.DisabledRuleSets,
+		}
+		// 统一路由模板优先，否则使用用户自定义规则（内置规则集规则由 sing-box 默认路由负责）
+		s.mu.RLock()
+		sbOpts.Routing = s.routingTemplate
+		s.mu.RUnlock()
+		if sbOpts.Routing == nil && options.Template != nil {
+			sbOpts.CustomRules = customRuleTemplates(options.Template.Rules)
+			sbOpts.SubRules = options.Template.SubRules
 		}
 		// TUN 模式设置
 		if options.EnableTUN {
@@ -768,6 +880,10 @@ func (s *Service) GenerateConfig(nodes []ProxyNode) (string, error) {
 		} else {
 			sbOpts.ClashAPIAddr = "127.0.0.1:9090"
 		}
+		sbOpts.ClashAPISecret = options.Secret
+		if s.config.ControllerUnix {
+			fmt.Printf("⚠️ Sing-Box 不支持 unix socket 控制器，继续使用 %s\n", sbOpts.ClashAPIAddr)
+		}
 
 		config, err := s.singboxGenerator.GenerateConfigV112(nodes, sbOpts)
 		if err != nil {
@@ -795,6 +911,69 @@ func (s *Service) GenerateConfig(nodes []ProxyNode) (string, error) {
 	return configPath, nil
 }
 
+// buildGeneratorOptions 根据当前代理配置与设置构建 Mihomo 生成选项
+func (s *Service) buildGeneratorOptions() ConfigGeneratorOptions {
+	// 根据透明代理模式设置
+	enableTUN := s.config.TransparentMode == "tun"
+	enableTProxy := s.config.TransparentMode == "tproxy" || s.config.TransparentMode == "redirect"
+
+	options := ConfigGeneratorOptions{
+		MixedPort:          s.config.MixedPort,
+		AllowLan:           s.config.AllowLan,
+		Mode:               s.config.Mode,
+		LogLevel:           s.config.LogLevel,
+		IPv6:               s.config.IPv6,
+		ExternalController: s.config.ExternalController,
+		Secret:             s.config.Secret,
+		EnableDNS:          true,
+		EnhancedMode:       "fake-ip",
+		EnableTUN:          enableTUN,
+		EnableTProxy:       enableTProxy,
+		TProxyPort:         s.config.TProxyPort,
+		Template:           s.configTemplate, // 使用配置模板
+		DisabledRuleSets:   s.config.DisabledRuleSets,
+	}
+	if s.useControllerUnix() {
+		options.ExternalControllerUnix = s.controllerSocketPath()
+	}
+	if s.routingTemplate != nil {
+		_, options.DNSPolicy, _ = s.routingTemplate.CompileMihomo()
+	}
+
+	// 从代理设置获取优化配置
+	if s.settingsProvider != nil {
+		settings := s.settingsProvider()
+		if settings != nil {
+			// 性能优化
+			options.UnifiedDelay = settings.UnifiedDelay
+			options.TCPConcurrent = settings.TCPConcurrent
+			options.FindProcessMode = settings.FindProcessMode
+			options.GlobalClientFingerprint = settings.GlobalClientFingerprint
+			options.KeepAliveInterval = settings.KeepAliveInterval
+			options.KeepAliveIdle = settings.KeepAliveIdle
+			options.DisableKeepAlive = settings.DisableKeepAlive
+
+			// GEO 数据
+			options.GeodataMode = settings.GeodataMode
+			options.GeodataLoader = settings.GeodataLoader
+			options.GeositeMatcher = settings.GeositeMatcher
+			options.GeoAutoUpdate = settings.GeoAutoUpdate
+			options.GeoUpdateInterval = settings.GeoUpdateInterval
+			options.GlobalUA = settings.GlobalUA
+			options.ETagSupport = settings.ETagSupport
+
+			// 额外入站与访问控制
+			options.Listeners = settings.Listeners
+			options.Access = settings.accessSettings()
+
+			// TUN 设置
+			options.TUNSettings = &settings.TUN
+		}
+	}
+
+	return options
+}
+
 // SetCoreType 设置核心类型
 func (s *Service) SetCoreType(coreType string) {
 	s.mu.Lock()
@@ -941,24 +1120,81 @@ func (s *Service) GetConfigTemplate() *ConfigTemplate {
 func (s *Service) UpdateProxyGroups(groups []ProxyGroupTemplate) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.configTemplate.ProxyGroups = groups
-	return s.saveConfigTemplate()
+	candidate := *s.configTemplate
+	candidate.ProxyGroups = groups
+	if err := s.lintConfigTemplate(&candidate); err != nil {
+		return err
+	}
+	return s.commitConfigTemplate(&candidate)
 }
 
 // UpdateRules 更新规则
 func (s *Service) UpdateRules(rules []RuleTemplate) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.configTemplate.Rules = rules
-	return s.saveConfigTemplate()
+	candidate := *s.configTemplate
+	candidate.Rules = rules
+	if err := s.lintConfigTemplate(&candidate); err != nil {
+		return err
+	}
+	return s.commitConfigTemplate(&candidate)
 }
 
 // UpdateRuleProviders 更新规则提供者
 func (s *Service) UpdateRuleProviders(providers []RuleProviderTemplate) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.configTemplate.RuleProviders = providers
-	return s.saveConfigTemplate()
+	candidate := *s.configTemplate
+	candidate.RuleProviders = providers
+	if err := s.lintConfigTemplate(&candidate); err != nil {
+		return err
+	}
+	return s.commitConfigTemplate(&candidate)
+}
+
+// UpdateSubRules 更新子规则（SUB-RULE 引用）
+func (s *Service) UpdateSubRules(subRules map[string][]RuleTemplate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	candidate := *s.configTemplate
+	candidate.SubRules = subRules
+	if err := s.lintConfigTemplate(&candidate); err != nil {
+		return err
+	}
+	return s.commitConfigTemplate(&candidate)
+}
+
+// lintConfigTemplate 检查配置模板，存在错误时返回 *TemplateLintError
+func (s *Service) lintConfigTemplate(template *ConfigTemplate) error {
+	return lintResult(LintConfigTemplate(template, s.lintNodes()))
+}
+
+// lintNodes 获取用于模板检查的节点（未设置节点提供者时返回 nil 跳过节点检查）
+func (s *Service) lintNodes() []ProxyNode {
+	if s.nodeProvider == nil {
+		return nil
+	}
+	nodes := s.listNodes()
+	if nodes == nil {
+		nodes = []ProxyNode{}
+	}
+	return nodes
+}
+
+// ReplaceConfigTemplate 整体替换配置模板（供配置方案调用）
+func (s *Service) ReplaceConfigTemplate(template *ConfigTemplate) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var copy ConfigTemplate
+	cloneJSON(template, &copy)
+	return s.commitConfigTemplate(&copy)
+}
+
+// LintConfigTemplate 检查当前配置模板
+func (s *Service) LintConfigTemplate() []TemplateLintIssue {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return LintConfigTemplate(s.configTemplate, s.lintNodes())
 }
 
 // ResetConfigTemplate 重置配置模板（只重置代理组，保留用户自定义规则）
@@ -986,23 +1222,22 @@ func (s *Service) ResetConfigTemplate() {
 		}
 	}
 
-	// 使用默认模板
-	s.configTemplate = defaultTemplate
-
 	// 将用户自定义规则插入到 MATCH 规则之前
 	if len(customRules) > 0 {
 		var newRules []RuleTemplate
-		for _, r := range s.configTemplate.Rules {
+		for _, r := range defaultTemplate.Rules {
 			if r.Type == "MATCH" {
 				// 在 MATCH 之前插入自定义规则
 				newRules = append(newRules, customRules...)
 			}
 			newRules = append(newRules, r)
 		}
-		s.configTemplate.Rules = newRules
+		defaultTemplate.Rules = newRules
 	}
 
-	s.saveConfigTemplate()
+	if err := s.commitConfigTemplate(defaultTemplate); err != nil {
+		fmt.Printf("⚠️ 重置配置模板失败: %v\n", err)
+	}
 }
 
 // prepareSystemForTUN 准备系统环境以启用 TUN 模式
@@ -1125,15 +1360,11 @@ func (s *Service) restoreSystemAfterTUN() {
 
 // GetAllNodes 获取所有节点
 func (s *Service) GetAllNodes() ([]ProxyNode, error) {
-	s.mu.RLock()
-	provider := s.nodeProvider
-	s.mu.RUnlock()
-
-	if provider == nil {
+	if s.nodeProvider == nil {
 		return nil, fmt.Errorf("节点提供者未设置")
 	}
 
-	nodes := provider()
+	nodes := s.listNodes()
 	if len(nodes) == 0 {
 		return nil, fmt.Errorf("没有可用节点")
 	}
@@ -1141,6 +1372,15 @@ func (s *Service) GetAllNodes() ([]ProxyNode, error) {
 	return nodes, nil
 }
 
+// SingBoxNodeReport 检查当前节点能否用于 Sing-Box，返回会被跳过的节点及原因
+func (s *Service) SingBoxNodeReport() ([]SingBoxSkippedNode, error) {
+	nodes, err := s.GetAllNodes()
+	if err != nil {
+		return nil, err
+	}
+	return SingBoxNodeReport(nodes), nil
+}
+
 // GetSingBoxConfigContent 读取 Sing-Box 配置文件内容
 func (s *Service) GetSingBoxConfigContent() (string, error) {
 	configPath := filepath.Join(s.dataDir, "configs", "singbox-config.json")
@@ -1158,11 +1398,158 @@ func (s *Service) GetSingBoxTemplate() *SingBoxTemplate {
 
 // UpdateSingBoxTemplate 更新 Sing-Box 模板配置
 func (s *Service) UpdateSingBoxTemplate(template *SingBoxTemplate) error {
+	if err := lintResult(LintSingBoxTemplate(template, s.lintNodes())); err != nil {
+		return err
+	}
 	return SaveSingBoxTemplate(s.dataDir, template)
 }
 
+// LintSingBoxTemplate 检查当前 Sing-Box 模板
+func (s *Service) LintSingBoxTemplate() []TemplateLintIssue {
+	return LintSingBoxTemplate(s.GetSingBoxTemplate(), s.lintNodes())
+}
+
 // ResetSingBoxTemplate 重置 Sing-Box 模板为默认值
 func (s *Service) ResetSingBoxTemplate() {
 	template := GetDefaultSingBoxTemplate()
 	SaveSingBoxTemplate(s.dataDir, template)
 }
+
+// loadRoutingTemplate 加载统一路由模板，启用时用其编译结果作为 Mihomo 模板
+func (s *Service) loadRoutingTemplate() {
+	routing, err := LoadRoutingTemplate(s.dataDir)
+	if err != nil {
+		fmt.Printf("⚠️ 读取统一路由模板失败: %v\n", err)
+		return
+	}
+	if routing == nil {
+		return
+	}
+	s.routingTemplate = routing
+	s.configTemplate, _, _ = routing.CompileMihomo()
+}
+
+// syncRoutingTemplate 将修改后的 Mihomo 模板写回统一路由模板，返回重新编译的模板（调用方需持有 s.mu）
+// 保存失败时统一路由模板保持不变
+func (s *Service) syncRoutingTemplate(candidate *ConfigTemplate) (*ConfigTemplate, error) {
+	if s.routingTemplate == nil {
+		return candidate, nil
+	}
+	var routing RoutingTemplate
+	cloneJSON(s.routingTemplate, &routing)
+	routing.syncFromConfigTemplate(candidate)
+	if err := SaveRoutingTemplate(s.dataDir, &routing); err != nil {
+		return nil, err
+	}
+	s.routingTemplate = &routing
+	compiled, _, _ := routing.CompileMihomo()
+	return compiled, nil
+}
+
+// commitConfigTemplate 同步统一路由模板后替换并保存 Mihomo 模板（调用方需持有 s.mu）
+func (s *Service) commitConfigTemplate(candidate *ConfigTemplate) error {
+	compiled, err := s.syncRoutingTemplate(candidate)
+	if err != nil {
+		return err
+	}
+	s.configTemplate = compiled
+	return s.saveConfigTemplate()
+}
+
+// GetRoutingTemplate 获取统一路由模板（未启用时返回 nil）
+func (s *Service) GetRoutingTemplate() *RoutingTemplate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.routingTemplate
+}
+
+// UpdateRoutingTemplate 保存并启用统一路由模板，返回无法翻译到各核心的功能
+func (s *Service) UpdateRoutingTemplate(routing *RoutingTemplate) ([]RoutingIssue, error) {
+	compiled, _, _ := routing.CompileMihomo()
+
+	s.mu.Lock()
+	if err := s.lintConfigTemplate(compiled); err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	if err := SaveRoutingTemplate(s.dataDir, routing); err != nil {
+		s.mu.Unlock()
+		return nil, err
+	}
+	s.routingTemplate = routing
+	s.configTemplate = compiled
+	err := s.saveConfigTemplate()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	s.regenerateIfRunning()
+	return s.RoutingReport(), nil
+}
+
+// MigrateRoutingTemplate 由当前两个核心的模板生成统一路由模板，dryRun 时只返回结果不保存
+func (s *Service) MigrateRoutingTemplate(dryRun bool) (*RoutingTemplate, []RoutingIssue, error) {
+	s.mu.RLock()
+	mihomo := s.configTemplate
+	s.mu.RUnlock()
+
+	routing, notes := MigrateRoutingTemplate(mihomo, s.GetSingBoxTemplate())
+	if dryRun {
+		return routing, append(notes, routing.TranslationReport(s.listNodes(), true)...), nil
+	}
+	issues, err := s.UpdateRoutingTemplate(routing)
+	if err != nil {
+		return nil, nil, err
+	}
+	return routing, append(notes, issues...), nil
+}
+
+// DisableRoutingTemplate 停用统一路由模板，恢复使用各核心独立模板（Mihomo 模板保留编译结果）
+func (s *Service) DisableRoutingTemplate() error {
+	s.mu.Lock()
+	if err := RemoveRoutingTemplate(s.dataDir); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+	s.routingTemplate = nil
+	s.mu.Unlock()
+
+	s.regenerateIfRunning()
+	return nil
+}
+
+// RoutingReport 统一路由模板的翻译报告
+func (s *Service) RoutingReport() []RoutingIssue {
+	s.mu.RLock()
+	routing := s.routingTemplate
+	s.mu.RUnlock()
+	if routing == nil {
+		return nil
+	}
+	return routing.TranslationReport(s.listNodes(), true)
+}
+
+// regenerateIfRunning 代理运行中时重新生成配置并重启使其生效
+func (s *Service) regenerateIfRunning() {
+	if !s.GetStatus().Running || s.nodeProvider == nil {
+		return
+	}
+	if _, err := s.regenerateConfig(); err != nil {
+		fmt.Printf("⚠️ 重新生成配置失败: %v\n", err)
+		return
+	}
+	if err := s.Restart(); err != nil {
+		fmt.Printf("⚠️ 重启代理失败: %v\n", err)
+	}
+}
+
+// GetOverride 获取指定核心的覆写内容
+func (s *Service) GetOverride(coreType string) (string, error) {
+	return LoadOverride(s.dataDir, coreType)
+}
+
+// UpdateOverride 保存指定核心的覆写内容，下次生成配置时生效
+func (s *Service) UpdateOverride(coreType, content string) error {
+	return SaveOverride(s.dataDir, coreType, content)
+}
@@ -5,6 +5,7 @@ type AuthUser struct {
 	Username string `json:"username" yaml:"username"`
 	Password string `json:"password" yaml:"password"`
 	Enabled  bool   `json:"enabled" yaml:"enabled"` // 是否启用此账号
+	Target   string `json:"target" yaml:"target"`   // 该用户的出口代理组，为空时按规则分流
 }
 
 // ProxySettings 代理核心设置
@@ -22,7 +23,15 @@ type ProxySettings struct {
 	TProxyPort        int  `json:"tproxyPort" yaml:"tproxy-port"`                // TProxy 端口 (Linux)
 
 	// === 认证设置 ===
-	Authentication []AuthUser `json:"authentication" yaml:"authentication"` // 代理认证用户列表 (启用的账号自动开启认证)
+	Authentication   []AuthUser `json:"authentication" yaml:"authentication"`       // 代理认证用户列表 (启用的账号自动开启认证)
+	SkipAuthPrefixes []string   `json:"skipAuthPrefixes" yaml:"skip-auth-prefixes"` // 免认证的来源网段
+
+	// === 局域网访问控制 ===
+	LanAllowedIPs    []string `json:"lanAllowedIps" yaml:"lan-allowed-ips"`       // 允许连接的来源网段，为空时不限制
+	LanDisallowedIPs []string `json:"lanDisallowedIps" yaml:"lan-disallowed-ips"` // 禁止连接的来源网段 (优先于允许列表)
+
+	// === 额外入站 ===
+	Listeners []ListenerSettings `json:"listeners" yaml:"listeners"` // 额外入站监听器 (Mihomo listeners / sing-box inbounds)
 
 	// === 基础设置 ===
 	AllowLan       bool   `json:"allowLan" yaml:"allow-lan"`              // 允许局域网连接
@@ -74,6 +83,28 @@ type ProxySettings struct {
 	Sniffer SnifferSettings `json:"sniffer" yaml:"sniffer"`
 }
 
+// ListenerSettings 额外入站监听器
+// 设置目标分组后自动生成 IN-NAME 规则，用户单独设置目标分组时生成 IN-NAME + IN-USER 规则
+type ListenerSettings struct {
+	Name     string         `json:"name" yaml:"name"`                             // 名称 (Mihomo listener name / sing-box inbound tag)
+	Type     string         `json:"type" yaml:"type"`                             // mixed/socks/http/shadowsocks/vmess
+	Enabled  bool           `json:"enabled" yaml:"enabled"`                       // 是否启用
+	Listen   string         `json:"listen" yaml:"listen"`                         // 监听地址，为空时使用 0.0.0.0
+	Port     int            `json:"port" yaml:"port"`                             // 监听端口
+	UDP      bool           `json:"udp" yaml:"udp"`                               // 启用 UDP (socks/mixed/shadowsocks)
+	Cipher   string         `json:"cipher,omitempty" yaml:"cipher,omitempty"`     // Shadowsocks 加密方式
+	Password string         `json:"password,omitempty" yaml:"password,omitempty"` // Shadowsocks 密码
+	Users    []ListenerUser `json:"users" yaml:"users"`                           // 认证用户 (VMess 用户的密码为 UUID)
+	Target   string         `json:"target" yaml:"target"`                         // 目标代理组，为空时按规则分流
+}
+
+// ListenerUser 入站监听器用户
+type ListenerUser struct {
+	Username string `json:"username" yaml:"username"`
+	Password string `json:"password" yaml:"password"`
+	Target   string `json:"target" yaml:"target"` // 该用户的目标代理组，为空时使用监听器的目标
+}
+
 // DNSSettings DNS 设置
 type DNSSettings struct {
 	Enable         bool   `json:"enable" yaml:"enable"`
@@ -153,7 +184,15 @@ func GetDefaultProxySettings() *ProxySettings {
 		TProxyPort:        7894,
 
 		// 认证 (默认为空，不启用认证)
-		Authentication: []AuthUser{},
+		Authentication:   []AuthUser{},
+		SkipAuthPrefixes: []string{"127.0.0.1/8", "::1/128"},
+
+		// 局域网访问控制 (默认不限制)
+		LanAllowedIPs:    []string{},
+		LanDisallowedIPs: []string{},
+
+		// 额外入站 (默认为空)
+		Listeners: []ListenerSettings{},
 
 		// 基础设置
 		AllowLan:       true,
@@ -16,7 +16,8 @@ type SettingsHandler struct {
 	dataDir      string
 	settings     *ProxySettings
 	mu           sync.RWMutex
-	proxyService *Service // 代理服务引用，用于同步配置
+	proxyService *Service        // 代理服务引用，用于同步配置
+	profiles     *ProfileManager // 配置方案管理，预设由方案提供
 }
 
 // NewSettingsHandler 创建设置处理器
@@ -35,6 +36,11 @@ func (h *SettingsHandler) SetProxyService(s *Service) {
 	h.proxyService = s
 }
 
+// SetProfileManager 设置配置方案管理器
+func (h *SettingsHandler) SetProfileManager(m *ProfileManager) {
+	h.profiles = m
+}
+
 // RegisterRoutes 注册路由
 func (h *SettingsHandler) RegisterRoutes(r *gin.RouterGroup) {
 	r.GET("/settings", h.GetSettings)
@@ -42,6 +48,8 @@ func (h *SettingsHandler) RegisterRoutes(r *gin.RouterGroup) {
 	r.POST("/settings/reset", h.ResetSettings)
 	r.GET("/settings/presets", h.GetPresets)
 	r.POST("/settings/apply-preset", h.ApplyPreset)
+	r.GET("/settings/access", h.GetAccessSettings)
+	r.PUT("/settings/access", h.UpdateAccessSettings)
 }
 
 // settingsFilePath 获取设置文件路径
@@ -114,6 +122,13 @@ func (h *SettingsHandler) UpdateSettings(c *gin.Context) {
 		})
 		return
 	}
+	if err := settings.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "Invalid settings: " + err.Error(),
+		})
+		return
+	}
 
 	h.mu.Lock()
 	h.settings = &settings
@@ -142,6 +157,59 @@ func (h *SettingsHandler) UpdateSettings(c *gin.Context) {
 	})
 }
 
+// GetAccessSettings 获取认证与局域网访问控制设置
+func (h *SettingsHandler) GetAccessSettings(c *gin.Context) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	c.JSON(http.StatusOK, gin.H{
+		"code": 0,
+		"data": h.settings.accessSettings(),
+	})
+}
+
+// UpdateAccessSettings 更新认证用户、免认证网段、局域网允许/禁止网段与按用户分流
+func (h *SettingsHandler) UpdateAccessSettings(c *gin.Context) {
+	var access AccessSettings
+	if err := c.ShouldBindJSON(&access); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "Invalid settings: " + err.Error(),
+		})
+		return
+	}
+	if err := ValidateAccessSettings(access); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "Invalid settings: " + err.Error(),
+		})
+		return
+	}
+
+	h.mu.Lock()
+	h.settings.applyAccessSettings(access)
+	err := h.saveSettings()
+	h.mu.Unlock()
+
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    1,
+			"message": "Failed to save settings: " + err.Error(),
+		})
+		return
+	}
+
+	// 代理运行中时重新生成配置，使访问控制立即生效
+	if h.proxyService != nil {
+		h.proxyService.regenerateIfRunning()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "Settings updated successfully",
+	})
+}
+
 // ResetSettings 重置为默认设置
 func (h *SettingsHandler) ResetSettings(c *gin.Context) {
 	h.mu.Lock()
@@ -172,8 +240,21 @@ type SettingsPreset struct {
 	Icon        string `json:"icon"`
 }
 
-// GetPresets 获取预设列表
+// GetPresets 获取预设列表（由配置方案提供）
 func (h *SettingsHandler) GetPresets(c *gin.Context) {
+	if h.profiles != nil {
+		profiles, _ := h.profiles.List()
+		presets := make([]SettingsPreset, 0, len(profiles))
+		for _, p := range profiles {
+			presets = append(presets, SettingsPreset{ID: p.ID, Name: p.Name, Description: p.Description, Icon: p.Icon})
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"code": 0,
+			"data": presets,
+		})
+		return
+	}
+
 	presets := []SettingsPreset{
 		{
 			ID:          "gateway",
@@ -208,6 +289,23 @@ func (h *SettingsHandler) ApplyPreset(c *gin.Context) {
 		return
 	}
 
+	// 预设来自同名配置方案，只应用其中的代理设置，不替换用户模板
+	if h.profiles != nil {
+		if err := h.profiles.ApplySettings(req.PresetID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"code":    1,
+				"message": err.Error(),
+			})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"code":    0,
+			"message": "Preset applied successfully",
+			"data":    h.GetCurrentSettings(),
+		})
+		return
+	}
+
 	h.mu.Lock()
 	defer h.mu.Unlock()
 
@@ -251,6 +349,31 @@ func (h *SettingsHandler) GetCurrentSettings() *ProxySettings {
 	return &copy
 }
 
+// ReplaceSettings 替换当前设置并同步到代理服务（供配置方案调用）
+func (h *SettingsHandler) ReplaceSettings(settings *ProxySettings) error {
+	var copy ProxySettings
+	cloneJSON(settings, &copy)
+	if err := copy.Validate(); err != nil {
+		return err
+	}
+
+	h.mu.Lock()
+	h.settings = &copy
+	err := h.saveSettings()
+	h.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	if h.proxyService != nil {
+		h.proxyService.PatchConfig(map[string]interface{}{
+			"autoStart":      copy.AutoStart,
+			"autoStartDelay": float64(copy.AutoStartDelay),
+		})
+	}
+	return nil
+}
+
 // === 预设配置 ===
 
 // getGatewayPreset Linux 网关预设
@@ -617,28 +617,46 @@ func (g *SingboxGenerator) GenerateConfigV112(nodes []ProxyNode, opts SingBoxGen
 	}
 
 	// 转换节点为 outbounds，并收集手动节点名称
-	nodeOutbounds := make([]SBOutbound, 0, len(nodes))
-	manualNodeNames := make([]string, 0)
-	for _, node := range nodes {
-		outbound, err := ParseNodeToSingBox(node)
-		if err != nil {
-			continue // 跳过无法解析的节点
-		}
-		nodeOutbounds = append(nodeOutbounds, *outbound)
-		// 收集手动节点名称（与 Mihomo 一致）
-		if node.IsManual {
-			manualNodeNames = append(manualNodeNames, outbound.Tag)
-		}
+	converted := convertNodesV112(nodes)
+	nodeOutbounds := converted.Nodes
+	manualNodeNames := converted.Manual
+	if manualNodeNames == nil {
+		manualNodeNames = []string{}
+	}
+	for _, skipped := range converted.Skipped {
+		fmt.Printf("⚠️ Sing-Box 跳过节点 %s (%s): %s\n", skipped.Name, skipped.Type, skipped.Reason)
 	}
 
 	// 生成代理组（传入手动节点名称列表）
-	proxyGroups := g.generateProxyGroupsV112(nodeOutbounds, manualNodeNames)
+	var routing *singBoxRouting
+	var proxyGroups []SBOutbound
+	if opts.Routing != nil {
+		nodeTags := make([]string, 0, len(nodeOutbounds))
+		for _, ob := range nodeOutbounds {
+			nodeTags = append(nodeTags, ob.Tag)
+		}
+		var issues []RoutingIssue
+		routing, issues = opts.Routing.CompileSingBox(nodeTags, manualNodeNames, opts.FakeIP)
+		for _, issue := range issues {
+			fmt.Printf("⚠️ 统一路由模板 %s: %s\n", issue.Path, issue.Message)
+		}
+		proxyGroups = routing.Groups
+	} else {
+		proxyGroups = g.generateProxyGroupsV112(nodeOutbounds, manualNodeNames)
+	}
 
 	// 组合所有 outbounds
-	// 顺序: 代理组 -> 节点 -> 特殊出站(direct/block/dns-out)
+	// 顺序: 代理组 -> 节点 -> 前置出站 -> 特殊出站(direct/block/dns-out)，WireGuard 节点作为端点
 	allOutbounds := make([]SBOutbound, 0)
 	allOutbounds = append(allOutbounds, proxyGroups...)
-	allOutbounds = append(allOutbounds, nodeOutbounds...)
+	for _, ob := range nodeOutbounds {
+		if ob.Type == "wireguard" {
+			config.Endpoints = append(config.Endpoints, wireGuardEndpoint(ob))
+			continue
+		}
+		allOutbounds = append(allOutbounds, ob)
+	}
+	allOutbounds = append(allOutbounds, converted.Detours...)
 	// 添加内置出站 (Sing-Box 内置 direct/block)
 	allOutbounds = append(allOutbounds,
 		SBOutbound{Type: "direct", Tag: "direct"},
@@ -651,9 +669,294 @@ func (g *SingboxGenerator) GenerateConfigV112(nodes []ProxyNode, opts SingBoxGen
 	// 添加路由规则
 	config.Route.Rules = GetDefaultRouteRules()
 	config.Route.RuleSet = GetDefaultRuleSets()
+	if routing != nil {
+		g.applyRoutingV112(config, routing)
+	} else {
+		g.applyCustomRulesV112(config, opts)
+	}
+
+	// 额外入站、访问控制及其分流规则（监听器规则优先于按用户规则）
+	listenerRules := applySingBoxListeners(config, opts.Listeners)
+	aclRules, userRules := applySingBoxAccess(config, opts.Access)
+	insertSingBoxRules(config.Route, aclRules, append(listenerRules, userRules...))
+
+	// 停用的规则集
+	removeSingBoxRuleSets(config, opts.DisabledRuleSets)
 
 	return config, nil
 }
+
+// applyRoutingV112 使用统一路由模板的编译结果替换内置分流规则
+// 保留嗅探、DNS 劫持、clash_mode 等基础规则，其余分流规则全部来自模板
+func (g *SingboxGenerator) applyRoutingV112(config *SingBoxConfig, routing *singBoxRouting) {
+	groups := make(map[string]bool, len(routing.Groups))
+	for _, ob := range routing.Groups {
+		groups[ob.Tag] = true
+	}
+
+	rules := make([]SBRouteRule, 0, len(routing.Rules)+8)
+	for _, rule := range config.Route.Rules {
+		if rule.ClashMode == "global" {
+			// 全局模式指向的分组不存在时改用模板的第一个分组
+			if !groups[rule.Outbound] && len(routing.Groups) > 0 {
+				rule.Outbound = routing.Groups[0].Tag
+			}
+			rules = append(rules, rule)
+			continue
+		}
+		if rule.RuleSet == nil && (rule.Outbound == "" || rule.Outbound == "direct") {
+			rules = append(rules, rule)
+		}
+	}
+	config.Route.Rules = append(rules, routing.Rules...)
+
+	// 规则集：内置定义 + 模板定义（同名时模板优先）
+	ruleSets := make([]SBRuleSet, 0, len(config.Route.RuleSet)+len(routing.RuleSets))
+	ruleSets = append(ruleSets, routing.RuleSets...)
+	for _, rs := range config.Route.RuleSet {
+		exists := false
+		for _, defined := range routing.RuleSets {
+			if defined.Tag == rs.Tag {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			ruleSets = append(ruleSets, rs)
+		}
+	}
+	config.Route.RuleSet = ruleSets
+	for _, rule := range config.Route.Rules {
+		ensureSingBoxRuleSets(config.Route, rule)
+	}
+
+	if routing.Final != "" {
+		config.Route.Final = routing.Final
+	}
+
+	// DNS 策略插入到 clash_mode DNS 规则之后
+	if config.DNS != nil && len(routing.DNSRules) > 0 {
+		insertAt := 0
+		for i, rule := range config.DNS.Rules {
+			if rule.ClashMode != "" {
+				insertAt = i + 1
+			}
+		}
+		dnsRules := make([]SBDNSRule, 0, len(config.DNS.Rules)+len(routing.DNSRules))
+		dnsRules = append(dnsRules, config.DNS.Rules[:insertAt]...)
+		dnsRules = append(dnsRules, routing.DNSRules...)
+		dnsRules = append(dnsRules, config.DNS.Rules[insertAt:]...)
+		config.DNS.Rules = dnsRules
+		for _, rule := range routing.DNSRules {
+			ensureSingBoxRuleSets(config.Route, SBRouteRule{RuleSet: rule.RuleSet})
+		}
+	}
+}
+
+// applyCustomRulesV112 将用户自定义规则转换后插入到内置分流规则之前
+// 无法用 sing-box 表达的规则会被跳过并输出警告
+func (g *SingboxGenerator) applyCustomRulesV112(config *SingBoxConfig, opts SingBoxGeneratorOptions) {
+	if len(opts.CustomRules) == 0 {
+		return
+	}
+
+	outbounds := make(map[string]bool, len(config.Outbounds))
+	for _, ob := range config.Outbounds {
+		outbounds[ob.Tag] = true
+	}
+
+	var custom []SBRouteRule
+	for _, t := range opts.CustomRules {
+		if strings.EqualFold(t.Type, "MATCH") {
+			continue // 兜底由 route.final 负责
+		}
+		rules, err := ConvertRuleToSingBox(t, opts.SubRules)
+		if err == nil {
+			for _, rule := range rules {
+				if rule.Outbound != "" && !outbounds[rule.Outbound] {
+					err = fmt.Errorf("出站 %s 不存在", rule.Outbound)
+					break
+				}
+			}
+		}
+		if err != nil {
+			fmt.Printf("⚠️ 跳过 sing-box 无法表达的规则 %s: %v\n", formatMihomoRule(t), err)
+			continue
+		}
+		custom = append(custom, rules...)
+	}
+	if len(custom) == 0 {
+		return
+	}
+
+	for _, rule := range custom {
+		ensureSingBoxRuleSets(config.Route, rule)
+	}
+
+	// 插入到 clash_mode 规则之后，保证全局/直连模式切换仍然优先
+	insertAt := -1
+	for i, rule := range config.Route.Rules {
+		if rule.ClashMode != "" {
+			insertAt = i + 1
+		}
+	}
+	if insertAt < 0 {
+		insertAt = len(config.Route.Rules)
+		for i, rule := range config.Route.Rules {
+			if rule.RuleSet != nil {
+				insertAt = i
+				break
+			}
+		}
+	}
+	rules := make([]SBRouteRule, 0, len(config.Route.Rules)+len(custom))
+	rules = append(rules, config.Route.Rules[:insertAt]...)
+	rules = append(rules, custom...)
+	rules = append(rules, config.Route.Rules[insertAt:]...)
+	config.Route.Rules = rules
+}
+
+// removeSingBoxRuleSets 移除停用的规则集定义，以及路由与 DNS 规则中对它们的引用
+// 规则只引用停用的规则集时整条移除，逻辑规则 and 中任一子规则被移除时整条移除
+func removeSingBoxRuleSets(config *SingBoxConfig, names []string) {
+	if len(names) == 0 || config.Route == nil {
+		return
+	}
+	disabled := make(map[string]bool, len(names))
+	for _, name := range names {
+		disabled[name] = true
+	}
+
+	ruleSets := make([]SBRuleSet, 0, len(config.Route.RuleSet))
+	for _, rs := range config.Route.RuleSet {
+		if !disabled[rs.Tag] {
+			ruleSets = append(ruleSets, rs)
+		}
+	}
+	config.Route.RuleSet = ruleSets
+
+	var routeRules []SBRouteRule
+	for _, rule := range config.Route.Rules {
+		if filtered, ok := filterSingBoxRouteRule(rule, disabled); ok {
+			routeRules = append(routeRules, filtered)
+		}
+	}
+	config.Route.Rules = routeRules
+
+	if config.DNS != nil {
+		var dnsRules []SBDNSRule
+		for _, rule := range config.DNS.Rules {
+			if filtered, ok := filterSingBoxDNSRule(rule, disabled); ok {
+				dnsRules = append(dnsRules, filtered)
+			}
+		}
+		config.DNS.Rules = dnsRules
+	}
+}
+
+// filterRuleSetRefs 去掉停用的规则集引用，返回新的引用与是否保留规则
+func filterRuleSetRefs(v interface{}, disabled map[string]bool) (interface{}, bool) {
+	refs := singBoxRuleSetRefs(v)
+	if len(refs) == 0 {
+		return v, true
+	}
+	var kept []string
+	for _, tag := range refs {
+		if !disabled[tag] {
+			kept = append(kept, tag)
+		}
+	}
+	switch {
+	case len(kept) == len(refs):
+		return v, true
+	case len(kept) == 0:
+		return nil, false
+	case len(kept) == 1:
+		return kept[0], true
+	}
+	return kept, true
+}
+
+// filterSingBoxRouteRule 过滤路由规则中的停用规则集
+func filterSingBoxRouteRule(rule SBRouteRule, disabled map[string]bool) (SBRouteRule, bool) {
+	var ok bool
+	if rule.RuleSet, ok = filterRuleSetRefs(rule.RuleSet, disabled); !ok {
+		return rule, false
+	}
+	if len(rule.Rules) == 0 {
+		return rule, true
+	}
+	var inner []SBRouteRule
+	for _, r := range rule.Rules {
+		filtered, ok := filterSingBoxRouteRule(r, disabled)
+		if !ok {
+			if rule.Mode != "or" {
+				return rule, false
+			}
+			continue
+		}
+		inner = append(inner, filtered)
+	}
+	rule.Rules = inner
+	return rule, len(inner) > 0
+}
+
+// filterSingBoxDNSRule 过滤 DNS 规则中的停用规则集
+func filterSingBoxDNSRule(rule SBDNSRule, disabled map[string]bool) (SBDNSRule, bool) {
+	var ok bool
+	if rule.RuleSet, ok = filterRuleSetRefs(rule.RuleSet, disabled); !ok {
+		return rule, false
+	}
+	if len(rule.Rules) == 0 {
+		return rule, true
+	}
+	var inner []SBDNSRule
+	for _, r := range rule.Rules {
+		filtered, ok := filterSingBoxDNSRule(r, disabled)
+		if !ok {
+			if rule.Mode != "or" {
+				return rule, false
+			}
+			continue
+		}
+		inner = append(inner, filtered)
+	}
+	rule.Rules = inner
+	return rule, len(inner) > 0
+}
+
+// ensureSingBoxRuleSets 为规则引用但未定义的 geosite/geoip 规则集补充远程定义
+func ensureSingBoxRuleSets(route *SBRoute, rule SBRouteRule) {
+	for _, inner := range rule.Rules {
+		ensureSingBoxRuleSets(route, inner)
+	}
+	for _, tag := range singBoxRuleSetRefs(rule.RuleSet) {
+		exists := false
+		for _, rs := range route.RuleSet {
+			if rs.Tag == tag {
+				exists = true
+				break
+			}
+		}
+		if exists {
+			continue
+		}
+		if localPath := GetSingBoxRulesetDir() + "/" + tag + ".srs"; fileExists(localPath) {
+			route.RuleSet = append(route.RuleSet, SBRuleSet{Tag: tag, Type: "local", Format: "binary", Path: localPath})
+			continue
+		}
+		baseURL := "https://raw.githubusercontent.com/SagerNet/sing-geosite/rule-set"
+		if strings.HasPrefix(tag, "geoip-") {
+			baseURL = "https://raw.githubusercontent.com/SagerNet/sing-geoip/rule-set"
+		}
+		route.RuleSet = append(route.RuleSet, SBRuleSet{
+			Tag:    tag,
+			Type:   "remote",
+			Format: "binary",
+			URL:    baseURL + "/" + tag + ".srs",
+		})
+	}
+}
 func (g *SingboxGenerator) generateProxyGroupsV112(nodes []SBOutbound, manualNodeNames []string) []SBOutbound {
 	// 地区过滤关键字 (与 Mihomo 保持一致)
 	regionFilters := map[string][]string{
@@ -804,6 +1107,12 @@ func (g *SingboxGenerator) SaveConfigV112(config *SingBoxConfig, filename string
 		return "", err
 	}
 
+	// 合并用户覆写
+	data, err = applySingBoxOverride(g.dataDir, data)
+	if err != nil {
+		return "", fmt.Errorf("应用覆写失败: %w", err)
+	}
+
 	if err := os.WriteFile(filePath, data, 0644); err != nil {
 		return "", err
 	}
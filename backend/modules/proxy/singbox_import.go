@@ -0,0 +1,531 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ============================================================================
+// sing-box 配置导入（1.10 - 1.12）
+// ============================================================================
+
+// singBoxImportDoc 导入时关心的 sing-box 配置部分
+type singBoxImportDoc struct {
+	Outbounds []map[string]interface{} `json:"outbounds"`
+	Route     map[string]interface{}   `json:"route"`
+}
+
+// singBoxImportedKeys 会被导入的顶层配置项
+var singBoxImportedKeys = map[string]bool{
+	"outbounds": true,
+	"route":     true,
+}
+
+// singBoxRouteImportedKeys 会被导入的 route 配置项
+var singBoxRouteImportedKeys = map[string]bool{
+	"rules":    true,
+	"rule_set": true,
+}
+
+// singBoxRuleImportedKeys 规则模板能表达的字段（规则模板只支持规则集匹配）
+var singBoxRuleImportedKeys = map[string]bool{
+	"rule_set": true,
+	"outbound": true,
+	"action":   true,
+}
+
+// ParseSingBoxConfig 解析 sing-box JSON 配置
+// selector/urltest 出站转为出站组模板，route.rules/route.rule_set 转为规则与规则集模板，协议出站转为手动节点
+func ParseSingBoxConfig(data []byte) (*ImportResult, error) {
+	var doc singBoxImportDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("JSON 解析失败: %w", err)
+	}
+	var top map[string]interface{}
+	json.Unmarshal(data, &top)
+
+	result := &ImportResult{Source: "singbox", SingBoxTemplate: &SingBoxTemplate{}}
+	template := result.SingBoxTemplate
+
+	for _, key := range sortedKeys(top) {
+		if !singBoxImportedKeys[key] {
+			result.issue(key, key, "配置项 %s 未导入", key)
+		}
+	}
+
+	// 先收集出站组与特殊出站，规则与组成员转换时需要
+	groups := make(map[string]bool)
+	aliases := make(map[string]string) // 特殊出站标签 -> 内置出站
+	for _, raw := range doc.Outbounds {
+		tag, _ := raw["tag"].(string)
+		switch raw["type"] {
+		case "selector", "urltest":
+			groups[tag] = true
+		case "direct":
+			aliases[tag] = "direct"
+		case "block":
+			aliases[tag] = "block"
+		case "dns":
+			aliases[tag] = "dns-out"
+		}
+	}
+	mapMember := func(tag string) string {
+		if alias, ok := aliases[tag]; ok {
+			return alias
+		}
+		return tag
+	}
+
+	nodeTags := make(map[string]bool)
+	groupKeys := structFieldNames(SBOutbound{}, "json")
+	for idx, raw := range doc.Outbounds {
+		path := fmt.Sprintf("outbounds[%d]", idx)
+		tag, _ := raw["tag"].(string)
+		outboundType, _ := raw["type"].(string)
+
+		switch outboundType {
+		case "selector", "urltest":
+			var ob SBOutbound
+			if err := remarshalJSON(raw, &ob); err != nil {
+				result.issue(path, tag, "出站组格式错误: %v", err)
+				continue
+			}
+			for _, key := range sortedKeys(raw) {
+				if !groupKeys[key] || key == "interrupt_exist_connections" {
+					result.issue(path+"."+key, tag, "出站组 %s 的字段 %s 未导入", tag, key)
+				}
+			}
+			group := SingBoxProxyGroupTemplate{
+				Tag:       tag,
+				Type:      outboundType,
+				Name:      tag,
+				Enabled:   true,
+				Outbounds: []string{},
+				Default:   ob.Default,
+				URL:       ob.URL,
+				Interval:  ob.Interval,
+				Tolerance: ob.Tolerance,
+			}
+			if name, ok := singBoxGroupNames[tag]; ok {
+				group.Name = name
+			}
+			for _, member := range ob.Outbounds {
+				group.Outbounds = append(group.Outbounds, mapMember(member))
+			}
+			template.ProxyGroups = append(template.ProxyGroups, group)
+
+		case "direct":
+			for _, key := range sortedKeys(raw) {
+				if key != "tag" && key != "type" {
+					result.issue(path+"."+key, tag, "直连出站 %s 的字段 %s 未导入", tag, key)
+				}
+			}
+
+		case "block", "dns":
+			result.issue(path, tag, "特殊出站 %s（%s）自 sing-box 1.11 起已废弃，规则改用 reject / hijack-dns 动作", tag, outboundType)
+
+		default:
+			proxy, unmapped, err := singBoxOutboundToClash(raw)
+			if err != nil {
+				result.issue(path, tag, "出站 %s 无法转换为节点: %v", tag, err)
+				continue
+			}
+			for _, key := range unmapped {
+				result.issue(path+"."+key, tag, "节点 %s 的字段 %s 未导入", tag, key)
+			}
+			node, err := newImportedNode(proxy)
+			if err != nil {
+				result.issue(path, tag, "出站 %s 无法转换为节点: %v", tag, err)
+				continue
+			}
+			if nodeTags[node.Name] {
+				result.issue(path, node.Name, "节点名称 %s 重复，已跳过", node.Name)
+				continue
+			}
+			nodeTags[node.Name] = true
+			result.Nodes = append(result.Nodes, node)
+		}
+	}
+
+	// 路由
+	for _, key := range sortedKeys(doc.Route) {
+		if !singBoxRouteImportedKeys[key] {
+			result.issue("route."+key, key, "路由配置项 %s 未导入", key)
+		}
+	}
+	var route struct {
+		Rules   []map[string]interface{} `json:"rules"`
+		RuleSet []map[string]interface{} `json:"rule_set"`
+	}
+	remarshalJSON(doc.Route, &route)
+
+	for idx, raw := range route.RuleSet {
+		path := fmt.Sprintf("route.rule_set[%d]", idx)
+		if rs, ok := parseSingBoxRuleSet(raw, path, result); ok {
+			template.RuleSets = append(template.RuleSets, rs)
+		}
+	}
+
+	for idx, raw := range route.Rules {
+		path := fmt.Sprintf("route.rules[%d]", idx)
+		if rule, ok := parseSingBoxRule(raw, path, groups, aliases, result); ok {
+			template.Rules = append(template.Rules, rule)
+		}
+	}
+
+	return result, nil
+}
+
+// parseSingBoxRuleSet 转换规则集定义
+func parseSingBoxRuleSet(raw map[string]interface{}, path string, result *ImportResult) (SingBoxRuleSetTemplate, bool) {
+	var rs SBRuleSet
+	if err := remarshalJSON(raw, &rs); err != nil || rs.Tag == "" {
+		result.issue(path, fmt.Sprint(raw["tag"]), "规则集格式错误")
+		return SingBoxRuleSetTemplate{}, false
+	}
+	if rs.Type != "remote" && rs.Type != "local" {
+		result.issue(path, rs.Tag, "不支持 %s 类型的规则集 %s，已跳过", rs.Type, rs.Tag)
+		return SingBoxRuleSetTemplate{}, false
+	}
+	for _, key := range sortedKeys(raw) {
+		switch key {
+		case "tag", "type", "format", "path", "url":
+		default:
+			result.issue(path+"."+key, rs.Tag, "规则集 %s 的字段 %s 未导入", rs.Tag, key)
+		}
+	}
+
+	format := rs.Format
+	if format == "" {
+		// 1.10 之后可由扩展名推断格式
+		format = "source"
+		if strings.HasSuffix(rs.URL, ".srs") || strings.HasSuffix(rs.Path, ".srs") {
+			format = "binary"
+		}
+	}
+	return SingBoxRuleSetTemplate{Tag: rs.Tag, Type: rs.Type, Format: format, Path: rs.Path, URL: rs.URL}, true
+}
+
+// parseSingBoxRule 转换路由规则，只有按规则集匹配的规则可以表达为规则模板
+func parseSingBoxRule(raw map[string]interface{}, path string, groups map[string]bool, aliases map[string]string, result *ImportResult) (SingBoxRuleTemplate, bool) {
+	action, _ := raw["action"].(string)
+	outbound, _ := raw["outbound"].(string)
+
+	switch action {
+	case "", "route":
+	case "reject":
+	case "sniff", "hijack-dns":
+		result.issue(path, action, "%s 动作由 P-BOX 自动生成，已跳过", action)
+		return SingBoxRuleTemplate{}, false
+	default:
+		result.issue(path, action, "不支持 %s 动作，已跳过", action)
+		return SingBoxRuleTemplate{}, false
+	}
+
+	if aliases[outbound] == "dns-out" {
+		result.issue(path, outbound, "路由到 DNS 出站的规则由 hijack-dns 动作代替，已跳过")
+		return SingBoxRuleTemplate{}, false
+	}
+	if raw["type"] == "logical" {
+		result.issue(path, "logical", "规则模板不支持逻辑规则，已跳过")
+		return SingBoxRuleTemplate{}, false
+	}
+	var matchers []string
+	for _, key := range sortedKeys(raw) {
+		if !singBoxRuleImportedKeys[key] {
+			matchers = append(matchers, key)
+		}
+	}
+	if len(matchers) > 0 {
+		switch {
+		case containsString(matchers, "clash_mode"):
+			result.issue(path, "clash_mode", "clash_mode 规则由 P-BOX 自动生成，已跳过")
+		case containsString(matchers, "geosite") || containsString(matchers, "geoip"):
+			result.issue(path, strings.Join(matchers, ","), "旧版 geosite/geoip 匹配已废弃，请改用规则集")
+		default:
+			result.issue(path, strings.Join(matchers, ","), "规则模板只支持规则集匹配，%s 条件无法导入", strings.Join(matchers, ", "))
+		}
+		return SingBoxRuleTemplate{}, false
+	}
+	if len(singBoxRuleSetRefs(raw["rule_set"])) == 0 {
+		result.issue(path, "", "规则没有匹配条件，已跳过")
+		return SingBoxRuleTemplate{}, false
+	}
+
+	var rule SingBoxRuleTemplate
+	if refs := singBoxRuleSetRefs(raw["rule_set"]); len(refs) == 1 {
+		rule.RuleSet = refs[0]
+	} else {
+		rule.RuleSet = refs
+	}
+
+	if action == "reject" {
+		rule.Action = "reject"
+		return rule, true
+	}
+	if alias, ok := aliases[outbound]; ok {
+		outbound = alias
+	}
+	switch {
+	case outbound == "block":
+		// 1.10 旧版写法：路由到 block 出站
+		rule.Action = "reject"
+	case outbound == "direct" || groups[outbound]:
+		rule.Outbound = outbound
+	default:
+		result.issue(path, outbound, "规则出站 %s 不是出站组，规则模板无法直接指向节点", outbound)
+		return SingBoxRuleTemplate{}, false
+	}
+	return rule, true
+}
+
+// ============================================================================
+// sing-box 出站 -> Clash 节点配置（singbox_parsers.go 的逆向转换）
+// ============================================================================
+
+// singBoxOutboundToClash 将协议出站转换为 Clash 格式的节点配置，返回未能映射的字段
+func singBoxOutboundToClash(raw map[string]interface{}) (map[string]interface{}, []string, error) {
+	var ob SBOutbound
+	if err := remarshalJSON(raw, &ob); err != nil {
+		return nil, nil, err
+	}
+
+	proxy := map[string]interface{}{
+		"name":   ob.Tag,
+		"server": ob.Server,
+		"port":   ob.ServerPort,
+	}
+	handled := map[string]bool{"tag": true, "type": true, "server": true, "server_port": true}
+	use := func(keys ...string) {
+		for _, key := range keys {
+			handled[key] = true
+		}
+	}
+	var unmapped []string
+
+	// vmess/vless 使用 servername，其他协议使用 sni；tlsAlways 表示协议固定启用 TLS
+	sniKey, tlsAlways := "sni", true
+	switch ob.Type {
+	case "vmess":
+		proxy["type"] = "vmess"
+		proxy["uuid"] = ob.UUID
+		proxy["alterId"] = ob.AlterId
+		proxy["cipher"] = ob.Security
+		if ob.Security == "" {
+			proxy["cipher"] = "auto"
+		}
+		use("uuid", "alter_id", "security", "packet_encoding")
+		sniKey, tlsAlways = "servername", false
+	case "vless":
+		proxy["type"] = "vless"
+		proxy["uuid"] = ob.UUID
+		if ob.Flow != "" {
+			proxy["flow"] = ob.Flow
+		}
+		use("uuid", "flow", "packet_encoding")
+		sniKey, tlsAlways = "servername", false
+	case "shadowsocks":
+		proxy["type"] = "ss"
+		proxy["cipher"] = ob.Method
+		proxy["password"] = ob.Password
+		if ob.Plugin != "" {
+			proxy["plugin"] = ob.Plugin
+			opts := make(map[string]interface{})
+			for _, kv := range strings.Split(ob.PluginOpts, ";") {
+				if parts := strings.SplitN(kv, "=", 2); len(parts) == 2 {
+					opts[parts[0]] = parts[1]
+				}
+			}
+			proxy["plugin-opts"] = opts
+		}
+		use("method", "password", "plugin", "plugin_opts")
+		tlsAlways = false
+	case "trojan":
+		proxy["type"] = "trojan"
+		proxy["password"] = ob.Password
+		use("password")
+	case "hysteria2":
+		proxy["type"] = "hysteria2"
+		proxy["password"] = ob.Password
+		if ob.UpMbps > 0 {
+			proxy["up"] = fmt.Sprintf("%d Mbps", ob.UpMbps)
+		}
+		if ob.DownMbps > 0 {
+			proxy["down"] = fmt.Sprintf("%d Mbps", ob.DownMbps)
+		}
+		if ob.Obfs != nil && ob.Obfs.Type != "" {
+			proxy["obfs"] = ob.Obfs.Type
+			proxy["obfs-password"] = ob.Obfs.Password
+		}
+		if len(ob.ServerPorts) > 0 {
+			proxy["ports"] = singBoxPortsToMihomo(ob.ServerPorts)
+			if seconds := hopIntervalSeconds(ob.HopInterval); seconds > 0 {
+				proxy["hop-interval"] = seconds
+			}
+		}
+		use("password", "up_mbps", "down_mbps", "obfs", "server_ports", "hop_interval")
+	case "tuic":
+		proxy["type"] = "tuic"
+		proxy["uuid"] = ob.UUID
+		proxy["password"] = ob.Password
+		if ob.CongestionControl != "" {
+			proxy["congestion-controller"] = ob.CongestionControl
+		}
+		if ob.UDPRelayMode != "" {
+			proxy["udp-relay-mode"] = ob.UDPRelayMode
+		}
+		if ob.ZeroRTTHandshake {
+			proxy["reduce-rtt"] = true
+		}
+		use("uuid", "password", "congestion_control", "udp_relay_mode", "zero_rtt_handshake")
+	case "anytls":
+		proxy["type"] = "anytls"
+		proxy["password"] = ob.Password
+		if d, err := time.ParseDuration(ob.IdleSessionCheckInterval); err == nil {
+			proxy["idle-session-check-interval"] = int(d.Seconds())
+		}
+		if d, err := time.ParseDuration(ob.IdleSessionTimeout); err == nil {
+			proxy["idle-session-timeout"] = int(d.Seconds())
+		}
+		if ob.MinIdleSession > 0 {
+			proxy["min-idle-session"] = ob.MinIdleSession
+		}
+		use("password", "idle_session_check_interval", "idle_session_timeout", "min_idle_session")
+	case "socks", "http":
+		proxy["type"] = ob.Type
+		if ob.Type == "socks" {
+			proxy["type"] = "socks5"
+		}
+		for _, key := range []string{"username", "password"} {
+			if v, ok := raw[key].(string); ok && v != "" {
+				proxy[key] = v
+			}
+		}
+		use("username", "password", "version")
+		tlsAlways = false
+	default:
+		return nil, nil, fmt.Errorf("不支持 %s 类型", ob.Type)
+	}
+
+	// TLS
+	if ob.TLS != nil && (ob.TLS.Enabled || tlsAlways) {
+		use("tls")
+		if !tlsAlways {
+			proxy["tls"] = true
+		}
+		if ob.TLS.ServerName != "" {
+			proxy[sniKey] = ob.TLS.ServerName
+		}
+		proxy["skip-cert-verify"] = ob.TLS.Insecure
+		if len(ob.TLS.ALPN) > 0 {
+			proxy["alpn"] = ob.TLS.ALPN
+		}
+		if ob.TLS.UTLS != nil && ob.TLS.UTLS.Enabled {
+			proxy["client-fingerprint"] = ob.TLS.UTLS.Fingerprint
+		}
+		if ob.TLS.Reality != nil && ob.TLS.Reality.Enabled {
+			proxy["reality-opts"] = map[string]interface{}{
+				"public-key": ob.TLS.Reality.PublicKey,
+				"short-id":   ob.TLS.Reality.ShortID,
+			}
+		}
+		if ob.TLS.ECH != nil && ob.TLS.ECH.Enabled {
+			opts := map[string]interface{}{"enable": true}
+			if config := echConfigBase64(ob.TLS.ECH.Config); config != "" {
+				opts["config"] = config
+			}
+			proxy["ech-opts"] = opts
+		}
+		if tlsRaw, ok := raw["tls"].(map[string]interface{}); ok {
+			known := structFieldNames(SBTLS{}, "json")
+			for _, key := range sortedKeys(tlsRaw) {
+				// TLS 分片在 Mihomo 中没有对应的节点选项
+				if !known[key] || key == "min_version" || key == "max_version" ||
+					key == "fragment" || key == "record_fragment" || key == "fragment_fallback_delay" {
+					unmapped = append(unmapped, "tls."+key)
+				}
+			}
+		}
+	}
+
+	// 多路复用
+	if ob.Multiplex != nil && ob.Multiplex.Enabled {
+		use("multiplex")
+		smux := map[string]interface{}{"enabled": true}
+		if ob.Multiplex.Protocol != "" {
+			smux["protocol"] = ob.Multiplex.Protocol
+		}
+		if ob.Multiplex.MaxConnections > 0 {
+			smux["max-connections"] = ob.Multiplex.MaxConnections
+		}
+		if ob.Multiplex.MinStreams > 0 {
+			smux["min-streams"] = ob.Multiplex.MinStreams
+		}
+		if ob.Multiplex.MaxStreams > 0 {
+			smux["max-streams"] = ob.Multiplex.MaxStreams
+		}
+		if ob.Multiplex.Padding {
+			smux["padding"] = true
+		}
+		proxy["smux"] = smux
+	}
+
+	// 传输层
+	if ob.Transport != nil {
+		use("transport")
+		switch ob.Transport.Type {
+		case "ws":
+			proxy["network"] = "ws"
+			opts := map[string]interface{}{}
+			path := ob.Transport.Path
+			if ob.Transport.MaxEarlyData > 0 {
+				path += fmt.Sprintf("?ed=%d", ob.Transport.MaxEarlyData)
+			}
+			if path != "" {
+				opts["path"] = path
+			}
+			if len(ob.Transport.Headers) > 0 {
+				opts["headers"] = ob.Transport.Headers
+			}
+			proxy["ws-opts"] = opts
+		case "grpc":
+			proxy["network"] = "grpc"
+			proxy["grpc-opts"] = map[string]interface{}{"grpc-service-name": ob.Transport.ServiceName}
+		case "http":
+			proxy["network"] = "h2"
+			opts := map[string]interface{}{}
+			switch host := ob.Transport.Host.(type) {
+			case string:
+				opts["host"] = []string{host}
+			case []interface{}:
+				opts["host"] = host
+			}
+			if ob.Transport.Path != "" {
+				opts["path"] = ob.Transport.Path
+			}
+			proxy["h2-opts"] = opts
+		default:
+			unmapped = append(unmapped, "transport")
+		}
+	}
+
+	for _, key := range sortedKeys(raw) {
+		if !handled[key] {
+			unmapped = append(unmapped, key)
+		}
+	}
+	sort.Strings(unmapped)
+	return proxy, unmapped, nil
+}
+
+// remarshalJSON 将通用结构重新解码为目标类型
+func remarshalJSON(src interface{}, dst interface{}) error {
+	data, err := json.Marshal(src)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, dst)
+}
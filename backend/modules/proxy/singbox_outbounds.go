@@ -0,0 +1,612 @@
+package proxy
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// 其余节点类型的 Sing-Box 出站转换
+// 节点 Config 可能来自 Clash 配置（连字符字段名）或订阅解析器（sing-box 风格字段名），两种写法都需要兼容
+// ============================================================================
+
+// ============================================================================
+// Hysteria (v1) 解析
+// ============================================================================
+
+func parseHysteriaConfig(config map[string]interface{}, out *SBOutbound) error {
+	if protocol := configString(config, "protocol"); protocol != "" && protocol != "udp" {
+		return fmt.Errorf("sing-box 的 Hysteria 出站只支持 udp 协议，不支持 %s", protocol)
+	}
+
+	out.AuthStr = configString(config, "auth-str", "auth_str", "auth")
+	if obfs := configString(config, "obfs"); obfs != "" {
+		out.Obfs = &SBObfs{Password: obfs}
+	}
+
+	// sing-box 要求填写带宽，未设置时使用订阅解析器的默认值
+	out.UpMbps = configMbps(config, "up", "up_mbps")
+	if out.UpMbps <= 0 {
+		out.UpMbps = 10
+	}
+	out.DownMbps = configMbps(config, "down", "down_mbps")
+	if out.DownMbps <= 0 {
+		out.DownMbps = 50
+	}
+
+	parsePortHopping(config, out)
+	out.RecvWindowConn = configInt(config, "recv-window-conn", "recv_window_conn")
+	out.RecvWindow = configInt(config, "recv-window", "recv_window")
+	out.DisableMTUDiscovery = configBool(config, "disable-mtu-discovery", "disable_mtu_discovery")
+
+	// TLS（Hysteria 固定启用）
+	out.TLS = &SBTLS{Enabled: true}
+	out.TLS.ServerName = configString(config, "sni", "peer", "server_name")
+	out.TLS.Insecure = configBool(config, "skip-cert-verify")
+	out.TLS.ALPN = configStrings(config, "alpn")
+	return nil
+}
+
+// ============================================================================
+// ShadowTLS 解析
+// ============================================================================
+
+func parseShadowTLSConfig(config map[string]interface{}, out *SBOutbound) {
+	out.Version = configInt(config, "version")
+	if out.Version == 0 {
+		out.Version = 3
+	}
+	out.Password = configString(config, "password")
+
+	out.TLS = &SBTLS{Enabled: true}
+	out.TLS.ServerName = configString(config, "sni", "host", "server_name")
+	out.TLS.Insecure = configBool(config, "skip-cert-verify")
+	if fp := configString(config, "client-fingerprint"); fp != "" {
+		out.TLS.UTLS = &SBUTLS{Enabled: true, Fingerprint: fp}
+	}
+}
+
+// shadowTLSPluginDetour 处理 Clash 的 shadow-tls 插件
+// sing-box 中由 ShadowTLS 出站连接服务器，Shadowsocks 出站经由它连接（detour）
+func shadowTLSPluginDetour(out *SBOutbound) (*SBOutbound, error) {
+	opts := make(map[string]interface{})
+	for _, kv := range strings.Split(out.PluginOpts, ";") {
+		if key, value, ok := strings.Cut(kv, "="); ok {
+			opts[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	detour := &SBOutbound{
+		Tag:        out.Tag + "-shadowtls",
+		Type:       "shadowtls",
+		Server:     out.Server,
+		ServerPort: out.ServerPort,
+		Detour:     out.Detour,
+	}
+	parseShadowTLSConfig(opts, detour)
+	if detour.TLS.UTLS == nil {
+		detour.TLS.UTLS = &SBUTLS{Enabled: true, Fingerprint: "chrome"}
+	}
+	if err := validateOutbound(detour); err != nil {
+		return nil, fmt.Errorf("shadow-tls 插件: %w", err)
+	}
+
+	out.Plugin = ""
+	out.PluginOpts = ""
+	out.Detour = detour.Tag
+	return detour, nil
+}
+
+// ============================================================================
+// SSH 解析
+// ============================================================================
+
+func parseSSHConfig(config map[string]interface{}, out *SBOutbound) {
+	out.User = configString(config, "username", "user")
+	if out.User == "" {
+		out.User = "root"
+	}
+	out.Password = configString(config, "password")
+	out.PrivateKey = configString(config, "private-key", "private_key")
+	out.PrivateKeyPassphrase = configString(config, "private-key-passphrase", "private_key_passphrase")
+	out.HostKey = configStrings(config, "host-key", "host_key")
+	out.HostKeyAlgorithms = configStrings(config, "host-key-algorithms", "host_key_algorithms")
+	out.ClientVersion = configString(config, "client-version", "client_version")
+}
+
+// ============================================================================
+// Naive 解析（需要 sing-box 1.13+）
+// ============================================================================
+
+func parseNaiveConfig(config map[string]interface{}, out *SBOutbound) {
+	out.Username = configString(config, "username")
+	out.Password = configString(config, "password")
+	out.QUIC = configString(config, "protocol") == "quic" || configBool(config, "quic")
+	out.UDPOverTCP = configBool(config, "udp-over-tcp", "udp_over_tcp")
+	out.ExtraHeaders = configHeaders(config, "extra-headers", "extra_headers")
+
+	out.TLS = &SBTLS{Enabled: true}
+	out.TLS.ServerName = configString(config, "sni", "server_name")
+	out.TLS.Insecure = configBool(config, "skip-cert-verify")
+}
+
+// ============================================================================
+// WireGuard 解析（生成配置时转换为端点）
+// ============================================================================
+
+func parseWireGuardConfig(config map[string]interface{}, out *SBOutbound) error {
+	out.PrivateKey = configString(config, "private-key", "private_key")
+	out.MTU = configInt(config, "mtu")
+
+	// 本地地址：Clash 使用 ip/ipv6，手动节点表单使用逗号分隔的 local_address
+	if ip := configString(config, "ip"); ip != "" {
+		out.LocalAddress = append(out.LocalAddress, withPrefixLength(ip, "/32"))
+	}
+	if ip := configString(config, "ipv6"); ip != "" {
+		out.LocalAddress = append(out.LocalAddress, withPrefixLength(ip, "/128"))
+	}
+	for _, addr := range configStrings(config, "local_address", "local-address") {
+		if strings.Contains(addr, ":") {
+			out.LocalAddress = append(out.LocalAddress, withPrefixLength(addr, "/128"))
+		} else {
+			out.LocalAddress = append(out.LocalAddress, withPrefixLength(addr, "/32"))
+		}
+	}
+
+	// 对端：Clash 可以使用 peers 列表，其余写法为单个对端
+	peer := config
+	if peers, ok := config["peers"].([]interface{}); ok && len(peers) > 0 {
+		if len(peers) > 1 {
+			return fmt.Errorf("暂不支持多个 WireGuard 对端")
+		}
+		p, ok := peers[0].(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("WireGuard 对端格式错误")
+		}
+		peer = p
+		if server := configString(p, "server"); server != "" {
+			out.Server = server
+		}
+		if port := configInt(p, "port"); port > 0 {
+			out.ServerPort = port
+		}
+	}
+	out.PeerPublicKey = configString(peer, "public-key", "peer_public_key", "public_key")
+	out.PreSharedKey = configString(peer, "pre-shared-key", "pre_shared_key")
+	out.AllowedIPs = configStrings(peer, "allowed-ips", "allowed_ips")
+
+	reserved, err := parseWireGuardReserved(peer["reserved"])
+	if err != nil {
+		return err
+	}
+	out.Reserved = reserved
+	return nil
+}
+
+// parseWireGuardReserved 解析 reserved，兼容数组、"1,2,3" 与 base64 三种写法
+func parseWireGuardReserved(v interface{}) ([]int, error) {
+	switch val := v.(type) {
+	case nil:
+		return nil, nil
+	case []interface{}:
+		reserved := make([]int, 0, len(val))
+		for _, item := range val {
+			n, ok := item.(float64)
+			if !ok {
+				return nil, fmt.Errorf("WireGuard reserved 格式错误")
+			}
+			reserved = append(reserved, int(n))
+		}
+		return reserved, nil
+	case string:
+		if val == "" {
+			return nil, nil
+		}
+		if strings.Contains(val, ",") {
+			var reserved []int
+			for _, part := range strings.Split(val, ",") {
+				n, err := strconv.Atoi(strings.TrimSpace(part))
+				if err != nil {
+					return nil, fmt.Errorf("WireGuard reserved 格式错误: %s", val)
+				}
+				reserved = append(reserved, n)
+			}
+			return reserved, nil
+		}
+		data, err := base64.StdEncoding.DecodeString(val)
+		if err != nil {
+			return nil, fmt.Errorf("WireGuard reserved 格式错误: %s", val)
+		}
+		reserved := make([]int, len(data))
+		for i, b := range data {
+			reserved[i] = int(b)
+		}
+		return reserved, nil
+	}
+	return nil, fmt.Errorf("WireGuard reserved 格式错误")
+}
+
+// wireGuardEndpoint 将 WireGuard 节点转换为端点（sing-box 1.11 起 WireGuard 出站已弃用）
+func wireGuardEndpoint(ob SBOutbound) SBEndpoint {
+	allowedIPs := ob.AllowedIPs
+	if len(allowedIPs) == 0 {
+		allowedIPs = []string{"0.0.0.0/0", "::/0"}
+	}
+	return SBEndpoint{
+		Tag:        ob.Tag,
+		Type:       "wireguard",
+		Address:    ob.LocalAddress,
+		PrivateKey: ob.PrivateKey,
+		MTU:        ob.MTU,
+		Detour:     ob.Detour,
+		Peers: []SBWireGuardPeer{{
+			Address:      ob.Server,
+			Port:         ob.ServerPort,
+			PublicKey:    ob.PeerPublicKey,
+			PreSharedKey: ob.PreSharedKey,
+			AllowedIPs:   allowedIPs,
+			Reserved:     ob.Reserved,
+		}},
+	}
+}
+
+// ============================================================================
+// SOCKS / HTTP 解析
+// ============================================================================
+
+func parseSocksConfig(config map[string]interface{}, out *SBOutbound) error {
+	if configBool(config, "tls") {
+		return fmt.Errorf("sing-box 的 SOCKS 出站不支持 TLS")
+	}
+	if tls, ok := config["tls"].(map[string]interface{}); ok && configBool(tls, "enabled") {
+		return fmt.Errorf("sing-box 的 SOCKS 出站不支持 TLS")
+	}
+	out.Username = configString(config, "username")
+	out.Password = configString(config, "password")
+	out.UDPOverTCP = configBool(config, "udp-over-tcp", "udp_over_tcp")
+	return nil
+}
+
+func parseHTTPConfig(config map[string]interface{}, out *SBOutbound) {
+	out.Username = configString(config, "username")
+	out.Password = configString(config, "password")
+	out.Path = configString(config, "path")
+	out.Headers = configHeaders(config, "headers")
+
+	if configBool(config, "tls") {
+		out.TLS = &SBTLS{Enabled: true}
+		out.TLS.ServerName = configString(config, "sni", "servername")
+		out.TLS.Insecure = configBool(config, "skip-cert-verify")
+		if fp := configString(config, "client-fingerprint"); fp != "" {
+			out.TLS.UTLS = &SBUTLS{Enabled: true, Fingerprint: fp}
+		}
+	}
+}
+
+// ============================================================================
+// 通用选项：TLS 对象、ECH、TLS 分片、多路复用与链式代理
+// ============================================================================
+
+// multiplexTypes 支持多路复用的出站类型
+var multiplexTypes = map[string]bool{
+	"vmess": true, "vless": true, "trojan": true, "shadowsocks": true,
+}
+
+func parseCommonOptions(config map[string]interface{}, out *SBOutbound) {
+	// 订阅解析器使用 sing-box 风格的 tls 对象
+	if tls, ok := config["tls"].(map[string]interface{}); ok {
+		mergeTLSObject(tls, out)
+	}
+
+	if out.TLS != nil {
+		if out.TLS.ServerName == "" {
+			out.TLS.ServerName = configString(config, "server_name")
+		}
+		// Clash 的 ech-opts，config 为 base64 编码的 ECHConfigList
+		if ech, ok := config["ech-opts"].(map[string]interface{}); ok && configBool(ech, "enable", "enabled") {
+			out.TLS.ECH = &SBECH{Enabled: true, Config: echConfigPEM(configString(ech, "config"))}
+		}
+		if configBool(config, "tls-fragment") {
+			out.TLS.Fragment = true
+		}
+		if configBool(config, "tls-record-fragment") {
+			out.TLS.RecordFragment = true
+		}
+		// 订阅解析器的 Reality 配置位于顶层
+		if reality, ok := config["reality"].(map[string]interface{}); ok && configBool(reality, "enabled") && out.TLS.Reality == nil {
+			out.TLS.Reality = &SBReality{Enabled: true, PublicKey: configString(reality, "public_key"), ShortID: configString(reality, "short_id")}
+		}
+	}
+
+	// 多路复用：Clash 使用 smux，sing-box 风格使用 multiplex
+	if multiplexTypes[out.Type] {
+		for _, key := range []string{"smux", "multiplex"} {
+			m, ok := config[key].(map[string]interface{})
+			if !ok || !configBool(m, "enabled") {
+				continue
+			}
+			out.Multiplex = &SBMultiplex{
+				Enabled:        true,
+				Protocol:       configString(m, "protocol"),
+				MaxConnections: configInt(m, "max-connections", "max_connections"),
+				MinStreams:     configInt(m, "min-streams", "min_streams"),
+				MaxStreams:     configInt(m, "max-streams", "max_streams"),
+				Padding:        configBool(m, "padding"),
+			}
+			break
+		}
+	}
+
+	// 链式代理：Clash 使用 dialer-proxy
+	if detour := configString(config, "dialer-proxy", "detour"); detour != "" {
+		out.Detour = detour
+	}
+}
+
+// mergeTLSObject 合并 sing-box 风格的 tls 对象
+func mergeTLSObject(tls map[string]interface{}, out *SBOutbound) {
+	if out.TLS == nil {
+		if !configBool(tls, "enabled") {
+			return
+		}
+		out.TLS = &SBTLS{Enabled: true}
+	}
+	if sn := configString(tls, "server_name"); sn != "" {
+		out.TLS.ServerName = sn
+	}
+	if insecure, ok := tls["insecure"].(bool); ok {
+		out.TLS.Insecure = insecure
+	}
+	if alpn := configStrings(tls, "alpn"); len(alpn) > 0 {
+		out.TLS.ALPN = alpn
+	}
+	fp := configString(tls, "fingerprint")
+	if utls, ok := tls["utls"].(map[string]interface{}); ok && configBool(utls, "enabled") {
+		fp = configString(utls, "fingerprint")
+	}
+	if fp != "" {
+		out.TLS.UTLS = &SBUTLS{Enabled: true, Fingerprint: fp}
+	}
+	if ech, ok := tls["ech"].(map[string]interface{}); ok && configBool(ech, "enabled") {
+		out.TLS.ECH = &SBECH{Enabled: true, Config: configStrings(ech, "config")}
+	}
+	out.TLS.Fragment = out.TLS.Fragment || configBool(tls, "fragment")
+	out.TLS.RecordFragment = out.TLS.RecordFragment || configBool(tls, "record_fragment")
+	if delay := configString(tls, "fragment_fallback_delay"); delay != "" {
+		out.TLS.FragmentFallbackDelay = delay
+	}
+}
+
+// echConfigPEM 将 base64 编码的 ECHConfigList 转换为 sing-box 使用的 PEM 行
+func echConfigPEM(config string) []string {
+	config = strings.TrimSpace(config)
+	if config == "" {
+		return nil
+	}
+	if strings.HasPrefix(config, "-----BEGIN") {
+		return strings.Split(config, "\n")
+	}
+	return []string{"-----BEGIN ECH CONFIGS-----", config, "-----END ECH CONFIGS-----"}
+}
+
+// ============================================================================
+// 出站校验与跳过报告
+// ============================================================================
+
+// validateOutbound 检查出站的必填字段，避免生成 sing-box 无法启动的配置
+func validateOutbound(out *SBOutbound) error {
+	if out.Server == "" || (out.ServerPort <= 0 && len(out.ServerPorts) == 0) {
+		return fmt.Errorf("缺少服务器地址或端口")
+	}
+	switch out.Type {
+	case "vmess", "vless", "tuic":
+		if out.UUID == "" {
+			return fmt.Errorf("缺少 UUID")
+		}
+	case "shadowsocks":
+		if out.Method == "" || out.Password == "" {
+			return fmt.Errorf("缺少加密方式或密码")
+		}
+	case "trojan", "hysteria2", "anytls":
+		if out.Password == "" {
+			return fmt.Errorf("缺少密码")
+		}
+	case "shadowtls":
+		if out.Version >= 2 && out.Password == "" {
+			return fmt.Errorf("ShadowTLS v%d 缺少密码", out.Version)
+		}
+	case "ssh":
+		if out.Password == "" && out.PrivateKey == "" {
+			return fmt.Errorf("SSH 节点需要密码或私钥")
+		}
+	case "wireguard":
+		if out.PrivateKey == "" || out.PeerPublicKey == "" {
+			return fmt.Errorf("缺少 WireGuard 私钥或对端公钥")
+		}
+		if len(out.LocalAddress) == 0 {
+			return fmt.Errorf("缺少 WireGuard 本地地址")
+		}
+	}
+	return nil
+}
+
+// SingBoxSkippedNode 无法转换为 sing-box 出站的节点
+type SingBoxSkippedNode struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// singBoxNodeOutbounds 节点转换结果
+type singBoxNodeOutbounds struct {
+	Nodes   []SBOutbound // 节点出站（可被代理组引用，WireGuard 在组装配置时转为端点）
+	Detours []SBOutbound // 节点的前置出站（如 ShadowTLS），不加入代理组
+	Manual  []string     // 手动节点标签
+	Skipped []SingBoxSkippedNode
+}
+
+// convertNodesV112 将节点转换为 sing-box 出站，记录无法转换的节点及原因
+func convertNodesV112(nodes []ProxyNode) singBoxNodeOutbounds {
+	var result singBoxNodeOutbounds
+	tags := make(map[string]bool)
+	skip := func(node ProxyNode, format string, args ...interface{}) {
+		result.Skipped = append(result.Skipped, SingBoxSkippedNode{Name: node.Name, Type: node.Type, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	for _, node := range nodes {
+		outbound, err := ParseNodeToSingBox(node)
+		if err != nil {
+			skip(node, "%v", err)
+			continue
+		}
+		if tags[outbound.Tag] {
+			skip(node, "节点名称重复")
+			continue
+		}
+
+		var detour *SBOutbound
+		if outbound.Type == "shadowsocks" && outbound.Plugin == "shadow-tls" {
+			if detour, err = shadowTLSPluginDetour(outbound); err != nil {
+				skip(node, "%v", err)
+				continue
+			}
+			if tags[detour.Tag] {
+				skip(node, "前置出站 %s 与其他节点重名", detour.Tag)
+				continue
+			}
+			tags[detour.Tag] = true
+			result.Detours = append(result.Detours, *detour)
+		}
+
+		tags[outbound.Tag] = true
+		result.Nodes = append(result.Nodes, *outbound)
+		// 收集手动节点名称（与 Mihomo 一致）
+		if node.IsManual {
+			result.Manual = append(result.Manual, outbound.Tag)
+		}
+	}
+	return result
+}
+
+// SingBoxNodeReport 检查节点能否用于 sing-box，返回会被跳过的节点及原因
+func SingBoxNodeReport(nodes []ProxyNode) []SingBoxSkippedNode {
+	skipped := convertNodesV112(nodes).Skipped
+	if skipped == nil {
+		skipped = []SingBoxSkippedNode{}
+	}
+	return skipped
+}
+
+// ============================================================================
+// 配置字段读取（兼容两种字段命名）
+// ============================================================================
+
+// configString 按顺序返回第一个非空字符串字段
+func configString(config map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if v, ok := config[key].(string); ok && v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// configInt 按顺序返回第一个整数字段，兼容数字与字符串
+func configInt(config map[string]interface{}, keys ...string) int {
+	for _, key := range keys {
+		switch v := config[key].(type) {
+		case float64:
+			return int(v)
+		case int:
+			return v
+		case string:
+			if n, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// configBool 按顺序返回第一个布尔字段，兼容 "true"/"1"
+func configBool(config map[string]interface{}, keys ...string) bool {
+	for _, key := range keys {
+		switch v := config[key].(type) {
+		case bool:
+			return v
+		case string:
+			if v != "" {
+				return v == "true" || v == "1"
+			}
+		}
+	}
+	return false
+}
+
+// configStrings 按顺序返回第一个字符串列表字段，兼容逗号分隔的字符串
+func configStrings(config map[string]interface{}, keys ...string) []string {
+	for _, key := range keys {
+		var values []string
+		switch v := config[key].(type) {
+		case []interface{}:
+			for _, item := range v {
+				if s, ok := item.(string); ok && s != "" {
+					values = append(values, s)
+				}
+			}
+		case string:
+			for _, s := range strings.Split(v, ",") {
+				if s = strings.TrimSpace(s); s != "" {
+					values = append(values, s)
+				}
+			}
+		}
+		if len(values) > 0 {
+			return values
+		}
+	}
+	return nil
+}
+
+// configHeaders 读取请求头字段
+func configHeaders(config map[string]interface{}, keys ...string) map[string]string {
+	for _, key := range keys {
+		raw, ok := config[key].(map[string]interface{})
+		if !ok || len(raw) == 0 {
+			continue
+		}
+		headers := make(map[string]string, len(raw))
+		for k, v := range raw {
+			headers[k] = fmt.Sprint(v)
+		}
+		return headers
+	}
+	return nil
+}
+
+// configMbps 读取带宽（Mbps），兼容 100、"100" 与 "100 Mbps"
+func configMbps(config map[string]interface{}, keys ...string) int {
+	for _, key := range keys {
+		switch v := config[key].(type) {
+		case float64:
+			return int(v)
+		case string:
+			value := strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v), "Mbps"))
+			if n, err := strconv.Atoi(value); err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
+
+// withPrefixLength 为不带前缀长度的地址补上前缀长度
+func withPrefixLength(addr, prefix string) string {
+	addr = strings.TrimSpace(addr)
+	if strings.Contains(addr, "/") {
+		return addr
+	}
+	return addr + prefix
+}
@@ -16,13 +16,22 @@ import (
 
 // ParseNodeToSingBox 将节点转换为 sing-box outbound
 func ParseNodeToSingBox(node ProxyNode) (*SBOutbound, error) {
-	// 优先使用完整的 Config JSON 解析
+	var outbound *SBOutbound
+	var err error
 	if node.Config != "" {
-		return parseFromConfigJSON(node)
+		// 优先使用完整的 Config JSON 解析
+		outbound, err = parseFromConfigJSON(node)
+	} else {
+		// 没有 Config JSON，使用基础字段构建
+		outbound, err = parseFromBasicFields(node)
 	}
-
-	// 没有 Config JSON，使用基础字段构建
-	return parseFromBasicFields(node)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateOutbound(outbound); err != nil {
+		return nil, err
+	}
+	return outbound, nil
 }
 
 // parseFromBasicFields 从基础字段构建 outbound
@@ -57,6 +66,8 @@ func parseFromBasicFields(node ProxyNode) (*SBOutbound, error) {
 		outbound.Type = "socks"
 	case "http":
 		outbound.Type = "http"
+	case "naive", "ssh", "wireguard", "wg", "shadowtls", "hysteria":
+		return nil, fmt.Errorf("%s 节点缺少协议配置", nodeType)
 	default:
 		return nil, fmt.Errorf("unsupported node type: %s", nodeType)
 	}
@@ -108,8 +119,36 @@ func parseFromConfigJSON(node ProxyNode) (*SBOutbound, error) {
 		parseTUICConfig(rawConfig, outbound)
 	case "anytls":
 		parseAnyTLSConfig(rawConfig, outbound)
+	case "hysteria", "hy":
+		outbound.Type = "hysteria"
+		if err := parseHysteriaConfig(rawConfig, outbound); err != nil {
+			return nil, err
+		}
+	case "shadowtls":
+		parseShadowTLSConfig(rawConfig, outbound)
+	case "ssh":
+		parseSSHConfig(rawConfig, outbound)
+	case "naive":
+		parseNaiveConfig(rawConfig, outbound)
+	case "wireguard", "wg":
+		outbound.Type = "wireguard"
+		if err := parseWireGuardConfig(rawConfig, outbound); err != nil {
+			return nil, err
+		}
+	case "socks", "socks5":
+		outbound.Type = "socks"
+		if err := parseSocksConfig(rawConfig, outbound); err != nil {
+			return nil, err
+		}
+	case "http":
+		parseHTTPConfig(rawConfig, outbound)
+	default:
+		return nil, fmt.Errorf("sing-box 不支持 %s 类型的节点", nodeType)
 	}
 
+	// 通用选项：TLS 对象、ECH、TLS 分片、多路复用与链式代理
+	parseCommonOptions(rawConfig, outbound)
+
 	return outbound, nil
 }
 
@@ -236,6 +275,8 @@ func parseShadowsocksConfig(config map[string]interface{}, out *SBOutbound) {
 			opts = append(opts, fmt.Sprintf("%s=%v", k, v))
 		}
 		out.PluginOpts = strings.Join(opts, ";")
+	} else if pluginOpts, ok := config["plugin-opts"].(string); ok {
+		out.PluginOpts = pluginOpts
 	}
 }
 
@@ -281,16 +322,11 @@ func parseHysteria2Config(config map[string]interface{}, out *SBOutbound) {
 		out.Password = password
 	}
 	// Up/Down Mbps
-	if up, ok := config["up"].(string); ok {
-		if val, err := strconv.Atoi(strings.TrimSuffix(up, " Mbps")); err == nil {
-			out.UpMbps = val
-		}
-	}
-	if down, ok := config["down"].(string); ok {
-		if val, err := strconv.Atoi(strings.TrimSuffix(down, " Mbps")); err == nil {
-			out.DownMbps = val
-		}
-	}
+	out.UpMbps = configMbps(config, "up", "up_mbps")
+	out.DownMbps = configMbps(config, "down", "down_mbps")
+
+	// 端口跳跃
+	parsePortHopping(config, out)
 
 	// Obfs
 	if obfs, ok := config["obfs"].(string); ok && obfs != "" {
@@ -300,6 +336,8 @@ func parseHysteria2Config(config map[string]interface{}, out *SBOutbound) {
 		if obfsPwd, ok := config["obfs-password"].(string); ok {
 			out.Obfs.Password = obfsPwd
 		}
+	} else if obfs, ok := config["obfs"].(map[string]interface{}); ok && configString(obfs, "type") != "" {
+		out.Obfs = &SBObfs{Type: configString(obfs, "type"), Password: configString(obfs, "password")}
 	}
 
 	// TLS
@@ -757,22 +757,23 @@ func GetSingBoxProxyGroups() []SBOutbound {
 	}
 }
 
+// singBoxGroupNames 旧版英文出站组标签与显示名称的映射
+var singBoxGroupNames = map[string]string{
+	"auto": "自动选择", "fallback": "故障转移", "proxy": "节点选择",
+	"DIRECT": "全球直连", "AdBlock": "广告拦截", "AI": "AI服务",
+	"Gaming": "游戏平台", "Streaming": "国外媒体", "Social": "社交媒体",
+	"Chat": "海外聊天", "Google": "谷歌服务", "GitHub": "GitHub",
+	"Microsoft": "微软服务", "Apple": "苹果服务", "BiliBili": "哔哩哔哩",
+	"Final": "漏网之鱼", "HongKong": "香港节点", "Taiwan": "台湾节点",
+	"Japan": "日本节点", "Singapore": "新加坡节点", "America": "美国节点",
+	"Manual": "手动节点", "Others": "其他节点",
+}
+
 // GetDefaultSingBoxTemplate 获取默认 Sing-Box 模板
 func GetDefaultSingBoxTemplate() *SingBoxTemplate {
 	groups := GetSingBoxProxyGroups()
 	proxyGroups := make([]SingBoxProxyGroupTemplate, len(groups))
 
-	// 名称映射
-	nameMap := map[string]string{
-		"auto": "自动选择", "fallback": "故障转移", "proxy": "节点选择",
-		"DIRECT": "全球直连", "AdBlock": "广告拦截", "AI": "AI服务",
-		"Gaming": "游戏平台", "Streaming": "国外媒体", "Social": "社交媒体",
-		"Chat": "海外聊天", "Google": "谷歌服务", "GitHub": "GitHub",
-		"Microsoft": "微软服务", "Apple": "苹果服务", "BiliBili": "哔哩哔哩",
-		"Final": "漏网之鱼", "HongKong": "香港节点", "Taiwan": "台湾节点",
-		"Japan": "日本节点", "Singapore": "新加坡节点", "America": "美国节点",
-		"Manual": "手动节点", "Others": "其他节点",
-	}
 	iconMap := map[string]string{
 		"auto": "⚡", "fallback": "🛡️", "proxy": "🚀", "DIRECT": "🎯",
 		"AdBlock": "🚫", "AI": "🤖", "Gaming": "🎮", "Streaming": "📺",
@@ -786,7 +787,7 @@ func GetDefaultSingBoxTemplate() *SingBoxTemplate {
 		proxyGroups[i] = SingBoxProxyGroupTemplate{
 			Tag:         g.Tag,
 			Type:        g.Type,
-			Name:        nameMap[g.Tag],
+			Name:        singBoxGroupNames[g.Tag],
 			Description: "",
 			Icon:        iconMap[g.Tag],
 			Enabled:     true,
@@ -801,21 +802,21 @@ func GetDefaultSingBoxTemplate() *SingBoxTemplate {
 		}
 	}
 
-	// 默认规则
+	// 默认规则（出站使用与出站组一致的标签）
 	rules := []SingBoxRuleTemplate{
-		{RuleSet: "geosite-category-ads-all", Outbound: "AdBlock"},
-		{RuleSet: []string{"geosite-openai", "geosite-anthropic"}, Outbound: "AI"},
-		{RuleSet: []string{"geosite-steam", "geosite-epicgames"}, Outbound: "Gaming"},
-		{RuleSet: []string{"geosite-youtube", "geosite-netflix", "geosite-spotify"}, Outbound: "Streaming"},
-		{RuleSet: []string{"geosite-telegram", "geosite-twitter", "geosite-facebook"}, Outbound: "Social"},
-		{RuleSet: []string{"geosite-discord", "geosite-whatsapp"}, Outbound: "Chat"},
-		{RuleSet: "geosite-google", Outbound: "Google"},
+		{RuleSet: "geosite-category-ads-all", Outbound: "广告拦截"},
+		{RuleSet: []string{"geosite-openai", "geosite-anthropic"}, Outbound: "AI服务"},
+		{RuleSet: []string{"geosite-steam", "geosite-epicgames"}, Outbound: "游戏平台"},
+		{RuleSet: []string{"geosite-youtube", "geosite-netflix", "geosite-spotify"}, Outbound: "国外媒体"},
+		{RuleSet: []string{"geosite-telegram", "geosite-twitter", "geosite-facebook"}, Outbound: "社交媒体"},
+		{RuleSet: []string{"geosite-discord", "geosite-whatsapp"}, Outbound: "海外聊天"},
+		{RuleSet: "geosite-google", Outbound: "谷歌服务"},
 		{RuleSet: "geosite-github", Outbound: "GitHub"},
-		{RuleSet: "geosite-microsoft", Outbound: "Microsoft"},
-		{RuleSet: "geosite-apple", Outbound: "Apple"},
-		{RuleSet: "geosite-bilibili", Outbound: "BiliBili"},
-		{RuleSet: []string{"geoip-cn", "geosite-cn"}, Outbound: "DIRECT"},
-		{RuleSet: "geosite-geolocation-!cn", Outbound: "Final"},
+		{RuleSet: "geosite-microsoft", Outbound: "微软服务"},
+		{RuleSet: "geosite-apple", Outbound: "苹果服务"},
+		{RuleSet: "geosite-bilibili", Outbound: "哔哩哔哩"},
+		{RuleSet: []string{"geoip-cn", "geosite-cn"}, Outbound: "全球直连"},
+		{RuleSet: "geosite-geolocation-!cn", Outbound: "漏网之鱼"},
 	}
 
 	// 获取规则集
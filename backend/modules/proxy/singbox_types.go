@@ -1,5 +1,7 @@
 package proxy
 
+import "encoding/json"
+
 // ============================================================================
 // Sing-Box 1.12+ 配置类型定义
 // ============================================================================
@@ -11,6 +13,7 @@ type SingBoxConfig struct {
 	DNS          *SBDNS          `json:"dns,omitempty"`
 	Inbounds     []SBInbound     `json:"inbounds"`
 	Outbounds    []SBOutbound    `json:"outbounds"`
+	Endpoints    []SBEndpoint    `json:"endpoints,omitempty"`
 	Route        *SBRoute        `json:"route,omitempty"`
 }
 
@@ -90,6 +93,7 @@ type SBDNSRule struct {
 	Invert bool        `json:"invert,omitempty"`
 
 	// 动作
+	Action string `json:"action,omitempty"` // route（默认）, reject
 	Server string `json:"server,omitempty"`
 }
 
@@ -124,6 +128,20 @@ type SBInbound struct {
 	// Sniff
 	Sniff                    bool `json:"sniff,omitempty"`
 	SniffOverrideDestination bool `json:"sniff_override_destination,omitempty"`
+
+	// 认证与协议 (mixed/socks/http/shadowsocks/vmess)
+	Users    []SBInboundUser `json:"users,omitempty"`
+	Method   string          `json:"method,omitempty"`   // Shadowsocks 加密方式
+	Password string          `json:"password,omitempty"` // Shadowsocks 密码
+	Network  string          `json:"network,omitempty"`  // Shadowsocks 网络: tcp, udp，为空时两者都启用
+}
+
+// SBInboundUser 入站用户，mixed/socks/http 使用 username，shadowsocks/vmess 使用 name
+type SBInboundUser struct {
+	Name     string `json:"name,omitempty"`
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	UUID     string `json:"uuid,omitempty"`
 }
 
 type SBPlatform struct {
@@ -158,6 +176,10 @@ type SBOutbound struct {
 	Server     string `json:"server,omitempty"`
 	ServerPort int    `json:"server_port,omitempty"`
 
+	// 端口跳跃（Hysteria/Hysteria2，与 server_port 互斥）
+	ServerPorts []string `json:"server_ports,omitempty"`
+	HopInterval string   `json:"hop_interval,omitempty"`
+
 	// ===== VMess =====
 	UUID           string `json:"uuid,omitempty"`
 	Security       string `json:"security,omitempty"`
@@ -198,13 +220,41 @@ type SBOutbound struct {
 	PreSharedKey  string   `json:"pre_shared_key,omitempty"`
 	LocalAddress  []string `json:"local_address,omitempty"`
 	Reserved      []int    `json:"reserved,omitempty"`
+	MTU           int      `json:"mtu,omitempty"`
+	AllowedIPs    []string `json:"-"` // 仅用于生成 WireGuard 端点
+
+	// ===== Hysteria =====
+	AuthStr             string `json:"auth_str,omitempty"`
+	RecvWindowConn      int    `json:"recv_window_conn,omitempty"`
+	RecvWindow          int    `json:"recv_window,omitempty"`
+	DisableMTUDiscovery bool   `json:"disable_mtu_discovery,omitempty"`
+
+	// ===== ShadowTLS =====
+	Version int `json:"version,omitempty"` // ShadowTLS 协议版本（SOCKS 使用默认的 5）
+
+	// ===== SSH =====
+	User                 string   `json:"user,omitempty"`
+	PrivateKeyPassphrase string   `json:"private_key_passphrase,omitempty"`
+	HostKey              []string `json:"host_key,omitempty"`
+	HostKeyAlgorithms    []string `json:"host_key_algorithms,omitempty"`
+	ClientVersion        string   `json:"client_version,omitempty"`
+
+	// ===== SOCKS / HTTP / Naive =====
+	Username     string            `json:"username,omitempty"`
+	Path         string            `json:"path,omitempty"`    // HTTP
+	Headers      map[string]string `json:"headers,omitempty"` // HTTP
+	UDPOverTCP   bool              `json:"udp_over_tcp,omitempty"`
+	QUIC         bool              `json:"quic,omitempty"` // Naive（sing-box 1.13+）
+	ExtraHeaders map[string]string `json:"extra_headers,omitempty"`
 
 	// ===== 通用传输层 =====
 	TLS       *SBTLS       `json:"tls,omitempty"`
 	Transport *SBTransport `json:"transport,omitempty"`
 	Multiplex *SBMultiplex `json:"multiplex,omitempty"`
 
-	// ===== Dial 性能优化字段 =====
+	// ===== Dial 字段 =====
+	Detour string `json:"detour,omitempty"` // 经由其他出站连接（链式代理）
+
 	TCPFastOpen  bool `json:"tcp_fast_open,omitempty"`
 	TCPMultiPath bool `json:"tcp_multi_path,omitempty"`
 	UDPFragment  bool `json:"udp_fragment,omitempty"`
@@ -215,6 +265,26 @@ type SBObfs struct {
 	Password string `json:"password,omitempty"`
 }
 
+// MarshalJSON hysteria (v1) 的 obfs 是混淆密码字符串，未设置 Type 时按字符串输出
+func (o SBObfs) MarshalJSON() ([]byte, error) {
+	if o.Type == "" {
+		return json.Marshal(o.Password)
+	}
+	type plain SBObfs
+	return json.Marshal(plain(o))
+}
+
+// UnmarshalJSON 同时接受 hysteria (v1) 的字符串与 hysteria2 的对象
+func (o *SBObfs) UnmarshalJSON(data []byte) error {
+	var password string
+	if err := json.Unmarshal(data, &password); err == nil {
+		*o = SBObfs{Password: password}
+		return nil
+	}
+	type plain SBObfs
+	return json.Unmarshal(data, (*plain)(o))
+}
+
 type SBTLS struct {
 	Enabled    bool       `json:"enabled,omitempty"`
 	ServerName string     `json:"server_name,omitempty"`
@@ -224,6 +294,17 @@ type SBTLS struct {
 	MaxVersion string     `json:"max_version,omitempty"`
 	UTLS       *SBUTLS    `json:"utls,omitempty"`
 	Reality    *SBReality `json:"reality,omitempty"`
+	ECH        *SBECH     `json:"ech,omitempty"`
+
+	// TLS 分片（sing-box 1.12+）
+	Fragment              bool   `json:"fragment,omitempty"`
+	FragmentFallbackDelay string `json:"fragment_fallback_delay,omitempty"`
+	RecordFragment        bool   `json:"record_fragment,omitempty"`
+}
+
+type SBECH struct {
+	Enabled bool     `json:"enabled,omitempty"`
+	Config  []string `json:"config,omitempty"` // PEM 格式，为空时通过 DNS 查询
 }
 
 type SBUTLS struct {
@@ -250,6 +331,29 @@ type SBTransport struct {
 	EarlyDataHeaderName string `json:"early_data_header_name,omitempty"`
 }
 
+// ============================================================================
+// Endpoint 配置（WireGuard 自 1.11 起改为端点）
+// ============================================================================
+
+type SBEndpoint struct {
+	Tag        string            `json:"tag"`
+	Type       string            `json:"type"` // wireguard
+	Address    []string          `json:"address"`
+	PrivateKey string            `json:"private_key"`
+	Peers      []SBWireGuardPeer `json:"peers"`
+	MTU        int               `json:"mtu,omitempty"`
+	Detour     string            `json:"detour,omitempty"`
+}
+
+type SBWireGuardPeer struct {
+	Address      string   `json:"address"`
+	Port         int      `json:"port"`
+	PublicKey    string   `json:"public_key"`
+	PreSharedKey string   `json:"pre_shared_key,omitempty"`
+	AllowedIPs   []string `json:"allowed_ips"`
+	Reserved     []int    `json:"reserved,omitempty"`
+}
+
 type SBMultiplex struct {
 	Enabled        bool   `json:"enabled,omitempty"`
 	Protocol       string `json:"protocol,omitempty"` // smux, yamux, h2mux
@@ -278,19 +382,25 @@ type SBDomainResolver struct {
 
 type SBRouteRule struct {
 	// 匹配条件
-	Inbound       []string    `json:"inbound,omitempty"`
-	Protocol      interface{} `json:"protocol,omitempty"` // string 或 []string
-	Port          interface{} `json:"port,omitempty"`     // int 或 []int
-	PortRange     []string    `json:"port_range,omitempty"`
-	Domain        []string    `json:"domain,omitempty"`
-	DomainSuffix  []string    `json:"domain_suffix,omitempty"`
-	DomainKeyword []string    `json:"domain_keyword,omitempty"`
-	DomainRegex   []string    `json:"domain_regex,omitempty"`
-	IPIsPrivate   bool        `json:"ip_is_private,omitempty"`
-	IPCIDR        []string    `json:"ip_cidr,omitempty"`
-	SourceIPCIDR  []string    `json:"source_ip_cidr,omitempty"`
-	ClashMode     string      `json:"clash_mode,omitempty"`
-	RuleSet       interface{} `json:"rule_set,omitempty"` // string 或 []string
+	Inbound         []string    `json:"inbound,omitempty"`
+	AuthUser        []string    `json:"auth_user,omitempty"`
+	Protocol        interface{} `json:"protocol,omitempty"` // string 或 []string
+	Port            interface{} `json:"port,omitempty"`     // int 或 []int
+	PortRange       []string    `json:"port_range,omitempty"`
+	SourcePort      []int       `json:"source_port,omitempty"`
+	SourcePortRange []string    `json:"source_port_range,omitempty"`
+	Network         interface{} `json:"network,omitempty"` // tcp, udp
+	ProcessName     []string    `json:"process_name,omitempty"`
+	ProcessPath     []string    `json:"process_path,omitempty"`
+	Domain          []string    `json:"domain,omitempty"`
+	DomainSuffix    []string    `json:"domain_suffix,omitempty"`
+	DomainKeyword   []string    `json:"domain_keyword,omitempty"`
+	DomainRegex     []string    `json:"domain_regex,omitempty"`
+	IPIsPrivate     bool        `json:"ip_is_private,omitempty"`
+	IPCIDR          []string    `json:"ip_cidr,omitempty"`
+	SourceIPCIDR    []string    `json:"source_ip_cidr,omitempty"`
+	ClashMode       string      `json:"clash_mode,omitempty"`
+	RuleSet         interface{} `json:"rule_set,omitempty"` // string 或 []string
 
 	// 逻辑规则
 	Type   string        `json:"type,omitempty"` // logical
@@ -359,4 +469,18 @@ type SingBoxGeneratorOptions struct {
 
 	// 日志
 	LogLevel string `json:"logLevel"`
+
+	// 用户自定义规则（由 Mihomo 规则模板转换，插入到内置规则集规则之前）
+	CustomRules []RuleTemplate            `json:"customRules,omitempty"`
+	SubRules    map[string][]RuleTemplate `json:"subRules,omitempty"`
+
+	// 统一路由模板（非空时代理组、分流规则、规则集与 DNS 策略均由其编译生成）
+	Routing *RoutingTemplate `json:"-"`
+
+	// 额外入站监听器与访问控制
+	Listeners []ListenerSettings `json:"listeners,omitempty"`
+	Access    AccessSettings     `json:"access"`
+
+	// 停用的规则集（移除规则集定义及引用它的规则）
+	DisabledRuleSets []string `json:"disabledRuleSets,omitempty"`
 }
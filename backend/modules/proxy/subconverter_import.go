@@ -0,0 +1,244 @@
+package proxy
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ============================================================================
+// subconverter / ACL4SSR 外部配置（.ini）导入
+// ============================================================================
+
+// subconverterGroupTypes 支持的分组类型
+var subconverterGroupTypes = map[string]bool{
+	"select": true, "url-test": true, "fallback": true, "load-balance": true,
+}
+
+// subconverterIgnoredKeys 与生成模板无关、可以静默忽略的配置项
+var subconverterIgnoredKeys = map[string]bool{
+	"enable_rule_generator":    true,
+	"overwrite_original_rules": true,
+}
+
+// ParseSubconverterConfig 解析 subconverter 外部配置
+// custom_proxy_group 转为代理组（正则筛选映射到 Filter），ruleset 转为规则集与规则
+func ParseSubconverterConfig(data []byte) (*ImportResult, error) {
+	result := &ImportResult{Source: "subconverter", Template: &ConfigTemplate{}}
+	// 规则集名称避开生成器内置的规则集
+	providers := make(map[string]bool)
+	for _, source := range defaultRuleProviderSources {
+		providers[source.name] = true
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") {
+			continue
+		}
+		pathRef := fmt.Sprintf("line %d", lineNo)
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			result.issue(pathRef, line, "无法识别的行: %s", line)
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		switch key {
+		case "ruleset", "surge_ruleset":
+			parseSubconverterRuleset(value, pathRef, providers, result)
+		case "custom_proxy_group":
+			if group, ok := parseSubconverterGroup(value, pathRef, result); ok {
+				result.Template.ProxyGroups = append(result.Template.ProxyGroups, group)
+			}
+		default:
+			if !subconverterIgnoredKeys[key] {
+				result.issue(pathRef, key, "配置项 %s 未导入", key)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(result.Template.ProxyGroups) == 0 && len(result.Template.Rules) == 0 {
+		return nil, fmt.Errorf("未找到 ruleset 或 custom_proxy_group 配置")
+	}
+
+	// MATCH 规则放到最后
+	var rules, final []RuleTemplate
+	for _, r := range result.Template.Rules {
+		if r.Type == "MATCH" {
+			final = append(final, r)
+		} else {
+			rules = append(rules, r)
+		}
+	}
+	if len(final) > 1 {
+		result.issue("ruleset", final[1].Proxy, "存在多条 FINAL 规则，只保留第一条")
+	}
+	if len(final) > 0 {
+		rules = append(rules, final[0])
+	}
+	result.Template.Rules = rules
+
+	return result, nil
+}
+
+// parseSubconverterRuleset 解析 ruleset=策略组,规则来源[,更新间隔]
+// 规则来源为 []GEOIP,CN 形式的内联规则，或带 clash-domain:/clash-ipcidr:/clash-classic:/surge: 前缀的规则文件地址
+func parseSubconverterRuleset(value, pathRef string, providers map[string]bool, result *ImportResult) {
+	group, source, ok := strings.Cut(value, ",")
+	if !ok || group == "" || source == "" {
+		result.issue(pathRef, value, "ruleset 格式错误: %s", value)
+		return
+	}
+	group, source = strings.TrimSpace(group), strings.TrimSpace(source)
+
+	// 内联规则
+	if strings.HasPrefix(source, "[]") {
+		fields := splitRuleFields(strings.TrimPrefix(source, "[]"))
+		ruleType := strings.ToUpper(fields[0])
+		if ruleType == "FINAL" || ruleType == "MATCH" {
+			result.Template.Rules = append(result.Template.Rules, RuleTemplate{Type: "MATCH", Proxy: group})
+			return
+		}
+		if len(fields) < 2 {
+			result.issue(pathRef, source, "内联规则 %s 缺少内容", source)
+			return
+		}
+		rule := RuleTemplate{Type: ruleType, Payload: fields[1], Proxy: group}
+		for _, extra := range fields[2:] {
+			if strings.EqualFold(extra, "no-resolve") {
+				rule.NoResolve = true
+			}
+		}
+		if err := ValidateRuleTemplate(rule, nil); err != nil {
+			result.issue(pathRef, source, "内联规则 %s 无法导入: %v", source, err)
+			return
+		}
+		result.Template.Rules = append(result.Template.Rules, rule)
+		return
+	}
+
+	// 规则文件
+	interval := defaultRuleSetUpdate
+	if idx := strings.LastIndex(source, ","); idx > 0 {
+		if v, err := strconv.Atoi(strings.TrimSpace(source[idx+1:])); err == nil {
+			interval = v
+			source = strings.TrimSpace(source[:idx])
+		}
+	}
+
+	behavior, format := "classical", "text"
+	switch {
+	case strings.HasPrefix(source, "clash-domain:"):
+		behavior, format = "domain", "yaml"
+	case strings.HasPrefix(source, "clash-ipcidr:"):
+		behavior, format = "ipcidr", "yaml"
+	case strings.HasPrefix(source, "clash-classic:"):
+		behavior, format = "classical", "yaml"
+	case strings.HasPrefix(source, "surge:"):
+	case strings.HasPrefix(source, "quanx:"):
+		result.issue(pathRef, source, "不支持 Quantumult X 格式的规则集")
+		return
+	}
+	if idx := strings.Index(source, ":"); idx > 0 && !strings.HasPrefix(source[idx:], "://") {
+		source = source[idx+1:]
+	}
+	if !strings.HasPrefix(source, "http://") && !strings.HasPrefix(source, "https://") {
+		result.issue(pathRef, source, "规则集 %s 是 subconverter 本地文件，无法导入", source)
+		return
+	}
+
+	name := strings.TrimSuffix(path.Base(source), path.Ext(source))
+	if name == "" || name == "." || name == "/" {
+		name = "ruleset"
+	}
+	unique := name
+	for n := 2; providers[unique]; n++ {
+		unique = fmt.Sprintf("%s-%d", name, n)
+	}
+	providers[unique] = true
+
+	ext := map[string]string{"yaml": ".yaml", "text": ".list"}[format]
+	result.Template.RuleProviders = append(result.Template.RuleProviders, RuleProviderTemplate{
+		Name:     unique,
+		Type:     "http",
+		Behavior: behavior,
+		URL:      source,
+		Path:     "./ruleset/" + unique + ext,
+		Interval: interval,
+		Format:   format,
+	})
+	result.Template.Rules = append(result.Template.Rules, RuleTemplate{Type: "RULE-SET", Payload: unique, Proxy: group})
+}
+
+// parseSubconverterGroup 解析 custom_proxy_group=名称`类型`成员...[`测速地址`间隔,超时,容差]
+// []开头的成员为策略组或 DIRECT/REJECT，其他成员为节点名称正则
+func parseSubconverterGroup(value, pathRef string, result *ImportResult) (ProxyGroupTemplate, bool) {
+	parts := strings.Split(value, "`")
+	if len(parts) < 2 || parts[0] == "" {
+		result.issue(pathRef, value, "custom_proxy_group 格式错误: %s", value)
+		return ProxyGroupTemplate{}, false
+	}
+	group := ProxyGroupTemplate{Name: parts[0], Type: parts[1], Enabled: true, Proxies: []string{}}
+	if !subconverterGroupTypes[group.Type] {
+		result.issue(pathRef, group.Name, "不支持 %s 类型的代理组 %s，已跳过", group.Type, group.Name)
+		return ProxyGroupTemplate{}, false
+	}
+
+	members := parts[2:]
+	if group.Type != "select" && len(members) >= 2 {
+		// 测速分组最后两项为测速地址与 间隔,超时,容差
+		group.URL = members[len(members)-2]
+		options := strings.Split(members[len(members)-1], ",")
+		group.Interval, _ = strconv.Atoi(strings.TrimSpace(options[0]))
+		if len(options) > 2 {
+			group.Tolerance, _ = strconv.Atoi(strings.TrimSpace(options[2]))
+		}
+		members = members[:len(members)-2]
+	}
+
+	var filters []string
+	for _, member := range members {
+		switch {
+		case member == "":
+		case strings.HasPrefix(member, "[]"):
+			group.Proxies = append(group.Proxies, strings.TrimPrefix(member, "[]"))
+		case strings.HasPrefix(member, "!!"):
+			result.issue(pathRef, member, "代理组 %s 的筛选条件 %s 无法导入，已改为包含全部节点", group.Name, member)
+			group.UseAll = true
+		default:
+			group.UseAll = true
+			if member == ".*" {
+				continue
+			}
+			if _, err := regexp.Compile(member); err != nil {
+				result.issue(pathRef, member, "代理组 %s 的正则 %s 不受支持（%v），已改为包含全部节点", group.Name, member, err)
+				continue
+			}
+			filters = append(filters, member)
+		}
+	}
+
+	if group.UseAll {
+		if len(filters) == 1 {
+			group.Filter = filters[0]
+		} else if len(filters) > 1 {
+			group.Filter = "(?:" + strings.Join(filters, ")|(?:") + ")"
+		}
+		if len(group.Proxies) > 0 {
+			result.issue(pathRef, group.Name, "代理组 %s 同时包含策略组与节点筛选，策略组成员 %s 未导入", group.Name, strings.Join(group.Proxies, ", "))
+			group.Proxies = []string{}
+		}
+	}
+	return group, true
+}
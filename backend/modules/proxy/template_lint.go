@@ -0,0 +1,382 @@
+package proxy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// 模板检查级别
+const (
+	LintLevelError   = "error"
+	LintLevelWarning = "warning"
+)
+
+// 模板检查问题代码
+const (
+	LintMissingGroup    = "missing_group"    // 规则指向不存在的代理组
+	LintMissingProvider = "missing_provider" // 规则引用不存在的规则集
+	LintMissingMember   = "missing_member"   // 代理组成员不存在
+	LintEmptyGroup      = "empty_group"      // 代理组过滤后没有节点
+	LintDuplicateGroup  = "duplicate_group"  // 代理组名称重复
+	LintUnreachableRule = "unreachable_rule" // MATCH 之后的规则永远不会命中
+	LintUnusedProvider  = "unused_provider"  // 规则集未被任何规则引用
+	LintInvalidFilter   = "invalid_filter"   // 节点过滤正则无效
+	LintInvalidRule     = "invalid_rule"     // 规则格式错误
+	LintUnsupportedRule = "unsupported_rule" // 规则无法被另一核心表达
+)
+
+// TemplateLintIssue 模板检查问题
+type TemplateLintIssue struct {
+	Level   string `json:"level"`
+	Code    string `json:"code"`
+	Path    string `json:"path"`             // 问题位置，如 rules[3]
+	Target  string `json:"target,omitempty"` // 相关名称
+	Message string `json:"message"`
+}
+
+// TemplateLintError 模板检查失败（包含至少一个 error 级别问题）
+type TemplateLintError struct {
+	Issues []TemplateLintIssue `json:"issues"`
+}
+
+func (e *TemplateLintError) Error() string {
+	count := 0
+	var first string
+	for _, issue := range e.Issues {
+		if issue.Level == LintLevelError {
+			if count == 0 {
+				first = issue.Message
+			}
+			count++
+		}
+	}
+	return fmt.Sprintf("模板检查发现 %d 个错误: %s", count, first)
+}
+
+// lintResult 将问题列表转换为错误（仅包含警告时返回 nil）
+func lintResult(issues []TemplateLintIssue) error {
+	for _, issue := range issues {
+		if issue.Level == LintLevelError {
+			return &TemplateLintError{Issues: issues}
+		}
+	}
+	return nil
+}
+
+// mihomoBuiltinTargets Mihomo 内置出站
+var mihomoBuiltinTargets = map[string]bool{
+	"DIRECT": true, "REJECT": true, "REJECT-DROP": true, "PASS": true, "COMPATIBLE": true, "GLOBAL": true,
+}
+
+// singBoxBuiltinOutbounds sing-box 生成器固定添加的出站
+var singBoxBuiltinOutbounds = map[string]bool{
+	"direct": true, "block": true, "dns-out": true,
+}
+
+// LintConfigTemplate 检查 Mihomo 配置模板的引用完整性
+// nodes 为 nil 时跳过与节点相关的检查
+func LintConfigTemplate(t *ConfigTemplate, nodes []ProxyNode) []TemplateLintIssue {
+	var issues []TemplateLintIssue
+	add := func(level, code, path, target, format string, args ...interface{}) {
+		issues = append(issues, TemplateLintIssue{Level: level, Code: code, Path: path, Target: target, Message: fmt.Sprintf(format, args...)})
+	}
+
+	nodeNames := make(map[string]bool, len(nodes))
+	hasManual := false
+	for _, n := range nodes {
+		nodeNames[n.Name] = true
+		if n.IsManual {
+			hasManual = true
+		}
+	}
+
+	// 与 generateProxyGroupsFromTemplate 保持一致：禁用且有说明的分组不会生成
+	groups := make(map[string]bool)
+	disabled := make(map[string]bool)
+	for i, g := range t.ProxyGroups {
+		path := fmt.Sprintf("proxyGroups[%d]", i)
+		if !g.Enabled && g.Description != "" {
+			disabled[g.Name] = true
+			continue
+		}
+		if groups[g.Name] {
+			add(LintLevelError, LintDuplicateGroup, path, g.Name, "代理组 %s 重复定义", g.Name)
+		}
+		groups[g.Name] = true
+	}
+
+	for i, g := range t.ProxyGroups {
+		if disabled[g.Name] {
+			continue
+		}
+		path := fmt.Sprintf("proxyGroups[%d]", i)
+
+		if g.UseAll {
+			if nodes == nil {
+				continue
+			}
+			switch {
+			case g.Filter == "__MANUAL__":
+				if !hasManual {
+					add(LintLevelWarning, LintEmptyGroup, path, g.Name, "代理组 %s 没有手动节点，将回退为 DIRECT", g.Name)
+				}
+			case g.Filter != "":
+				re, err := regexp.Compile(g.Filter)
+				if err != nil {
+					add(LintLevelError, LintInvalidFilter, path, g.Name, "代理组 %s 的过滤正则无效: %v", g.Name, err)
+					continue
+				}
+				matched := false
+				for name := range nodeNames {
+					if re.MatchString(name) {
+						matched = true
+						break
+					}
+				}
+				if !matched {
+					add(LintLevelWarning, LintEmptyGroup, path, g.Name, "代理组 %s 过滤后没有节点，将回退为全部节点", g.Name)
+				}
+			case len(nodes) == 0:
+				add(LintLevelWarning, LintEmptyGroup, path, g.Name, "代理组 %s 没有可用节点，将回退为 DIRECT", g.Name)
+			}
+			continue
+		}
+
+		if len(g.Proxies) == 0 {
+			add(LintLevelWarning, LintEmptyGroup, path, g.Name, "代理组 %s 没有成员，将回退为 DIRECT", g.Name)
+		}
+		for j, member := range g.Proxies {
+			if groups[member] || mihomoBuiltinTargets[member] || nodeNames[member] {
+				continue
+			}
+			if nodes == nil && !disabled[member] {
+				continue
+			}
+			reason := "不存在"
+			if disabled[member] {
+				reason = "已禁用"
+			}
+			add(LintLevelError, LintMissingMember, fmt.Sprintf("%s.proxies[%d]", path, j), member, "代理组 %s 的成员 %s %s", g.Name, member, reason)
+		}
+	}
+
+	// 规则集：模板中定义的规则集与生成器固定生成的规则集
+	providers := make(map[string]bool)
+	for _, p := range t.RuleProviders {
+		providers[p.Name] = true
+	}
+	builtinProviders := make(map[string]bool)
+	for _, source := range defaultRuleProviderSources {
+		builtinProviders[source.name] = true
+	}
+
+	// 传递给 sing-box 的自定义规则需要检查能否转换
+	custom := make(map[int]bool)
+	defaults := defaultRuleKeys()
+	for i, r := range t.Rules {
+		custom[i] = isCustomRule(r, defaults)
+	}
+
+	// 规则目标可以是代理组、内置出站或节点；未提供节点时无法判断，跳过检查
+	targetMissing := func(target string) bool {
+		if groups[target] || mihomoBuiltinTargets[target] || nodeNames[target] {
+			return false
+		}
+		return nodes != nil || disabled[target]
+	}
+
+	used := make(map[string]bool)
+	matchIndex := -1
+	for i, r := range t.Rules {
+		path := fmt.Sprintf("rules[%d]", i)
+		if matchIndex >= 0 {
+			add(LintLevelWarning, LintUnreachableRule, path, r.Payload, "规则位于 rules[%d] 的 MATCH 之后，永远不会命中", matchIndex)
+		}
+		if strings.EqualFold(r.Type, "MATCH") && matchIndex < 0 {
+			matchIndex = i
+		}
+
+		if err := ValidateRuleTemplate(r, t.SubRules); err != nil {
+			add(LintLevelError, LintInvalidRule, path, r.Payload, "规则 %s 无效: %v", formatMihomoRule(r), err)
+		} else if custom[i] && !strings.EqualFold(r.Type, "MATCH") {
+			if _, err := ConvertRuleToSingBox(r, t.SubRules); err != nil {
+				add(LintLevelWarning, LintUnsupportedRule, path, r.Payload, "规则 %s 无法用于 sing-box: %v", formatMihomoRule(r), err)
+			}
+		}
+
+		if !strings.EqualFold(r.Type, "SUB-RULE") && targetMissing(r.Proxy) {
+			add(LintLevelError, LintMissingGroup, path, r.Proxy, "规则 %s,%s 指向不存在的代理组或节点 %s", r.Type, r.Payload, r.Proxy)
+		}
+
+		for _, name := range ruleProviderRefs(r) {
+			used[name] = true
+			if !providers[name] && !builtinProviders[name] {
+				add(LintLevelError, LintMissingProvider, path, name, "规则引用不存在的规则集 %s", name)
+			}
+		}
+	}
+
+	// 子规则
+	for name, rules := range t.SubRules {
+		for i, r := range rules {
+			path := fmt.Sprintf("subRules.%s[%d]", name, i)
+			if err := ValidateRuleTemplate(r, t.SubRules); err != nil {
+				add(LintLevelError, LintInvalidRule, path, r.Payload, "子规则 %s 的规则 %s 无效: %v", name, formatMihomoRule(r), err)
+			}
+			if !strings.EqualFold(r.Type, "SUB-RULE") && targetMissing(r.Proxy) {
+				add(LintLevelError, LintMissingGroup, path, r.Proxy, "子规则 %s 指向不存在的代理组或节点 %s", name, r.Proxy)
+			}
+			for _, provider := range ruleProviderRefs(r) {
+				used[provider] = true
+				if !providers[provider] && !builtinProviders[provider] {
+					add(LintLevelError, LintMissingProvider, path, provider, "子规则引用不存在的规则集 %s", provider)
+				}
+			}
+		}
+	}
+
+	for i, p := range t.RuleProviders {
+		if !used[p.Name] {
+			add(LintLevelWarning, LintUnusedProvider, fmt.Sprintf("ruleProviders[%d]", i), p.Name, "规则集 %s 未被任何规则引用", p.Name)
+		}
+	}
+
+	return issues
+}
+
+// ruleProviderRefs 获取规则引用的规则集名称（包括逻辑规则中的条件）
+func ruleProviderRefs(r RuleTemplate) []string {
+	switch strings.ToUpper(r.Type) {
+	case "RULE-SET":
+		return []string{r.Payload}
+	case "AND", "OR", "NOT":
+		conditions, err := parseLogicalPayload(r.Payload)
+		if err != nil {
+			return nil
+		}
+		var refs []string
+		for _, condition := range conditions {
+			refs = append(refs, ruleProviderRefs(condition)...)
+		}
+		return refs
+	case "SUB-RULE":
+		condition, err := parseRuleCondition(r.Payload)
+		if err != nil {
+			return nil
+		}
+		return ruleProviderRefs(condition)
+	}
+	return nil
+}
+
+// LintSingBoxTemplate 检查 sing-box 配置模板的引用完整性
+// nodes 为 nil 时跳过与节点相关的检查
+func LintSingBoxTemplate(t *SingBoxTemplate, nodes []ProxyNode) []TemplateLintIssue {
+	var issues []TemplateLintIssue
+	add := func(level, code, path, target, format string, args ...interface{}) {
+		issues = append(issues, TemplateLintIssue{Level: level, Code: code, Path: path, Target: target, Message: fmt.Sprintf(format, args...)})
+	}
+
+	nodeNames := make(map[string]bool, len(nodes))
+	for _, n := range nodes {
+		nodeNames[n.Name] = true
+	}
+
+	groups := make(map[string]bool)
+	disabled := make(map[string]bool)
+	for i, g := range t.ProxyGroups {
+		if !g.Enabled {
+			disabled[g.Tag] = true
+			continue
+		}
+		if groups[g.Tag] {
+			add(LintLevelError, LintDuplicateGroup, fmt.Sprintf("proxyGroups[%d]", i), g.Tag, "出站组 %s 重复定义", g.Tag)
+		}
+		groups[g.Tag] = true
+	}
+
+	for i, g := range t.ProxyGroups {
+		if disabled[g.Tag] {
+			continue
+		}
+		path := fmt.Sprintf("proxyGroups[%d]", i)
+		// 成员为空的组由生成器动态填充全部节点
+		if len(g.Outbounds) == 0 && nodes != nil && len(nodes) == 0 {
+			add(LintLevelWarning, LintEmptyGroup, path, g.Tag, "出站组 %s 没有可用节点", g.Tag)
+		}
+		for j, member := range g.Outbounds {
+			if groups[member] || singBoxBuiltinOutbounds[member] || nodeNames[member] {
+				continue
+			}
+			if nodes == nil && !disabled[member] {
+				continue
+			}
+			add(LintLevelError, LintMissingMember, fmt.Sprintf("%s.outbounds[%d]", path, j), member, "出站组 %s 的成员 %s 不存在", g.Tag, member)
+		}
+		if g.Default != "" && !containsString(g.Outbounds, g.Default) && len(g.Outbounds) > 0 {
+			add(LintLevelError, LintMissingMember, path+".default", g.Default, "出站组 %s 的默认出站 %s 不在成员列表中", g.Tag, g.Default)
+		}
+	}
+
+	ruleSets := make(map[string]bool)
+	for _, rs := range t.RuleSets {
+		ruleSets[rs.Tag] = true
+	}
+
+	used := make(map[string]bool)
+	for i, r := range t.Rules {
+		path := fmt.Sprintf("rules[%d]", i)
+		if r.Outbound != "" && !groups[r.Outbound] && !singBoxBuiltinOutbounds[r.Outbound] {
+			add(LintLevelError, LintMissingGroup, path, r.Outbound, "规则指向不存在的出站 %s", r.Outbound)
+		}
+		if r.Outbound == "" && r.Action == "" {
+			add(LintLevelError, LintMissingGroup, path, "", "规则既没有出站也没有动作")
+		}
+		for _, tag := range singBoxRuleSetRefs(r.RuleSet) {
+			used[tag] = true
+			if !ruleSets[tag] {
+				add(LintLevelError, LintMissingProvider, path, tag, "规则引用不存在的规则集 %s", tag)
+			}
+		}
+	}
+
+	for i, rs := range t.RuleSets {
+		if !used[rs.Tag] {
+			add(LintLevelWarning, LintUnusedProvider, fmt.Sprintf("ruleSets[%d]", i), rs.Tag, "规则集 %s 未被任何规则引用", rs.Tag)
+		}
+	}
+
+	return issues
+}
+
+// singBoxRuleSetRefs 解析 rule_set 字段（string 或 []string）
+func singBoxRuleSetRefs(v interface{}) []string {
+	switch rs := v.(type) {
+	case string:
+		if rs != "" {
+			return []string{rs}
+		}
+	case []string:
+		return rs
+	case []interface{}:
+		var tags []string
+		for _, item := range rs {
+			if s, ok := item.(string); ok {
+				tags = append(tags, s)
+			}
+		}
+		return tags
+	}
+	return nil
+}
+
+// containsString 判断字符串切片是否包含指定值
+func containsString(list []string, value string) bool {
+	for _, item := range list {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
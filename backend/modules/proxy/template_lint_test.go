@@ -0,0 +1,119 @@
+package proxy
+
+import "testing"
+
+func TestLintRuleTargets(t *testing.T) {
+	nodes := []ProxyNode{{Name: "HK-01", Type: "ss", Server: "hk.example.com", Port: 8388}}
+	template := func(rules []RuleTemplate, subRules map[string][]RuleTemplate) *ConfigTemplate {
+		return &ConfigTemplate{
+			ProxyGroups: []ProxyGroupTemplate{
+				{Name: "Proxy", Type: "select", Enabled: true, Proxies: []string{"HK-01", "DIRECT"}},
+				{Name: "Old", Type: "select", Enabled: false, Description: "已停用", Proxies: []string{"DIRECT"}},
+			},
+			Rules:    rules,
+			SubRules: subRules,
+		}
+	}
+
+	tests := []struct {
+		name     string
+		rules    []RuleTemplate
+		subRules map[string][]RuleTemplate
+		nodes    []ProxyNode
+		missing  []string // 期望报告 missing_group 的路径
+	}{
+		{
+			name:  "规则指向代理组与内置出站",
+			rules: []RuleTemplate{{Type: "DOMAIN", Payload: "a.com", Proxy: "Proxy"}, {Type: "MATCH", Proxy: "DIRECT"}},
+			nodes: nodes,
+		},
+		{
+			name:  "规则直接指向节点",
+			rules: []RuleTemplate{{Type: "DOMAIN", Payload: "x.com", Proxy: "HK-01"}, {Type: "MATCH", Proxy: "Proxy"}},
+			nodes: nodes,
+		},
+		{
+			name:     "子规则直接指向节点",
+			rules:    []RuleTemplate{{Type: "SUB-RULE", Payload: "(NETWORK,tcp)", Proxy: "sub"}, {Type: "MATCH", Proxy: "Proxy"}},
+			subRules: map[string][]RuleTemplate{"sub": {{Type: "DOMAIN", Payload: "x.com", Proxy: "HK-01"}}},
+			nodes:    nodes,
+		},
+		{
+			name:    "规则指向不存在的目标",
+			rules:   []RuleTemplate{{Type: "DOMAIN", Payload: "x.com", Proxy: "JP-01"}, {Type: "MATCH", Proxy: "Proxy"}},
+			nodes:   nodes,
+			missing: []string{"rules[0]"},
+		},
+		{
+			name:     "子规则指向不存在的目标",
+			rules:    []RuleTemplate{{Type: "SUB-RULE", Payload: "(NETWORK,tcp)", Proxy: "sub"}, {Type: "MATCH", Proxy: "Proxy"}},
+			subRules: map[string][]RuleTemplate{"sub": {{Type: "DOMAIN", Payload: "x.com", Proxy: "JP-01"}}},
+			nodes:    nodes,
+			missing:  []string{"subRules.sub[0]"},
+		},
+		{
+			name:  "未提供节点时跳过目标检查",
+			rules: []RuleTemplate{{Type: "DOMAIN", Payload: "x.com", Proxy: "JP-01"}, {Type: "MATCH", Proxy: "Proxy"}},
+		},
+		{
+			name:    "未提供节点时仍报告已禁用的代理组",
+			rules:   []RuleTemplate{{Type: "DOMAIN", Payload: "x.com", Proxy: "Old"}, {Type: "MATCH", Proxy: "Proxy"}},
+			missing: []string{"rules[0]"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, issue := range LintConfigTemplate(template(tt.rules, tt.subRules), tt.nodes) {
+				if issue.Code == LintMissingGroup {
+					got = append(got, issue.Path)
+				}
+			}
+			if len(got) != len(tt.missing) {
+				t.Fatalf("missing_group issues = %v, want %v", got, tt.missing)
+			}
+			for i := range got {
+				if got[i] != tt.missing[i] {
+					t.Errorf("missing_group issues = %v, want %v", got, tt.missing)
+				}
+			}
+		})
+	}
+}
+
+func TestLintResultAcceptsNodeTarget(t *testing.T) {
+	nodes := []ProxyNode{{Name: "HK-01", Type: "ss", Server: "hk.example.com", Port: 8388}}
+	template := &ConfigTemplate{
+		ProxyGroups: []ProxyGroupTemplate{{Name: "Proxy", Type: "select", Enabled: true, Proxies: []string{"HK-01"}}},
+		Rules:       []RuleTemplate{{Type: "DOMAIN", Payload: "x.com", Proxy: "HK-01"}, {Type: "MATCH", Proxy: "Proxy"}},
+	}
+	if err := lintResult(LintConfigTemplate(template, nodes)); err != nil {
+		t.Errorf("lintResult: %v", err)
+	}
+}
+
+func TestImportClashRuleTargetingNode(t *testing.T) {
+	config := []byte(`
+proxies:
+  - {name: HK-01, type: ss, server: hk.example.com, port: 8388, cipher: aes-128-gcm, password: pw}
+proxy-groups:
+  - {name: Proxy, type: select, proxies: [HK-01, DIRECT]}
+rules:
+  - DOMAIN,x.com,HK-01
+  - MATCH,Proxy
+`)
+	result, err := ParseClashConfig(config)
+	if err != nil {
+		t.Fatalf("ParseClashConfig: %v", err)
+	}
+	service := NewService(t.TempDir())
+	service.SetNodeProvider(func() []ProxyNode { return nil })
+	summary, err := NewConfigImporter(service, nil).Apply(result, ImportOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	if summary.NodesAdded != 1 {
+		t.Errorf("nodesAdded = %d, want 1", summary.NodesAdded)
+	}
+}
@@ -0,0 +1,557 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"p-box/backend/clashapi"
+)
+
+// ============================================================================
+// 流量统计：定时采样控制器 /connections 的增量，按节点、代理链、规则、目标域名与来源设备
+// 汇总到小时与天两级桶中并持久化。两次采样之间关闭的连接，其最后一段流量无法计入
+// ============================================================================
+
+// 统计维度
+const (
+	TrafficDimNode   = "node"   // 实际出口节点
+	TrafficDimChain  = "chain"  // 代理链（分组 → 节点）
+	TrafficDimRule   = "rule"   // 命中规则
+	TrafficDimHost   = "host"   // 目标域名或 IP
+	TrafficDimSource = "source" // 来源 IP（局域网设备）
+)
+
+// trafficDimensions 全部统计维度
+var trafficDimensions = []string{TrafficDimNode, TrafficDimChain, TrafficDimRule, TrafficDimHost, TrafficDimSource}
+
+// trafficMaxKeys 单个桶每个维度最多记录的键数，超出部分计入 trafficOtherKey
+const trafficMaxKeys = 5000
+
+// trafficOtherKey 超出键数上限时的汇总键
+const trafficOtherKey = "(其他)"
+
+// TrafficSettings 流量统计设置
+type TrafficSettings struct {
+	Enabled          bool `json:"enabled"`
+	Interval         int  `json:"interval"`         // 采样间隔（秒）
+	HourlyRetention  int  `json:"hourlyRetention"`  // 小时数据保留时长（小时）
+	DailyRetention   int  `json:"dailyRetention"`   // 天数据保留时长（天）
+	QuotaWarnPercent int  `json:"quotaWarnPercent"` // 订阅流量使用超过该百分比时提示
+}
+
+// DefaultTrafficSettings 默认流量统计设置
+func DefaultTrafficSettings() TrafficSettings {
+	return TrafficSettings{
+		Enabled:          true,
+		Interval:         5,
+		HourlyRetention:  168,
+		DailyRetention:   90,
+		QuotaWarnPercent: 80,
+	}
+}
+
+// TrafficCounter 上传与下载字节数
+type TrafficCounter struct {
+	Upload   int64 `json:"upload"`
+	Download int64 `json:"download"`
+}
+
+// trafficBucket 时间桶
+type trafficBucket struct {
+	Start time.Time                             `json:"start"`
+	Dims  map[string]map[string]*TrafficCounter `json:"dims"` // 维度 -> 键 -> 流量
+}
+
+// add 累加流量
+func (b *trafficBucket) add(dim, key string, upload, download int64) {
+	if b.Dims == nil {
+		b.Dims = make(map[string]map[string]*TrafficCounter)
+	}
+	counters, ok := b.Dims[dim]
+	if !ok {
+		counters = make(map[string]*TrafficCounter)
+		b.Dims[dim] = counters
+	}
+	counter, ok := counters[key]
+	if !ok {
+		if len(counters) >= trafficMaxKeys {
+			key = trafficOtherKey
+			counter = counters[key]
+		}
+		if counter == nil {
+			counter = &TrafficCounter{}
+			counters[key] = counter
+		}
+	}
+	counter.Upload += upload
+	counter.Download += download
+}
+
+// trafficStore 流量统计持久化结构
+type trafficStore struct {
+	Settings TrafficSettings  `json:"settings"`
+	Hourly   []*trafficBucket `json:"hourly"`
+	Daily    []*trafficBucket `json:"daily"`
+}
+
+// SubscriptionQuota 订阅流量配额（由订阅模块提供）
+type SubscriptionQuota struct {
+	ID         string
+	Name       string
+	Upload     int64
+	Download   int64
+	Total      int64
+	ExpireTime *time.Time
+}
+
+// QuotaProvider 订阅配额提供者
+type QuotaProvider func() []SubscriptionQuota
+
+// TrafficCollector 流量统计采集器
+type TrafficCollector struct {
+	dataDir  string
+	service  *Service
+	store    trafficStore
+	last     map[string]TrafficCounter // 连接 ID -> 上次采样时的累计流量
+	seeded   bool                      // last 是否已由首次采样初始化
+	dirty    bool
+	quotas   QuotaProvider
+	mu       sync.Mutex
+	stopChan chan struct{}
+}
+
+// NewTrafficCollector 创建流量统计采集器并启动采样循环
+func NewTrafficCollector(dataDir string, service *Service) *TrafficCollector {
+	t := &TrafficCollector{
+		dataDir:  dataDir,
+		service:  service,
+		store:    trafficStore{Settings: DefaultTrafficSettings()},
+		last:     make(map[string]TrafficCounter),
+		stopChan: make(chan struct{}),
+	}
+	t.load()
+	go t.loop()
+	return t
+}
+
+// SetQuotaProvider 设置订阅配额提供者
+func (t *TrafficCollector) SetQuotaProvider(provider QuotaProvider) {
+	t.quotas = provider
+}
+
+// Stop 停止采样并保存数据
+func (t *TrafficCollector) Stop() {
+	close(t.stopChan)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.dirty {
+		t.save()
+	}
+}
+
+// storePath 获取流量统计文件路径
+func (t *TrafficCollector) storePath() string {
+	return filepath.Join(t.dataDir, "traffic_stats.json")
+}
+
+// load 加载流量统计
+func (t *TrafficCollector) load() {
+	data, err := os.ReadFile(t.storePath())
+	if err != nil {
+		return
+	}
+	if err := json.Unmarshal(data, &t.store); err != nil {
+		fmt.Printf("⚠️ 读取流量统计失败: %v\n", err)
+	}
+}
+
+// save 保存流量统计（调用者需持有锁）
+func (t *TrafficCollector) save() error {
+	data, err := json.Marshal(t.store)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(t.storePath(), data, 0644); err != nil {
+		return err
+	}
+	t.dirty = false
+	return nil
+}
+
+// loop 按采样间隔采集，每分钟保存一次
+func (t *TrafficCollector) loop() {
+	lastSave := time.Now()
+	for {
+		interval := time.Duration(t.GetSettings().Interval) * time.Second
+		if interval < time.Second {
+			interval = time.Second
+		}
+		select {
+		case <-time.After(interval):
+			t.sample()
+			if time.Since(lastSave) >= time.Minute {
+				t.mu.Lock()
+				if t.dirty {
+					if err := t.save(); err != nil {
+						fmt.Printf("⚠️ 保存流量统计失败: %v\n", err)
+					}
+				}
+				t.mu.Unlock()
+				lastSave = time.Now()
+			}
+		case <-t.stopChan:
+			return
+		}
+	}
+}
+
+// sample 采样一次连接快照并累加增量
+func (t *TrafficCollector) sample() {
+	if !t.GetSettings().Enabled || !t.service.GetStatus().Running {
+		t.mu.Lock()
+		t.last = make(map[string]TrafficCounter)
+		t.seeded = false
+		t.mu.Unlock()
+		return
+	}
+	snapshot, err := t.service.Controller().Connections()
+	if err != nil {
+		return
+	}
+	t.record(snapshot.Connections, time.Now())
+}
+
+// record 按连接累计流量与上次采样的差值记账
+// 首次采样（启动或重新启用后）只记录基准，不记账，避免把已有连接的历史累计流量重复计入
+func (t *TrafficCollector) record(conns []clashapi.Connection, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.seeded {
+		t.last = make(map[string]TrafficCounter, len(conns))
+		for _, conn := range conns {
+			t.last[conn.ID] = TrafficCounter{Upload: conn.Upload, Download: conn.Download}
+		}
+		t.seeded = true
+		return
+	}
+
+	hourly := t.bucket(&t.store.Hourly, now.Truncate(time.Hour))
+	daily := t.bucket(&t.store.Daily, startOfDay(now))
+
+	current := make(map[string]TrafficCounter, len(conns))
+	for _, conn := range conns {
+		prev := t.last[conn.ID]
+		upload, download := conn.Upload-prev.Upload, conn.Download-prev.Download
+		if upload < 0 || download < 0 {
+			upload, download = conn.Upload, conn.Download
+		}
+		current[conn.ID] = TrafficCounter{Upload: conn.Upload, Download: conn.Download}
+		if upload == 0 && download == 0 {
+			continue
+		}
+		for dim, key := range trafficKeys(conn) {
+			hourly.add(dim, key, upload, download)
+			daily.add(dim, key, upload, download)
+		}
+		t.dirty = true
+	}
+	t.last = current
+}
+
+// bucket 获取（必要时创建）指定起始时间的桶，并清理过期数据（调用者需持有锁）
+func (t *TrafficCollector) bucket(buckets *[]*trafficBucket, start time.Time) *trafficBucket {
+	list := *buckets
+	if n := len(list); n > 0 && list[n-1].Start.Equal(start) {
+		return list[n-1]
+	}
+	b := &trafficBucket{Start: start, Dims: make(map[string]map[string]*TrafficCounter)}
+	*buckets = append(list, b)
+	t.prune()
+	return b
+}
+
+// prune 按保留设置清理过期的桶（调用者需持有锁）
+func (t *TrafficCollector) prune() {
+	now := time.Now()
+	settings := t.store.Settings
+	if settings.HourlyRetention > 0 {
+		t.store.Hourly = pruneBuckets(t.store.Hourly, now.Add(-time.Duration(settings.HourlyRetention)*time.Hour))
+	}
+	if settings.DailyRetention > 0 {
+		t.store.Daily = pruneBuckets(t.store.Daily, startOfDay(now).AddDate(0, 0, -settings.DailyRetention))
+	}
+}
+
+// pruneBuckets 移除早于 cutoff 的桶
+func pruneBuckets(buckets []*trafficBucket, cutoff time.Time) []*trafficBucket {
+	for i, b := range buckets {
+		if !b.Start.Before(cutoff) {
+			return buckets[i:]
+		}
+	}
+	return buckets[:0]
+}
+
+// startOfDay 本地时间当天零点
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// trafficKeys 连接在各维度上的统计键
+func trafficKeys(conn clashapi.Connection) map[string]string {
+	keys := make(map[string]string, len(trafficDimensions))
+
+	// 控制器返回的代理链从实际出口开始，例如 [节点, 分组]
+	if len(conn.Chains) > 0 {
+		keys[TrafficDimNode] = conn.Chains[0]
+		chain := make([]string, len(conn.Chains))
+		for i, name := range conn.Chains {
+			chain[len(conn.Chains)-1-i] = name
+		}
+		keys[TrafficDimChain] = strings.Join(chain, " → ")
+	}
+	if conn.Rule != "" {
+		rule := conn.Rule
+		if conn.RulePayload != "" {
+			rule += "," + conn.RulePayload
+		}
+		keys[TrafficDimRule] = rule
+	}
+	if host := conn.Metadata.Host; host != "" {
+		keys[TrafficDimHost] = host
+	} else if conn.Metadata.DestinationIP != "" {
+		keys[TrafficDimHost] = conn.Metadata.DestinationIP
+	}
+	if conn.Metadata.SourceIP != "" {
+		keys[TrafficDimSource] = conn.Metadata.SourceIP
+	}
+	return keys
+}
+
+// GetSettings 获取流量统计设置
+func (t *TrafficCollector) GetSettings() TrafficSettings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.store.Settings
+}
+
+// UpdateSettings 更新流量统计设置
+func (t *TrafficCollector) UpdateSettings(settings TrafficSettings) error {
+	if settings.Interval < 1 {
+		return fmt.Errorf("采样间隔不能小于 1 秒")
+	}
+	if settings.HourlyRetention < 0 || settings.DailyRetention < 0 {
+		return fmt.Errorf("保留时长不能为负数")
+	}
+	if settings.QuotaWarnPercent < 0 || settings.QuotaWarnPercent > 100 {
+		return fmt.Errorf("配额提示百分比应在 0-100 之间")
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.store.Settings = settings
+	t.prune()
+	return t.save()
+}
+
+// Reset 清空流量统计
+func (t *TrafficCollector) Reset() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.store.Hourly = nil
+	t.store.Daily = nil
+	return t.save()
+}
+
+// ========== 查询 ==========
+
+// TrafficStat 维度汇总
+type TrafficStat struct {
+	Key      string `json:"key"`
+	Upload   int64  `json:"upload"`
+	Download int64  `json:"download"`
+	Total    int64  `json:"total"`
+}
+
+// TrafficPoint 时间序列数据点
+type TrafficPoint struct {
+	Time     time.Time `json:"time"`
+	Upload   int64     `json:"upload"`
+	Download int64     `json:"download"`
+}
+
+// validTrafficQuery 校验维度与周期
+func validTrafficQuery(dim, period string) error {
+	found := false
+	for _, d := range trafficDimensions {
+		if d == dim {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("不支持的统计维度: %s", dim)
+	}
+	if period != "hourly" && period != "daily" {
+		return fmt.Errorf("不支持的统计周期: %s", period)
+	}
+	return nil
+}
+
+// buckets 获取周期内 [from, to) 的桶（调用者需持有锁）
+func (t *TrafficCollector) buckets(period string, from, to time.Time) []*trafficBucket {
+	source := t.store.Daily
+	if period == "hourly" {
+		source = t.store.Hourly
+	}
+	var result []*trafficBucket
+	for _, b := range source {
+		if !b.Start.Before(from) && b.Start.Before(to) {
+			result = append(result, b)
+		}
+	}
+	return result
+}
+
+// Stats 按维度汇总 [from, to) 的流量，按总量倒序，limit 为 0 时不限制
+func (t *TrafficCollector) Stats(dim, period string, from, to time.Time, limit int) ([]TrafficStat, error) {
+	if err := validTrafficQuery(dim, period); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	totals := make(map[string]*TrafficStat)
+	for _, b := range t.buckets(period, from, to) {
+		for key, c := range b.Dims[dim] {
+			stat, ok := totals[key]
+			if !ok {
+				stat = &TrafficStat{Key: key}
+				totals[key] = stat
+			}
+			stat.Upload += c.Upload
+			stat.Download += c.Download
+			stat.Total += c.Upload + c.Download
+		}
+	}
+	t.mu.Unlock()
+
+	stats := make([]TrafficStat, 0, len(totals))
+	for _, stat := range totals {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Total != stats[j].Total {
+			return stats[i].Total > stats[j].Total
+		}
+		return stats[i].Key < stats[j].Key
+	})
+	if limit > 0 && len(stats) > limit {
+		stats = stats[:limit]
+	}
+	return stats, nil
+}
+
+// Series 获取 [from, to) 的时间序列，key 为空时统计该维度的全部流量
+func (t *TrafficCollector) Series(dim, key, period string, from, to time.Time) ([]TrafficPoint, error) {
+	if err := validTrafficQuery(dim, period); err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	points := []TrafficPoint{}
+	for _, b := range t.buckets(period, from, to) {
+		point := TrafficPoint{Time: b.Start}
+		for k, c := range b.Dims[dim] {
+			if key == "" || k == key {
+				point.Upload += c.Upload
+				point.Download += c.Download
+			}
+		}
+		points = append(points, point)
+	}
+	return points, nil
+}
+
+// QuotaStatus 订阅配额使用情况
+type QuotaStatus struct {
+	SubscriptionID  string     `json:"subscriptionId"`
+	Name            string     `json:"name"`
+	Total           int64      `json:"total"`           // 订阅总流量
+	Used            int64      `json:"used"`            // 订阅方统计的已用流量
+	Remaining       int64      `json:"remaining"`       // 剩余流量
+	UsedPercent     float64    `json:"usedPercent"`     // 已用百分比
+	LocalMonthUsage int64      `json:"localMonthUsage"` // 本地统计的本月经该订阅节点的流量
+	ExpireTime      *time.Time `json:"expireTime,omitempty"`
+	Warning         bool       `json:"warning"`
+	WarningMessage  string     `json:"warningMessage,omitempty"`
+}
+
+// QuotaReport 对比订阅配额与本地统计
+func (t *TrafficCollector) QuotaReport() []QuotaStatus {
+	report := []QuotaStatus{}
+	if t.quotas == nil {
+		return report
+	}
+
+	// 节点名称 -> 订阅 ID
+	nodeSubscription := make(map[string]string)
+	if t.service.nodeProvider != nil {
+		for _, n := range t.service.nodeProvider() {
+			if n.SubscriptionID != "" {
+				nodeSubscription[n.Name] = n.SubscriptionID
+			}
+		}
+	}
+
+	now := time.Now()
+	monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	local := make(map[string]int64)
+	nodeStats, _ := t.Stats(TrafficDimNode, "daily", monthStart, now.Add(time.Hour), 0)
+	for _, stat := range nodeStats {
+		if id, ok := nodeSubscription[stat.Key]; ok {
+			local[id] += stat.Total
+		}
+	}
+
+	warnPercent := float64(t.GetSettings().QuotaWarnPercent)
+	for _, q := range t.quotas() {
+		status := QuotaStatus{
+			SubscriptionID:  q.ID,
+			Name:            q.Name,
+			Total:           q.Total,
+			Used:            q.Upload + q.Download,
+			LocalMonthUsage: local[q.ID],
+			ExpireTime:      q.ExpireTime,
+		}
+		var warnings []string
+		if q.Total > 0 {
+			status.Remaining = q.Total - status.Used
+			status.UsedPercent = float64(status.Used) * 100 / float64(q.Total)
+			if warnPercent > 0 && status.UsedPercent >= warnPercent {
+				warnings = append(warnings, fmt.Sprintf("已使用 %.1f%% 流量", status.UsedPercent))
+			}
+		}
+		if q.ExpireTime != nil {
+			if q.ExpireTime.Before(now) {
+				warnings = append(warnings, "订阅已过期")
+			} else if q.ExpireTime.Sub(now) < 3*24*time.Hour {
+				warnings = append(warnings, "订阅将在 3 天内过期")
+			}
+		}
+		if len(warnings) > 0 {
+			status.Warning = true
+			status.WarningMessage = strings.Join(warnings, "；")
+		}
+		report = append(report, status)
+	}
+	return report
+}
@@ -0,0 +1,185 @@
+package proxy
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TrafficHandler 流量统计处理器
+type TrafficHandler struct {
+	collector *TrafficCollector
+}
+
+// NewTrafficHandler 创建流量统计处理器
+func NewTrafficHandler(collector *TrafficCollector) *TrafficHandler {
+	return &TrafficHandler{collector: collector}
+}
+
+// RegisterRoutes 注册路由
+func (h *TrafficHandler) RegisterRoutes(r *gin.RouterGroup) {
+	r.GET("/traffic/stats", h.GetStats)
+	r.DELETE("/traffic/stats", h.ResetStats)
+	r.GET("/traffic/series", h.GetSeries)
+	r.GET("/traffic/quota", h.GetQuota)
+	r.GET("/traffic/settings", h.GetSettings)
+	r.PUT("/traffic/settings", h.UpdateSettings)
+}
+
+// parseTrafficRange 解析 from/to 参数（RFC3339 或 2006-01-02），默认最近 defaultSpan
+func parseTrafficRange(c *gin.Context, defaultSpan time.Duration) (time.Time, time.Time, error) {
+	to := time.Now()
+	from := to.Add(-defaultSpan)
+	parse := func(value string) (time.Time, error) {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t, nil
+		}
+		return time.ParseInLocation("2006-01-02", value, time.Local)
+	}
+	if v := c.Query("from"); v != "" {
+		t, err := parse(v)
+		if err != nil {
+			return from, to, err
+		}
+		from = t
+	}
+	if v := c.Query("to"); v != "" {
+		t, err := parse(v)
+		if err != nil {
+			return from, to, err
+		}
+		to = t
+	}
+	return from, to, nil
+}
+
+// trafficDefaultSpan 各周期的默认查询范围
+func trafficDefaultSpan(period string) time.Duration {
+	if period == "hourly" {
+		return 24 * time.Hour
+	}
+	return 30 * 24 * time.Hour
+}
+
+// GetStats 按维度汇总流量
+// 参数：dimension=node|chain|rule|host|source，period=hourly|daily，from，to，limit
+func (h *TrafficHandler) GetStats(c *gin.Context) {
+	dim := c.DefaultQuery("dimension", TrafficDimNode)
+	period := c.DefaultQuery("period", "daily")
+	from, to, err := parseTrafficRange(c, trafficDefaultSpan(period))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "时间参数错误: " + err.Error(),
+		})
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+
+	stats, err := h.collector.Stats(dim, period, from, to, limit)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    stats,
+	})
+}
+
+// GetSeries 获取流量时间序列
+// 参数：dimension，key（为空表示全部），period，from，to
+func (h *TrafficHandler) GetSeries(c *gin.Context) {
+	dim := c.DefaultQuery("dimension", TrafficDimNode)
+	period := c.DefaultQuery("period", "hourly")
+	from, to, err := parseTrafficRange(c, trafficDefaultSpan(period))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "时间参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	points, err := h.collector.Series(dim, c.Query("key"), period, from, to)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    points,
+	})
+}
+
+// ResetStats 清空流量统计
+func (h *TrafficHandler) ResetStats(c *gin.Context) {
+	if err := h.collector.Reset(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+	})
+}
+
+// GetQuota 获取订阅配额使用情况
+func (h *TrafficHandler) GetQuota(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.collector.QuotaReport(),
+	})
+}
+
+// GetSettings 获取流量统计设置
+func (h *TrafficHandler) GetSettings(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.collector.GetSettings(),
+	})
+}
+
+// UpdateSettings 更新流量统计设置
+func (h *TrafficHandler) UpdateSettings(c *gin.Context) {
+	var settings TrafficSettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": "参数错误: " + err.Error(),
+		})
+		return
+	}
+
+	if err := h.collector.UpdateSettings(settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"code":    1,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"code":    0,
+		"message": "success",
+		"data":    h.collector.GetSettings(),
+	})
+}
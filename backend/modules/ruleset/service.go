@@ -10,6 +10,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"p-box/backend/events"
 )
 
 // RuleFile 规则文件定义
@@ -41,6 +43,7 @@ type Service struct {
 	configPath string
 	mu         sync.RWMutex
 	updating   bool
+	bus        *events.Bus // 事件总线（规则集下载结果）
 }
 
 // NewService 创建规则集服务
@@ -253,6 +256,11 @@ func (s *Service) DownloadFile(url, path string) error {
 	return nil
 }
 
+// SetEventBus 设置事件总线
+func (s *Service) SetEventBus(bus *events.Bus) {
+	s.bus = bus
+}
+
 // DownloadAllFiles 下载所有规则文件
 func (s *Service) DownloadAllFiles() (int, int, []string) {
 	s.mu.Lock()
@@ -298,6 +306,7 @@ func (s *Service) DownloadAllFiles() (int, int, []string) {
 	s.saveConfig()
 	s.mu.Unlock()
 
+	s.bus.Publish(events.RulesetUpdated, events.RulesetPayload{Success: success, Failed: failed, Errors: errors})
 	return success, failed, errors
 }
 
@@ -436,5 +445,6 @@ func (s *Service) DownloadAllFilesWithProxy(proxy string) (int, int, []string) {
 	s.saveConfig()
 	s.mu.Unlock()
 
+	s.bus.Publish(events.RulesetUpdated, events.RulesetPayload{Success: success, Failed: failed, Errors: errors})
 	return success, failed, errors
 }
@@ -90,6 +90,7 @@ func ParseAnyTLSURL(anytlsURL string) (*ProxyNode, error) {
 		}
 	}
 
+	ParseTLSExtras(params, tlsConfig)
 	config.TLS = tlsConfig
 
 	// 解析 Reality 配置
@@ -125,12 +125,15 @@ func ParseBool(s string, defaultValue bool) bool {
 
 // TLSConfig TLS配置结构 (共享)
 type TLSConfig struct {
-	Enabled     bool        `json:"enabled"`
-	ServerName  string      `json:"server_name,omitempty"`
-	Insecure    bool        `json:"insecure,omitempty"`
-	ALPN        []string    `json:"alpn,omitempty"`
-	Fingerprint string      `json:"fingerprint,omitempty"`
-	UTLS        *UTLSConfig `json:"utls,omitempty"`
+	Enabled        bool        `json:"enabled"`
+	ServerName     string      `json:"server_name,omitempty"`
+	Insecure       bool        `json:"insecure,omitempty"`
+	ALPN           []string    `json:"alpn,omitempty"`
+	Fingerprint    string      `json:"fingerprint,omitempty"`
+	UTLS           *UTLSConfig `json:"utls,omitempty"`
+	ECH            *ECHConfig  `json:"ech,omitempty"`
+	Fragment       bool        `json:"fragment,omitempty"`        // TLS 握手分片
+	RecordFragment bool        `json:"record_fragment,omitempty"` // TLS 记录分片
 }
 
 // UTLSConfig uTLS配置结构 (共享)
@@ -138,3 +141,91 @@ type UTLSConfig struct {
 	Enabled     bool   `json:"enabled"`
 	Fingerprint string `json:"fingerprint,omitempty"`
 }
+
+// ECHConfig ECH配置结构 (共享)
+type ECHConfig struct {
+	Enabled bool     `json:"enabled"`
+	Config  []string `json:"config,omitempty"` // PEM 格式，为空时通过 DNS 查询
+}
+
+// MultiplexConfig 多路复用配置结构 (共享)
+type MultiplexConfig struct {
+	Enabled        bool   `json:"enabled"`
+	Protocol       string `json:"protocol,omitempty"` // smux, yamux, h2mux
+	MaxConnections int    `json:"max_connections,omitempty"`
+	MinStreams     int    `json:"min_streams,omitempty"`
+	MaxStreams     int    `json:"max_streams,omitempty"`
+	Padding        bool   `json:"padding,omitempty"`
+}
+
+// ParseTLSExtras 解析分享链接中的 ECH 与 TLS 分片参数
+// ech=1 表示启用并通过 DNS 查询配置，其他值视为 base64 编码的 ECHConfigList
+func ParseTLSExtras(params map[string]string, tls *TLSConfig) {
+	if tls == nil {
+		return
+	}
+	if ech := params["ech"]; ech != "" && ech != "0" && ech != "false" {
+		tls.ECH = &ECHConfig{Enabled: true}
+		if ech != "1" && ech != "true" {
+			// 未转义的 base64 中 + 会被查询参数解码为空格
+			ech = strings.ReplaceAll(ech, " ", "+")
+			tls.ECH.Config = []string{"-----BEGIN ECH CONFIGS-----", ech, "-----END ECH CONFIGS-----"}
+		}
+	}
+	tls.Fragment = ParseBool(params["fragment"], false)
+	tls.RecordFragment = ParseBool(params["record_fragment"], false)
+}
+
+// ParseMultiplex 解析分享链接中的多路复用参数
+// mux（或 multiplex）为 1/true 或协议名称 smux/yamux/h2mux
+func ParseMultiplex(params map[string]string) *MultiplexConfig {
+	mux := params["mux"]
+	if mux == "" {
+		mux = params["multiplex"]
+	}
+	if mux == "" || mux == "0" || mux == "false" {
+		return nil
+	}
+	config := &MultiplexConfig{
+		Enabled:        true,
+		MaxConnections: ParseInt(params["mux_max_connections"], 0),
+		MinStreams:     ParseInt(params["mux_min_streams"], 0),
+		MaxStreams:     ParseInt(params["mux_max_streams"], 0),
+		Padding:        ParseBool(params["mux_padding"], false),
+	}
+	if mux != "1" && mux != "true" {
+		config.Protocol = mux
+	}
+	return config
+}
+
+// ParseHopInterval 解析端口跳跃间隔，纯数字视为秒，返回 sing-box 时长格式（如 30s）
+func ParseHopInterval(interval string) string {
+	interval = strings.TrimSpace(interval)
+	if _, err := strconv.Atoi(interval); err == nil {
+		return interval + "s"
+	}
+	return interval
+}
+
+// ParsePortRanges 解析端口跳跃范围，如 "20000-30000,443"，返回 sing-box 格式 ["20000:30000", "443:443"]
+func ParsePortRanges(ports string) []string {
+	var ranges []string
+	for _, part := range strings.Split(ports, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		start, end, isRange := strings.Cut(strings.ReplaceAll(part, ":", "-"), "-")
+		if _, err := strconv.Atoi(start); err != nil {
+			continue
+		}
+		if !isRange {
+			end = start
+		} else if _, err := strconv.Atoi(end); err != nil {
+			continue
+		}
+		ranges = append(ranges, start+":"+end)
+	}
+	return ranges
+}
@@ -16,7 +16,7 @@ type HysteriaConfig struct {
 	UpMbps         int        `json:"up_mbps,omitempty"`
 	DownMbps       int        `json:"down_mbps,omitempty"`
 	HopInterval    string     `json:"hop_interval,omitempty"`
-	Ports          string     `json:"ports,omitempty"`
+	ServerPorts    []string   `json:"server_ports,omitempty"` // 端口跳跃，sing-box 格式如 20000:30000
 	RecvWindowConn int        `json:"recv_window_conn,omitempty"`
 	RecvWindow     int        `json:"recv_window,omitempty"`
 	DisableMTU     bool       `json:"disable_mtu_discovery,omitempty"`
@@ -55,8 +55,8 @@ func ParseHysteriaURL(hysteriaURL string) (*ProxyNode, error) {
 		Obfs:           query.Get("obfs"),
 		UpMbps:         ParseInt(query.Get("upmbps"), 10),
 		DownMbps:       ParseInt(query.Get("downmbps"), 50),
-		HopInterval:    query.Get("hop_interval"),
-		Ports:          query.Get("ports"),
+		HopInterval:    ParseHopInterval(query.Get("hop_interval")),
+		ServerPorts:    ParsePortRanges(query.Get("mport") + "," + query.Get("ports")),
 		RecvWindowConn: ParseInt(query.Get("recv_window_conn"), 0),
 		RecvWindow:     ParseInt(query.Get("recv_window"), 0),
 		DisableMTU:     ParseBool(query.Get("disable_mtu_discovery"), false),
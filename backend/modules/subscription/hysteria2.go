@@ -14,6 +14,10 @@ type Hysteria2Config struct {
 	ServerName string         `json:"server_name,omitempty"`
 	UpMbps     int            `json:"up_mbps,omitempty"`
 	DownMbps   int            `json:"down_mbps,omitempty"`
+
+	// 端口跳跃
+	ServerPorts []string `json:"server_ports,omitempty"` // sing-box 格式，如 20000:30000
+	HopInterval string   `json:"hop_interval,omitempty"` // 如 30s
 }
 
 // Hysteria2Obfs Hysteria2混淆配置
@@ -25,6 +29,7 @@ type Hysteria2Obfs struct {
 // ParseHysteria2URL 解析Hysteria2链接
 // 格式: hysteria2://password@server:port?params#name
 // 或: hy2://password@server:port?params#name
+// 端口跳跃: hysteria2://password@server:443,20000-30000?params#name 或 mport=20000-30000
 func ParseHysteria2URL(hy2URL string) (*ProxyNode, error) {
 	// 移除协议前缀
 	urlStr := strings.TrimPrefix(hy2URL, "hysteria2://")
@@ -65,7 +70,10 @@ func ParseHysteria2URL(hy2URL string) (*ProxyNode, error) {
 	}
 
 	server := serverParts[0]
-	port := ParseInt(serverParts[1], 443)
+	// 多端口写法中第一个端口作为默认端口
+	portPart := serverParts[1]
+	firstPort, _, _ := strings.Cut(strings.Split(portPart, ",")[0], "-")
+	port := ParseInt(firstPort, 443)
 
 	// 解析查询参数
 	params := ParseQueryParams(queryString)
@@ -77,6 +85,20 @@ func ParseHysteria2URL(hy2URL string) (*ProxyNode, error) {
 		DownMbps: ParseInt(params["down"], 0),
 	}
 
+	// 端口跳跃
+	if strings.ContainsAny(portPart, ",-") {
+		config.ServerPorts = ParsePortRanges(portPart)
+	} else if mport := params["mport"]; mport != "" {
+		config.ServerPorts = ParsePortRanges(mport)
+	}
+	if len(config.ServerPorts) > 0 {
+		hopInterval := params["hop_interval"]
+		if hopInterval == "" {
+			hopInterval = params["hop-interval"]
+		}
+		config.HopInterval = ParseHopInterval(hopInterval)
+	}
+
 	// 解析混淆
 	if obfsType := params["obfs"]; obfsType != "" {
 		config.Obfs = &Hysteria2Obfs{
@@ -103,6 +125,8 @@ func ParseHysteria2URL(hy2URL string) (*ProxyNode, error) {
 		tlsConfig.Insecure = true
 	}
 
+	ParseTLSExtras(params, tlsConfig)
+
 	config.TLS = tlsConfig
 	config.ServerName = tlsConfig.ServerName
 
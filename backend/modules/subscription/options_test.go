@@ -0,0 +1,165 @@
+package subscription
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// lookup 按点分路径读取 JSON 对象中的字段
+func lookup(m map[string]interface{}, path string) (interface{}, bool) {
+	var cur interface{} = m
+	for _, key := range strings.Split(path, ".") {
+		obj, ok := cur.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = obj[key]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func TestShareLinkExtraOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		link    string
+		port    int
+		want    map[string]string // 路径 -> fmt.Sprint 后的值
+		missing []string          // 不应出现的路径
+	}{
+		{
+			name: "hysteria2 多端口写法",
+			link: "hysteria2://pw@example.com:443,20000-30000?sni=a.example.com&hop_interval=30#hy2",
+			port: 443,
+			want: map[string]string{
+				"server_ports": "[443:443 20000:30000]",
+				"hop_interval": "30s",
+			},
+		},
+		{
+			name: "hysteria2 mport 参数",
+			link: "hy2://pw@example.com:8443?mport=20000-30000&hop-interval=15s#hy2",
+			port: 8443,
+			want: map[string]string{
+				"server_ports": "[20000:30000]",
+				"hop_interval": "15s",
+			},
+		},
+		{
+			name:    "hysteria2 单端口",
+			link:    "hysteria2://pw@example.com:443#hy2",
+			port:    443,
+			missing: []string{"server_ports", "hop_interval", "tls.ech"},
+		},
+		{
+			name: "hysteria2 ECH 与 TLS 分片",
+			link: "hysteria2://pw@example.com:443?sni=a.example.com&ech=1&fragment=1&record_fragment=true#hy2",
+			port: 443,
+			want: map[string]string{
+				"tls.ech.enabled":     "true",
+				"tls.fragment":        "true",
+				"tls.record_fragment": "true",
+				"tls.server_name":     "a.example.com",
+			},
+			missing: []string{"tls.ech.config"},
+		},
+		{
+			name: "vless smux 与 ECH 配置",
+			link: "vless://uuid@example.com:443?security=tls&sni=a.example.com&type=tcp&mux=smux&mux_max_streams=8&ech=AEX+abc#vless",
+			port: 443,
+			want: map[string]string{
+				"multiplex.enabled":     "true",
+				"multiplex.protocol":    "smux",
+				"multiplex.max_streams": "8",
+				"tls.ech.enabled":       "true",
+				"tls.ech.config":        "[-----BEGIN ECH CONFIGS----- AEX+abc -----END ECH CONFIGS-----]",
+			},
+		},
+		{
+			name:    "vless vision 流控不启用多路复用",
+			link:    "vless://uuid@example.com:443?security=tls&sni=a.example.com&flow=xtls-rprx-vision&mux=smux#vless",
+			port:    443,
+			missing: []string{"multiplex"},
+		},
+		{
+			name: "trojan h2mux 与填充",
+			link: "trojan://pw@example.com:443?sni=a.example.com&mux=h2mux&mux_padding=1&fragment=1#trojan",
+			port: 443,
+			want: map[string]string{
+				"multiplex.protocol": "h2mux",
+				"multiplex.padding":  "true",
+				"tls.fragment":       "true",
+			},
+		},
+		{
+			name: "shadowsocks 默认多路复用",
+			link: "ss://YWVzLTEyOC1nY206cHc@example.com:8388?mux=1#ss",
+			port: 8388,
+			want: map[string]string{
+				"multiplex.enabled": "true",
+			},
+			missing: []string{"multiplex.protocol"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := ParseURL(tt.link)
+			if err != nil {
+				t.Fatalf("ParseURL: %v", err)
+			}
+			if node.ServerPort != tt.port {
+				t.Errorf("port = %d, want %d", node.ServerPort, tt.port)
+			}
+
+			var config map[string]interface{}
+			if err := json.Unmarshal([]byte(node.Config), &config); err != nil {
+				t.Fatalf("config: %v", err)
+			}
+			for path, want := range tt.want {
+				got, ok := lookup(config, path)
+				if !ok {
+					t.Errorf("%s missing in %s", path, node.Config)
+					continue
+				}
+				if fmt.Sprint(got) != want {
+					t.Errorf("%s = %v, want %s", path, got, want)
+				}
+			}
+			for _, path := range tt.missing {
+				if got, ok := lookup(config, path); ok {
+					t.Errorf("%s = %v, want absent", path, got)
+				}
+			}
+		})
+	}
+}
+
+func TestParsePortRanges(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"20000-30000", "[20000:30000]"},
+		{"443,20000-30000", "[443:443 20000:30000]"},
+		{"20000:30000", "[20000:30000]"},
+		{" 443 , abc, 1-x", "[443:443]"},
+		{"", "[]"},
+	}
+	for _, tt := range tests {
+		if got := fmt.Sprint(ParsePortRanges(tt.in)); got != tt.want {
+			t.Errorf("ParsePortRanges(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseHopInterval(t *testing.T) {
+	for in, want := range map[string]string{"30": "30s", "15s": "15s", "1m": "1m", "": ""} {
+		if got := ParseHopInterval(in); got != want {
+			t.Errorf("ParseHopInterval(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
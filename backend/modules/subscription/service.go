@@ -14,6 +14,8 @@ import (
 
 	"github.com/google/uuid"
 	"gopkg.in/yaml.v3"
+
+	"p-box/backend/events"
 )
 
 type Subscription struct {
@@ -57,6 +59,7 @@ type Service struct {
 	dataDir       string
 	subscriptions map[string]*Subscription
 	stopChan      chan struct{}
+	bus           *events.Bus // 事件总线（订阅更新结果）
 	mu            sync.RWMutex
 }
 
@@ -359,7 +362,32 @@ func (s *Service) UpdateAll() error {
 	return s.saveSubscriptions()
 }
 
+// SetEventBus 设置事件总线
+func (s *Service) SetEventBus(bus *events.Bus) {
+	s.bus = bus
+}
+
+// updateSubscription 拉取订阅并发布更新结果事件
 func (s *Service) updateSubscription(sub *Subscription) error {
+	err := s.fetchSubscription(sub)
+	payload := events.SubscriptionPayload{
+		ID:        sub.ID,
+		Name:      sub.Name,
+		Success:   err == nil,
+		NodeCount: sub.NodeCount,
+	}
+	if err != nil {
+		payload.Error = sub.LastError
+		if payload.Error == "" {
+			payload.Error = err.Error()
+		}
+	}
+	s.bus.Publish(events.SubUpdated, payload)
+	return err
+}
+
+// fetchSubscription 拉取并解析订阅内容
+func (s *Service) fetchSubscription(sub *Subscription) error {
 	// 辅助函数：设置失败状态
 	setFailed := func(errMsg string) {
 		sub.LastUpdateStatus = "failed"
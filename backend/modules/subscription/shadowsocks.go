@@ -14,6 +14,8 @@ type ShadowsocksConfig struct {
 	Plugin     string `json:"plugin,omitempty"`
 	PluginOpts string `json:"plugin-opts,omitempty"` // Mihomo 使用连字符
 	UDP        bool   `json:"udp,omitempty"`
+
+	Multiplex *MultiplexConfig `json:"multiplex,omitempty"`
 }
 
 // ParseShadowsocksURL 解析Shadowsocks链接
@@ -112,6 +114,7 @@ func ParseShadowsocksURL(ssURL string) (*ProxyNode, error) {
 		Plugin:     params["plugin"],
 		PluginOpts: params["plugin-opts"],
 		UDP:        true, // 默认启用 UDP
+		Multiplex:  ParseMultiplex(params),
 	}
 
 	// 转换为JSON字符串
@@ -14,6 +14,7 @@ type TrojanConfig struct {
 	TLS        *TLSConfig             `json:"tls,omitempty"`
 	Transport  map[string]interface{} `json:"transport,omitempty"`
 	ServerName string                 `json:"server_name,omitempty"`
+	Multiplex  *MultiplexConfig       `json:"multiplex,omitempty"`
 }
 
 // ParseTrojanURL 解析Trojan链接
@@ -178,8 +179,11 @@ func ParseTrojanURL(trojanURL string) (*ProxyNode, error) {
 		tlsConfig.Insecure = false // 默认验证证书
 	}
 
+	ParseTLSExtras(params, tlsConfig)
+
 	config.TLS = tlsConfig
 	config.ServerName = tlsConfig.ServerName
+	config.Multiplex = ParseMultiplex(params)
 
 	// 转换为JSON字符串
 	configJSON, err := ToJSONString(config)
@@ -109,6 +109,8 @@ func ParseTUICURL(tuicURL string) (*ProxyNode, error) {
 		tlsConfig.Insecure = true
 	}
 
+	ParseTLSExtras(params, tlsConfig)
+
 	config.TLS = tlsConfig
 	config.ServerName = tlsConfig.ServerName
 
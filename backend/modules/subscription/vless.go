@@ -19,6 +19,7 @@ type VLESSConfig struct {
 	Reality        *RealityConfig         `json:"reality,omitempty"`
 	Transport      map[string]interface{} `json:"transport,omitempty"`
 	ServerName     string                 `json:"server_name,omitempty"`
+	Multiplex      *MultiplexConfig       `json:"multiplex,omitempty"`
 }
 
 // RealityConfig Reality配置
@@ -287,10 +288,17 @@ func ParseVLESSURL(vlessURL string) (*ProxyNode, error) {
 			config.Reality = realityConfig
 		}
 
+		ParseTLSExtras(params, tlsConfig)
+
 		config.TLS = tlsConfig
 		config.ServerName = sni
 	}
 
+	// 多路复用（与 xtls-rprx-vision 流控不兼容）
+	if config.Flow == "" {
+		config.Multiplex = ParseMultiplex(params)
+	}
+
 	// 转换为JSON字符串
 	configJSON, err := ToJSONString(config)
 	if err != nil {
@@ -13,6 +13,9 @@ import (
 // Handler 系统管理 API 处理器
 type Handler struct {
 	service *Service
+
+	// 代理入站地址提供者（出口检测通过该地址请求）
+	proxyAddress func() string
 }
 
 // NewHandler 创建处理器
@@ -22,6 +25,11 @@ func NewHandler(dataDir string) *Handler {
 	}
 }
 
+// SetProxyAddressProvider 设置代理入站地址提供者
+func (h *Handler) SetProxyAddressProvider(provider func() string) {
+	h.proxyAddress = provider
+}
+
 // RegisterRoutes 注册路由
 func (h *Handler) RegisterRoutes(r *gin.RouterGroup) {
 	r.GET("/config", h.GetConfig)
@@ -288,13 +296,18 @@ func findSubstring(s, substr string) bool {
 	return false
 }
 
-// createProxyClient 创建通过 Mihomo 代理的 HTTP 客户端
-func createProxyClient() *http.Client {
-	// 尝试通过 SOCKS5 代理（端口 7891）
-	dialer, err := proxy.SOCKS5("tcp", "127.0.0.1:7890", nil, proxy.Direct)
+// createProxyClient 创建通过核心混合代理入站的 HTTP 客户端
+func (h *Handler) createProxyClient() *http.Client {
+	addr := "127.0.0.1:7890"
+	if h.proxyAddress != nil {
+		addr = h.proxyAddress()
+	}
+
+	// 尝试通过 SOCKS5 代理
+	dialer, err := proxy.SOCKS5("tcp", addr, nil, proxy.Direct)
 	if err != nil {
 		// 如果 SOCKS5 失败，尝试 HTTP 代理
-		proxyURL, _ := url.Parse("http://127.0.0.1:7890")
+		proxyURL, _ := url.Parse("http://" + addr)
 		return &http.Client{
 			Timeout: 10 * time.Second,
 			Transport: &http.Transport{
@@ -314,7 +327,7 @@ func createProxyClient() *http.Client {
 func (h *Handler) GetGeoIP(c *gin.Context) {
 	lang := c.DefaultQuery("lang", "zh")
 	// 通过代理请求，获取代理出口的真实 IP
-	client := createProxyClient()
+	client := h.createProxyClient()
 	var geoInfo GeoIPInfo
 
 	if lang == "zh" {
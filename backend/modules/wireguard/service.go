@@ -11,7 +11,9 @@ import (
 	"sync"
 	"time"
 
+	"p-box/backend/clashapi"
 	"p-box/backend/config"
+	"p-box/backend/events"
 
 	"github.com/google/uuid"
 )
@@ -22,6 +24,12 @@ type Service struct {
 	configPath string
 	config     WireGuardConfig
 	mu         sync.RWMutex
+
+	// 核心控制器客户端提供者（读取运行中的 TUN 配置）
+	controller func() *clashapi.Client
+
+	// 事件总线（接口应用与停止）
+	bus *events.Bus
 }
 
 // NewService 创建服务
@@ -34,6 +42,16 @@ func NewService(dataDir string) *Service {
 	return s
 }
 
+// SetControllerProvider 设置核心控制器客户端提供者
+func (s *Service) SetControllerProvider(provider func() *clashapi.Client) {
+	s.controller = provider
+}
+
+// SetEventBus 设置事件总线
+func (s *Service) SetEventBus(bus *events.Bus) {
+	s.bus = bus
+}
+
 func (s *Service) loadConfig() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -9,6 +9,8 @@ import (
 	"path/filepath"
 	"strings"
 	"time"
+
+	"p-box/backend/events"
 )
 
 // GetTunInterface 获取 Mihomo TUN 接口
@@ -59,6 +61,17 @@ func getLocalNetworks() []string {
 
 // isMihomoAutoRouteEnabled 检查 Mihomo 配置中是否启用了 auto_route
 func (s *Service) isMihomoAutoRouteEnabled() bool {
+	// 优先读取运行中核心的 TUN 配置
+	if s.controller != nil {
+		if configs, err := s.controller().Configs(); err == nil {
+			if tun, ok := configs["tun"].(map[string]interface{}); ok {
+				if autoRoute, ok := tun["auto-route"].(bool); ok {
+					return autoRoute
+				}
+			}
+		}
+	}
+
 	// 读取 mihomo 配置文件
 	configPath := filepath.Join(s.dataDir, "configs", "config.yaml")
 	data, err := os.ReadFile(configPath)
@@ -315,8 +328,22 @@ func (s *Service) ForceCleanupInterface(tag string) {
 	fmt.Printf("✅ 接口 %s 清理完成\n", tag)
 }
 
-// ApplyConfig 应用配置并启动
+// ApplyConfig 应用配置并启动，发布应用结果事件
 func (s *Service) ApplyConfig(serverID string) error {
+	err := s.applyConfig(serverID)
+	payload := events.WireGuardPayload{ServerID: serverID, Success: err == nil}
+	if server, e := s.GetServer(serverID); e == nil {
+		payload.Tag = server.Tag
+	}
+	if err != nil {
+		payload.Error = err.Error()
+	}
+	s.bus.Publish(events.WGApplied, payload)
+	return err
+}
+
+// applyConfig 生成配置并通过 wg-quick 启动接口
+func (s *Service) applyConfig(serverID string) error {
 	server, err := s.GetServer(serverID)
 	if err != nil {
 		return err
@@ -386,6 +413,7 @@ func (s *Service) StopInterface(tag string) error {
 	s.saveConfig()
 	s.mu.Unlock()
 
+	s.bus.Publish(events.WGStopped, events.WireGuardPayload{Tag: tag, Success: true})
 	return nil
 }
 
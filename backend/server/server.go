@@ -2,7 +2,9 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"log"
 	"net/http"
 	"time"
 
@@ -10,10 +12,13 @@ import (
 	"github.com/gin-gonic/gin"
 
 	"p-box/backend/config"
+	"p-box/backend/events"
+	"p-box/backend/logging"
 	"p-box/backend/middleware"
 	"p-box/backend/modules/auth"
 	"p-box/backend/modules/core"
 	"p-box/backend/modules/node"
+	"p-box/backend/modules/notify"
 	"p-box/backend/modules/proxy"
 	"p-box/backend/modules/ruleset"
 	"p-box/backend/modules/speedtest"
@@ -36,7 +41,13 @@ type Server struct {
 	httpServer   *http.Server
 	wsHub        *websocket.Hub
 	proxyHandler *proxy.Handler
+	scheduler    *proxy.Scheduler
+	watchdog     *proxy.Watchdog
+	traffic      *proxy.TrafficCollector
 	authHandler  *auth.Handler
+	logs         *logging.Manager
+	bus          *events.Bus
+	notifier     *notify.Service
 }
 
 // New 创建服务器实例
@@ -55,6 +66,27 @@ func New(cfg *config.Config) *Server {
 		config: cfg,
 		router: router,
 		wsHub:  wsHub,
+		bus:    events.NewBus(),
+	}
+
+	// 日志文件：接管标准输出，P-BOX 与核心日志分别写入轮转文件
+	logs, err := logging.New(logging.Options{
+		Level:      cfg.Log.Level,
+		File:       cfg.Log.File,
+		Console:    cfg.Log.Console,
+		MaxSize:    cfg.Log.MaxSize,
+		MaxAge:     cfg.Log.MaxAge,
+		MaxBackups: cfg.Log.MaxBackups,
+	})
+	if err != nil {
+		fmt.Printf("⚠️ 初始化日志文件失败: %v\n", err)
+	} else {
+		s.logs = logs
+		if err := logs.CaptureStdout(); err != nil {
+			fmt.Printf("⚠️ 接管标准输出失败: %v\n", err)
+		}
+		log.SetOutput(logs.Writer(logging.SourceApp))
+		gin.DefaultErrorWriter = logs.Writer(logging.SourceApp)
 	}
 
 	s.setupMiddleware()
@@ -119,7 +151,14 @@ func (s *Server) setupRoutes() {
 
 		// 代理模块
 		s.proxyHandler = proxy.NewHandler(s.config.DataDir)
+		s.proxyHandler.GetService().SetEventBus(s.bus)
+		if s.logs != nil {
+			s.proxyHandler.GetService().SetLogSink(s.logs.Core)
+			logging.NewHandler(s.logs).RegisterRoutes(api.Group("/logs"))
+		}
 		s.proxyHandler.RegisterRoutes(api.Group("/proxy"))
+		// WebSocket 转发使用代理配置中的控制器地址与密钥
+		s.wsHub.SetControllerProvider(s.proxyHandler.GetService().Controller)
 
 		// 代理设置模块
 		settingsHandler := proxy.NewSettingsHandler(s.config.DataDir)
@@ -132,15 +171,31 @@ func (s *Server) setupRoutes() {
 			return settingsHandler.GetCurrentSettings()
 		})
 
+		// 配置方案模块（设置预设由方案提供）
+		profileManager := proxy.NewProfileManager(s.config.DataDir, s.proxyHandler.GetService(), settingsHandler)
+		settingsHandler.SetProfileManager(profileManager)
+		proxy.NewProfileHandler(profileManager).RegisterRoutes(api.Group("/proxy"))
+
+		// 配置导入模块（节点写入在节点模块初始化后设置）
+		configImporter := proxy.NewConfigImporter(s.proxyHandler.GetService(), settingsHandler)
+		proxy.NewImportHandler(configImporter).RegisterRoutes(api.Group("/proxy"))
+
 		// 检查自动启动
 		s.proxyHandler.GetService().AutoStartIfEnabled()
 
 		// 核心模块
 		coreHandler := core.NewHandler(s.config.DataDir)
 		coreHandler.RegisterRoutes(api.Group("/core"))
-
-		// 设置核心切换回调，同步更新 proxy 模块的核心类型
-		coreHandler.GetService().SetOnCoreSwitch(func(coreType string) {
+		coreHandler.GetService().SetEventBus(s.bus)
+
+		// 核心切换时同步更新 proxy 模块的核心类型
+		s.bus.On(events.CoreSwitched, func(e events.Event) {
+			payload, ok := e.Data.(events.CorePayload)
+			if !ok || payload.Core == "" {
+				fmt.Printf("⚠️ 忽略无效的核心切换事件: %T\n", e.Data)
+				return
+			}
+			coreType := payload.Core
 			s.proxyHandler.GetService().SetCoreType(coreType)
 			fmt.Printf("🔄 核心已切换为: %s\n", coreType)
 		})
@@ -151,6 +206,19 @@ func (s *Server) setupRoutes() {
 		// 订阅模块
 		subHandler := subscription.NewHandler(s.config.DataDir)
 		subHandler.RegisterRoutes(api.Group("/subscriptions"))
+		subHandler.GetService().SetEventBus(s.bus)
+
+		// 通知模块（订阅事件总线，订阅到期信息来自订阅模块）
+		s.notifier = notify.NewService(s.config.DataDir, s.bus)
+		s.notifier.SetSubscriptionProvider(func() []notify.SubscriptionInfo {
+			subs := subHandler.GetService().List()
+			result := make([]notify.SubscriptionInfo, 0, len(subs))
+			for _, sub := range subs {
+				result = append(result, notify.SubscriptionInfo{ID: sub.ID, Name: sub.Name, ExpireTime: sub.ExpireTime})
+			}
+			return result
+		})
+		notify.NewHandler(s.notifier).RegisterRoutes(api.Group("/notify"))
 
 		// 节点模块
 		nodeHandler := node.NewHandler(s.config.DataDir, subHandler.GetService())
@@ -162,23 +230,68 @@ func (s *Server) setupRoutes() {
 			result := make([]proxy.ProxyNode, 0, len(nodes))
 			for _, n := range nodes {
 				result = append(result, proxy.ProxyNode{
-					Name:       n.Name,
-					Type:       n.Type,
-					Server:     n.Server,
-					ServerPort: n.ServerPort,
-					Config:     n.Config,
-					IsManual:   n.IsManual,
+					Name:           n.Name,
+					Type:           n.Type,
+					Server:         n.Server,
+					ServerPort:     n.ServerPort,
+					Config:         n.Config,
+					IsManual:       n.IsManual,
+					SubscriptionID: n.SubscriptionID,
 				})
 			}
 			return result
 		})
 
+		// 导入的节点作为手动节点保存
+		configImporter.SetNodeSink(func(nodes []proxy.ProxyNode) error {
+			inputs := make([]node.ManualNodeInput, 0, len(nodes))
+			for _, n := range nodes {
+				var config map[string]interface{}
+				if err := json.Unmarshal([]byte(n.Config), &config); err != nil {
+					return fmt.Errorf("节点 %s 配置无效: %w", n.Name, err)
+				}
+				inputs = append(inputs, node.ManualNodeInput{Name: n.Name, Type: n.Type, Server: n.Server, Port: n.GetPort(), Config: config})
+			}
+			_, err := nodeHandler.GetService().AddManualBatch(inputs)
+			return err
+		})
+
+		// 定时任务模块（依赖节点提供者生成配置）
+		s.scheduler = proxy.NewScheduler(s.config.DataDir, s.proxyHandler.GetService(), profileManager)
+		proxy.NewSchedulerHandler(s.scheduler).RegisterRoutes(api.Group("/proxy"))
+
+		// 选择器故障转移模块
+		s.watchdog = proxy.NewWatchdog(s.config.DataDir, s.proxyHandler.GetService())
+		proxy.NewFailoverHandler(s.watchdog).RegisterRoutes(api.Group("/proxy"))
+
+		// 流量统计模块（订阅配额来自订阅模块）
+		s.traffic = proxy.NewTrafficCollector(s.config.DataDir, s.proxyHandler.GetService())
+		s.traffic.SetQuotaProvider(func() []proxy.SubscriptionQuota {
+			subs := subHandler.GetService().List()
+			result := make([]proxy.SubscriptionQuota, 0, len(subs))
+			for _, sub := range subs {
+				quota := proxy.SubscriptionQuota{ID: sub.ID, Name: sub.Name, ExpireTime: sub.ExpireTime}
+				if sub.Traffic != nil {
+					quota.Upload = sub.Traffic.Upload
+					quota.Download = sub.Traffic.Download
+					quota.Total = sub.Traffic.Total
+				}
+				result = append(result, quota)
+			}
+			return result
+		})
+		proxy.NewTrafficHandler(s.traffic).RegisterRoutes(api.Group("/proxy"))
+
 		// 系统管理模块
 		systemHandler := system.NewHandler(s.config.DataDir)
+		systemHandler.SetProxyAddressProvider(func() string {
+			return fmt.Sprintf("127.0.0.1:%d", s.proxyHandler.GetService().GetConfig().MixedPort)
+		})
 		systemHandler.RegisterRoutes(api.Group("/system"))
 
 		// 规则集模块 (Mihomo)
 		rulesetService := ruleset.NewService(s.config.DataDir)
+		rulesetService.SetEventBus(s.bus)
 		rulesetHandler := ruleset.NewHandler(rulesetService)
 		rulesetHandler.RegisterRoutes(api)
 
@@ -192,11 +305,13 @@ func (s *Server) setupRoutes() {
 
 		// WireGuard 模块
 		wgService := wireguard.NewService(s.config.DataDir)
+		wgService.SetControllerProvider(s.proxyHandler.GetService().Controller)
+		wgService.SetEventBus(s.bus)
 		wgHandler := wireguard.NewHandler(wgService)
 		wgHandler.RegisterRoutes(api)
 
-		// 监听 VPN 启动事件，延迟 5 秒后启动 WireGuard
-		s.proxyHandler.GetService().SetOnStartCallback(func() {
+		// 监听核心启动事件，延迟 5 秒后启动 WireGuard
+		s.bus.On(events.CoreStarted, func(events.Event) {
 			go func() {
 				time.Sleep(5 * time.Second)
 				wgService.AutoStartIfEnabled()
@@ -207,9 +322,14 @@ func (s *Server) setupRoutes() {
 	// WebSocket 路由
 	ws := s.router.Group("/ws")
 	{
-		ws.GET("/traffic", s.wsHub.HandleTraffic)
-		ws.GET("/logs", s.wsHub.HandleLogs)
-		ws.GET("/connections", s.wsHub.HandleConnections)
+		// 转发与推送均需要认证（转发时携带控制器密钥；浏览器通过 cookie 或 token 参数携带令牌）
+		ws.GET("/traffic", s.authHandler.AuthMiddleware(), s.wsHub.HandleTraffic)
+		ws.GET("/logs", s.authHandler.AuthMiddleware(), s.wsHub.HandleLogs)
+		ws.GET("/connections", s.authHandler.AuthMiddleware(), s.wsHub.HandleConnections)
+		if s.logs != nil {
+			ws.GET("/logs/tail", s.authHandler.AuthMiddleware(), logging.NewHandler(s.logs).HandleTail)
+		}
+		ws.GET("/events", s.authHandler.AuthMiddleware(), events.NewHandler(s.bus).HandleWebSocket)
 	}
 
 	// 前端路由 fallback (SPA)
@@ -259,6 +379,20 @@ func (s *Server) Start() error {
 
 // Shutdown 关闭服务器
 func (s *Server) Shutdown() {
+	// 停止定时任务与故障转移，避免关闭过程中重新启动或切换核心
+	if s.scheduler != nil {
+		s.scheduler.Stop()
+	}
+	if s.watchdog != nil {
+		s.watchdog.Stop()
+	}
+	if s.traffic != nil {
+		s.traffic.Stop()
+	}
+	if s.notifier != nil {
+		s.notifier.Stop()
+	}
+
 	// 先停止代理核心
 	if s.proxyHandler != nil {
 		fmt.Println("正在停止代理核心...")
@@ -276,4 +410,9 @@ func (s *Server) Shutdown() {
 	if s.httpServer != nil {
 		s.httpServer.Shutdown(ctx)
 	}
+
+	// 最后关闭日志文件并恢复标准输出
+	if s.logs != nil {
+		s.logs.Close()
+	}
 }
@@ -6,6 +6,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+
+	"p-box/backend/clashapi"
 )
 
 var upgrader = websocket.Upgrader{
@@ -17,13 +19,29 @@ var upgrader = websocket.Upgrader{
 }
 
 // Hub WebSocket 连接管理中心 (代理模式)
-type Hub struct{}
+type Hub struct {
+	// 控制器客户端提供者（地址与密钥取自代理配置）
+	controller func() *clashapi.Client
+}
 
 // NewHub 创建 Hub
 func NewHub() *Hub {
 	return &Hub{}
 }
 
+// SetControllerProvider 设置控制器客户端提供者
+func (h *Hub) SetControllerProvider(provider func() *clashapi.Client) {
+	h.controller = provider
+}
+
+// client 获取控制器客户端，未设置提供者时使用默认地址
+func (h *Hub) client() *clashapi.Client {
+	if h.controller != nil {
+		return h.controller()
+	}
+	return clashapi.New("", "")
+}
+
 // Run 运行 Hub (保留接口兼容)
 func (h *Hub) Run() {
 	// 代理模式不需要运行循环
@@ -56,18 +74,10 @@ func (h *Hub) proxyMihomoWebSocket(c *gin.Context, path string) {
 	}
 	defer clientConn.Close()
 
-	// 连接到 Mihomo WebSocket
-	// Mihomo API 默认监听 127.0.0.1:9090
-	mihomoURL := "ws://127.0.0.1:9090" + path
-
-	// 转发所有查询参数 (token, level 等)
-	queryString := c.Request.URL.RawQuery
-	if queryString != "" {
-		mihomoURL += "?" + queryString
-	}
-
-	log.Printf("[WebSocket] 连接 Mihomo: %s", mihomoURL)
-	mihomoConn, _, err := websocket.DefaultDialer.Dial(mihomoURL, nil)
+	// 连接到核心 WebSocket，转发所有查询参数 (level 等)，密钥通过请求头传递
+	controller := h.client()
+	log.Printf("[WebSocket] 连接 Mihomo: ws://%s%s", controller.Address(), path)
+	mihomoConn, err := controller.DialWebSocket(path, c.Request.URL.RawQuery)
 	if err != nil {
 		log.Printf("[WebSocket] 连接 Mihomo 失败: %v", err)
 		clientConn.WriteMessage(websocket.TextMessage, []byte(`{"error":"无法连接到 Mihomo: `+err.Error()+`"}`))
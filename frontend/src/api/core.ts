@@ -43,9 +43,11 @@ export const coreApi = {
     await client.post('/core/switch', { coreType })
   },
 
-  // Download core
-  downloadCore: async (coreType: string): Promise<void> => {
-    await client.post(`/core/download/${coreType}`)
+  // Download core (allowUnverified installs even when the release checksum cannot be fetched)
+  downloadCore: async (coreType: string, allowUnverified = false): Promise<void> => {
+    await client.post(`/core/download/${coreType}`, null, {
+      params: allowUnverified ? { allowUnverified: true } : undefined,
+    })
   },
 
   // Get download progress
@@ -3,10 +3,13 @@
 // Use backend proxy (avoid CORS issues)
 const getProxyApiBase = () => '/api/proxy/mihomo'
 
-// Direct access to Mihomo API (only for WebSocket)
-const getDirectApiBase = () => {
-  const host = window.location.hostname || '127.0.0.1'
-  return `http://${host}:9090`
+// Core controller API forwarded by backend (backend adds the controller secret)
+const getDirectApiBase = () => '/api/proxy/core-api'
+
+// WebSocket cannot set headers, so the login token is passed as a query parameter
+const wsAuthQuery = (prefix: '?' | '&' = '?') => {
+  const token = localStorage.getItem('p-box-token')
+  return token ? `${prefix}token=${encodeURIComponent(token)}` : ''
 }
 
 export interface ProxyNode {
@@ -159,7 +162,7 @@ export const mihomoApi = {
   createConnectionsWs(onMessage: (data: unknown) => void): WebSocket {
     const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:'
     const host = window.location.host
-    const ws = new WebSocket(`${protocol}//${host}/ws/connections`)
+    const ws = new WebSocket(`${protocol}//${host}/ws/connections${wsAuthQuery()}`)
     ws.onmessage = (e) => {
       try {
         const data = JSON.parse(e.data)
@@ -175,7 +178,7 @@ export const mihomoApi = {
   createTrafficWs(onMessage: (data: { up: number; down: number }) => void): WebSocket {
     const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:'
     const host = window.location.host
-    const ws = new WebSocket(`${protocol}//${host}/ws/traffic`)
+    const ws = new WebSocket(`${protocol}//${host}/ws/traffic${wsAuthQuery()}`)
     ws.onmessage = (e) => {
       try {
         const data = JSON.parse(e.data)
@@ -191,7 +194,7 @@ export const mihomoApi = {
   createLogsWs(onMessage: (data: LogEntry) => void, level = 'info'): WebSocket {
     const protocol = window.location.protocol === 'https:' ? 'wss:' : 'ws:'
     const host = window.location.host
-    const ws = new WebSocket(`${protocol}//${host}/ws/logs?level=${level}`)
+    const ws = new WebSocket(`${protocol}//${host}/ws/logs?level=${level}${wsAuthQuery('&')}`)
     ws.onmessage = (e) => {
       try {
         const data = JSON.parse(e.data)